@@ -17,15 +17,22 @@ package settings
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/pkg/configmap"
 
 	"github.com/aws/karpenter-core/pkg/apis/config"
+	"github.com/aws/karpenter-core/pkg/utils/maintenancewindow"
 )
 
 var ContextKey = Registration
@@ -35,14 +42,152 @@ var Registration = &config.Registration{
 	Constructor:   NewSettingsFromConfigMap,
 }
 
+const (
+	// ConsolidationPolicyDeleteOrReplace allows consolidation to delete nodes or replace them with cheaper nodes
+	ConsolidationPolicyDeleteOrReplace = "DeleteOrReplace"
+	// ConsolidationPolicyDeleteOnly restricts consolidation to deleting nodes whose pods fit on existing capacity
+	ConsolidationPolicyDeleteOnly = "DeleteOnly"
+)
+
 var defaultSettings = Settings{
-	BatchMaxDuration:  metav1.Duration{Duration: time.Second * 10},
-	BatchIdleDuration: metav1.Duration{Duration: time.Second * 1},
+	BatchMaxDuration:               metav1.Duration{Duration: time.Second * 10},
+	BatchIdleDuration:              metav1.Duration{Duration: time.Second * 1},
+	ConsolidationOscillationWindow: metav1.Duration{Duration: time.Hour},
+	DrainFinalizerTimeout:          metav1.Duration{Duration: time.Minute * 5},
+	EvictionQoSFactors:             EvictionQoSFactors{Guaranteed: 1.5, Burstable: 1, BestEffort: 0.75},
+	ConsolidationPolicy:            ConsolidationPolicyDeleteOrReplace,
+	ConsolidationScaleUpThreshold:  10,
+	IdleUsageThreshold:             0.05,
+	OwnerDisruptionWindow:          metav1.Duration{Duration: time.Hour},
+	SimulationConcurrency:          1,
+	StatefulSetEvictionCostFactor:  2,
 }
 
 type Settings struct {
 	BatchMaxDuration  metav1.Duration `json:"batchMaxDuration"`
 	BatchIdleDuration metav1.Duration `json:"batchIdleDuration"`
+	// ConsolidationCapacityFloor is the total capacity of the cluster's nodes below which consolidation won't reduce
+	// it, so that there's headroom for bursts. It's parsed from a comma separated list of resource=quantity pairs.
+	ConsolidationCapacityFloor v1.ResourceList `json:"consolidationCapacityFloor"`
+	// ConsolidationCascadeLimit is the number of additional passes that deprovisioning makes over the cluster after a
+	// successful action, so that consolidations enabled by that action happen without waiting for the next trigger.
+	// Zero disables the additional passes.
+	ConsolidationCascadeLimit int `json:"consolidationCascadeLimit"`
+	// ConsolidationOscillationWindow is how long consolidation remembers the instance types that it replaced, so that a
+	// replacement that reverses a recent one (e.g. because prices are flapping) is reported. Zero disables detection.
+	ConsolidationOscillationWindow metav1.Duration `json:"consolidationOscillationWindow"`
+	// ConsolidationPolicy controls whether consolidation can launch replacement nodes, or may only delete nodes
+	ConsolidationPolicy string `json:"consolidationPolicy"`
+	// ConsolidationScaleUpCooldown is how long consolidation is suppressed after at least ConsolidationScaleUpThreshold
+	// nodes launched within it, since consolidating right after a large scale-up is likely premature. Zero disables
+	// the cool-down.
+	ConsolidationScaleUpCooldown  metav1.Duration `json:"consolidationScaleUpCooldown"`
+	ConsolidationScaleUpThreshold int             `json:"consolidationScaleUpThreshold"`
+	// ConsolidationSpotFallbackWeight is the fraction of the difference between an instance type's on-demand and spot
+	// prices that's added to its spot price when pricing a consolidation replacement that may launch as either, since
+	// spot capacity may be unavailable. One prices the replacement as on-demand in the worst case, and zero disables it.
+	ConsolidationSpotFallbackWeight float64 `json:"consolidationSpotFallbackWeight"`
+	// ConsolidationValidationPeriod is how long consolidation waits after computing a command before validating that it
+	// still works, trading responsiveness for churn. Zero uses the default of fifteen seconds.
+	ConsolidationValidationPeriod metav1.Duration `json:"consolidationValidationPeriod"`
+	// CrashLoopingPodWindow is how long a pod must have been crash looping without becoming ready before deprovisioning
+	// treats it as providing no service, so that it doesn't prevent its node from being considered empty or from being
+	// consolidated. Zero disables it.
+	CrashLoopingPodWindow metav1.Duration `json:"crashLoopingPodWindow"`
+	// DeprovisioningCooldowns is the minimum time between consecutive actions of a deprovisioner (e.g. expiration or
+	// consolidation), so that each kind of deprovisioning is paced independently. It's parsed from a comma separated
+	// list of deprovisioner=duration pairs, and deprovisioners that aren't listed have no cool-down.
+	DeprovisioningCooldowns map[string]metav1.Duration `json:"deprovisioningCooldowns"`
+	// DisruptionCostResourceWeights scales the disruption cost of a node by one plus the sum of each resource's weight
+	// times the node's capacity of that resource, so that consolidation prefers to keep nodes whose resources are hard
+	// to replace (e.g. GPUs). It's parsed from a comma separated list of resource=weight pairs.
+	DisruptionCostResourceWeights map[string]float64 `json:"disruptionCostResourceWeights"`
+	// DrainExclusionSelector selects pods that are never evicted when their node is drained, e.g. monitoring agents.
+	// Nodes with these pods aren't deprovisioned. It's parsed from a label selector string and selects nothing if unset.
+	DrainExclusionSelector labels.Selector `json:"drainExclusionSelector"`
+	// DrainReevictionDelay is how long after its grace period a terminating pod on a draining node is deleted again with
+	// the shorter DrainReevictionGracePeriod. Zero disables re-eviction.
+	DrainReevictionDelay       metav1.Duration `json:"drainReevictionDelay"`
+	DrainReevictionGracePeriod metav1.Duration `json:"drainReevictionGracePeriod"`
+	// DrainForceDeleteDelay is how long after its grace period a terminating pod on a draining node is force deleted.
+	// Zero disables force deletion, in which case the drain stops waiting for a pod a minute after its grace period but
+	// leaves it to the kubelet. Force deleting a pod on a partitioned kubelet that's still running it means that its
+	// controller may replace it while it's still running, e.g. leaving two copies of a StatefulSet pod.
+	DrainForceDeleteDelay metav1.Duration `json:"drainForceDeleteDelay"`
+	// DrainFinalizerTimeout is how long after its grace period a draining node waits for a terminating pod that's held by
+	// its finalizers, since force deleting the pod doesn't remove it, before the drain proceeds without it
+	DrainFinalizerTimeout metav1.Duration `json:"drainFinalizerTimeout"`
+	// DriftEnabled is the feature flag for replacing nodes whose backing configuration the cloud provider reports has
+	// drifted from their provisioner's configuration
+	DriftEnabled bool `json:"driftEnabled"`
+	// EvictionQoSFactors scale the eviction cost of pods by their QoS class, so that consolidation prefers to disrupt
+	// pods whose eviction is less likely to cause latency regressions. It's parsed from a comma separated list of
+	// class=factor pairs, e.g. "Guaranteed=1.5, BestEffort=0.75", and classes that aren't listed keep their defaults.
+	EvictionQoSFactors EvictionQoSFactors `json:"evictionQoSFactors"`
+	// HonorSafeToEvictAnnotation treats the cluster-autoscaler safe-to-evict pod annotation like Karpenter's do-not-evict
+	HonorSafeToEvictAnnotation bool `json:"honorSafeToEvictAnnotation"`
+	// IdleUsageThreshold is the fraction of a node's allocatable resources below which its actual usage is considered
+	// idle when a usage source is available to emptiness
+	IdleUsageThreshold float64 `json:"idleUsageThreshold"`
+	// LoadBalancerDrainDelay is how long a terminating node waits after it's excluded from load balancers before its
+	// pods are evicted, so that load balancers can deregister it without dropping in-flight connections
+	LoadBalancerDrainDelay metav1.Duration `json:"loadBalancerDrainDelay"`
+	// MaintenanceWindows restrict deprovisioning to the times that they contain, so that disruptive actions only happen
+	// during approved maintenance periods. It's parsed from a comma separated list of windows in UTC, each an optional
+	// day or range of days followed by a range of times, e.g. "Mon-Fri 22:00-04:00, Sat 00:00-24:00". Windows whose
+	// end is before their start continue into the next day. Deprovisioning is unrestricted if it's empty.
+	MaintenanceWindows []maintenancewindow.Window `json:"maintenanceWindows"`
+	// MaxConsolidationSizeRatio caps the CPU and memory of a consolidation replacement at this multiple of the capacity
+	// that it replaces, so that many small nodes aren't replaced by one enormous node. Zero disables the cap.
+	MaxConsolidationSizeRatio float64 `json:"maxConsolidationSizeRatio"`
+	// MinConsolidationSavingsPercent is the percentage of the price of the nodes being replaced that a consolidation
+	// replacement must save. Provisioners can override it for their nodes.
+	MinConsolidationSavingsPercent int `json:"minConsolidationSavingsPercent"`
+	// MinNodeLifetime is the minimum amount of time that a node lives before it can be expired, regardless of its TTL
+	MinNodeLifetime metav1.Duration `json:"minNodeLifetime"`
+	// SpreadConsolidationTies randomly breaks ties between equally disruptive consolidation candidates so that
+	// repeated consolidations don't always target the same node
+	SpreadConsolidationTies bool `json:"spreadConsolidationTies"`
+	// OwnerDisruptionLimit is the number of times that the pods of an owner (e.g. a ReplicaSet) can be disrupted by
+	// deprovisioning within the OwnerDisruptionWindow before further disruptions back off. Zero disables the limit.
+	OwnerDisruptionLimit  int             `json:"ownerDisruptionLimit"`
+	OwnerDisruptionWindow metav1.Duration `json:"ownerDisruptionWindow"`
+	// PinResourceClaimPods treats pods that have dynamically allocated resource claims as unable to move, so that
+	// consolidation never relies on another node being able to satisfy their claims
+	PinResourceClaimPods bool `json:"pinResourceClaimPods"`
+	// PreserveCapacityType restricts consolidation replacements to the capacity types of the nodes being replaced,
+	// unless the Provisioner allows capacity type changes through its annotation
+	PreserveCapacityType bool `json:"preserveCapacityType"`
+	// ReplacementInstanceTypes restricts the instance types that may be launched as replacements for deprovisioned nodes,
+	// in addition to the Provisioner's requirements. All instance types are allowed if it's empty.
+	ReplacementInstanceTypes sets.String `json:"replacementInstanceTypes"`
+	// RescheduleVerificationThreshold is how long the pods displaced by deleting nodes without replacements may stay
+	// pending before it's reported that they didn't reschedule to existing nodes as the simulation expected. Zero
+	// disables verification.
+	RescheduleVerificationThreshold metav1.Duration `json:"rescheduleVerificationThreshold"`
+	// SessionAffinityDrainDelay is how long a terminating node waits after it's cordoned before its pods are evicted
+	// when it hosts pods behind a Service with ClientIP session affinity, so that sticky sessions have time to migrate
+	// to other endpoints. Zero disables the delay.
+	SessionAffinityDrainDelay metav1.Duration `json:"sessionAffinityDrainDelay"`
+	// SimulationConcurrency is the number of consolidation candidates whose reschedule simulations are run in parallel
+	SimulationConcurrency int `json:"simulationConcurrency"`
+	// StatefulSetEvictionCostFactor scales the eviction cost of pods that are controlled by a StatefulSet, since their
+	// persistent identity and storage make them riskier to evict than other pods. One disables it.
+	StatefulSetEvictionCostFactor float64 `json:"statefulSetEvictionCostFactor"`
+	// SuppressConsolidationOscillation skips consolidation replacements that would reverse a recent replacement, rather
+	// than only reporting them
+	SuppressConsolidationOscillation bool `json:"suppressConsolidationOscillation"`
+	// TerminationHardLimit is how long a node can take to terminate after it's cordoned before a warning event is
+	// published for it, so that stuck terminations (e.g. finalizers or long drains) can be spotted. Zero disables it.
+	TerminationHardLimit metav1.Duration `json:"terminationHardLimit"`
+	// ValidationTaintEffect is the effect of a taint that's applied to the nodes that consolidation will remove while it
+	// waits to validate the command, so that the scheduler avoids them before they're cordoned when the command
+	// executes. PreferNoSchedule still lets pods schedule to the nodes if there's nowhere else for them. Empty disables it.
+	ValidationTaintEffect string `json:"validationTaintEffect"`
+	// ZonalConsolidation restricts multi-node consolidation to merging nodes within the same zone, and keeps
+	// replacement nodes in the zone of the nodes that they replace, so that consolidation doesn't move pods across zones
+	// unless a provisioner allows cross zone consolidation
+	ZonalConsolidation bool `json:"zonalConsolidation"`
 }
 
 // NewSettingsFromConfigMap creates a Settings from the supplied ConfigMap
@@ -52,6 +197,45 @@ func NewSettingsFromConfigMap(cm *v1.ConfigMap) (Settings, error) {
 	if err := configmap.Parse(cm.Data,
 		AsMetaDuration("batchMaxDuration", &s.BatchMaxDuration),
 		AsMetaDuration("batchIdleDuration", &s.BatchIdleDuration),
+		AsResourceList("consolidationCapacityFloor", &s.ConsolidationCapacityFloor),
+		configmap.AsInt("consolidationCascadeLimit", &s.ConsolidationCascadeLimit),
+		AsMetaDuration("consolidationOscillationWindow", &s.ConsolidationOscillationWindow),
+		configmap.AsString("consolidationPolicy", &s.ConsolidationPolicy),
+		AsMetaDuration("consolidationScaleUpCooldown", &s.ConsolidationScaleUpCooldown),
+		configmap.AsInt("consolidationScaleUpThreshold", &s.ConsolidationScaleUpThreshold),
+		configmap.AsFloat64("consolidationSpotFallbackWeight", &s.ConsolidationSpotFallbackWeight),
+		AsMetaDuration("consolidationValidationPeriod", &s.ConsolidationValidationPeriod),
+		AsMetaDuration("crashLoopingPodWindow", &s.CrashLoopingPodWindow),
+		AsDurationMap("deprovisioningCooldowns", &s.DeprovisioningCooldowns),
+		AsFloat64Map("disruptionCostResourceWeights", &s.DisruptionCostResourceWeights),
+		AsSelector("drainExclusionSelector", &s.DrainExclusionSelector),
+		AsMetaDuration("drainReevictionDelay", &s.DrainReevictionDelay),
+		AsMetaDuration("drainReevictionGracePeriod", &s.DrainReevictionGracePeriod),
+		AsMetaDuration("drainForceDeleteDelay", &s.DrainForceDeleteDelay),
+		AsMetaDuration("drainFinalizerTimeout", &s.DrainFinalizerTimeout),
+		configmap.AsBool("driftEnabled", &s.DriftEnabled),
+		AsEvictionQoSFactors("evictionQoSFactors", &s.EvictionQoSFactors),
+		configmap.AsBool("honorSafeToEvictAnnotation", &s.HonorSafeToEvictAnnotation),
+		configmap.AsFloat64("idleUsageThreshold", &s.IdleUsageThreshold),
+		AsMetaDuration("loadBalancerDrainDelay", &s.LoadBalancerDrainDelay),
+		AsMaintenanceWindows("maintenanceWindows", &s.MaintenanceWindows),
+		configmap.AsFloat64("maxConsolidationSizeRatio", &s.MaxConsolidationSizeRatio),
+		configmap.AsInt("minConsolidationSavingsPercent", &s.MinConsolidationSavingsPercent),
+		AsMetaDuration("minNodeLifetime", &s.MinNodeLifetime),
+		configmap.AsInt("ownerDisruptionLimit", &s.OwnerDisruptionLimit),
+		AsMetaDuration("ownerDisruptionWindow", &s.OwnerDisruptionWindow),
+		configmap.AsBool("pinResourceClaimPods", &s.PinResourceClaimPods),
+		configmap.AsBool("preserveCapacityType", &s.PreserveCapacityType),
+		configmap.AsStringSet("replacementInstanceTypes", &s.ReplacementInstanceTypes),
+		AsMetaDuration("rescheduleVerificationThreshold", &s.RescheduleVerificationThreshold),
+		AsMetaDuration("sessionAffinityDrainDelay", &s.SessionAffinityDrainDelay),
+		configmap.AsInt("simulationConcurrency", &s.SimulationConcurrency),
+		configmap.AsBool("spreadConsolidationTies", &s.SpreadConsolidationTies),
+		configmap.AsFloat64("statefulSetEvictionCostFactor", &s.StatefulSetEvictionCostFactor),
+		configmap.AsBool("suppressConsolidationOscillation", &s.SuppressConsolidationOscillation),
+		AsMetaDuration("terminationHardLimit", &s.TerminationHardLimit),
+		configmap.AsString("validationTaintEffect", &s.ValidationTaintEffect),
+		configmap.AsBool("zonalConsolidation", &s.ZonalConsolidation),
 	); err != nil {
 		// Failing to parse means that there is some error in the Settings, so we should crash
 		panic(fmt.Sprintf("parsing settings, %v", err))
@@ -77,6 +261,96 @@ func (s Settings) Validate() (err error) {
 	if s.BatchIdleDuration.Duration <= 0 {
 		err = multierr.Append(err, fmt.Errorf("batchMaxDuration cannot be negative"))
 	}
+	for resourceName, quantity := range s.ConsolidationCapacityFloor {
+		if quantity.Sign() < 0 {
+			err = multierr.Append(err, fmt.Errorf("consolidationCapacityFloor for %s cannot be negative", resourceName))
+		}
+	}
+	if s.ConsolidationCascadeLimit < 0 {
+		err = multierr.Append(err, fmt.Errorf("consolidationCascadeLimit cannot be negative"))
+	}
+	if s.ConsolidationOscillationWindow.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("consolidationOscillationWindow cannot be negative"))
+	}
+	if s.ConsolidationPolicy != ConsolidationPolicyDeleteOrReplace && s.ConsolidationPolicy != ConsolidationPolicyDeleteOnly {
+		err = multierr.Append(err, fmt.Errorf("consolidationPolicy must be one of %s, %s", ConsolidationPolicyDeleteOrReplace, ConsolidationPolicyDeleteOnly))
+	}
+	if s.ConsolidationScaleUpCooldown.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("consolidationScaleUpCooldown cannot be negative"))
+	}
+	if s.ConsolidationScaleUpThreshold <= 0 {
+		err = multierr.Append(err, fmt.Errorf("consolidationScaleUpThreshold must be positive"))
+	}
+	if s.ConsolidationSpotFallbackWeight < 0 || s.ConsolidationSpotFallbackWeight > 1 {
+		err = multierr.Append(err, fmt.Errorf("consolidationSpotFallbackWeight must be between 0 and 1"))
+	}
+	if s.ConsolidationValidationPeriod.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("consolidationValidationPeriod cannot be negative"))
+	}
+	if s.CrashLoopingPodWindow.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("crashLoopingPodWindow cannot be negative"))
+	}
+	for deprovisioner, cooldown := range s.DeprovisioningCooldowns {
+		if cooldown.Duration < 0 {
+			err = multierr.Append(err, fmt.Errorf("deprovisioningCooldowns for %s cannot be negative", deprovisioner))
+		}
+	}
+	for resourceName, weight := range s.DisruptionCostResourceWeights {
+		if weight < 0 {
+			err = multierr.Append(err, fmt.Errorf("disruptionCostResourceWeights for %s cannot be negative", resourceName))
+		}
+	}
+	if s.DrainReevictionDelay.Duration < 0 || s.DrainReevictionGracePeriod.Duration < 0 || s.DrainForceDeleteDelay.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("drainReevictionDelay, drainReevictionGracePeriod and drainForceDeleteDelay cannot be negative"))
+	}
+	if s.DrainFinalizerTimeout.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("drainFinalizerTimeout cannot be negative"))
+	}
+	if s.DrainReevictionDelay.Duration > 0 && s.DrainForceDeleteDelay.Duration > 0 && s.DrainReevictionDelay.Duration >= s.DrainForceDeleteDelay.Duration {
+		err = multierr.Append(err, fmt.Errorf("drainReevictionDelay must be less than drainForceDeleteDelay"))
+	}
+	if s.EvictionQoSFactors.Guaranteed <= 0 || s.EvictionQoSFactors.Burstable <= 0 || s.EvictionQoSFactors.BestEffort <= 0 {
+		err = multierr.Append(err, fmt.Errorf("evictionQoSFactors must be positive"))
+	}
+	if s.IdleUsageThreshold < 0 || s.IdleUsageThreshold > 1 {
+		err = multierr.Append(err, fmt.Errorf("idleUsageThreshold must be between 0 and 1"))
+	}
+	if s.LoadBalancerDrainDelay.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("loadBalancerDrainDelay cannot be negative"))
+	}
+	if s.MaxConsolidationSizeRatio < 0 {
+		err = multierr.Append(err, fmt.Errorf("maxConsolidationSizeRatio cannot be negative"))
+	}
+	if s.MinConsolidationSavingsPercent < 0 || s.MinConsolidationSavingsPercent > 99 {
+		err = multierr.Append(err, fmt.Errorf("minConsolidationSavingsPercent must be between 0 and 99"))
+	}
+	if s.MinNodeLifetime.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("minNodeLifetime cannot be negative"))
+	}
+	if s.OwnerDisruptionLimit < 0 {
+		err = multierr.Append(err, fmt.Errorf("ownerDisruptionLimit cannot be negative"))
+	}
+	if s.OwnerDisruptionWindow.Duration <= 0 {
+		err = multierr.Append(err, fmt.Errorf("ownerDisruptionWindow must be positive"))
+	}
+	if s.RescheduleVerificationThreshold.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("rescheduleVerificationThreshold cannot be negative"))
+	}
+	if s.SessionAffinityDrainDelay.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("sessionAffinityDrainDelay cannot be negative"))
+	}
+	if s.SimulationConcurrency < 1 {
+		err = multierr.Append(err, fmt.Errorf("simulationConcurrency must be at least 1"))
+	}
+	if s.StatefulSetEvictionCostFactor <= 0 {
+		err = multierr.Append(err, fmt.Errorf("statefulSetEvictionCostFactor must be positive"))
+	}
+	if s.TerminationHardLimit.Duration < 0 {
+		err = multierr.Append(err, fmt.Errorf("terminationHardLimit cannot be negative"))
+	}
+	if s.ValidationTaintEffect != "" && s.ValidationTaintEffect != string(v1.TaintEffectPreferNoSchedule) && s.ValidationTaintEffect != string(v1.TaintEffectNoSchedule) {
+		err = multierr.Append(err, fmt.Errorf("validationTaintEffect must be one of %s, %s", v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoSchedule))
+	}
 	return multierr.Append(err, validate.Struct(s))
 }
 
@@ -94,6 +368,157 @@ func AsMetaDuration(key string, target *metav1.Duration) configmap.ParseFunc {
 	}
 }
 
+// AsSelector parses the value at key as a label selector into the target, if it exists.
+func AsSelector(key string, target *labels.Selector) configmap.ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok {
+			selector, err := labels.Parse(raw)
+			if err != nil {
+				return fmt.Errorf("failed to parse %q: %w", key, err)
+			}
+			*target = selector
+		}
+		return nil
+	}
+}
+
+// AsFloat64Map parses a comma separated list of key=value pairs into a map of float64 values
+func AsFloat64Map(key string, target *map[string]float64) configmap.ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok {
+			m := map[string]float64{}
+			for _, pair := range strings.Split(raw, ",") {
+				k, v, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if !found {
+					return fmt.Errorf("failed to parse %q: %q is not a key=value pair", key, pair)
+				}
+				val, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil {
+					return fmt.Errorf("failed to parse %q: %w", key, err)
+				}
+				m[strings.TrimSpace(k)] = val
+			}
+			*target = m
+		}
+		return nil
+	}
+}
+
+// AsDurationMap parses a comma separated list of key=duration pairs into a map of durations
+func AsDurationMap(key string, target *map[string]metav1.Duration) configmap.ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok {
+			m := map[string]metav1.Duration{}
+			for _, pair := range strings.Split(raw, ",") {
+				k, v, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if !found {
+					return fmt.Errorf("failed to parse %q: %q is not a key=duration pair", key, pair)
+				}
+				val, err := time.ParseDuration(strings.TrimSpace(v))
+				if err != nil {
+					return fmt.Errorf("failed to parse %q: %w", key, err)
+				}
+				m[strings.TrimSpace(k)] = metav1.Duration{Duration: val}
+			}
+			*target = m
+		}
+		return nil
+	}
+}
+
+// AsResourceList parses a comma separated list of resource=quantity pairs into a resource list
+func AsResourceList(key string, target *v1.ResourceList) configmap.ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok {
+			list := v1.ResourceList{}
+			for _, pair := range strings.Split(raw, ",") {
+				k, v, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if !found {
+					return fmt.Errorf("failed to parse %q: %q is not a resource=quantity pair", key, pair)
+				}
+				quantity, err := resource.ParseQuantity(strings.TrimSpace(v))
+				if err != nil {
+					return fmt.Errorf("failed to parse %q: %w", key, err)
+				}
+				list[v1.ResourceName(strings.TrimSpace(k))] = quantity
+			}
+			*target = list
+		}
+		return nil
+	}
+}
+
+// EvictionQoSFactors are the factors that the eviction cost of a pod is scaled by for each QoS class
+type EvictionQoSFactors struct {
+	Guaranteed float64 `json:"guaranteed"`
+	Burstable  float64 `json:"burstable"`
+	BestEffort float64 `json:"bestEffort"`
+}
+
+// Factor returns the factor for the QoS class. Factors that aren't set, and unknown classes, don't scale the cost.
+func (f EvictionQoSFactors) Factor(qosClass v1.PodQOSClass) float64 {
+	factor := map[v1.PodQOSClass]float64{
+		v1.PodQOSGuaranteed: f.Guaranteed,
+		v1.PodQOSBurstable:  f.Burstable,
+		v1.PodQOSBestEffort: f.BestEffort,
+	}[qosClass]
+	if factor == 0 {
+		return 1
+	}
+	return factor
+}
+
+// AsEvictionQoSFactors parses a comma separated list of class=factor pairs, e.g. "Guaranteed=1.5, BestEffort=0.75",
+// into the factors of the classes that are listed
+func AsEvictionQoSFactors(key string, target *EvictionQoSFactors) configmap.ParseFunc {
+	return func(data map[string]string) error {
+		factors := map[string]float64{}
+		if err := AsFloat64Map(key, &factors)(data); err != nil {
+			return err
+		}
+		for class, factor := range factors {
+			switch v1.PodQOSClass(class) {
+			case v1.PodQOSGuaranteed:
+				target.Guaranteed = factor
+			case v1.PodQOSBurstable:
+				target.Burstable = factor
+			case v1.PodQOSBestEffort:
+				target.BestEffort = factor
+			default:
+				return fmt.Errorf("failed to parse %q: %q is not a QoS class", key, class)
+			}
+		}
+		return nil
+	}
+}
+
+// AsMaintenanceWindows parses a comma separated list of maintenance windows, e.g. "Mon-Fri 22:00-04:00, Sat 00:00-24:00"
+func AsMaintenanceWindows(key string, target *[]maintenancewindow.Window) configmap.ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok && strings.TrimSpace(raw) != "" {
+			var windows []maintenancewindow.Window
+			for _, rawWindow := range strings.Split(raw, ",") {
+				window, err := maintenancewindow.Parse(strings.TrimSpace(rawWindow))
+				if err != nil {
+					return fmt.Errorf("failed to parse %q: %w", key, err)
+				}
+				windows = append(windows, window)
+			}
+			*target = windows
+		}
+		return nil
+	}
+}
+
+// InMaintenanceWindow returns true if deprovisioning is allowed at the time, because it's within one of the
+// maintenance windows or none are configured
+func (s Settings) InMaintenanceWindow(t time.Time) bool {
+	if len(s.MaintenanceWindows) == 0 {
+		return true
+	}
+	return lo.SomeBy(s.MaintenanceWindows, func(w maintenancewindow.Window) bool { return w.Contains(t) })
+}
+
 func ToContext(ctx context.Context, s Settings) context.Context {
 	return context.WithValue(ctx, ContextKey, s)
 }
@@ -22,11 +22,14 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	. "knative.dev/pkg/logging/testing"
 
 	. "github.com/aws/karpenter-core/pkg/test/expectations"
 
 	"github.com/aws/karpenter-core/pkg/apis/config/settings"
+	"github.com/aws/karpenter-core/pkg/utils/maintenancewindow"
 )
 
 var ctx context.Context
@@ -45,17 +48,153 @@ var _ = Describe("Validation", func() {
 		s, _ := settings.NewSettingsFromConfigMap(cm)
 		Expect(s.BatchMaxDuration.Duration).To(Equal(time.Second * 10))
 		Expect(s.BatchIdleDuration.Duration).To(Equal(time.Second))
+		Expect(s.ConsolidationCapacityFloor).To(BeEmpty())
+		Expect(s.ConsolidationCascadeLimit).To(BeZero())
+		Expect(s.ConsolidationOscillationWindow.Duration).To(Equal(time.Hour))
+		Expect(s.ConsolidationPolicy).To(Equal(settings.ConsolidationPolicyDeleteOrReplace))
+		Expect(s.ConsolidationScaleUpCooldown.Duration).To(BeZero())
+		Expect(s.ConsolidationScaleUpThreshold).To(Equal(10))
+		Expect(s.ConsolidationSpotFallbackWeight).To(BeZero())
+		Expect(s.ConsolidationValidationPeriod.Duration).To(BeZero())
+		Expect(s.CrashLoopingPodWindow.Duration).To(BeZero())
+		Expect(s.DeprovisioningCooldowns).To(BeEmpty())
+		Expect(s.DisruptionCostResourceWeights).To(BeEmpty())
+		Expect(s.DrainExclusionSelector).To(BeNil())
+		Expect(s.DrainReevictionDelay.Duration).To(BeZero())
+		Expect(s.DriftEnabled).To(BeFalse())
+		Expect(s.DrainReevictionGracePeriod.Duration).To(BeZero())
+		Expect(s.DrainForceDeleteDelay.Duration).To(BeZero())
+		Expect(s.DrainFinalizerTimeout.Duration).To(Equal(time.Minute * 5))
+		Expect(s.EvictionQoSFactors).To(Equal(settings.EvictionQoSFactors{Guaranteed: 1.5, Burstable: 1, BestEffort: 0.75}))
+		Expect(s.HonorSafeToEvictAnnotation).To(BeFalse())
+		Expect(s.IdleUsageThreshold).To(Equal(0.05))
+		Expect(s.LoadBalancerDrainDelay.Duration).To(BeZero())
+		Expect(s.MaintenanceWindows).To(BeEmpty())
+		Expect(s.MaxConsolidationSizeRatio).To(BeZero())
+		Expect(s.MinConsolidationSavingsPercent).To(BeZero())
+		Expect(s.MinNodeLifetime.Duration).To(BeZero())
+		Expect(s.OwnerDisruptionLimit).To(BeZero())
+		Expect(s.OwnerDisruptionWindow.Duration).To(Equal(time.Hour))
+		Expect(s.PinResourceClaimPods).To(BeFalse())
+		Expect(s.PreserveCapacityType).To(BeFalse())
+		Expect(s.ReplacementInstanceTypes).To(BeEmpty())
+		Expect(s.RescheduleVerificationThreshold.Duration).To(BeZero())
+		Expect(s.SessionAffinityDrainDelay.Duration).To(BeZero())
+		Expect(s.SimulationConcurrency).To(Equal(1))
+		Expect(s.SpreadConsolidationTies).To(BeFalse())
+		Expect(s.StatefulSetEvictionCostFactor).To(Equal(2.0))
+		Expect(s.SuppressConsolidationOscillation).To(BeFalse())
+		Expect(s.TerminationHardLimit.Duration).To(BeZero())
+		Expect(s.ValidationTaintEffect).To(BeEmpty())
+		Expect(s.ZonalConsolidation).To(BeFalse())
 	})
 	It("should succeed to set custom values", func() {
 		cm := &v1.ConfigMap{
 			Data: map[string]string{
-				"batchMaxDuration":  "30s",
-				"batchIdleDuration": "5s",
+				"batchMaxDuration":                 "30s",
+				"batchIdleDuration":                "5s",
+				"consolidationCapacityFloor":       "cpu=100, memory=512Gi",
+				"consolidationCascadeLimit":        "2",
+				"consolidationOscillationWindow":   "30m",
+				"consolidationPolicy":              "DeleteOnly",
+				"consolidationScaleUpCooldown":     "10m",
+				"consolidationScaleUpThreshold":    "5",
+				"consolidationSpotFallbackWeight":  "0.5",
+				"consolidationValidationPeriod":    "1m",
+				"crashLoopingPodWindow":            "15m",
+				"deprovisioningCooldowns":          "expiration=10m, consolidation=1m",
+				"disruptionCostResourceWeights":    "nvidia.com/gpu=10, cpu=0.5",
+				"drainExclusionSelector":           "app in (monitoring)",
+				"drainReevictionDelay":             "30s",
+				"drainReevictionGracePeriod":       "5s",
+				"drainForceDeleteDelay":            "2m",
+				"drainFinalizerTimeout":            "10m",
+				"driftEnabled":                     "true",
+				"evictionQoSFactors":               "Guaranteed=2, BestEffort=0.5",
+				"honorSafeToEvictAnnotation":       "true",
+				"idleUsageThreshold":               "0.1",
+				"loadBalancerDrainDelay":           "15s",
+				"maintenanceWindows":               "Mon-Fri 22:00-04:00, Sat 00:00-24:00",
+				"maxConsolidationSizeRatio":        "2",
+				"minConsolidationSavingsPercent":   "15",
+				"minNodeLifetime":                  "30m",
+				"ownerDisruptionLimit":             "3",
+				"ownerDisruptionWindow":            "30m",
+				"pinResourceClaimPods":             "true",
+				"preserveCapacityType":             "true",
+				"replacementInstanceTypes":         "m5.large,m5.xlarge",
+				"rescheduleVerificationThreshold":  "5m",
+				"sessionAffinityDrainDelay":        "2m",
+				"simulationConcurrency":            "4",
+				"spreadConsolidationTies":          "true",
+				"statefulSetEvictionCostFactor":    "1",
+				"suppressConsolidationOscillation": "true",
+				"terminationHardLimit":             "1h",
+				"validationTaintEffect":            "PreferNoSchedule",
+				"zonalConsolidation":               "true",
 			},
 		}
 		s, _ := settings.NewSettingsFromConfigMap(cm)
 		Expect(s.BatchMaxDuration.Duration).To(Equal(time.Second * 30))
 		Expect(s.BatchIdleDuration.Duration).To(Equal(time.Second * 5))
+		Expect(s.ConsolidationCapacityFloor.Cpu().String()).To(Equal("100"))
+		Expect(s.ConsolidationCapacityFloor.Memory().String()).To(Equal("512Gi"))
+		Expect(s.ConsolidationCascadeLimit).To(Equal(2))
+		Expect(s.ConsolidationOscillationWindow.Duration).To(Equal(time.Minute * 30))
+		Expect(s.ConsolidationPolicy).To(Equal(settings.ConsolidationPolicyDeleteOnly))
+		Expect(s.ConsolidationScaleUpCooldown.Duration).To(Equal(time.Minute * 10))
+		Expect(s.ConsolidationScaleUpThreshold).To(Equal(5))
+		Expect(s.ConsolidationSpotFallbackWeight).To(Equal(0.5))
+		Expect(s.ConsolidationValidationPeriod.Duration).To(Equal(time.Minute))
+		Expect(s.CrashLoopingPodWindow.Duration).To(Equal(time.Minute * 15))
+		Expect(s.DeprovisioningCooldowns).To(Equal(map[string]metav1.Duration{
+			"expiration":    {Duration: time.Minute * 10},
+			"consolidation": {Duration: time.Minute},
+		}))
+		Expect(s.DisruptionCostResourceWeights).To(Equal(map[string]float64{"nvidia.com/gpu": 10, "cpu": 0.5}))
+		Expect(s.DrainExclusionSelector.Matches(labels.Set{"app": "monitoring"})).To(BeTrue())
+		Expect(s.DrainExclusionSelector.Matches(labels.Set{"app": "web"})).To(BeFalse())
+		Expect(s.DrainReevictionDelay.Duration).To(Equal(time.Second * 30))
+		Expect(s.DrainReevictionGracePeriod.Duration).To(Equal(time.Second * 5))
+		Expect(s.DrainForceDeleteDelay.Duration).To(Equal(time.Minute * 2))
+		Expect(s.DrainFinalizerTimeout.Duration).To(Equal(time.Minute * 10))
+		Expect(s.DriftEnabled).To(BeTrue())
+		Expect(s.EvictionQoSFactors).To(Equal(settings.EvictionQoSFactors{Guaranteed: 2, Burstable: 1, BestEffort: 0.5}))
+		Expect(s.HonorSafeToEvictAnnotation).To(BeTrue())
+		Expect(s.IdleUsageThreshold).To(Equal(0.1))
+		Expect(s.LoadBalancerDrainDelay.Duration).To(Equal(time.Second * 15))
+		Expect(s.MaintenanceWindows).To(Equal([]maintenancewindow.Window{
+			{Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, Start: 22 * time.Hour, End: 4 * time.Hour},
+			{Days: []time.Weekday{time.Saturday}, Start: 0, End: 24 * time.Hour},
+		}))
+		Expect(s.MaxConsolidationSizeRatio).To(Equal(2.0))
+		Expect(s.MinConsolidationSavingsPercent).To(Equal(15))
+		Expect(s.MinNodeLifetime.Duration).To(Equal(time.Minute * 30))
+		Expect(s.OwnerDisruptionLimit).To(Equal(3))
+		Expect(s.OwnerDisruptionWindow.Duration).To(Equal(time.Minute * 30))
+		Expect(s.PinResourceClaimPods).To(BeTrue())
+		Expect(s.PreserveCapacityType).To(BeTrue())
+		Expect(s.ReplacementInstanceTypes.List()).To(ConsistOf("m5.large", "m5.xlarge"))
+		Expect(s.RescheduleVerificationThreshold.Duration).To(Equal(time.Minute * 5))
+		Expect(s.SessionAffinityDrainDelay.Duration).To(Equal(time.Minute * 2))
+		Expect(s.SimulationConcurrency).To(Equal(4))
+		Expect(s.SpreadConsolidationTies).To(BeTrue())
+		Expect(s.StatefulSetEvictionCostFactor).To(Equal(1.0))
+		Expect(s.SuppressConsolidationOscillation).To(BeTrue())
+		Expect(s.TerminationHardLimit.Duration).To(Equal(time.Hour))
+		Expect(s.ValidationTaintEffect).To(Equal("PreferNoSchedule"))
+		Expect(s.ZonalConsolidation).To(BeTrue())
+	})
+	It("should succeed to set drainReevictionDelay when force deletion is disabled", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"drainReevictionDelay": "2m",
+			},
+		}
+		s, err := settings.NewSettingsFromConfigMap(cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.DrainReevictionDelay.Duration).To(Equal(time.Minute * 2))
+		Expect(s.DrainForceDeleteDelay.Duration).To(BeZero())
 	})
 	It("should fail validation with panic when batchMaxDuration is negative", func() {
 		defer ExpectPanic()
@@ -75,4 +214,329 @@ var _ = Describe("Validation", func() {
 		}
 		_, _ = settings.NewSettingsFromConfigMap(cm)
 	})
+	It("should fail validation with panic when drainReevictionDelay isn't less than drainForceDeleteDelay", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"drainReevictionDelay":  "2m",
+				"drainForceDeleteDelay": "1m",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when drainFinalizerTimeout is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"drainFinalizerTimeout": "-1s",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when evictionQoSFactors has an unknown QoS class", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"evictionQoSFactors": "Critical=2",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when an evictionQoSFactors factor is not positive", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"evictionQoSFactors": "BestEffort=0",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when idleUsageThreshold is greater than one", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"idleUsageThreshold": "1.5",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when loadBalancerDrainDelay is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"loadBalancerDrainDelay": "-1s",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when maintenanceWindows is invalid", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"maintenanceWindows": "Someday 25:00-26:00",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when maxConsolidationSizeRatio is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"maxConsolidationSizeRatio": "-1",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when minNodeLifetime is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"idleUsageThreshold": "0.1",
+				"minNodeLifetime":    "-1m",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when consolidationCascadeLimit is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationCascadeLimit": "-1",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when consolidationOscillationWindow is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationOscillationWindow": "-1m",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when consolidationScaleUpCooldown is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationScaleUpCooldown": "-1m",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when consolidationScaleUpThreshold is not positive", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationScaleUpThreshold": "0",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when consolidationCapacityFloor is malformed", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationCapacityFloor": "cpu=lots",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when a consolidationCapacityFloor quantity is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationCapacityFloor": "cpu=-1",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when consolidationSpotFallbackWeight is out of bounds", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationSpotFallbackWeight": "1.5",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when consolidationValidationPeriod is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationValidationPeriod": "-1s",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when crashLoopingPodWindow is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"crashLoopingPodWindow": "-1s",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when deprovisioningCooldowns is malformed", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningCooldowns": "expiration=10",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when a deprovisioningCooldowns cooldown is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningCooldowns": "expiration=-1m",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when disruptionCostResourceWeights is malformed", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"disruptionCostResourceWeights": "nvidia.com/gpu",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when a disruptionCostResourceWeights weight is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"disruptionCostResourceWeights": "nvidia.com/gpu=-1",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when drainExclusionSelector is malformed", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"drainExclusionSelector": "app in monitoring",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when rescheduleVerificationThreshold is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"rescheduleVerificationThreshold": "-1s",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when sessionAffinityDrainDelay is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"sessionAffinityDrainDelay": "-1s",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when consolidationPolicy is unknown", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationPolicy": "ReplaceOnly",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when minConsolidationSavingsPercent is out of bounds", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"minConsolidationSavingsPercent": "100",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when ownerDisruptionLimit is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"ownerDisruptionLimit": "-1",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when ownerDisruptionWindow is not positive", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"ownerDisruptionWindow": "0s",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when simulationConcurrency is less than one", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"simulationConcurrency": "0",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when statefulSetEvictionCostFactor is not positive", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"statefulSetEvictionCostFactor": "0",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when terminationHardLimit is negative", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"terminationHardLimit": "-1m",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should fail validation with panic when validationTaintEffect is invalid", func() {
+		defer ExpectPanic()
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"validationTaintEffect": "NoExecute",
+			},
+		}
+		_, _ = settings.NewSettingsFromConfigMap(cm)
+	})
+	It("should trim whitespace around replacementInstanceTypes", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"replacementInstanceTypes": " m5.large , m5.xlarge ",
+			},
+		}
+		s, _ := settings.NewSettingsFromConfigMap(cm)
+		Expect(s.ReplacementInstanceTypes.List()).To(ConsistOf("m5.large", "m5.xlarge"))
+	})
+})
+
+var _ = Describe("Maintenance Windows", func() {
+	// 2022-10-03 is a Monday
+	monday := time.Date(2022, time.October, 3, 0, 0, 0, 0, time.UTC)
+	It("should allow deprovisioning at any time if no windows are configured", func() {
+		Expect(settings.Settings{}.InMaintenanceWindow(monday)).To(BeTrue())
+	})
+	It("should only allow deprovisioning within one of the configured windows", func() {
+		s := settings.Settings{MaintenanceWindows: []maintenancewindow.Window{
+			{Start: 2 * time.Hour, End: 6 * time.Hour},
+			{Days: []time.Weekday{time.Saturday}, Start: 0, End: 24 * time.Hour},
+		}}
+		Expect(s.InMaintenanceWindow(monday.Add(3 * time.Hour))).To(BeTrue())
+		Expect(s.InMaintenanceWindow(monday.Add(12 * time.Hour))).To(BeFalse())
+		Expect(s.InMaintenanceWindow(monday.AddDate(0, 0, 5).Add(12 * time.Hour))).To(BeTrue())
+	})
 })
@@ -30,17 +30,30 @@ var (
 	CapacityTypeOnDemand = "on-demand"
 
 	// Karpenter specific domains and labels
-	ProvisionerNameLabelKey           = Group + "/provisioner-name"
-	DoNotEvictPodAnnotationKey        = Group + "/do-not-evict"
-	DoNotConsolidateNodeAnnotationKey = Group + "/do-not-consolidate"
-	EmptinessTimestampAnnotationKey   = Group + "/emptiness-timestamp"
-	TerminationFinalizer              = Group + "/termination"
-	LabelNodeInitialized              = Group + "/initialized"
-	LabelCapacityType                 = Group + "/capacity-type"
+	ProvisionerNameLabelKey                = Group + "/provisioner-name"
+	DoNotEvictPodAnnotationKey             = Group + "/do-not-evict"
+	DoNotConsolidateNodeAnnotationKey      = Group + "/do-not-consolidate"
+	DoNotExpireNodeAnnotationKey           = Group + "/do-not-expire"
+	ExpirationOverrideAnnotationKey        = Group + "/expiration-override"
+	ExpirationWarningAnnotationKey         = Group + "/expiration-warning"
+	DeprovisioningCordonAnnotationKey      = Group + "/deprovisioning-cordon"
+	DeprovisioningValidationTaintKey       = Group + "/deprovisioning-validation"
+	DeprovisioningReplacementAnnotationKey = Group + "/deprovisioning-replacement"
+	TerminationReasonAnnotationKey         = Group + "/termination-reason"
+	DeprovisioningSummaryAnnotationKey     = Group + "/deprovisioning-summary"
+	AllowCapacityTypeChangeAnnotationKey   = Group + "/allow-capacity-type-change"
+	EmptinessTimestampAnnotationKey        = Group + "/emptiness-timestamp"
+	CordonTimestampAnnotationKey           = Group + "/cordon-timestamp"
+	TerminationFinalizer                   = Group + "/termination"
+	LabelNodeInitialized                   = Group + "/initialized"
+	LabelCapacityType                      = Group + "/capacity-type"
 
 	// Tags for infrastructure resources deployed into cloudproviders' accounts
 	DiscoveryTagKey = Group + "/discovery"
 	ManagedByTagKey = Group + "/managed-by"
+	// DeprovisioningTagKey is applied to the instances of nodes that are cordoned for deprovisioning, for cloud
+	// providers that can tag instances
+	DeprovisioningTagKey = Group + "/deprovisioning"
 
 	// RestrictedLabelDomains are either prohibited by the kubelet or reserved by karpenter
 	RestrictedLabelDomains = sets.NewString(
@@ -74,6 +87,7 @@ var (
 	// because they may interfere with the internal provisioning logic.
 	RestrictedLabels = sets.NewString(
 		EmptinessTimestampAnnotationKey,
+		CordonTimestampAnnotationKey,
 		v1.LabelHostname,
 	)
 
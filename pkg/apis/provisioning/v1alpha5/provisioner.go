@@ -20,6 +20,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/ptr"
 )
 
@@ -58,6 +59,13 @@ type ProvisionerSpec struct {
 	// additional configuration options
 	// +optional
 	ProviderRef *ProviderRef `json:"providerRef,omitempty"`
+	// TerminationGracePeriod is the maximum duration the controller will spend draining a node, measured from
+	// when the node is deleted. Pods that remain on the node once it elapses are force deleted, regardless of
+	// their own termination grace period or the drain escalation settings.
+	//
+	// Nodes are drained without a deadline if this field is not set.
+	// +optional
+	TerminationGracePeriod *metav1.Duration `json:"terminationGracePeriod,omitempty"`
 	// TTLSecondsAfterEmpty is the number of seconds the controller will wait
 	// before attempting to delete a node, measured from when the node is
 	// detected to be empty. A Node is considered to be empty when it does not
@@ -66,6 +74,14 @@ type ProvisionerSpec struct {
 	// Termination due to no utilization is disabled if this field is not set.
 	// +optional
 	TTLSecondsAfterEmpty *int64 `json:"ttlSecondsAfterEmpty,omitempty"`
+	// TTLSecondsAfterNotReady is the number of seconds the controller will wait
+	// before deleting a node whose Ready condition is False or Unknown,
+	// measured from when the condition last changed, or from when the node
+	// was created if it has never reported it.
+	//
+	// Termination of nodes that aren't ready is disabled if this field is not set.
+	// +optional
+	TTLSecondsAfterNotReady *int64 `json:"ttlSecondsAfterNotReady,omitempty"`
 	// TTLSecondsUntilExpired is the number of seconds the controller will wait
 	// before terminating a node, measured from when the node is created. This
 	// is useful to implement features like eventually consistent node upgrade,
@@ -74,6 +90,23 @@ type ProvisionerSpec struct {
 	// Termination due to expiration is disabled if this field is not set.
 	// +optional
 	TTLSecondsUntilExpired *int64 `json:"ttlSecondsUntilExpired,omitempty"`
+	// ExpirationJitterFactor spreads the expiration of the provisioner's nodes uniformly across plus or minus this
+	// fraction of TTLSecondsUntilExpired, so that nodes that launched together don't all expire together. Each node's
+	// offset is derived from its UID, so it doesn't change.
+	//
+	// Nodes expire exactly TTLSecondsUntilExpired after they're created if this field is not set.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=1
+	// +optional
+	ExpirationJitterFactor *float64 `json:"expirationJitterFactor,omitempty"`
+	// ExpirationOrder is the order in which the provisioner's expired nodes are replaced. MostExpired replaces the
+	// nodes that expired first, MostExpensive replaces the nodes with the most expensive offerings first, and
+	// LeastUtilized replaces the nodes whose pods request the smallest fraction of their allocatable CPU first.
+	//
+	// Expired nodes are replaced in the order that they expired if this field is not set.
+	// +kubebuilder:validation:Enum:={MostExpired,MostExpensive,LeastUtilized}
+	// +optional
+	ExpirationOrder ExpirationOrder `json:"expirationOrder,omitempty"`
 	// Limits define a set of bounds for provisioning capacity.
 	Limits *Limits `json:"limits,omitempty"`
 	// Weight is the priority given to the provisioner during scheduling. A higher
@@ -87,11 +120,81 @@ type ProvisionerSpec struct {
 	// Consolidation are the consolidation parameters
 	// +optional
 	Consolidation *Consolidation `json:"consolidation,omitempty"`
+	// Disruption are the disruption parameters
+	// +optional
+	Disruption *Disruption `json:"disruption,omitempty"`
+	// Expiration are the expiration parameters
+	// +optional
+	Expiration *Expiration `json:"expiration,omitempty"`
 }
 
+type ExpirationOrder string
+
+const (
+	ExpirationOrderMostExpired   ExpirationOrder = "MostExpired"
+	ExpirationOrderMostExpensive ExpirationOrder = "MostExpensive"
+	ExpirationOrderLeastUtilized ExpirationOrder = "LeastUtilized"
+)
+
 type Consolidation struct {
 	// Enabled enables consolidation if it has been set
 	Enabled *bool `json:"enabled,omitempty"`
+	// CrossZone allows multi-node consolidation to merge the provisioner's nodes across zones when zonal consolidation
+	// is enabled, as long as the pods being moved have no zonal topology constraints. Defaults to false.
+	// +optional
+	CrossZone *bool `json:"crossZone,omitempty"`
+	// MinNodeAgeSeconds is how long after the provisioner's nodes are created that they become eligible for
+	// consolidation, so that freshly launched replacements aren't immediately replaced again. Defaults to 300.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	MinNodeAgeSeconds *int64 `json:"minNodeAgeSeconds,omitempty"`
+	// MinSavingsPercent is the percentage of the price of the nodes being replaced that a consolidation replacement
+	// must save, overriding the global minConsolidationSavingsPercent setting for this provisioner's nodes
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=99
+	// +optional
+	MinSavingsPercent *int32 `json:"minSavingsPercent,omitempty"`
+	// MinimumSavingsPercent is the percentage of the price of the nodes being replaced that the cheapest offering of a
+	// consolidation replacement must save for the nodes to be replaced. It doesn't apply to nodes that are deleted
+	// without a replacement. Defaults to 0, which replaces nodes for any saving.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=99
+	// +optional
+	MinimumSavingsPercent *float64 `json:"minimumSavingsPercent,omitempty"`
+	// WarmSpareNodes is the number of the provisioner's empty nodes that consolidation keeps as warm capacity to absorb
+	// bursts, rather than deleting them
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	WarmSpareNodes *int32 `json:"warmSpareNodes,omitempty"`
+}
+
+type Disruption struct {
+	// Budget is the number or percentage of the provisioner's nodes that deprovisioning may disrupt at once, including
+	// the nodes that are already being deleted. Percentages are rounded up, and the budget is unlimited if it's not set.
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	Budget *intstr.IntOrString `json:"budget,omitempty"`
+}
+
+type Expiration struct {
+	// AllowNodeOverride allows a node's expiration override annotation to replace its provisioner's expiration TTL.
+	// Defaults to true.
+	// +optional
+	AllowNodeOverride *bool `json:"allowNodeOverride,omitempty"`
+	// MaintenanceWindows restrict the replacement of expired nodes to the UTC times that they contain, e.g.
+	// "Mon-Fri 02:00-06:00" or "22:00-04:00". Expired nodes are replaced at any time if there are none.
+	// +optional
+	MaintenanceWindows []string `json:"maintenanceWindows,omitempty"`
+	// MaxNodesPerCycle is the number of the provisioner's expired nodes that may be replaced by a single deprovisioning
+	// command, so that a cluster-wide rolling upgrade doesn't evict too many pods at once. Defaults to 1.
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	MaxNodesPerCycle *int32 `json:"maxNodesPerCycle,omitempty"`
+	// WarningThresholdSeconds is how long before a node expires that a warning event is recorded on it, so that
+	// operators can prepare for its replacement. No warning is recorded if it's not set.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	WarningThresholdSeconds *int64 `json:"warningThresholdSeconds,omitempty"`
 }
 
 // +kubebuilder:object:generate=false
@@ -24,10 +24,13 @@ import (
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
+
+	"github.com/aws/karpenter-core/pkg/utils/maintenancewindow"
 )
 
 var (
@@ -72,18 +75,35 @@ func (p *Provisioner) Validate(ctx context.Context) (errs *apis.FieldError) {
 func (s *ProvisionerSpec) validate(ctx context.Context) (errs *apis.FieldError) {
 	return errs.Also(
 		s.validateTTLSecondsUntilExpired(),
+		s.validateExpirationOrder(),
 		s.validateTTLSecondsAfterEmpty(),
+		s.validateTTLSecondsAfterNotReady(),
+		s.validateTerminationGracePeriod(),
+		s.validateConsolidation(),
+		s.validateDisruption(),
+		s.validateExpiration(),
 		s.Validate(ctx),
 	)
 }
 
 func (s *ProvisionerSpec) validateTTLSecondsUntilExpired() (errs *apis.FieldError) {
 	if ptr.Int64Value(s.TTLSecondsUntilExpired) < 0 {
-		return errs.Also(apis.ErrInvalidValue("cannot be negative", "ttlSecondsUntilExpired"))
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "ttlSecondsUntilExpired"))
+	}
+	if factor := s.ExpirationJitterFactor; factor != nil && (*factor < 0 || *factor > 1) {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(*factor, 0, 1, "expirationJitterFactor"))
 	}
 	return errs
 }
 
+func (s *ProvisionerSpec) validateExpirationOrder() (errs *apis.FieldError) {
+	switch s.ExpirationOrder {
+	case "", ExpirationOrderMostExpired, ExpirationOrderMostExpensive, ExpirationOrderLeastUtilized:
+		return errs
+	}
+	return errs.Also(apis.ErrInvalidValue(s.ExpirationOrder, "expirationOrder"))
+}
+
 func (s *ProvisionerSpec) validateTTLSecondsAfterEmpty() (errs *apis.FieldError) {
 	if ptr.Int64Value(s.TTLSecondsAfterEmpty) < 0 {
 		return errs.Also(apis.ErrInvalidValue("cannot be negative", "ttlSecondsAfterEmpty"))
@@ -95,6 +115,72 @@ func (s *ProvisionerSpec) validateTTLSecondsAfterEmpty() (errs *apis.FieldError)
 	return errs
 }
 
+func (s *ProvisionerSpec) validateTTLSecondsAfterNotReady() (errs *apis.FieldError) {
+	if ptr.Int64Value(s.TTLSecondsAfterNotReady) < 0 {
+		return errs.Also(apis.ErrInvalidValue("cannot be negative", "ttlSecondsAfterNotReady"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateTerminationGracePeriod() (errs *apis.FieldError) {
+	if s.TerminationGracePeriod != nil && s.TerminationGracePeriod.Duration < 0 {
+		return errs.Also(apis.ErrInvalidValue("cannot be negative", "terminationGracePeriod"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateDisruption() (errs *apis.FieldError) {
+	if s.Disruption == nil || s.Disruption.Budget == nil {
+		return errs
+	}
+	// a percentage is validated against a scale of 100, so that it must be between 0% and 100%
+	budget, err := intstr.GetScaledValueFromIntOrPercent(s.Disruption.Budget, 100, true)
+	if err != nil {
+		return errs.Also(apis.ErrInvalidValue(s.Disruption.Budget.String(), "disruption.budget", err.Error()))
+	}
+	if budget < 0 || (s.Disruption.Budget.Type == intstr.String && budget > 100) {
+		errs = errs.Also(apis.ErrInvalidValue(s.Disruption.Budget.String(), "disruption.budget", "must be a non-negative number or a percentage between 0% and 100%"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateExpiration() (errs *apis.FieldError) {
+	if s.Expiration == nil {
+		return errs
+	}
+	if maxNodes := s.Expiration.MaxNodesPerCycle; maxNodes != nil && *maxNodes < 1 {
+		errs = errs.Also(apis.ErrInvalidValue("must be at least 1", "expiration.maxNodesPerCycle"))
+	}
+	if ptr.Int64Value(s.Expiration.WarningThresholdSeconds) < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "expiration.warningThresholdSeconds"))
+	}
+	for i, window := range s.Expiration.MaintenanceWindows {
+		if _, err := maintenancewindow.Parse(window); err != nil {
+			errs = errs.Also(apis.ErrInvalidArrayValue(window, "expiration.maintenanceWindows", i))
+		}
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateConsolidation() (errs *apis.FieldError) {
+	if s.Consolidation == nil {
+		return errs
+	}
+	if ptr.Int64Value(s.Consolidation.MinNodeAgeSeconds) < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "consolidation.minNodeAgeSeconds"))
+	}
+	if percent := s.Consolidation.MinSavingsPercent; percent != nil && (*percent < 0 || *percent > 99) {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(*percent, 0, 99, "consolidation.minSavingsPercent"))
+	}
+	if percent := s.Consolidation.MinimumSavingsPercent; percent != nil && (*percent < 0 || *percent > 99) {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(*percent, 0, 99, "consolidation.minimumSavingsPercent"))
+	}
+	if ptr.Int32Value(s.Consolidation.WarmSpareNodes) < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "consolidation.warmSpareNodes"))
+	}
+	return errs
+}
+
 // Validate the constraints
 func (s *ProvisionerSpec) Validate(ctx context.Context) (errs *apis.FieldError) {
 	return errs.Also(
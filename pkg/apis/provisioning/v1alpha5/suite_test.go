@@ -25,11 +25,13 @@ import (
 	"github.com/Pallinder/go-randomdata"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
 	. "knative.dev/pkg/logging/testing"
 	"knative.dev/pkg/ptr"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -60,10 +62,98 @@ var _ = Describe("Validation", func() {
 		provisioner.Spec.TTLSecondsUntilExpired = nil
 		Expect(provisioner.Validate(ctx)).To(Succeed())
 	})
+	It("should succeed on a valid expiration order", func() {
+		provisioner.Spec.ExpirationOrder = ExpirationOrderLeastUtilized
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on an invalid expiration order", func() {
+		provisioner.Spec.ExpirationOrder = "Random"
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on negative termination grace period", func() {
+		provisioner.Spec.TerminationGracePeriod = &metav1.Duration{Duration: -time.Second}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on a consolidation min savings percent within bounds", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), MinSavingsPercent: ptr.Int32(20)}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on a consolidation min savings percent out of bounds", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), MinSavingsPercent: ptr.Int32(100)}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.Consolidation.MinSavingsPercent = ptr.Int32(-1)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on a consolidation minimum savings percent within bounds", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), MinimumSavingsPercent: ptr.Float64(12.5)}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on a consolidation minimum savings percent out of bounds", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), MinimumSavingsPercent: ptr.Float64(99.5)}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.Consolidation.MinimumSavingsPercent = ptr.Float64(-0.5)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on negative consolidation warm spare nodes", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), WarmSpareNodes: ptr.Int32(-1)}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on a disruption budget that's a number or a percentage", func() {
+		provisioner.Spec.Disruption = &Disruption{Budget: lo.ToPtr(intstr.FromInt(2))}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+		provisioner.Spec.Disruption = &Disruption{Budget: lo.ToPtr(intstr.FromString("10%"))}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on an invalid disruption budget", func() {
+		provisioner.Spec.Disruption = &Disruption{Budget: lo.ToPtr(intstr.FromInt(-1))}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.Disruption = &Disruption{Budget: lo.ToPtr(intstr.FromString("150%"))}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.Disruption = &Disruption{Budget: lo.ToPtr(intstr.FromString("ten"))}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on a negative consolidation min node age", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), MinNodeAgeSeconds: ptr.Int64(-1)}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on an expiration max nodes per cycle below one", func() {
+		provisioner.Spec.Expiration = &Expiration{MaxNodesPerCycle: ptr.Int32(0)}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on an expiration max nodes per cycle of one", func() {
+		provisioner.Spec.Expiration = &Expiration{MaxNodesPerCycle: ptr.Int32(1)}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on a negative expiration warning threshold", func() {
+		provisioner.Spec.Expiration = &Expiration{WarningThresholdSeconds: ptr.Int64(-1)}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on valid expiration maintenance windows", func() {
+		provisioner.Spec.Expiration = &Expiration{MaintenanceWindows: []string{"Mon-Fri 02:00-06:00", "22:00-04:00"}}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on invalid expiration maintenance windows", func() {
+		provisioner.Spec.Expiration = &Expiration{MaintenanceWindows: []string{"0 2 * * *"}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.Expiration = &Expiration{MaintenanceWindows: []string{"Someday 02:00-06:00"}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.Expiration = &Expiration{MaintenanceWindows: []string{"02:00-02:00"}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on an expiration jitter factor out of bounds", func() {
+		provisioner.Spec.ExpirationJitterFactor = ptr.Float64(1.5)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.ExpirationJitterFactor = ptr.Float64(-0.1)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
 	It("should fail on negative empty ttl", func() {
 		provisioner.Spec.TTLSecondsAfterEmpty = ptr.Int64(-1)
 		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
 	})
+	It("should fail on negative not ready ttl", func() {
+		provisioner.Spec.TTLSecondsAfterNotReady = ptr.Int64(-1)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
 	It("should succeed on a missing empty ttl", func() {
 		provisioner.Spec.TTLSecondsAfterEmpty = nil
 		Expect(provisioner.Validate(ctx)).To(Succeed())
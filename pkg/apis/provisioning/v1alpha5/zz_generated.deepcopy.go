@@ -23,6 +23,7 @@ import (
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/apis"
 )
 
@@ -34,6 +35,31 @@ func (in *Consolidation) DeepCopyInto(out *Consolidation) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.CrossZone != nil {
+		in, out := &in.CrossZone, &out.CrossZone
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MinNodeAgeSeconds != nil {
+		in, out := &in.MinNodeAgeSeconds, &out.MinNodeAgeSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MinSavingsPercent != nil {
+		in, out := &in.MinSavingsPercent, &out.MinSavingsPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinimumSavingsPercent != nil {
+		in, out := &in.MinimumSavingsPercent, &out.MinimumSavingsPercent
+		*out = new(float64)
+		**out = **in
+	}
+	if in.WarmSpareNodes != nil {
+		in, out := &in.WarmSpareNodes, &out.WarmSpareNodes
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Consolidation.
@@ -46,6 +72,61 @@ func (in *Consolidation) DeepCopy() *Consolidation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Disruption) DeepCopyInto(out *Disruption) {
+	*out = *in
+	if in.Budget != nil {
+		in, out := &in.Budget, &out.Budget
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Disruption.
+func (in *Disruption) DeepCopy() *Disruption {
+	if in == nil {
+		return nil
+	}
+	out := new(Disruption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Expiration) DeepCopyInto(out *Expiration) {
+	*out = *in
+	if in.AllowNodeOverride != nil {
+		in, out := &in.AllowNodeOverride, &out.AllowNodeOverride
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxNodesPerCycle != nil {
+		in, out := &in.MaxNodesPerCycle, &out.MaxNodesPerCycle
+		*out = new(int32)
+		**out = **in
+	}
+	if in.WarningThresholdSeconds != nil {
+		in, out := &in.WarningThresholdSeconds, &out.WarningThresholdSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Expiration.
+func (in *Expiration) DeepCopy() *Expiration {
+	if in == nil {
+		return nil
+	}
+	out := new(Expiration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubeletConfiguration) DeepCopyInto(out *KubeletConfiguration) {
 	*out = *in
@@ -270,16 +351,31 @@ func (in *ProvisionerSpec) DeepCopyInto(out *ProvisionerSpec) {
 		*out = new(ProviderRef)
 		**out = **in
 	}
+	if in.TerminationGracePeriod != nil {
+		in, out := &in.TerminationGracePeriod, &out.TerminationGracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.TTLSecondsAfterEmpty != nil {
 		in, out := &in.TTLSecondsAfterEmpty, &out.TTLSecondsAfterEmpty
 		*out = new(int64)
 		**out = **in
 	}
+	if in.TTLSecondsAfterNotReady != nil {
+		in, out := &in.TTLSecondsAfterNotReady, &out.TTLSecondsAfterNotReady
+		*out = new(int64)
+		**out = **in
+	}
 	if in.TTLSecondsUntilExpired != nil {
 		in, out := &in.TTLSecondsUntilExpired, &out.TTLSecondsUntilExpired
 		*out = new(int64)
 		**out = **in
 	}
+	if in.ExpirationJitterFactor != nil {
+		in, out := &in.ExpirationJitterFactor, &out.ExpirationJitterFactor
+		*out = new(float64)
+		**out = **in
+	}
 	if in.Limits != nil {
 		in, out := &in.Limits, &out.Limits
 		*out = new(Limits)
@@ -295,6 +391,16 @@ func (in *ProvisionerSpec) DeepCopyInto(out *ProvisionerSpec) {
 		*out = new(Consolidation)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Disruption != nil {
+		in, out := &in.Disruption, &out.Disruption
+		*out = new(Disruption)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Expiration != nil {
+		in, out := &in.Expiration, &out.Expiration
+		*out = new(Expiration)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerSpec.
@@ -0,0 +1,42 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"errors"
+)
+
+// NodeNotFoundError is an error type returned by CloudProviders when the instance backing a node no longer exists
+type NodeNotFoundError struct {
+	error
+}
+
+func NewNodeNotFoundError(err error) NodeNotFoundError {
+	return NodeNotFoundError{
+		error: err,
+	}
+}
+
+func (e NodeNotFoundError) Unwrap() error {
+	return e.error
+}
+
+func IsNodeNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var nnfErr NodeNotFoundError
+	return errors.As(err, &nnfErr)
+}
@@ -34,14 +34,33 @@ import (
 )
 
 var _ cloudprovider.CloudProvider = (*CloudProvider)(nil)
+var _ cloudprovider.InstanceTagger = (*CloudProvider)(nil)
+var _ cloudprovider.NodeGetter = (*CloudProvider)(nil)
+var _ cloudprovider.CapacityChecker = (*CloudProvider)(nil)
+var _ cloudprovider.DriftDetector = (*CloudProvider)(nil)
 
 type CloudProvider struct {
 	InstanceTypes []*cloudprovider.InstanceType
 
 	// CreateCalls contains the arguments for every create call that was made since it was cleared
-	mu                 sync.Mutex
-	CreateCalls        []*cloudprovider.NodeRequest
+	mu          sync.Mutex
+	CreateCalls []*cloudprovider.NodeRequest
+	// CreatedNodes contains the nodes returned by every create call that succeeded since it was cleared
+	CreatedNodes []*v1.Node
+	// AllowedCreateCalls is the number of create calls that succeed before further calls fail, so that a request for
+	// several nodes can be made to create fewer nodes than were asked for
 	AllowedCreateCalls int
+	// InstanceLimit is the number of create calls after which CanCreate reports that no more instances can be created
+	InstanceLimit int
+	// MissingProviderIDs are the provider IDs of nodes whose instances no longer exist
+	MissingProviderIDs sets.String
+	// DriftedProviderIDs are the provider IDs of nodes whose instances have drifted from their provisioner's configuration
+	DriftedProviderIDs sets.String
+	// Tags are the current tags of each instance by provider ID, and TagCalls and UntagCalls contain the provider IDs
+	// of every tag and untag call that was made since they were cleared
+	Tags       map[string]map[string]string
+	TagCalls   []string
+	UntagCalls []string
 }
 
 var _ cloudprovider.CloudProvider = (*CloudProvider)(nil)
@@ -49,6 +68,7 @@ var _ cloudprovider.CloudProvider = (*CloudProvider)(nil)
 func NewCloudProvider() *CloudProvider {
 	return &CloudProvider{
 		AllowedCreateCalls: math.MaxInt,
+		InstanceLimit:      math.MaxInt,
 	}
 }
 
@@ -90,6 +110,9 @@ func (c *CloudProvider) Create(ctx context.Context, nodeRequest *cloudprovider.N
 			ProviderID: fmt.Sprintf("fake://%s", name),
 		},
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.CreatedNodes = append(c.CreatedNodes, n)
 	return n, nil
 }
 
@@ -137,6 +160,56 @@ func (c *CloudProvider) GetInstanceTypes(_ context.Context, provisioner *v1alpha
 	}, nil
 }
 
+func (c *CloudProvider) CanCreate(context.Context, *cloudprovider.InstanceType) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.CreateCalls) < c.InstanceLimit, nil
+}
+
+func (c *CloudProvider) Get(_ context.Context, node *v1.Node) (*v1.Node, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.MissingProviderIDs.Has(node.Spec.ProviderID) {
+		return nil, cloudprovider.NewNodeNotFoundError(fmt.Errorf("instance %s not found", node.Spec.ProviderID))
+	}
+	return node.DeepCopy(), nil
+}
+
+func (c *CloudProvider) IsDrifted(_ context.Context, node *v1.Node) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.DriftedProviderIDs.Has(node.Spec.ProviderID), nil
+}
+
+func (c *CloudProvider) Tag(_ context.Context, providerID string, tags map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.TagCalls = append(c.TagCalls, providerID)
+	if c.Tags == nil {
+		c.Tags = map[string]map[string]string{}
+	}
+	if c.Tags[providerID] == nil {
+		c.Tags[providerID] = map[string]string{}
+	}
+	for k, v := range tags {
+		c.Tags[providerID][k] = v
+	}
+	return nil
+}
+
+func (c *CloudProvider) Untag(_ context.Context, providerID string, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.UntagCalls = append(c.UntagCalls, providerID)
+	for _, k := range keys {
+		delete(c.Tags[providerID], k)
+	}
+	if len(c.Tags[providerID]) == 0 {
+		delete(c.Tags, providerID)
+	}
+	return nil
+}
+
 func (c *CloudProvider) Delete(context.Context, *v1.Node) error {
 	return nil
 }
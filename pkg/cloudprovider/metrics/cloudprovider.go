@@ -77,6 +77,58 @@ func (d *decorator) Delete(ctx context.Context, node *v1.Node) error {
 	return d.CloudProvider.Delete(ctx, node)
 }
 
+// Get delegates to the decorated cloud provider if it's able to look up instances, and otherwise returns the node as is
+func (d *decorator) Get(ctx context.Context, node *v1.Node) (*v1.Node, error) {
+	getter, ok := d.CloudProvider.(cloudprovider.NodeGetter)
+	if !ok {
+		return node, nil
+	}
+	defer metrics.Measure(methodDurationHistogramVec.WithLabelValues(injection.GetControllerName(ctx), "Get", d.Name()))()
+	return getter.Get(ctx, node)
+}
+
+// CanCreate delegates to the decorated cloud provider if it's able to check for capacity, and otherwise assumes that
+// the instance can be created
+func (d *decorator) CanCreate(ctx context.Context, instanceType *cloudprovider.InstanceType) (bool, error) {
+	checker, ok := d.CloudProvider.(cloudprovider.CapacityChecker)
+	if !ok {
+		return true, nil
+	}
+	defer metrics.Measure(methodDurationHistogramVec.WithLabelValues(injection.GetControllerName(ctx), "CanCreate", d.Name()))()
+	return checker.CanCreate(ctx, instanceType)
+}
+
+// IsDrifted delegates to the decorated cloud provider if it's able to detect drift, and otherwise reports that the
+// node hasn't drifted
+func (d *decorator) IsDrifted(ctx context.Context, node *v1.Node) (bool, error) {
+	detector, ok := d.CloudProvider.(cloudprovider.DriftDetector)
+	if !ok {
+		return false, nil
+	}
+	defer metrics.Measure(methodDurationHistogramVec.WithLabelValues(injection.GetControllerName(ctx), "IsDrifted", d.Name()))()
+	return detector.IsDrifted(ctx, node)
+}
+
+// Tag delegates to the decorated cloud provider if it's able to tag instances
+func (d *decorator) Tag(ctx context.Context, providerID string, tags map[string]string) error {
+	tagger, ok := d.CloudProvider.(cloudprovider.InstanceTagger)
+	if !ok {
+		return nil
+	}
+	defer metrics.Measure(methodDurationHistogramVec.WithLabelValues(injection.GetControllerName(ctx), "Tag", d.Name()))()
+	return tagger.Tag(ctx, providerID, tags)
+}
+
+// Untag delegates to the decorated cloud provider if it's able to tag instances
+func (d *decorator) Untag(ctx context.Context, providerID string, keys ...string) error {
+	tagger, ok := d.CloudProvider.(cloudprovider.InstanceTagger)
+	if !ok {
+		return nil
+	}
+	defer metrics.Measure(methodDurationHistogramVec.WithLabelValues(injection.GetControllerName(ctx), "Untag", d.Name()))()
+	return tagger.Untag(ctx, providerID, keys...)
+}
+
 func (d *decorator) GetInstanceTypes(ctx context.Context, provisioner *v1alpha5.Provisioner) ([]*cloudprovider.InstanceType, error) {
 	defer metrics.Measure(methodDurationHistogramVec.WithLabelValues(injection.GetControllerName(ctx), "GetInstanceTypes", d.Name()))()
 	return d.CloudProvider.GetInstanceTypes(ctx, provisioner)
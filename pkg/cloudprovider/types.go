@@ -62,6 +62,39 @@ type CloudProvider interface {
 	Name() string
 }
 
+// InstanceTagger is optionally implemented by cloud providers that can tag the instances backing nodes, so that
+// external automation can tell which instances are about to be deprovisioned.
+type InstanceTagger interface {
+	// Tag adds the tags to the instance with the given provider ID
+	Tag(ctx context.Context, providerID string, tags map[string]string) error
+	// Untag removes the tags with the given keys from the instance with the given provider ID
+	Untag(ctx context.Context, providerID string, keys ...string) error
+}
+
+// NodeGetter is optionally implemented by cloud providers that can look up the instances backing nodes, so that nodes
+// whose instances no longer exist can be removed.
+type NodeGetter interface {
+	// Get returns the node as it's known to the cloudprovider. A NodeNotFoundError is returned if the instance backing
+	// the node no longer exists.
+	Get(context.Context, *v1.Node) (*v1.Node, error)
+}
+
+// CapacityChecker is optionally implemented by cloud providers that know ahead of time whether an instance can be
+// launched, so that existing nodes aren't disrupted to launch replacements that can't be created.
+type CapacityChecker interface {
+	// CanCreate returns false if an instance of the given instance type can't currently be launched, e.g. because an
+	// account or provisioner instance limit has been reached.
+	CanCreate(context.Context, *InstanceType) (bool, error)
+}
+
+// DriftDetector is optionally implemented by cloud providers that can tell when a node no longer matches the
+// configuration that its provisioner would launch it with.
+type DriftDetector interface {
+	// IsDrifted returns true if the node's backing configuration (e.g. its image) no longer matches the configuration
+	// that its provisioner would launch it with, so that the node should be replaced
+	IsDrifted(context.Context, *v1.Node) (bool, error)
+}
+
 type NodeRequest struct {
 	Template            *scheduling.NodeTemplate
 	InstanceTypeOptions []*InstanceType
@@ -106,6 +139,14 @@ type Offering struct {
 	// Available is added so that Offerings can return all offerings that have ever existed for an instance type,
 	// so we can get historical pricing data for calculating savings in consolidation
 	Available bool
+	// CommittedDiscount is the fraction of Price, in the range [0.0, 1.0], that is already covered by a commitment
+	// (e.g. a savings plan or committed use discount), so that running the offering costs less than its list price
+	CommittedDiscount float64
+}
+
+// EffectivePrice is the price of the offering after any committed discount
+func (o Offering) EffectivePrice() float64 {
+	return o.Price * (1 - o.CommittedDiscount)
 }
 
 type Offerings []Offering
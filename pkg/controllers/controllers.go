@@ -35,6 +35,7 @@ import (
 	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/metrics"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
+	"github.com/aws/karpenter-core/pkg/operator/injection"
 	"github.com/aws/karpenter-core/pkg/operator/settingsstore"
 )
 
@@ -57,7 +58,7 @@ func NewControllers(
 	return []controller.Controller{
 		provisioner,
 		metricsstate.NewController(cluster),
-		deprovisioning.NewController(clock, kubeClient, provisioner, cloudProvider, eventRecorder, cluster),
+		deprovisioning.NewController(clock, kubeClient, provisioner, cloudProvider, eventRecorder, cluster, injection.GetOptions(ctx).DryRun),
 		provisioning.NewController(kubeClient, provisioner, eventRecorder),
 		state.NewNodeController(kubeClient, cluster),
 		state.NewPodController(kubeClient, cluster),
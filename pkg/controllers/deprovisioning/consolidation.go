@@ -18,21 +18,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	deprovisioningevents "github.com/aws/karpenter-core/pkg/controllers/deprovisioning/events"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
+	pscheduling "github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/metrics"
 	"github.com/aws/karpenter-core/pkg/scheduling"
+	podutil "github.com/aws/karpenter-core/pkg/utils/pod"
+	"github.com/aws/karpenter-core/pkg/utils/resources"
 )
 
 // consolidation is the base consolidation controller that provides common functionality used across the different
@@ -43,12 +53,47 @@ type consolidation struct {
 	kubeClient             client.Client
 	provisioner            *provisioning.Provisioner
 	cloudProvider          cloudprovider.CloudProvider
+	recorder               events.Recorder
 	lastConsolidationState int64
+	disruptionHistory      *DisruptionHistory
+	// pass counts the candidate sorts performed, seeding the spreading of ties between candidates
+	pass int64
 }
 
-// consolidationTTL is the TTL between creating a consolidation command and validating that it still works.
+// consolidationTTL is the default TTL between creating a consolidation command and validating that it still works.
 const consolidationTTL = 15 * time.Second
 
+// defaultConsolidationMinNodeAge is how old nodes must be to be consolidated, unless their provisioner configures it
+const defaultConsolidationMinNodeAge = 5 * time.Minute
+
+// consolidationMinNodeAge returns how old the provisioner's nodes must be to be consolidated, whether or not they're empty
+func consolidationMinNodeAge(provisioner *v1alpha5.Provisioner) time.Duration {
+	if provisioner.Spec.Consolidation == nil || provisioner.Spec.Consolidation.MinNodeAgeSeconds == nil {
+		return defaultConsolidationMinNodeAge
+	}
+	return time.Duration(*provisioner.Spec.Consolidation.MinNodeAgeSeconds) * time.Second
+}
+
+type validationPeriodKey struct{}
+
+// withValidationPeriod returns a context that carries the consolidation validation period from settings, so that it's
+// read once for an entire deprovisioning pass, along with the period
+func withValidationPeriod(ctx context.Context) (context.Context, time.Duration) {
+	period := settings.FromContext(ctx).ConsolidationValidationPeriod.Duration
+	if period == 0 {
+		period = consolidationTTL
+	}
+	return context.WithValue(ctx, validationPeriodKey{}, period), period
+}
+
+// validationPeriod returns the consolidation validation period carried by the context, falling back to the default
+func validationPeriod(ctx context.Context) time.Duration {
+	if period, ok := ctx.Value(validationPeriodKey{}).(time.Duration); ok {
+		return period
+	}
+	return consolidationTTL
+}
+
 // string is the string representation of the deprovisioner
 func (c *consolidation) String() string {
 	return metrics.ConsolidationReason
@@ -75,18 +120,94 @@ func (c *consolidation) sortAndFilterCandidates(ctx context.Context, nodes []Can
 	}
 
 	// filter out nodes that can't be terminated
-	nodes = lo.Filter(nodes, func(c CandidateNode, _ int) bool {
-		return canBeTerminated(c, pdbs)
+	nodes = lo.Filter(nodes, func(n CandidateNode, _ int) bool {
+		return canBeTerminated(ctx, n, pdbs, c.disruptionHistory)
+	})
+
+	// nodes that launched recently, e.g. as replacements, aren't consolidated again until they're old enough
+	nodes = lo.Reject(nodes, func(n CandidateNode, _ int) bool {
+		return c.clock.Since(n.CreationTimestamp.Time) < consolidationMinNodeAge(n.provisioner)
 	})
 
-	sort.Slice(nodes, func(i int, j int) bool {
+	// we can't simulate whether another node could satisfy the resource claims of pods, so their nodes stay put
+	if settings.FromContext(ctx).PinResourceClaimPods {
+		claims, err := NewResourceClaims(ctx, c.kubeClient)
+		if err != nil {
+			return nil, fmt.Errorf("tracking ResourceClaims, %w", err)
+		}
+		nodes = lo.Reject(nodes, func(n CandidateNode, _ int) bool {
+			_, pinned := claims.Pinned(n.pods)
+			return pinned
+		})
+	}
+
+	nodes = c.SortCandidates(nodes)
+	if settings.FromContext(ctx).SpreadConsolidationTies {
+		// evaluating nodes orders them as the next pass will, without advancing it
+		pass := c.pass + 1
+		if !sideEffectsDisabled(ctx) {
+			c.pass = pass
+		}
+		spreadTies(nodes, pass)
+	}
+	return withoutWarmSpares(nodes), nil
+}
+
+// withoutWarmSpares removes the empty nodes that each provisioner keeps as warm capacity. Candidates are ordered by
+// their disruption cost, so the spares are the empty nodes that are the most costly to disrupt.
+func withoutWarmSpares(nodes []CandidateNode) []CandidateNode {
+	spares := map[string]int{}
+	var kept []CandidateNode
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i]
+		if n.isEmpty() && n.provisioner.Spec.Consolidation != nil &&
+			spares[n.provisioner.Name] < int(ptr.Int32Value(n.provisioner.Spec.Consolidation.WarmSpareNodes)) {
+			spares[n.provisioner.Name]++
+			continue
+		}
+		kept = append(kept, n)
+	}
+	return lo.Reverse(kept)
+}
+
+// SortCandidates orders deprovisionable nodes by their disruption cost, using a stable sort so that nodes with equal
+// disruption costs are always considered in the same order. Nodes that were cordoned by another controller are being
+// drained anyway, so they're considered first.
+func (c *consolidation) SortCandidates(nodes []CandidateNode) []CandidateNode {
+	sort.SliceStable(nodes, func(i int, j int) bool {
+		if cordonedI, cordonedJ := externallyCordoned(nodes[i].Node), externallyCordoned(nodes[j].Node); cordonedI != cordonedJ {
+			return cordonedI
+		}
 		return nodes[i].disruptionCost < nodes[j].disruptionCost
 	})
-	return nodes, nil
+	return nodes
+}
+
+// spreadTies shuffles each run of nodes with equal disruption costs (and that are equally cordoned) so that repeated
+// consolidations spread their churn across equivalent nodes rather than always targeting the first one. The shuffle is
+// seeded by the pass, so a given pass always orders the nodes in the same way.
+func spreadTies(nodes []CandidateNode, pass int64) {
+	// nolint:gosec
+	r := rand.New(rand.NewSource(pass))
+	for start := 0; start < len(nodes); {
+		end := start + 1
+		for end < len(nodes) && nodes[end].disruptionCost == nodes[start].disruptionCost &&
+			externallyCordoned(nodes[end].Node) == externallyCordoned(nodes[start].Node) {
+			end++
+		}
+		r.Shuffle(end-start, func(i, j int) {
+			nodes[start+i], nodes[start+j] = nodes[start+j], nodes[start+i]
+		})
+		start = end
+	}
 }
 
 // ShouldDeprovision is a predicate used to filter deprovisionable nodes
-func (c *consolidation) ShouldDeprovision(_ context.Context, n *state.Node, provisioner *v1alpha5.Provisioner, _ []*v1.Pod) bool {
+func (c *consolidation) ShouldDeprovision(_ context.Context, n *state.Node, provisioner *v1alpha5.Provisioner, pods []*v1.Pod) bool {
+	// the placement of indexed Job pods that spread their completion indexes is deliberate, so we don't disrupt them
+	if lo.SomeBy(pods, podutil.HasSpreadCompletionIndex) {
+		return false
+	}
 	if val, ok := n.Node.Annotations[v1alpha5.DoNotConsolidateNodeAnnotationKey]; ok {
 		return val != "true"
 	}
@@ -102,7 +223,7 @@ func (c *consolidation) ValidateCommand(ctx context.Context, cmd Command, candid
 		return false, nil
 	}
 
-	newNodes, allPodsScheduled, err := simulateScheduling(ctx, c.kubeClient, c.cluster, c.provisioner, nodesToDelete...)
+	newNodes, _, allPodsScheduled, err := simulateScheduling(ctx, c.kubeClient, c.cluster, c.provisioner, nodesToDelete...)
 	if err != nil {
 		return false, fmt.Errorf("simluating scheduling, %w", err)
 	}
@@ -163,7 +284,7 @@ func (c *consolidation) ValidateCommand(ctx context.Context, cmd Command, candid
 func (c *consolidation) computeConsolidation(ctx context.Context, nodes ...CandidateNode) (Command, error) {
 	defer metrics.Measure(deprovisioningDurationHistogram.WithLabelValues("Replace/Delete"))()
 	// Run scheduling simulation to compute consolidation option
-	newNodes, allPodsScheduled, err := simulateScheduling(ctx, c.kubeClient, c.cluster, c.provisioner, nodes...)
+	newNodes, targets, allPodsScheduled, err := simulateScheduling(ctx, c.kubeClient, c.cluster, c.provisioner, nodes...)
 	if err != nil {
 		// if a candidate node is now deleting, just retry
 		if errors.Is(err, errCandidateNodeDeleting) {
@@ -182,25 +303,53 @@ func (c *consolidation) computeConsolidation(ctx context.Context, nodes ...Candi
 		return Command{
 			nodesToRemove: lo.Map(nodes, func(n CandidateNode, _ int) *v1.Node { return n.Node }),
 			action:        actionDelete,
+			targetNodes:   targetNodes(targets),
+			placements:    reschedulePlacements(nodes, targets),
 		}, nil
 	}
 
+	// the delete-only policy never launches nodes, so anything that needs a replacement is left alone
+	if settings.FromContext(ctx).ConsolidationPolicy == settings.ConsolidationPolicyDeleteOnly {
+		return Command{action: actionDoNothing}, nil
+	}
+
 	// we're not going to turn a single node into multiple nodes
 	if len(newNodes) != 1 {
 		return Command{action: actionDoNothing}, nil
 	}
 
+	// displaced pods must be able to satisfy their required node affinity on the replacement, and the replacement must
+	// be an instance type, capacity type, size and zone that settings allow
+	filterByRequiredNodeAffinity(nodes, newNodes[0])
+	filterByReplacementAllowList(ctx, newNodes[0])
+	filterByCapacityType(ctx, nodes, newNodes[0])
+	filterBySizeRatio(ctx, nodes, newNodes[0])
+	filterByZone(ctx, nodes, newNodes[0])
+	// a replacement can't take its provisioner over its limits
+	if err := filterByProvisionerLimits(ctx, c.kubeClient, c.cluster, newNodes[0]); err != nil {
+		return Command{}, fmt.Errorf("filtering by provisioner limits, %w", err)
+	}
+	if len(newNodes[0].InstanceTypeOptions) == 0 {
+		c.reportLimitingRequirements(ctx, nodes, newNodes[0])
+		return Command{action: actionDoNothing}, nil
+	}
+
 	// get the current node price based on the offering
 	// fallback if we can't find the specific zonal pricing data
 	nodesPrice, err := getNodePrices(nodes)
 	if err != nil {
 		return Command{}, fmt.Errorf("getting offering price from candidate node, %w", err)
 	}
-	newNodes[0].InstanceTypeOptions = filterByPrice(newNodes[0].InstanceTypeOptions, newNodes[0].Requirements, nodesPrice)
+	newNodes[0].InstanceTypeOptions = filterByPrice(ctx, newNodes[0].InstanceTypeOptions, newNodes[0].Requirements, maxReplacementPrice(ctx, nodes, nodesPrice))
 	if len(newNodes[0].InstanceTypeOptions) == 0 {
 		// no instance types remain after filtering by price
 		return Command{action: actionDoNothing}, nil
 	}
+	// a replacement that may launch as either capacity type is restricted to the one that provisioning prefers
+	filterByPreferredCapacityType(newNodes[0])
+	if !savesMinimumPercent(nodes, nodesPrice, newNodes[0]) {
+		return Command{action: actionDoNothing}, nil
+	}
 
 	// If the existing nodes are all spot and the replacement is spot, we don't consolidate.  We don't have a reliable
 	// mechanism to determine if this replacement makes sense given instance type availability (e.g. we may replace
@@ -217,23 +366,42 @@ func (c *consolidation) computeConsolidation(ctx context.Context, nodes ...Candi
 		return Command{action: actionDoNothing}, nil
 	}
 
-	// We are consolidating a node from OD -> [OD,Spot] but have filtered the instance types by cost based on the
-	// assumption, that the spot variant will launch. We also need to add a requirement to the node to ensure that if
-	// spot capacity is insufficient we don't replace the node with a more expensive on-demand node.  Instead the launch
-	// should fail and we'll just leave the node alone.
-	ctReq := newNodes[0].Requirements.Get(v1alpha5.LabelCapacityType)
-	if ctReq.Has(v1alpha5.CapacityTypeSpot) && ctReq.Has(v1alpha5.CapacityTypeOnDemand) {
-		newNodes[0].Requirements.Add(scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, v1alpha5.CapacityTypeSpot))
-	}
-
 	return Command{
 		nodesToRemove:    lo.Map(nodes, func(n CandidateNode, _ int) *v1.Node { return n.Node }),
 		action:           actionReplace,
 		replacementNodes: newNodes,
+		targetNodes:      targetNodes(targets),
 	}, nil
 }
 
-// getNodePrices returns the sum of the prices of the given candidate nodes
+// reportLimitingRequirements explains why no instance type could replace the candidate nodes when the replacement's
+// requirements are too tight, naming the requirements that eliminated every instance type
+func (c *consolidation) reportLimitingRequirements(ctx context.Context, nodes []CandidateNode, newNode *pscheduling.Node) {
+	provisioner := &v1alpha5.Provisioner{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: newNode.ProvisionerName}, provisioner); err != nil {
+		logging.FromContext(ctx).Errorf("Getting provisioner, %s", err)
+		return
+	}
+	instanceTypes, err := c.cloudProvider.GetInstanceTypes(ctx, provisioner)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Getting instance types, %s", err)
+		return
+	}
+	limiting := limitingRequirements(instanceTypes, resources.RequestsForPods(newNode.Pods...), newNode.Requirements)
+	if len(limiting) == 0 {
+		return
+	}
+	reason := fmt.Sprintf("%s yields 0 instance types", strings.Join(lo.Map(limiting, func(r *scheduling.Requirement, _ int) string { return r.String() }), " AND "))
+	logging.FromContext(ctx).Debugf("no feasible replacement for %d node(s), %s", len(nodes), reason)
+	if sideEffectsDisabled(ctx) {
+		return
+	}
+	for _, n := range nodes {
+		c.recorder.Publish(deprovisioningevents.NoFeasibleReplacement(n.Node, reason))
+	}
+}
+
+// getNodePrices returns the sum of the effective prices of the given candidate nodes
 func getNodePrices(nodes []CandidateNode) (float64, error) {
 	var price float64
 	for _, n := range nodes {
@@ -241,7 +409,36 @@ func getNodePrices(nodes []CandidateNode) (float64, error) {
 		if !ok {
 			return 0.0, fmt.Errorf("unable to determine offering for %s/%s/%s", n.instanceType.Name, n.capacityType, n.zone)
 		}
-		price += offering.Price
+		price += offering.EffectivePrice()
 	}
 	return price, nil
 }
+
+// maxReplacementPrice returns the price that a replacement for the given candidate nodes must be cheaper than, so that
+// it saves the minimum percentage of their price required by the strictest of their provisioners or the global setting
+func maxReplacementPrice(ctx context.Context, nodes []CandidateNode, price float64) float64 {
+	percent := 0
+	for _, n := range nodes {
+		nodePercent := settings.FromContext(ctx).MinConsolidationSavingsPercent
+		if n.provisioner.Spec.Consolidation != nil && n.provisioner.Spec.Consolidation.MinSavingsPercent != nil {
+			nodePercent = int(*n.provisioner.Spec.Consolidation.MinSavingsPercent)
+		}
+		percent = lo.Max([]int{percent, nodePercent})
+	}
+	return price * float64(100-percent) / 100
+}
+
+// savesMinimumPercent returns true if the cheapest offering that the replacement can launch with saves at least the
+// minimumSavingsPercent of the strictest of the candidate nodes' provisioners, relative to the price of the nodes
+func savesMinimumPercent(nodes []CandidateNode, price float64, replacement *pscheduling.Node) bool {
+	percent := 0.0
+	for _, n := range nodes {
+		if n.provisioner.Spec.Consolidation != nil && n.provisioner.Spec.Consolidation.MinimumSavingsPercent != nil {
+			percent = lo.Max([]float64{percent, *n.provisioner.Spec.Consolidation.MinimumSavingsPercent})
+		}
+	}
+	if percent == 0 {
+		return true
+	}
+	return (price-cheapestLaunchPrice(replacement))/price*100 >= percent
+}
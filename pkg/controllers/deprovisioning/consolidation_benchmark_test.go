@@ -0,0 +1,147 @@
+//go:build test_performance
+
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis"
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
+	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/controllers/deprovisioning"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/operator/scheme"
+	"github.com/aws/karpenter-core/pkg/test"
+	. "github.com/aws/karpenter-core/pkg/test/expectations"
+)
+
+func BenchmarkSingleNodeConsolidation1(b *testing.B) {
+	benchmarkProcessCluster(b, 100, 1)
+}
+func BenchmarkSingleNodeConsolidation4(b *testing.B) {
+	benchmarkProcessCluster(b, 100, 4)
+}
+func BenchmarkSingleNodeConsolidation16(b *testing.B) {
+	benchmarkProcessCluster(b, 100, 16)
+}
+
+// BenchmarkProcessCluster measures deprovisioning passes over a large cluster. Once the first pass has found that
+// nothing can be consolidated, later passes skip the reschedule simulations, so candidate gathering dominates.
+func BenchmarkProcessCluster(b *testing.B) {
+	benchmarkProcessCluster(b, 2000, 4)
+}
+
+// benchmarkProcessCluster measures a deprovisioning pass over a cluster where every node is a consolidation
+// candidate but none of them can be consolidated, so every candidate's reschedule simulation is run on each pass.
+func benchmarkProcessCluster(b *testing.B, nodeCount int, concurrency int) {
+	RegisterFailHandler(func(message string, _ ...int) { b.Fatal(message) })
+	s := test.Settings()
+	s.SimulationConcurrency = concurrency
+	ctx := settings.ToContext(logging.WithLogger(context.Background(), zap.NewNop().Sugar()), s)
+
+	env := test.NewEnvironment(scheme.Scheme, apis.CRDs...)
+	defer func() { Expect(env.Stop()).To(Succeed()) }()
+
+	// there is only a single instance type, so a replacement is never cheaper than the node it would replace
+	instanceType := fake.NewInstanceType(fake.InstanceTypeOptions{
+		Name: "default-instance-type",
+		Offerings: []cloudprovider.Offering{
+			{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 1, Available: true},
+		},
+	})
+	cloudProvider := fake.NewCloudProvider()
+	cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{instanceType}
+	fakeClock := clock.NewFakeClock(time.Now())
+	cluster := state.NewCluster(ctx, fakeClock, env.Client, cloudProvider)
+	nodeStateController := state.NewNodeController(env.Client, cluster)
+	recorder := test.NewEventRecorder()
+	provisioner := provisioning.NewProvisioner(ctx, env.Client, env.KubernetesInterface.CoreV1(), recorder, cloudProvider, cluster, test.SettingsStore{})
+	deprovisioningController := deprovisioning.NewController(fakeClock, env.Client, provisioner, cloudProvider, recorder, cluster, false)
+
+	labels := map[string]string{
+		"app": "test",
+	}
+	rs := test.ReplicaSet()
+	ExpectApplied(ctx, env.Client, rs)
+	Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+	prov := test.Provisioner(test.ProvisionerOptions{
+		Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+	})
+	ExpectApplied(ctx, env.Client, prov)
+	for i := 0; i < nodeCount; i++ {
+		// pod anti-affinity prevents any of the pods from sharing a node, so no node can be deleted
+		pod := test.Pod(test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}},
+			PodAntiRequirements: []v1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
+					TopologyKey:   v1.LabelHostname,
+				},
+			},
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       instanceType.Name,
+					v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+					v1.LabelTopologyZone:             "test-zone-1",
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		ExpectApplied(ctx, env.Client, pod, node)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+	}
+	fakeClock.Step(10 * time.Minute)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := deprovisioningController.ProcessCluster(ctx); err != nil {
+			b.Fatalf("processing cluster, %s", err)
+		}
+	}
+	b.StopTimer()
+	Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+}
@@ -17,15 +17,22 @@ package deprovisioning
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
@@ -33,6 +40,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
 
@@ -42,6 +50,8 @@ import (
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/metrics"
+	nodeutils "github.com/aws/karpenter-core/pkg/utils/node"
+	"github.com/aws/karpenter-core/pkg/utils/pod"
 )
 
 // Controller is the deprovisioning controller.
@@ -54,14 +64,57 @@ type Controller struct {
 	cloudProvider           cloudprovider.CloudProvider
 	emptiness               *Emptiness
 	expiration              *Expiration
+	notReady                *NotReady
+	drift                   *Drift
 	singleNodeConsolidation *SingleNodeConsolidation
 	multiNodeConsolidation  *MultiNodeConsolidation
 	emptyNodeConsolidation  *EmptyNodeConsolidation
+	disruptionHistory       *DisruptionHistory
+	replacementHistory      *ReplacementHistory
+	summaries               *Summaries
+	// tracer is optional, if set each deprovisioning pass is traced
+	tracer trace.Tracer
+	// dryRun computes commands without executing them, so that the actions deprovisioning would take can be previewed
+	dryRun bool
+	// deprovisioners are attempted in order, the built-in deprovisioners followed by any that were registered
+	deprovisioners []Deprovisioner
+	// lastLaunchFailure is the last time that a replacement node failed to launch or become ready
+	lastLaunchFailure time.Time
+	// lastAction is the last time that each deprovisioner executed a command, by the deprovisioner's name
+	lastAction map[string]time.Time
+	// lastCostProjection is the projected cost of the cluster for the last command that was executed
+	lastCostProjection CostProjection
+	// lastCordonRecovery is the last time that orphaned cordons were recovered
+	lastCordonRecovery time.Time
+	// inflight are the names of the nodes that a command is currently executing for, and is guarded by mu
+	inflight sets.String
+	// validationPeriod is the consolidation validation period read from settings by the latest pass, and is guarded by mu
+	validationPeriod time.Duration
+	// rescheduleVerifications are the commands whose displaced pods are checked for having rescheduled by a later pass,
+	// and is guarded by mu
+	rescheduleVerifications []rescheduleVerification
+	mu                      sync.Mutex
 }
 
 // pollingPeriod that we inspect cluster to look for opportunities to deprovision
 const pollingPeriod = 10 * time.Second
 
+// launchFailureSuppressionPeriod is how long consolidation that moves pods onto the remaining capacity is deferred after
+// a replacement node fails to launch, so that we don't compound a bad state while the cluster's capacity is uncertain
+const launchFailureSuppressionPeriod = 2 * time.Minute
+
+// savingsPeriod is how long the savings of a consolidation action are reported for, so that the savings of an action
+// taken long ago aren't mistaken for current savings
+const savingsPeriod = 15 * time.Minute
+
+// cordonRecoveryPeriod is how often nodes are checked for orphaned cordons. Cordons are only orphaned when the
+// controller restarts mid-command, so this is done on startup and infrequently after that.
+const cordonRecoveryPeriod = 10 * time.Minute
+
+// maxAnnotatedReplacementTypes is the number of a replacement's cheapest instance types that are recorded on the nodes
+// that it replaces
+const maxAnnotatedReplacementTypes = 5
+
 var errCandidateNodeDeleting = fmt.Errorf("candidate node is deleting")
 
 // waitRetryOptions are the retry options used when waiting on a node to become ready or to be deleted
@@ -74,21 +127,63 @@ var waitRetryOptions = []retry.Option{
 	retry.MaxDelay(10 * time.Second), // 22 + (60-5)*10 =~ 9.5 minutes in total
 }
 
+// NewController constructs the deprovisioning controller. Any deprovisioners that are passed are registered after the
+// built-in deprovisioners, and are only attempted when none of the built-in deprovisioners find something to do. In dry
+// run mode commands are computed and reported, but no nodes are terminated or launched, and ProcessCluster returns
+// ResultNothingToDo along with the command that it would have executed.
 func NewController(clk clock.Clock, kubeClient client.Client, provisioner *provisioning.Provisioner,
-	cp cloudprovider.CloudProvider, recorder events.Recorder, cluster *state.Cluster) *Controller {
-	return &Controller{
+	cp cloudprovider.CloudProvider, recorder events.Recorder, cluster *state.Cluster, dryRun bool, deprovisioners ...Deprovisioner) *Controller {
+	history := NewDisruptionHistory(clk)
+	c := &Controller{
 		clock:                   clk,
 		kubeClient:              kubeClient,
 		cluster:                 cluster,
 		provisioner:             provisioner,
 		recorder:                recorder,
 		cloudProvider:           cp,
-		expiration:              NewExpiration(clk, kubeClient, cluster, provisioner),
-		emptiness:               NewEmptiness(clk, kubeClient, cluster),
-		emptyNodeConsolidation:  NewEmptyNodeConsolidation(clk, cluster, kubeClient, provisioner, cp),
-		multiNodeConsolidation:  NewMultiNodeConsolidation(clk, cluster, kubeClient, provisioner, cp),
-		singleNodeConsolidation: NewSingleNodeConsolidation(clk, cluster, kubeClient, provisioner, cp),
+		disruptionHistory:       history,
+		replacementHistory:      NewReplacementHistory(clk),
+		summaries:               NewSummaries(clk),
+		dryRun:                  dryRun,
+		inflight:                sets.NewString(),
+		lastAction:              map[string]time.Time{},
+		expiration:              NewExpiration(clk, kubeClient, cluster, provisioner, recorder),
+		notReady:                NewNotReady(clk),
+		drift:                   NewDrift(clk, kubeClient, cluster, provisioner, cp),
+		emptiness:               NewEmptiness(clk, kubeClient, cluster, provisioner, history),
+		emptyNodeConsolidation:  NewEmptyNodeConsolidation(clk, cluster, kubeClient, provisioner, cp, recorder, history),
+		multiNodeConsolidation:  NewMultiNodeConsolidation(clk, cluster, kubeClient, provisioner, cp, recorder, history),
+		singleNodeConsolidation: NewSingleNodeConsolidation(clk, cluster, kubeClient, provisioner, cp, recorder, history),
 	}
+	c.deprovisioners = append([]Deprovisioner{
+		// Expire any nodes that must be deleted, allowing their pods to potentially land on currently
+		// empty nodes
+		c.expiration,
+
+		// Delete any nodes that have failed to become ready, as they aren't running their pods
+		c.notReady,
+
+		// Replace any nodes that have drifted from their provisioner's configuration
+		c.drift,
+
+		// Delete any remaining empty nodes as there is zero cost in terms of dirsuption.  Emptiness and
+		// emptyNodeConsolidation are mutually exclusive, only one of these will operate
+		c.emptiness,
+		c.emptyNodeConsolidation,
+
+		// Attempt to identify multiple nodes that we can consolidate simultaneously to reduce pod churn
+		c.multiNodeConsolidation,
+
+		// And finally fall back our single node consolidation to further reduce cluster cost.
+		c.singleNodeConsolidation,
+	}, deprovisioners...)
+	return c
+}
+
+// WithUsageSource allows emptiness to treat nodes whose reported resource usage is idle as empty
+func (c *Controller) WithUsageSource(usageSource UsageSource) *Controller {
+	c.emptiness.usageSource = usageSource
+	return c
 }
 
 func (c *Controller) Name() string {
@@ -102,9 +197,17 @@ func (c *Controller) Builder(_ context.Context, m manager.Manager) controller.Bu
 func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
 	// capture the state of the cluster before we do any analysis
 	currentState := c.cluster.ClusterConsolidationState()
+	if c.lastCordonRecovery.IsZero() || c.clock.Since(c.lastCordonRecovery) >= cordonRecoveryPeriod {
+		if err := c.recoverOrphanedCordons(ctx); err != nil {
+			logging.FromContext(ctx).Errorf("recovering orphaned cordons, %s", err)
+		} else {
+			c.lastCordonRecovery = c.clock.Now()
+		}
+	}
+	c.expireSavings()
 	result, err := c.ProcessCluster(ctx)
 
-	switch result {
+	switch result.Result {
 	case ResultFailed:
 		return reconcile.Result{}, fmt.Errorf("processing cluster, %w", err)
 	case ResultRetry:
@@ -130,98 +233,400 @@ type CandidateNode struct {
 	provisioner    *v1alpha5.Provisioner
 	disruptionCost float64
 	pods           []*v1.Pod
+	// crashLoopingPods is how many of the pods have been crash looping for the CrashLoopingPodWindow. They're rescheduled
+	// along with the other pods, but as they provide no service they don't keep the node occupied.
+	crashLoopingPods int
 }
 
-// ProcessCluster is exposed for unit testing purposes
-// ProcessCluster loops through implemented deprovisioners
-func (c *Controller) ProcessCluster(ctx context.Context) (Result, error) {
-	// range over the different deprovisioning methods. We'll only let one method perform an action
-	for _, d := range []Deprovisioner{
-		// Expire any nodes that must be deleted, allowing their pods to potentially land on currently
-		// empty nodes
-		c.expiration,
+// isEmpty returns true if none of the node's pods provide service
+func (c CandidateNode) isEmpty() bool {
+	return len(c.pods) == c.crashLoopingPods
+}
 
-		// Delete any remaining empty nodes as there is zero cost in terms of dirsuption.  Emptiness and
-		// emptyNodeConsolidation are mutually exclusive, only one of these will operate
-		c.emptiness,
-		c.emptyNodeConsolidation,
+// WithTracer allows each deprovisioning pass to be traced, with spans for the pass, the commands that each deprovisioner
+// computes and the scheduling simulations that they run
+func (c *Controller) WithTracer(tracer trace.Tracer) *Controller {
+	c.tracer = tracer
+	return c
+}
 
-		// Attempt to identify multiple nodes that we can consolidate simultaneously to reduce pod churn
-		c.multiNodeConsolidation,
+// ProcessCluster is exposed for unit testing purposes
+// ProcessCluster loops through implemented deprovisioners, and after a successful action makes up to
+// ConsolidationCascadeLimit more passes to pick up deprovisioning that the action enabled. The deprovisioning summary
+// of each provisioner is written once the passes are complete.
+func (c *Controller) ProcessCluster(ctx context.Context) (result DeprovisioningResult, err error) {
+	ctx, span := startSpan(withTracer(ctx, c.tracer), "deprovisioning.ProcessCluster")
+	ctx, period := withValidationPeriod(ctx)
+	c.mu.Lock()
+	c.validationPeriod = period
+	c.mu.Unlock()
+	defer func() {
+		span.SetAttributes(attribute.String("result", result.Result.String()))
+		span.End()
+	}()
+	c.emptiness.pruneIdleTimers()
+	c.verifyRescheduled(ctx)
+	// warnings are recorded before checking the maintenance windows, so that they're given ahead of the next window
+	if !c.dryRun {
+		if err := c.expiration.WarnOfExpirations(ctx); err != nil {
+			logging.FromContext(ctx).Errorf("warning of expirations, %s", err)
+		}
+	}
+	// consolidation reconsiders the cluster at least every five minutes even if nothing changes, so it's attempted
+	// shortly after a window opens
+	if !settings.FromContext(ctx).InMaintenanceWindow(c.clock.Now()) {
+		logging.FromContext(ctx).Debugf("deferring deprovisioning until the next maintenance window")
+		return DeprovisioningResult{Result: ResultNothingToDo}, nil
+	}
+	// a dry run mustn't change the cluster, so it neither taints the nodes it validates nor writes summaries
+	if c.dryRun {
+		ctx = withDryRun(ctx)
+	} else {
+		defer c.summaries.Write(ctx, c.kubeClient)
+	}
+	result, err = c.processCluster(ctx)
+	// a dry run leaves the cluster as it was, so a cascade would only compute the same command again
+	for i := 0; i < settings.FromContext(ctx).ConsolidationCascadeLimit && result.Result == ResultSuccess && !c.dryRun; i++ {
+		next, err := c.processCluster(ctx)
+		if err != nil {
+			return DeprovisioningResult{Result: ResultFailed}, err
+		}
+		if next.Result == ResultNothingToDo {
+			// the cascade has finished, but we still deprovisioned something in this pass
+			break
+		}
+		result = result.cascade(next)
+	}
+	return result, err
+}
 
-		// And finally fall back our single node consolidation to further reduce cluster cost.
-		c.singleNodeConsolidation,
-	} {
-		candidates, err := candidateNodes(ctx, c.cluster, c.kubeClient, c.clock, c.cloudProvider, d.ShouldDeprovision)
+// processCluster makes a single pass through the deprovisioners, performing at most one action
+func (c *Controller) processCluster(ctx context.Context) (DeprovisioningResult, error) {
+	// range over the different deprovisioning methods. We'll only let one method perform an action
+	for _, d := range c.deprovisioners {
+		if reason, deferred := c.deferralReason(ctx, d); deferred {
+			logging.FromContext(ctx).Debugf("deferring %s, %s", d, reason)
+			continue
+		}
+		candidates, err := candidateNodes(ctx, c.cluster, c.kubeClient, c.clock, c.cloudProvider, d.ShouldDeprovision, includesUninitialized(d))
 		if err != nil {
-			return ResultFailed, fmt.Errorf("determining candidate nodes, %w", err)
+			return DeprovisioningResult{Result: ResultFailed}, fmt.Errorf("determining candidate nodes, %w", err)
 		}
 		// If there are no candidate nodes, move to the next deprovisioner
 		if len(candidates) == 0 {
 			continue
 		}
+		// nodes of provisioners whose disruption budgets are exhausted aren't deprovisioned until their disrupted nodes
+		// are gone
+		budgets, err := NewDisruptionBudgets(ctx, c.kubeClient, c.cluster)
+		if err != nil {
+			return DeprovisioningResult{Result: ResultFailed}, fmt.Errorf("tracking disruption budgets, %w", err)
+		}
+		exhausted := sets.NewString()
+		candidates = lo.Reject(candidates, func(n CandidateNode, _ int) bool {
+			if !budgets.Exhausted(n.provisioner.Name) {
+				return false
+			}
+			if !exhausted.Has(n.provisioner.Name) {
+				exhausted.Insert(n.provisioner.Name)
+				logging.FromContext(ctx).With("provisioner", n.provisioner.Name).Debugf("deferring %s, disruption budget is exhausted", d)
+			}
+			return true
+		})
+		if len(candidates) == 0 {
+			continue
+		}
 
-		result, err := c.executeDeprovisioning(ctx, d, candidates...)
+		result, err := c.executeDeprovisioning(ctx, d, d.SortCandidates(candidates)...)
 		if err != nil {
-			return ResultFailed, fmt.Errorf("deprovisioning nodes, %w", err)
+			return DeprovisioningResult{Result: ResultFailed}, fmt.Errorf("deprovisioning nodes, %w", err)
 		}
 
-		switch result {
+		switch result.Result {
 		case ResultFailed:
-			return ResultFailed, err
+			return result, err
 		case ResultRetry, ResultSuccess:
 			// the controller wants to retry, or was successful in deprovisioning
 			return result, nil
 		case ResultNothingToDo:
+			// a dry run computed a command, so report it rather than computing another
+			if result.Action != "" {
+				return result, nil
+			}
 			// found nothing to do, so try the next deprovisioner
 			continue
 		default:
-			logging.FromContext(ctx).Errorf("unexpected result %s", result)
+			logging.FromContext(ctx).Errorf("unexpected result %s", result.Result)
 		}
 	}
 
 	// All deprovisioners did nothing, so return nothing to do
-	return ResultNothingToDo, nil
+	return DeprovisioningResult{Result: ResultNothingToDo}, nil
+}
+
+// LastCostProjection returns the hourly cost of the cluster before and after the last command that was executed, and
+// is exposed for unit testing purposes
+func (c *Controller) LastCostProjection() CostProjection {
+	return c.lastCostProjection
+}
+
+// ValidationTimeout returns how long consolidation waits before re-validating a command, and is exposed for unit testing
+// purposes
+func (c *Controller) ValidationTimeout() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.validationPeriod == 0 {
+		return consolidationTTL
+	}
+	return c.validationPeriod
+}
+
+// deferralReason returns why the deprovisioner is deferred from acting this pass, if it is
+func (c *Controller) deferralReason(ctx context.Context, d Deprovisioner) (string, bool) {
+	switch {
+	case c.suppressedByLaunchFailure(d):
+		return "a replacement launch failed recently", true
+	case c.suppressedByScaleUp(ctx, d):
+		return "nodes were scaled up recently", true
+	case c.suppressedByCooldown(ctx, d):
+		return "its cool-down hasn't elapsed", true
+	}
+	return "", false
+}
+
+// suppressedByLaunchFailure returns true if the deprovisioner moves pods onto remaining capacity and a replacement node
+// recently failed to launch
+func (c *Controller) suppressedByLaunchFailure(d Deprovisioner) bool {
+	if d != c.multiNodeConsolidation && d != c.singleNodeConsolidation {
+		return false
+	}
+	return !c.lastLaunchFailure.IsZero() && c.clock.Since(c.lastLaunchFailure) < launchFailureSuppressionPeriod
+}
+
+// suppressedByScaleUp returns true if the deprovisioner is consolidation and at least the scale-up threshold of nodes
+// launched within the cool-down, since consolidating right after a large scale-up is likely premature
+func (c *Controller) suppressedByScaleUp(ctx context.Context, d Deprovisioner) bool {
+	cooldown := settings.FromContext(ctx).ConsolidationScaleUpCooldown.Duration
+	if cooldown == 0 || (d != c.multiNodeConsolidation && d != c.singleNodeConsolidation && d != c.emptyNodeConsolidation) {
+		return false
+	}
+	launched := 0
+	c.cluster.ForEachNode(func(n *state.Node) bool {
+		if _, ok := n.Node.Labels[v1alpha5.ProvisionerNameLabelKey]; ok && c.clock.Since(n.Node.CreationTimestamp.Time) < cooldown {
+			launched++
+		}
+		return true
+	})
+	return launched >= settings.FromContext(ctx).ConsolidationScaleUpThreshold
+}
+
+// suppressedByCooldown returns true if the deprovisioner executed a command within its configured cool-down
+func (c *Controller) suppressedByCooldown(ctx context.Context, d Deprovisioner) bool {
+	cooldown, ok := settings.FromContext(ctx).DeprovisioningCooldowns[d.String()]
+	if !ok || cooldown.Duration == 0 {
+		return false
+	}
+	last, ok := c.lastAction[d.String()]
+	return ok && c.clock.Since(last) < cooldown.Duration
 }
 
 // Given candidate nodes, compute best deprovisioning action
-func (c *Controller) executeDeprovisioning(ctx context.Context, d Deprovisioner, nodes ...CandidateNode) (Result, error) {
+func (c *Controller) executeDeprovisioning(ctx context.Context, d Deprovisioner, nodes ...CandidateNode) (DeprovisioningResult, error) {
 	// Each attempt will try at least one node, limit to that many attempts.
-	cmd, err := d.ComputeCommand(ctx, nodes...)
+	cmd, err := c.computeCommand(ctx, d, nodes...)
 	if err != nil {
-		return ResultFailed, err
+		return DeprovisioningResult{Result: ResultFailed}, err
 	}
 	// Convert action to result
 	switch cmd.action {
 	case actionFailed:
-		return ResultFailed, err
+		return DeprovisioningResult{Result: ResultFailed}, err
 	case actionDoNothing:
-		return ResultNothingToDo, nil
+		return DeprovisioningResult{Result: ResultNothingToDo}, nil
 	case actionRetry:
-		return ResultRetry, nil
+		return DeprovisioningResult{Result: ResultRetry}, nil
+	}
+	return c.applyCommand(ctx, d, cmd)
+}
+
+// computeCommand computes the deprovisioner's command within a span that records the candidates and the decision
+func (c *Controller) computeCommand(ctx context.Context, d Deprovisioner, nodes ...CandidateNode) (Command, error) {
+	ctx, span := startSpan(ctx, "deprovisioning.ComputeCommand")
+	defer span.End()
+	cmd, err := d.ComputeCommand(ctx, nodes...)
+	attributes := []attribute.KeyValue{
+		attribute.String("deprovisioner", d.String()),
+		attribute.Int("candidates", len(nodes)),
+		attribute.String("action", cmd.action.String()),
+	}
+	if len(cmd.nodesToRemove) != 0 {
+		attributes = append(attributes, attribute.StringSlice("nodesToRemove", lo.Map(cmd.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name })))
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.SetAttributes(attributes...)
+	return cmd, err
+}
+
+// applyCommand checks that a computed delete or replace command can proceed, and executes it if so
+func (c *Controller) applyCommand(ctx context.Context, d Deprovisioner, cmd Command) (DeprovisioningResult, error) {
+	// nodes are cordoned when the command executes, so any validation taint that's left is from a command that didn't
+	defer func() {
+		if err := setValidationTaint(ctx, c.kubeClient, false, lo.Map(cmd.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name })...); err != nil {
+			logging.FromContext(ctx).Errorf("removing validation taint, %s", err)
+		}
+	}()
+	if d == c.multiNodeConsolidation || d == c.singleNodeConsolidation {
+		if reason, reverses := c.replacementHistory.Reverses(ctx, cmd); reverses {
+			consolidationOscillationsCounter.Inc()
+			logging.FromContext(ctx).Infof("detected consolidation oscillation, %s", reason)
+			for _, node := range cmd.nodesToRemove {
+				c.recorder.Publish(deprovisioningevents.ConsolidationOscillation(node, reason))
+			}
+			if settings.FromContext(ctx).SuppressConsolidationOscillation {
+				c.summaries.RecordSkip(cmd.nodesToRemove, reason)
+				return DeprovisioningResult{Result: ResultNothingToDo}, nil
+			}
+		}
+	}
+	// the command may disrupt more of a provisioner's nodes than its budget allows, even if each candidate was within it
+	budgets, err := NewDisruptionBudgets(ctx, c.kubeClient, c.cluster)
+	if err != nil {
+		return DeprovisioningResult{Result: ResultFailed}, fmt.Errorf("tracking disruption budgets, %w", err)
+	}
+	if provisionerName, ok := budgets.CanDisrupt(cmd.nodesToRemove); !ok {
+		reason := fmt.Sprintf("disruption budget of provisioner %s would be exceeded", provisionerName)
+		logging.FromContext(ctx).Infof("skipping %s, %s", d, reason)
+		c.summaries.RecordSkip(cmd.nodesToRemove, reason)
+		return DeprovisioningResult{Result: ResultNothingToDo}, nil
+	}
+	if c.isConsolidation(d) {
+		if reason, below := c.belowCapacityFloor(ctx, cmd); below {
+			logging.FromContext(ctx).Infof("skipping consolidation, %s", reason)
+			c.summaries.RecordSkip(cmd.nodesToRemove, reason)
+			return DeprovisioningResult{Result: ResultNothingToDo}, nil
+		}
 	}
+	// If we need to launch replacements, ensure that we are able to before we start cordoning nodes
+	if cmd.action == actionReplace {
+		canCreate, err := c.canCreateReplacementNodes(ctx, cmd)
+		if err != nil {
+			return DeprovisioningResult{Result: ResultFailed}, fmt.Errorf("checking if replacement nodes can be created, %w", err)
+		}
+		if !canCreate {
+			for _, node := range cmd.nodesToRemove {
+				c.recorder.Publish(deprovisioningevents.LaunchBlocked(node, cmd.String()))
+			}
+			c.summaries.RecordSkip(cmd.nodesToRemove, "unable to launch replacement node, instance limit reached")
+			return DeprovisioningResult{Result: ResultNothingToDo}, nil
+		}
+	}
+	projection, err := c.projectCost(ctx, cmd)
+	if err != nil {
+		return DeprovisioningResult{Result: ResultFailed}, fmt.Errorf("projecting cluster cost, %w", err)
+	}
+	c.lastCostProjection = projection
+	logging.FromContext(ctx).Debugf("projected hourly cluster cost of $%.4f after deprovisioning, from $%.4f", projection.Projected, projection.Current)
+	cmd.dryRun = c.dryRun
 	// If delete or replace, execute command
 	result, err := c.executeCommand(ctx, cmd, d)
 	if err != nil {
-		return ResultFailed, err
+		return DeprovisioningResult{Result: ResultFailed}, err
+	}
+	deprovisioningResult := DeprovisioningResult{
+		Result:                   result,
+		Action:                   fmt.Sprintf("%s/%s", d, cmd.action),
+		RemovedNodes:             lo.Map(cmd.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name }),
+		ReplacementInstanceTypes: replacementInstanceTypes(cmd),
+		CostDelta:                projection.Projected - projection.Current,
+	}
+	if cmd.dryRun {
+		return deprovisioningResult, nil
+	}
+	c.lastAction[d.String()] = c.clock.Now()
+	if c.isConsolidation(d) && result == ResultSuccess && (cmd.action == actionReplace || cmd.action == actionDelete) {
+		deprovisioningSavingsGauge.Set(-deprovisioningResult.CostDelta)
+	}
+	if _, instanceTypesByProvisioner, err := buildProvisionerMap(ctx, c.kubeClient, c.cloudProvider); err != nil {
+		logging.FromContext(ctx).Errorf("summarizing deprovisioning, %s", err)
+	} else {
+		c.summaries.RecordCommand(cmd, instanceTypesByProvisioner)
+	}
+	return deprovisioningResult, nil
+}
+
+// isConsolidation returns true if the deprovisioner is one of the consolidation deprovisioners
+func (c *Controller) isConsolidation(d Deprovisioner) bool {
+	return d == c.multiNodeConsolidation || d == c.singleNodeConsolidation || d == c.emptyNodeConsolidation
+}
+
+// expireSavings zeroes the reported savings if consolidation hasn't taken an action within the savings period
+func (c *Controller) expireSavings() {
+	if last, ok := c.lastAction[metrics.ConsolidationReason]; ok && c.clock.Since(last) < savingsPeriod {
+		return
 	}
-	return result, nil
+	deprovisioningSavingsGauge.Set(0)
 }
 
 func (c *Controller) executeCommand(ctx context.Context, command Command, d Deprovisioner) (Result, error) {
+	if command.dryRun {
+		deprovisioningDryRunActionsCounter.With(prometheus.Labels{"action": fmt.Sprintf("%s/%s", d, command.action)}).Inc()
+		logging.FromContext(ctx).With(
+			"deprovisioner", d.String(),
+			"action", command.action.String(),
+			"nodes", strings.Join(lo.Map(command.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name }), ","),
+			"replacements", len(command.replacementNodes),
+		).Infof("dry run, skipping deprovisioning via %s %s", d, command)
+		return ResultNothingToDo, nil
+	}
 	deprovisioningActionsPerformedCounter.With(prometheus.Labels{"action": fmt.Sprintf("%s/%s", d, command.action)}).Add(1)
 	logging.FromContext(ctx).Infof("deprovisioning via %s %s", d, command)
 
+	// cordon all of the old nodes before any of them start draining, so that pods evicted from one of the nodes can't
+	// schedule to another node that is about to be removed
+	nodeNamesToRemove := lo.Map(command.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name })
+	c.setInflight(true, nodeNamesToRemove...)
+	defer c.setInflight(false, nodeNamesToRemove...)
+	// let operators watching the nodes see what's going to replace them before it launches
 	if command.action == actionReplace {
-		if err := c.launchReplacementNodes(ctx, command); err != nil {
+		for _, oldNode := range command.nodesToRemove {
+			c.annotateReplacement(ctx, oldNode, strings.Join(replacementInstanceTypes(command), ","))
+		}
+	}
+	if err := c.setNodesUnschedulable(ctx, true, nodeNamesToRemove...); err != nil {
+		return ResultFailed, multierr.Combine(fmt.Errorf("cordoning nodes, %w", err), c.setNodesUnschedulable(ctx, false, nodeNamesToRemove...))
+	}
+
+	if command.action == actionReplace {
+		launched, err := c.launchReplacementNodes(ctx, command)
+		if err != nil {
+			c.lastLaunchFailure = c.clock.Now()
 			// If we failed to launch the replacement, don't deprovision.  If this is some permanent failure,
 			// we don't want to disrupt workloads with no way to provision new nodes for them.
-			return ResultFailed, fmt.Errorf("launching replacement node, %w", err)
+			return ResultFailed, multierr.Combine(fmt.Errorf("launching replacement node, %w", err), c.setNodesUnschedulable(ctx, false, nodeNamesToRemove...))
+		}
+		if d == c.multiNodeConsolidation || d == c.singleNodeConsolidation {
+			c.replacementHistory.Record(command.nodesToRemove, launched)
 		}
 	}
 
+	pods, err := nodeutils.GetNodePods(ctx, c.kubeClient, command.nodesToRemove...)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Listing pods for disruption history, %s", err)
+	} else {
+		c.disruptionHistory.Record(pods)
+	}
+	// let operators know why existing nodes are about to receive more pods
+	for _, targetNode := range command.targetNodes {
+		c.recorder.Publish(deprovisioningevents.ReschedulingTarget(targetNode, command.String()))
+	}
+	for pod, nodeName := range command.placements {
+		logging.FromContext(ctx).With("pod", pod, "node", nodeName).Debugf("expecting displaced pod to reschedule to existing node")
+	}
 	for _, oldNode := range command.nodesToRemove {
 		c.recorder.Publish(deprovisioningevents.TerminatingNode(oldNode, command.String()))
+		c.annotateTerminationReason(ctx, oldNode, fmt.Sprintf("%s/%s", d, command.action))
 		if err := c.kubeClient.Delete(ctx, oldNode); err != nil {
 			logging.FromContext(ctx).Errorf("Deleting node, %s", err)
 		} else {
@@ -234,9 +639,113 @@ func (c *Controller) executeCommand(ctx context.Context, command Command, d Depr
 	for _, oldnode := range command.nodesToRemove {
 		c.waitForDeletion(ctx, oldnode)
 	}
+	if threshold := settings.FromContext(ctx).RescheduleVerificationThreshold.Duration; threshold > 0 && command.action == actionDelete {
+		c.scheduleRescheduleVerification(fmt.Sprintf("%s/%s", d, command.action), pods, threshold)
+	}
 	return ResultSuccess, nil
 }
 
+// rescheduleVerification is a check that the pods displaced by deleting nodes without replacements have rescheduled
+type rescheduleVerification struct {
+	// reason is the deprovisioner and action of the command that displaced the pods
+	reason string
+	// owners are the UIDs of the displaced pods' controllers, by the namespace of the pods
+	owners    map[string]sets.String
+	threshold time.Duration
+	due       time.Time
+}
+
+// scheduleRescheduleVerification records the controllers of the displaced pods, so that the first pass after the
+// threshold can verify that they rescheduled. Pods without a controller aren't recreated, so they can't be verified.
+func (c *Controller) scheduleRescheduleVerification(reason string, displaced []*v1.Pod, threshold time.Duration) {
+	owners := map[string]sets.String{}
+	for _, p := range displaced {
+		owner := metav1.GetControllerOf(p)
+		if owner == nil {
+			continue
+		}
+		if _, ok := owners[p.Namespace]; !ok {
+			owners[p.Namespace] = sets.NewString()
+		}
+		owners[p.Namespace].Insert(string(owner.UID))
+	}
+	if len(owners) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rescheduleVerifications = append(c.rescheduleVerifications, rescheduleVerification{
+		reason:    reason,
+		owners:    owners,
+		threshold: threshold,
+		due:       c.clock.Now().Add(threshold),
+	})
+}
+
+// verifyRescheduled reports the pods of the displaced pods' controllers that are still pending once a verification is
+// due, since deleting the nodes without replacements relied upon the pods rescheduling to existing nodes
+func (c *Controller) verifyRescheduled(ctx context.Context) {
+	c.mu.Lock()
+	var due []rescheduleVerification
+	pending := c.rescheduleVerifications[:0]
+	for _, verification := range c.rescheduleVerifications {
+		if c.clock.Now().Before(verification.due) {
+			pending = append(pending, verification)
+		} else {
+			due = append(due, verification)
+		}
+	}
+	c.rescheduleVerifications = pending
+	c.mu.Unlock()
+
+	for _, verification := range due {
+		for namespace, owners := range verification.owners {
+			var podList v1.PodList
+			if err := c.kubeClient.List(ctx, &podList, client.InNamespace(namespace)); err != nil {
+				logging.FromContext(ctx).Errorf("listing pods to verify rescheduling, %s", err)
+				continue
+			}
+			for i := range podList.Items {
+				p := &podList.Items[i]
+				owner := metav1.GetControllerOf(p)
+				if owner == nil || !owners.Has(string(owner.UID)) || pod.IsScheduled(p) || pod.IsTerminal(p) || pod.IsTerminating(p) {
+					continue
+				}
+				logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(p)).Errorf("displaced pod is still pending %s after deprovisioning via %s", verification.threshold, verification.reason)
+				deprovisioningRescheduleMismatchCounter.Inc()
+				c.recorder.Publish(deprovisioningevents.RescheduleMismatch(p, verification.reason))
+			}
+		}
+	}
+}
+
+// canCreateReplacementNodes returns true if the cloud provider is able to launch at least one of the instance type
+// options for every replacement node in the command. Replacements are assumed to be launchable if the cloud provider
+// isn't able to check for capacity.
+func (c *Controller) canCreateReplacementNodes(ctx context.Context, command Command) (bool, error) {
+	checker, ok := c.cloudProvider.(cloudprovider.CapacityChecker)
+	if !ok {
+		return true, nil
+	}
+	for _, node := range command.replacementNodes {
+		canCreate := false
+		for _, it := range node.InstanceTypeOptions {
+			ok, err := checker.CanCreate(ctx, it)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				canCreate = true
+				break
+			}
+		}
+		if !canCreate {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 // waitForDeletion waits for the specified node to be removed from the API server. This deletion can take some period
 // of time if there are PDBs that govern pods on the node as we need to  wait until the node drains before
 // it's actually deleted.
@@ -261,25 +770,21 @@ func (c *Controller) waitForDeletion(ctx context.Context, node *v1.Node) {
 	}
 }
 
-// launchReplacementNodes launches replacement nodes and blocks until it is ready
+// launchReplacementNodes launches replacement nodes and blocks until they are ready, returning the ready nodes. The
+// nodes being replaced must already be cordoned, and are left cordoned on failure for the caller to uncordon.
 // nolint:gocyclo
-func (c *Controller) launchReplacementNodes(ctx context.Context, action Command) error {
+func (c *Controller) launchReplacementNodes(ctx context.Context, action Command) ([]*v1.Node, error) {
 	defer metrics.Measure(deprovisioningReplacementNodeInitializedHistogram)()
 	nodeNamesToRemove := lo.Map(action.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name })
-	// cordon the old nodes before we launch the replacements to prevent new pods from scheduling to the old nodes
-	if err := c.setNodesUnschedulable(ctx, true, nodeNamesToRemove...); err != nil {
-		return fmt.Errorf("cordoning nodes, %w", err)
-	}
-
 	nodeNames, err := c.provisioner.LaunchNodes(ctx, provisioning.LaunchOptions{RecordPodNomination: false}, action.replacementNodes...)
 	if err != nil {
-		// uncordon the nodes as the launch may fail (e.g. ICE or incompatible AMI)
-		err = multierr.Append(err, c.setNodesUnschedulable(ctx, false, nodeNamesToRemove...))
-		return err
+		// the replacements that did launch can't hold all of the pods, so remove them rather than leave them to be
+		// consolidated away later
+		return nil, multierr.Combine(err, c.deleteNodes(ctx, lo.Compact(nodeNames)...))
 	}
 	if len(nodeNames) != len(action.replacementNodes) {
 		// shouldn't ever occur since a partially failed LaunchNodes should return an error
-		return fmt.Errorf("expected %d node names, got %d", len(action.replacementNodes), len(nodeNames))
+		return nil, fmt.Errorf("expected %d node names, got %d", len(action.replacementNodes), len(nodeNames))
 	}
 	metrics.NodesCreatedCounter.WithLabelValues(metrics.DeprovisioningReason).Add(float64(len(nodeNames)))
 
@@ -288,6 +793,7 @@ func (c *Controller) launchReplacementNodes(ctx context.Context, action Command)
 	// Wait for nodes to be ready
 	// TODO @njtran: Allow to bypass this check for certain deprovisioners
 	errs := make([]error, len(nodeNames))
+	nodes := make([]*v1.Node, len(nodeNames))
 	workqueue.ParallelizeUntil(ctx, len(nodeNames), len(nodeNames), func(i int) {
 		var k8Node v1.Node
 		// Wait for the node to be ready
@@ -310,14 +816,26 @@ func (c *Controller) launchReplacementNodes(ctx context.Context, action Command)
 			// nodes never become ready, so uncordon the nodes we were trying to delete and report the error
 			errs[i] = err
 		}
+		nodes[i] = &k8Node
 	})
 	multiErr := multierr.Combine(errs...)
 	if multiErr != nil {
 		c.cluster.UnmarkForDeletion(nodeNamesToRemove...)
-		return multierr.Combine(c.setNodesUnschedulable(ctx, false, nodeNamesToRemove...),
-			fmt.Errorf("timed out checking node readiness, %w", multiErr))
+		return nil, fmt.Errorf("timed out checking node readiness, %w", multiErr)
 	}
-	return nil
+	return nodes, nil
+}
+
+// deleteNodes deletes the nodes with the given names, ignoring nodes that are already gone
+func (c *Controller) deleteNodes(ctx context.Context, nodeNames ...string) error {
+	var multiErr error
+	for _, nodeName := range nodeNames {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+		if err := c.kubeClient.Delete(ctx, node); client.IgnoreNotFound(err) != nil {
+			multiErr = multierr.Append(multiErr, fmt.Errorf("deleting node %s, %w", nodeName, err))
+		}
+	}
+	return multiErr
 }
 
 func (c *Controller) setNodesUnschedulable(ctx context.Context, isUnschedulable bool, nodeNames ...string) error {
@@ -338,11 +856,125 @@ func (c *Controller) setNodesUnschedulable(ctx context.Context, isUnschedulable
 			continue
 		}
 
+		// the node was cordoned by another controller or an operator, so the cordon isn't ours to remove
+		if !isUnschedulable && externallyCordoned(&node) {
+			continue
+		}
+
 		persisted := node.DeepCopy()
 		node.Spec.Unschedulable = isUnschedulable
+		// the cordon supersedes the taint that was applied while the command was validated
+		node.Spec.Taints = withoutValidationTaint(node.Spec.Taints)
+		// the annotation distinguishes our cordons from those of operators, so that orphaned cordons can be recovered
+		if isUnschedulable {
+			node.Annotations = lo.Assign(node.Annotations, map[string]string{v1alpha5.DeprovisioningCordonAnnotationKey: "true"})
+		} else {
+			// the node is no longer being replaced
+			delete(node.Annotations, v1alpha5.DeprovisioningCordonAnnotationKey)
+			delete(node.Annotations, v1alpha5.DeprovisioningReplacementAnnotationKey)
+		}
 		if err := c.kubeClient.Patch(ctx, &node, client.MergeFrom(persisted)); err != nil {
 			multiErr = multierr.Append(multiErr, fmt.Errorf("patching node %s, %w", node.Name, err))
+			continue
 		}
+		c.tagInstance(ctx, &node, isUnschedulable)
 	}
 	return multiErr
 }
+
+// annotateTerminationReason records why deprovisioning is terminating the node, for the termination controller's
+// metrics. It's best effort, so failures don't block deprovisioning.
+func (c *Controller) annotateTerminationReason(ctx context.Context, node *v1.Node, reason string) {
+	annotated := node.DeepCopy()
+	annotated.Annotations = lo.Assign(annotated.Annotations, map[string]string{v1alpha5.TerminationReasonAnnotationKey: reason})
+	if err := c.kubeClient.Patch(ctx, annotated, client.MergeFrom(node)); err != nil {
+		logging.FromContext(ctx).With("node", node.Name).Errorf("annotating termination reason, %s", err)
+	}
+}
+
+// annotateReplacement records the instance types that are going to replace the node, overwriting those of any earlier
+// command. It's best effort, so failures don't block deprovisioning.
+func (c *Controller) annotateReplacement(ctx context.Context, node *v1.Node, instanceTypes string) {
+	annotated := node.DeepCopy()
+	annotated.Annotations = lo.Assign(annotated.Annotations, map[string]string{v1alpha5.DeprovisioningReplacementAnnotationKey: instanceTypes})
+	if err := c.kubeClient.Patch(ctx, annotated, client.MergeFrom(node)); err != nil {
+		logging.FromContext(ctx).With("node", node.Name).Errorf("annotating replacement, %s", err)
+	}
+}
+
+// replacementInstanceTypes returns the instance types that the command's replacements may launch as, cheapest first,
+// limited to the maxAnnotatedReplacementTypes cheapest of each replacement
+func replacementInstanceTypes(cmd Command) []string {
+	var names []string
+	for _, replacement := range cmd.replacementNodes {
+		prices := map[string]float64{}
+		for _, it := range replacement.InstanceTypeOptions {
+			prices[it.Name] = math.MaxFloat64
+			for _, of := range it.Offerings.Available() {
+				if compatibleOffering(of, replacement.Requirements) && of.Price < prices[it.Name] {
+					prices[it.Name] = of.Price
+				}
+			}
+		}
+		options := lo.Keys(prices)
+		sort.Slice(options, func(i, j int) bool {
+			if prices[options[i]] != prices[options[j]] {
+				return prices[options[i]] < prices[options[j]]
+			}
+			return options[i] < options[j]
+		})
+		names = append(names, options[:lo.Min([]int{len(options), maxAnnotatedReplacementTypes})]...)
+	}
+	return names
+}
+
+func (c *Controller) setInflight(inflight bool, nodeNames ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if inflight {
+		c.inflight.Insert(nodeNames...)
+	} else {
+		c.inflight.Delete(nodeNames...)
+	}
+}
+
+// recoverOrphanedCordons uncordons nodes that deprovisioning cordoned, but that no command is in-flight for, e.g.
+// because the controller restarted while it was executing the command
+func (c *Controller) recoverOrphanedCordons(ctx context.Context) error {
+	var nodeList v1.NodeList
+	if err := c.kubeClient.List(ctx, &nodeList); err != nil {
+		return fmt.Errorf("listing nodes, %w", err)
+	}
+	c.mu.Lock()
+	orphaned := lo.FilterMap(nodeList.Items, func(n v1.Node, _ int) (string, bool) {
+		return n.Name, n.Annotations[v1alpha5.DeprovisioningCordonAnnotationKey] == "true" && n.Spec.Unschedulable &&
+			n.DeletionTimestamp.IsZero() && !c.inflight.Has(n.Name)
+	})
+	c.mu.Unlock()
+	// validation taints are removed once a command is validated, so any that remain were orphaned the same way
+	tainted := lo.FilterMap(nodeList.Items, func(n v1.Node, _ int) (string, bool) {
+		return n.Name, len(withoutValidationTaint(n.Spec.Taints)) != len(n.Spec.Taints) && n.DeletionTimestamp.IsZero()
+	})
+	for _, name := range orphaned {
+		logging.FromContext(ctx).With("node", name).Infof("uncordoning node that was cordoned for deprovisioning without a command in-flight")
+	}
+	return multierr.Combine(c.setNodesUnschedulable(ctx, false, orphaned...), setValidationTaint(ctx, c.kubeClient, false, tainted...))
+}
+
+// tagInstance tags the instance of a node that's cordoned for deprovisioning, and untags it when the node is uncordoned,
+// if the cloud provider is able to tag instances. Tagging is best effort, so failures don't block deprovisioning.
+func (c *Controller) tagInstance(ctx context.Context, node *v1.Node, cordoned bool) {
+	tagger, ok := c.cloudProvider.(cloudprovider.InstanceTagger)
+	if !ok || node.Spec.ProviderID == "" {
+		return
+	}
+	var err error
+	if cordoned {
+		err = tagger.Tag(ctx, node.Spec.ProviderID, map[string]string{v1alpha5.DeprovisioningTagKey: "true"})
+	} else {
+		err = tagger.Untag(ctx, node.Spec.ProviderID, v1alpha5.DeprovisioningTagKey)
+	}
+	if err != nil {
+		logging.FromContext(ctx).With("node", node.Name).Errorf("tagging instance, %s", err)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
+	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	pscheduling "github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/scheduling"
+	"github.com/aws/karpenter-core/pkg/utils/resources"
+)
+
+// CostProjection is the hourly cost of the cluster's nodes before and after a deprovisioning command executes
+type CostProjection struct {
+	Current   float64
+	Projected float64
+}
+
+// projectCost computes the hourly cost of the cluster's nodes from their current offerings, and projects it after the
+// command removes its nodes and launches the cheapest offering for each of its replacements. Nodes whose offering
+// can't be determined don't contribute to either cost.
+func (c *Controller) projectCost(ctx context.Context, cmd Command) (CostProjection, error) {
+	_, instanceTypesByProvisioner, err := buildProvisionerMap(ctx, c.kubeClient, c.cloudProvider)
+	if err != nil {
+		return CostProjection{}, err
+	}
+	removed := sets.NewString(lo.Map(cmd.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name })...)
+	var projection CostProjection
+	c.cluster.ForEachNode(func(n *state.Node) bool {
+		price, ok := nodePrice(n.Node, instanceTypesByProvisioner)
+		if !ok {
+			return true
+		}
+		projection.Current += price
+		if !removed.Has(n.Node.Name) {
+			projection.Projected += price
+		}
+		return true
+	})
+	for _, replacement := range cmd.replacementNodes {
+		if price := cheapestLaunchPrice(replacement); price != math.MaxFloat64 {
+			projection.Projected += price
+		}
+	}
+	return projection, nil
+}
+
+// belowCapacityFloor returns a description and true if the command would reduce the total capacity of the cluster's
+// nodes of a resource below the configured floor. Replacements are assumed to launch with the smallest capacity of
+// their instance type options, and nodes that are already being deleted don't contribute to either total.
+func (c *Controller) belowCapacityFloor(ctx context.Context, cmd Command) (string, bool) {
+	floor := settings.FromContext(ctx).ConsolidationCapacityFloor
+	if len(floor) == 0 {
+		return "", false
+	}
+	removed := sets.NewString(lo.Map(cmd.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name })...)
+	var current, projected []v1.ResourceList
+	c.cluster.ForEachNode(func(n *state.Node) bool {
+		if n.MarkedForDeletion {
+			return true
+		}
+		current = append(current, n.Capacity)
+		if !removed.Has(n.Node.Name) {
+			projected = append(projected, n.Capacity)
+		}
+		return true
+	})
+	for _, replacement := range cmd.replacementNodes {
+		projected = append(projected, smallestCapacity(replacement.InstanceTypeOptions))
+	}
+	currentTotal, projectedTotal := resources.Merge(current...), resources.Merge(projected...)
+	for resourceName, minimum := range floor {
+		total := projectedTotal[resourceName]
+		if total.Cmp(minimum) < 0 && total.Cmp(currentTotal[resourceName]) < 0 {
+			return fmt.Sprintf("projected %s capacity of %s would be below the floor of %s", resourceName, total.String(), minimum.String()), true
+		}
+	}
+	return "", false
+}
+
+// smallestCapacity returns the smallest capacity of each resource across the instance types
+func smallestCapacity(instanceTypes []*cloudprovider.InstanceType) v1.ResourceList {
+	if len(instanceTypes) == 0 {
+		return v1.ResourceList{}
+	}
+	capacity := lo.Assign(instanceTypes[0].Capacity)
+	for _, it := range instanceTypes[1:] {
+		for resourceName, quantity := range capacity {
+			// a resource that an instance type doesn't have has no capacity in the worst case
+			other, ok := it.Capacity[resourceName]
+			if !ok {
+				delete(capacity, resourceName)
+			} else if other.Cmp(quantity) < 0 {
+				capacity[resourceName] = other
+			}
+		}
+	}
+	return capacity
+}
+
+// nodePrice returns the effective price of the offering that the node was launched with
+func nodePrice(node *v1.Node, instanceTypesByProvisioner map[string]map[string]*cloudprovider.InstanceType) (float64, bool) {
+	instanceType, ok := instanceTypesByProvisioner[node.Labels[v1alpha5.ProvisionerNameLabelKey]][node.Labels[v1.LabelInstanceTypeStable]]
+	if !ok {
+		return 0, false
+	}
+	offering, ok := instanceType.Offerings.Get(node.Labels[v1alpha5.LabelCapacityType], node.Labels[v1.LabelTopologyZone])
+	if !ok {
+		return 0, false
+	}
+	return offering.EffectivePrice(), true
+}
+
+// cheapestLaunchPrice returns the price of the cheapest available offering that the replacement node could launch with
+func cheapestLaunchPrice(node *pscheduling.Node) float64 {
+	if _, offering, ok := CheapestInstanceTypeFor(node.Pods, node.InstanceTypeOptions, node.Requirements); ok {
+		return offering.Price
+	}
+	return math.MaxFloat64
+}
+
+func compatibleOffering(of cloudprovider.Offering, reqs scheduling.Requirements) bool {
+	return reqs.Get(v1.LabelTopologyZone).Has(of.Zone) && reqs.Get(v1alpha5.LabelCapacityType).Has(of.CapacityType)
+}
@@ -0,0 +1,87 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+)
+
+// DisruptionBudgets is used to evaluate if deprovisioning more of a provisioner's nodes is possible. Nodes that are
+// already marked for deletion count against their provisioner's budget.
+type DisruptionBudgets struct {
+	// allowed is the number of each budgeted provisioner's nodes that may still be disrupted, by provisioner name
+	allowed map[string]int
+}
+
+func NewDisruptionBudgets(ctx context.Context, kubeClient client.Client, cluster *state.Cluster) (*DisruptionBudgets, error) {
+	provisionerList := &v1alpha5.ProvisionerList{}
+	if err := kubeClient.List(ctx, provisionerList); err != nil {
+		return nil, fmt.Errorf("listing provisioners, %w", err)
+	}
+	nodes := map[string]int{}
+	disrupting := map[string]int{}
+	cluster.ForEachNode(func(n *state.Node) bool {
+		name, ok := n.Node.Labels[v1alpha5.ProvisionerNameLabelKey]
+		if !ok {
+			return true
+		}
+		nodes[name]++
+		if n.MarkedForDeletion {
+			disrupting[name]++
+		}
+		return true
+	})
+	b := &DisruptionBudgets{allowed: map[string]int{}}
+	for _, provisioner := range provisionerList.Items {
+		if provisioner.Spec.Disruption == nil || provisioner.Spec.Disruption.Budget == nil {
+			continue
+		}
+		// percentages are rounded up, so that a budget of a small percentage of a small provisioner still disrupts nodes
+		budget, err := intstr.GetScaledValueFromIntOrPercent(provisioner.Spec.Disruption.Budget, nodes[provisioner.Name], true)
+		if err != nil {
+			return nil, fmt.Errorf("parsing disruption budget of provisioner %s, %w", provisioner.Name, err)
+		}
+		b.allowed[provisioner.Name] = budget - disrupting[provisioner.Name]
+	}
+	return b, nil
+}
+
+// Exhausted returns true if none of the provisioner's nodes may be disrupted
+func (b *DisruptionBudgets) Exhausted(provisionerName string) bool {
+	allowed, ok := b.allowed[provisionerName]
+	return ok && allowed <= 0
+}
+
+// CanDisrupt returns the name of a provisioner and false if disrupting the nodes would exceed its budget
+func (b *DisruptionBudgets) CanDisrupt(nodes []*v1.Node) (string, bool) {
+	disrupting := map[string]int{}
+	for _, n := range nodes {
+		disrupting[n.Labels[v1alpha5.ProvisionerNameLabelKey]]++
+	}
+	for name, count := range disrupting {
+		if allowed, ok := b.allowed[name]; ok && count > allowed {
+			return name, false
+		}
+	}
+	return "", true
+}
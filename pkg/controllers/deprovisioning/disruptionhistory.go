@@ -0,0 +1,101 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
+)
+
+// DisruptionHistory tracks when the pods of each controlling owner (e.g. a ReplicaSet) have recently been disrupted
+// by deprovisioning, so that further disruptions of an owner's pods can back off to protect latency-sensitive
+// workloads. Disruptions older than the configured window decay out of the history.
+type DisruptionHistory struct {
+	mu          sync.Mutex
+	clock       clock.Clock
+	disruptions map[types.UID][]time.Time
+}
+
+func NewDisruptionHistory(clk clock.Clock) *DisruptionHistory {
+	return &DisruptionHistory{
+		clock:       clk,
+		disruptions: map[types.UID][]time.Time{},
+	}
+}
+
+// Record records a disruption of each distinct owner of the pods
+func (h *DisruptionHistory) Record(pods []*v1.Pod) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.clock.Now()
+	recorded := map[types.UID]bool{}
+	for _, p := range pods {
+		owner := metav1.GetControllerOf(p)
+		if owner == nil || recorded[owner.UID] {
+			continue
+		}
+		recorded[owner.UID] = true
+		h.disruptions[owner.UID] = append(h.disruptions[owner.UID], now)
+	}
+}
+
+// Blocked returns the reason and true if any of the pods belong to an owner that has been disrupted at least the
+// configured number of times within the window
+func (h *DisruptionHistory) Blocked(ctx context.Context, pods []*v1.Pod) (string, bool) {
+	s := settings.FromContext(ctx)
+	if h == nil || s.OwnerDisruptionLimit == 0 {
+		return "", false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.decay(s.OwnerDisruptionWindow.Duration)
+	for _, p := range pods {
+		owner := metav1.GetControllerOf(p)
+		if owner == nil {
+			continue
+		}
+		if count := len(h.disruptions[owner.UID]); count >= s.OwnerDisruptionLimit {
+			return fmt.Sprintf("%s %s/%s was disrupted %d times in the last %s", owner.Kind, p.Namespace, owner.Name, count, s.OwnerDisruptionWindow.Duration), true
+		}
+	}
+	return "", false
+}
+
+// decay removes disruptions that are older than the window
+func (h *DisruptionHistory) decay(window time.Duration) {
+	cutoff := h.clock.Now().Add(-window)
+	for uid, disruptions := range h.disruptions {
+		var recent []time.Time
+		for _, t := range disruptions {
+			if t.After(cutoff) {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) == 0 {
+			delete(h.disruptions, uid)
+			continue
+		}
+		h.disruptions[uid] = recent
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
+	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
+	pscheduling "github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/metrics"
+)
+
+// Drift is a subreconciler that replaces nodes whose backing configuration has drifted from their provisioner's
+// configuration, e.g. because the provisioner's image has changed since they launched
+type Drift struct {
+	clock         clock.Clock
+	kubeClient    client.Client
+	cluster       *state.Cluster
+	provisioner   *provisioning.Provisioner
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewDrift(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, provisioner *provisioning.Provisioner, cp cloudprovider.CloudProvider) *Drift {
+	return &Drift{
+		clock:         clk,
+		kubeClient:    kubeClient,
+		cluster:       cluster,
+		provisioner:   provisioner,
+		cloudProvider: cp,
+	}
+}
+
+// ShouldDeprovision is a predicate used to filter deprovisionable nodes
+func (d *Drift) ShouldDeprovision(ctx context.Context, n *state.Node, _ *v1alpha5.Provisioner, _ []*v1.Pod) bool {
+	detector, ok := d.cloudProvider.(cloudprovider.DriftDetector)
+	if !ok || !settings.FromContext(ctx).DriftEnabled {
+		return false
+	}
+	drifted, err := detector.IsDrifted(ctx, n.Node)
+	if err != nil {
+		logging.FromContext(ctx).With("node", n.Node.Name).Errorf("checking if node has drifted, %s", err)
+		return false
+	}
+	return drifted
+}
+
+// SortCandidates orders drifted nodes by their age, so that the oldest nodes are replaced first
+func (d *Drift) SortCandidates(nodes []CandidateNode) []CandidateNode {
+	sort.SliceStable(nodes, func(i int, j int) bool {
+		return nodes[i].CreationTimestamp.Before(&nodes[j].CreationTimestamp)
+	})
+	return nodes
+}
+
+// ComputeCommand generates a deprovisioning command given deprovisionable nodes
+func (d *Drift) ComputeCommand(ctx context.Context, candidates ...CandidateNode) (Command, error) {
+	pdbs, err := NewPDBLimits(ctx, d.kubeClient)
+	if err != nil {
+		return Command{}, fmt.Errorf("tracking PodDisruptionBudgets, %w", err)
+	}
+	for _, candidate := range candidates {
+		// is this a node that we can terminate?  This check is meant to be fast so we can save the expense of simulated
+		// scheduling unless its really needed. Drifted nodes must be replaced, so recent disruptions don't block them.
+		if !canBeTerminated(ctx, candidate, pdbs, nil) {
+			continue
+		}
+
+		// Check if we need to create any nodes.
+		newNodes, _, allPodsScheduled, err := simulateScheduling(ctx, d.kubeClient, d.cluster, d.provisioner, candidate)
+		if err != nil {
+			// if a candidate node is now deleting, just retry
+			if errors.Is(err, errCandidateNodeDeleting) {
+				continue
+			}
+			return Command{}, err
+		}
+		// unlike expiration, drift isn't a deadline, so a drifted node is left alone until its pods can be rescheduled
+		if !allPodsScheduled {
+			logging.FromContext(ctx).With("node", candidate.Name).Debugf("unable to replace drifted node, scheduling simulation failed to schedule all pods")
+			continue
+		}
+		// the replacements must be instance types that settings allow
+		for _, n := range newNodes {
+			filterByReplacementAllowList(ctx, n)
+		}
+		if lo.SomeBy(newNodes, func(n *pscheduling.Node) bool { return len(n.InstanceTypeOptions) == 0 }) {
+			logging.FromContext(ctx).With("node", candidate.Name).Debugf("unable to replace drifted node with an allowed instance type")
+			continue
+		}
+		logging.FromContext(ctx).With("node", candidate.Name).Infof("triggering termination for drifted node")
+		// were we able to schedule all the pods on the inflight nodes?
+		if len(newNodes) == 0 {
+			return Command{
+				nodesToRemove: []*v1.Node{candidate.Node},
+				action:        actionDelete,
+			}, nil
+		}
+		return Command{
+			nodesToRemove:    []*v1.Node{candidate.Node},
+			action:           actionReplace,
+			replacementNodes: newNodes,
+		}, nil
+	}
+	return Command{action: actionDoNothing}, nil
+}
+
+// String is the string representation of the deprovisioner
+func (d *Drift) String() string {
+	return metrics.DriftReason
+}
@@ -16,43 +16,67 @@ package deprovisioning
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"k8s.io/utils/clock"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/samber/lo"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/metrics"
 )
 
+// UsageSource reports the actual resource usage of nodes, e.g. from the metrics API
+type UsageSource interface {
+	NodeUsage(context.Context, *v1.Node) (v1.ResourceList, error)
+}
+
 // Emptiness is a subreconciler that deletes empty nodes.
 // Emptiness will respect TTLSecondsAfterEmpty
 type Emptiness struct {
-	clock      clock.Clock
-	kubeClient client.Client
-	cluster    *state.Cluster
+	clock       clock.Clock
+	kubeClient  client.Client
+	cluster     *state.Cluster
+	provisioner *provisioning.Provisioner
+	// usageSource is optional, if set nodes whose usage has been idle for TTLSecondsAfterEmpty are treated as empty
+	usageSource       UsageSource
+	mu                sync.Mutex
+	idleSince         map[string]time.Time
+	disruptionHistory *DisruptionHistory
 }
 
-func NewEmptiness(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster) *Emptiness {
+func NewEmptiness(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, provisioner *provisioning.Provisioner,
+	history *DisruptionHistory) *Emptiness {
 	return &Emptiness{
-		clock:      clk,
-		kubeClient: kubeClient,
-		cluster:    cluster,
+		clock:             clk,
+		kubeClient:        kubeClient,
+		cluster:           cluster,
+		provisioner:       provisioner,
+		idleSince:         map[string]time.Time{},
+		disruptionHistory: history,
 	}
 }
 
 // ShouldDeprovision is a predicate used to filter deprovisionable nodes
 func (e *Emptiness) ShouldDeprovision(ctx context.Context, n *state.Node, provisioner *v1alpha5.Provisioner, nodePods []*v1.Pod) bool {
-	if provisioner == nil || provisioner.Spec.TTLSecondsAfterEmpty == nil || len(nodePods) != 0 {
+	if provisioner == nil || provisioner.Spec.TTLSecondsAfterEmpty == nil {
 		return false
 	}
+	if len(nodePods) != 0 {
+		return e.isIdle(ctx, n.Node, time.Duration(ptr.Int64Value(provisioner.Spec.TTLSecondsAfterEmpty))*time.Second)
+	}
 
 	emptinessTimestamp, hasEmptinessTimestamp := n.Node.Annotations[v1alpha5.EmptinessTimestampAnnotationKey]
 	if !hasEmptinessTimestamp {
@@ -69,16 +93,106 @@ func (e *Emptiness) ShouldDeprovision(ctx context.Context, n *state.Node, provis
 	return e.clock.Now().After(emptinessTime.Add(ttl))
 }
 
+// SortCandidates leaves the candidates in the order that they were found, since every empty node is deleted at once
+func (e *Emptiness) SortCandidates(nodes []CandidateNode) []CandidateNode {
+	return nodes
+}
+
 // ComputeCommand generates a deprovisioning command given deprovisionable nodes
-func (e *Emptiness) ComputeCommand(_ context.Context, nodes ...CandidateNode) (Command, error) {
-	emptyNodes := lo.Filter(nodes, func(n CandidateNode, _ int) bool { return len(n.pods) == 0 })
-	if len(emptyNodes) == 0 {
+func (e *Emptiness) ComputeCommand(ctx context.Context, nodes ...CandidateNode) (Command, error) {
+	emptyNodes := lo.Filter(nodes, func(n CandidateNode, _ int) bool { return n.isEmpty() })
+	if len(emptyNodes) != 0 {
+		return Command{
+			nodesToRemove: lo.Map(emptyNodes, func(n CandidateNode, _ int) *v1.Node { return n.Node }),
+			action:        actionDelete,
+		}, nil
+	}
+	// the remaining nodes are idle, but their pods still need to be able to reschedule onto the existing nodes
+	idleNodes := lo.Filter(nodes, func(n CandidateNode, _ int) bool { return !n.isEmpty() })
+	if len(idleNodes) == 0 {
 		return Command{action: actionDoNothing}, nil
 	}
-	return Command{
-		nodesToRemove: lo.Map(emptyNodes, func(n CandidateNode, _ int) *v1.Node { return n.Node }),
-		action:        actionDelete,
-	}, nil
+	pdbs, err := NewPDBLimits(ctx, e.kubeClient)
+	if err != nil {
+		return Command{}, fmt.Errorf("tracking PodDisruptionBudgets, %w", err)
+	}
+	for _, candidate := range idleNodes {
+		if !canBeTerminated(ctx, candidate, pdbs, e.disruptionHistory) {
+			continue
+		}
+		newNodes, _, allPodsScheduled, err := simulateScheduling(ctx, e.kubeClient, e.cluster, e.provisioner, candidate)
+		if err != nil {
+			// if a candidate node is now deleting, just retry
+			if errors.Is(err, errCandidateNodeDeleting) {
+				continue
+			}
+			return Command{}, err
+		}
+		if allPodsScheduled && len(newNodes) == 0 {
+			return Command{
+				nodesToRemove: []*v1.Node{candidate.Node},
+				action:        actionDelete,
+			}, nil
+		}
+	}
+	return Command{action: actionDoNothing}, nil
+}
+
+// isIdle returns true if the usage source has reported the node's usage as idle for at least the TTL. Evaluating a
+// node reads the node's idle timer without starting or resetting it.
+func (e *Emptiness) isIdle(ctx context.Context, node *v1.Node, ttl time.Duration) bool {
+	if e.usageSource == nil {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	usage, err := e.usageSource.NodeUsage(ctx, node)
+	if err != nil {
+		logging.FromContext(ctx).With("node", node.Name).Debugf("unable to get node usage, %s", err)
+		if !sideEffectsDisabled(ctx) {
+			delete(e.idleSince, node.Name)
+		}
+		return false
+	}
+	threshold := settings.FromContext(ctx).IdleUsageThreshold
+	for resourceName, allocatable := range node.Status.Allocatable {
+		if (resourceName != v1.ResourceCPU && resourceName != v1.ResourceMemory) || allocatable.IsZero() {
+			continue
+		}
+		used := usage[resourceName]
+		if float64(used.MilliValue())/float64(allocatable.MilliValue()) >= threshold {
+			if !sideEffectsDisabled(ctx) {
+				delete(e.idleSince, node.Name)
+			}
+			return false
+		}
+	}
+	idleSince, ok := e.idleSince[node.Name]
+	if !ok {
+		if sideEffectsDisabled(ctx) {
+			return false
+		}
+		idleSince = e.clock.Now()
+		e.idleSince[node.Name] = idleSince
+	}
+	return !e.clock.Now().Before(idleSince.Add(ttl))
+}
+
+// pruneIdleTimers forgets when nodes that have left the cluster became idle, so that a node that later joins with the
+// same name starts its own idle timer
+func (e *Emptiness) pruneIdleTimers() {
+	nodeNames := sets.NewString()
+	e.cluster.ForEachNode(func(n *state.Node) bool {
+		nodeNames.Insert(n.Node.Name)
+		return true
+	})
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for name := range e.idleSince {
+		if !nodeNames.Has(name) {
+			delete(e.idleSince, name)
+		}
+	}
 }
 
 // string is the string representation of the deprovisioner
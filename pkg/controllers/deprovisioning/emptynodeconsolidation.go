@@ -28,6 +28,7 @@ import (
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/events"
 )
 
 // EmptyNodeConsolidation is the consolidation controller that performs multi-node consolidation of entirely empty nodes
@@ -35,13 +36,16 @@ type EmptyNodeConsolidation struct {
 	consolidation
 }
 
-func NewEmptyNodeConsolidation(clk clock.Clock, cluster *state.Cluster, kubeClient client.Client, provisioner *provisioning.Provisioner, cp cloudprovider.CloudProvider) *EmptyNodeConsolidation {
+func NewEmptyNodeConsolidation(clk clock.Clock, cluster *state.Cluster, kubeClient client.Client, provisioner *provisioning.Provisioner, cp cloudprovider.CloudProvider,
+	recorder events.Recorder, history *DisruptionHistory) *EmptyNodeConsolidation {
 	return &EmptyNodeConsolidation{consolidation: consolidation{
-		clock:         clk,
-		cluster:       cluster,
-		kubeClient:    kubeClient,
-		provisioner:   provisioner,
-		cloudProvider: cp,
+		clock:             clk,
+		cluster:           cluster,
+		kubeClient:        kubeClient,
+		provisioner:       provisioner,
+		cloudProvider:     cp,
+		recorder:          recorder,
+		disruptionHistory: history,
 	},
 	}
 }
@@ -57,7 +61,7 @@ func (c *EmptyNodeConsolidation) ComputeCommand(ctx context.Context, candidates
 	}
 
 	// select the entirely empty nodes
-	emptyNodes := lo.Filter(candidates, func(n CandidateNode, _ int) bool { return len(n.pods) == 0 })
+	emptyNodes := lo.Filter(candidates, func(n CandidateNode, _ int) bool { return n.isEmpty() })
 	if len(emptyNodes) == 0 {
 		return Command{action: actionDoNothing}, nil
 	}
@@ -73,9 +77,9 @@ func (c *EmptyNodeConsolidation) ComputeCommand(ctx context.Context, candidates
 	select {
 	case <-ctx.Done():
 		return Command{}, errors.New("interrupted")
-	case <-c.clock.After(consolidationTTL):
+	case <-c.clock.After(validationPeriod(ctx)):
 	}
-	validationCandidates, err := candidateNodes(ctx, c.cluster, c.kubeClient, c.clock, c.cloudProvider, c.ShouldDeprovision)
+	validationCandidates, err := candidateNodes(ctx, c.cluster, c.kubeClient, c.clock, c.cloudProvider, c.ShouldDeprovision, false)
 	if err != nil {
 		logging.FromContext(ctx).Errorf("computing validation candidates %s", err)
 		return Command{}, err
@@ -85,7 +89,8 @@ func (c *EmptyNodeConsolidation) ComputeCommand(ctx context.Context, candidates
 	// the deletion of empty nodes is easy to validate, we just ensure that all the nodesToDelete are still empty and that
 	// the node isn't a target of a recent scheduling simulation
 	for _, n := range nodesToDelete {
-		if len(n.pods) != 0 && !c.cluster.IsNodeNominated(n.Name) {
+		if !n.isEmpty() && !c.cluster.IsNodeNominated(n.Name) {
+			deprovisioningAbortedCounter.WithLabelValues(abortReasonBecameNonEmpty).Inc()
 			return Command{action: actionRetry}, nil
 		}
 	}
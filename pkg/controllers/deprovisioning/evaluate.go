@@ -0,0 +1,222 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+)
+
+type sideEffectsKey struct{}
+
+// withoutSideEffects returns a context in which deprovisioners compute commands without leaving a trace, i.e. without
+// starting idle timers, publishing events or recording metrics, so that evaluating nodes doesn't change what a later
+// deprovisioning pass does
+func withoutSideEffects(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sideEffectsKey{}, true)
+}
+
+// sideEffectsDisabled returns true if the context is only being used to evaluate nodes
+func sideEffectsDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(sideEffectsKey{}).(bool)
+	return disabled
+}
+
+// EvaluateNode reports what deprovisioning would do with a single node without taking any action, e.g. to answer
+// "what would Karpenter do with this node?" from a debugging tool. The node is evaluated against each deprovisioner
+// in the same order as ProcessCluster. The reason and command of the first deprovisioner that would act on the node are
+// returned. If none would act, the command is nil and the reason describes what is blocking the node, if anything.
+func (c *Controller) EvaluateNode(ctx context.Context, node *v1.Node) (string, *Command, error) {
+	ctx = withoutSideEffects(ctx)
+	pdbs, err := NewPDBLimits(ctx, c.kubeClient)
+	if err != nil {
+		return "", nil, fmt.Errorf("tracking PodDisruptionBudgets, %w", err)
+	}
+	budgets, err := NewDisruptionBudgets(ctx, c.kubeClient, c.cluster)
+	if err != nil {
+		return "", nil, fmt.Errorf("tracking disruption budgets, %w", err)
+	}
+	candidates, err := c.candidatesByDeprovisioner(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return c.evaluateCandidate(ctx, pdbs, budgets, candidates, node.Name)
+}
+
+// CandidateEvaluation is what deprovisioning would do with a single node
+type CandidateEvaluation struct {
+	// Node is the name of the node
+	Node string `json:"node"`
+	// Candidacy are the names of the deprovisioners that the node is a candidate of
+	Candidacy []string `json:"candidacy"`
+	// Deprovisioner is the name of the deprovisioner that would act upon the node, if any
+	Deprovisioner string `json:"deprovisioner,omitempty"`
+	// Action is either delete, replace or do nothing
+	Action string `json:"action"`
+	// Reason describes the command that would act upon the node or, if there's none, what is blocking the node
+	Reason string `json:"reason,omitempty"`
+}
+
+// ListCandidates reports what deprovisioning would do with each node of the cluster without taking any action, e.g. to
+// back a status dashboard. Each node is evaluated on its own, as EvaluateNode does.
+func (c *Controller) ListCandidates(ctx context.Context) ([]CandidateEvaluation, error) {
+	ctx = withoutSideEffects(ctx)
+	pdbs, err := NewPDBLimits(ctx, c.kubeClient)
+	if err != nil {
+		return nil, fmt.Errorf("tracking PodDisruptionBudgets, %w", err)
+	}
+	budgets, err := NewDisruptionBudgets(ctx, c.kubeClient, c.cluster)
+	if err != nil {
+		return nil, fmt.Errorf("tracking disruption budgets, %w", err)
+	}
+	candidates, err := c.candidatesByDeprovisioner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	c.cluster.ForEachNode(func(n *state.Node) bool {
+		names = append(names, n.Node.Name)
+		return true
+	})
+	sort.Strings(names)
+
+	evaluations := []CandidateEvaluation{}
+	for _, name := range names {
+		evaluation := CandidateEvaluation{Node: name, Candidacy: []string{}, Action: actionDoNothing.String()}
+		for i, d := range c.deprovisioners {
+			if lo.ContainsBy(candidates[i], func(n CandidateNode) bool { return n.Name == name }) {
+				evaluation.Candidacy = append(evaluation.Candidacy, d.String())
+			}
+		}
+		// consolidation deprovisioners share a name
+		evaluation.Candidacy = lo.Uniq(evaluation.Candidacy)
+		reason, cmd, err := c.evaluateCandidate(ctx, pdbs, budgets, candidates, name)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating node %s, %w", name, err)
+		}
+		evaluation.Reason = reason
+		if cmd != nil {
+			evaluation.Deprovisioner = reason
+			evaluation.Action = cmd.action.String()
+			evaluation.Reason = cmd.String()
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+	return evaluations, nil
+}
+
+// candidatesByDeprovisioner returns the candidate nodes of each deprovisioner, in the order of the deprovisioners
+func (c *Controller) candidatesByDeprovisioner(ctx context.Context) ([][]CandidateNode, error) {
+	var candidates [][]CandidateNode
+	for _, d := range c.deprovisioners {
+		dc, err := candidateNodes(ctx, c.cluster, c.kubeClient, c.clock, c.cloudProvider, d.ShouldDeprovision, includesUninitialized(d))
+		if err != nil {
+			return nil, fmt.Errorf("determining candidate nodes, %w", err)
+		}
+		candidates = append(candidates, dc)
+	}
+	return candidates, nil
+}
+
+// evaluateCandidate evaluates the named node against each deprovisioner that it's a candidate of, in the same order and
+// with the same deferrals as ProcessCluster, and returns the reason and command of the first deprovisioner that would
+// act on it
+func (c *Controller) evaluateCandidate(ctx context.Context, pdbs *PDBLimits, budgets *DisruptionBudgets, candidates [][]CandidateNode,
+	name string) (string, *Command, error) {
+	if !settings.FromContext(ctx).InMaintenanceWindow(c.clock.Now()) {
+		return "deferred until the next maintenance window", nil, nil
+	}
+	var blockedReason string
+	for i, d := range c.deprovisioners {
+		switch d.(type) {
+		// a single node is evaluated for consolidation by single node consolidation, which also handles empty nodes
+		case *EmptyNodeConsolidation, *MultiNodeConsolidation:
+			continue
+		}
+		candidate, ok := lo.Find(candidates[i], func(n CandidateNode) bool { return n.Name == name })
+		if !ok {
+			continue
+		}
+		if reason, deferred := c.deferralReason(ctx, d); deferred {
+			blockedReason = fmt.Sprintf("%s deferred, %s", d, reason)
+			continue
+		}
+		if budgets.Exhausted(candidate.provisioner.Name) {
+			blockedReason = fmt.Sprintf("%s deferred, disruption budget of provisioner %s is exhausted", d, candidate.provisioner.Name)
+			continue
+		}
+		// nodes that aren't ready aren't running their pods, so nothing blocks their deletion
+		if reason, blocked := terminationBlockedReason(ctx, candidate, pdbs, lo.Ternary(d == c.expiration || d == c.drift, nil, c.disruptionHistory)); blocked && d != c.notReady {
+			blockedReason = fmt.Sprintf("%s blocked, %s", d, reason)
+			continue
+		}
+		peers := lo.Reject(candidates[i], func(n CandidateNode, _ int) bool { return budgets.Exhausted(n.provisioner.Name) })
+		cmd, err := c.evaluate(ctx, d, peers, candidate)
+		if err != nil {
+			return "", nil, fmt.Errorf("evaluating %s, %w", d, err)
+		}
+		if cmd.action == actionDelete || cmd.action == actionReplace {
+			return d.String(), &cmd, nil
+		}
+	}
+	return blockedReason, nil, nil
+}
+
+// PodBlocksConsolidation reports whether the pod prevents its node from being consolidated and why, e.g. to answer
+// "which pod is pinning this node?" from a debugging tool. The pod is checked with the same rules that consolidation
+// applies to the pods of its candidate nodes.
+func (c *Controller) PodBlocksConsolidation(ctx context.Context, pod *v1.Pod) (bool, string, error) {
+	pdbs, err := NewPDBLimits(ctx, c.kubeClient)
+	if err != nil {
+		return false, "", fmt.Errorf("tracking PodDisruptionBudgets, %w", err)
+	}
+	if reason, blocked := podsBlockedReason(ctx, []*v1.Pod{pod}, pdbs, c.disruptionHistory); blocked {
+		return true, reason, nil
+	}
+	if settings.FromContext(ctx).PinResourceClaimPods {
+		claims, err := NewResourceClaims(ctx, c.kubeClient)
+		if err != nil {
+			return false, "", fmt.Errorf("tracking ResourceClaims, %w", err)
+		}
+		if claim, pinned := claims.Pinned([]*v1.Pod{pod}); pinned {
+			return true, fmt.Sprintf("resource claim %s is allocated to the node", claim), nil
+		}
+	}
+	return false, "", nil
+}
+
+// evaluate computes the command that the deprovisioner would execute for the candidate without validating it, as
+// validation waits for the cluster to settle. The candidate is first filtered alongside its peers, i.e. the other
+// candidates of the deprovisioner, as consolidation filters them.
+func (c *Controller) evaluate(ctx context.Context, d Deprovisioner, peers []CandidateNode, candidate CandidateNode) (Command, error) {
+	if d == c.singleNodeConsolidation {
+		filtered, err := c.singleNodeConsolidation.sortAndFilterCandidates(ctx, peers)
+		if err != nil {
+			return Command{}, fmt.Errorf("sorting candidates, %w", err)
+		}
+		if !lo.ContainsBy(filtered, func(n CandidateNode) bool { return n.Name == candidate.Name }) {
+			return Command{action: actionDoNothing}, nil
+		}
+		return c.singleNodeConsolidation.computeConsolidation(ctx, candidate)
+	}
+	return d.ComputeCommand(ctx, candidate)
+}
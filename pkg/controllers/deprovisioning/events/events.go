@@ -16,6 +16,7 @@ package events
 
 import (
 	"fmt"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 
@@ -61,3 +62,63 @@ func WaitingOnDeletion(node *v1.Node) events.Event {
 		DedupeValues:   []string{node.Name},
 	}
 }
+
+func ExpiringShortly(node *v1.Node, expirationTime time.Time) events.Event {
+	return events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeWarning,
+		Reason:         "NodeExpiringShortly",
+		Message:        fmt.Sprintf("Node expires at %s and will be replaced", expirationTime.UTC().Format(time.RFC3339)),
+		DedupeValues:   []string{node.Name},
+	}
+}
+
+func LaunchBlocked(node *v1.Node, reason string) events.Event {
+	return events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeNormal,
+		Reason:         "DeprovisioningLaunchBlocked",
+		Message:        fmt.Sprintf("Unable to launch replacement node for %s, instance limit reached", reason),
+		DedupeValues:   []string{node.Name, reason},
+	}
+}
+
+func ConsolidationOscillation(node *v1.Node, reason string) events.Event {
+	return events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeWarning,
+		Reason:         "DeprovisioningConsolidationOscillation",
+		Message:        fmt.Sprintf("Consolidation is oscillating, %s", reason),
+		DedupeValues:   []string{node.Name},
+	}
+}
+
+func NoFeasibleReplacement(node *v1.Node, reason string) events.Event {
+	return events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeNormal,
+		Reason:         "DeprovisioningNoFeasibleReplacement",
+		Message:        fmt.Sprintf("Unable to find a replacement instance type, %s", reason),
+		DedupeValues:   []string{node.Name, reason},
+	}
+}
+
+func RescheduleMismatch(pod *v1.Pod, reason string) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeWarning,
+		Reason:         "DeprovisioningRescheduleMismatch",
+		Message:        fmt.Sprintf("Pod is still pending after deprovisioning via %s, although it was expected to reschedule to an existing node", reason),
+		DedupeValues:   []string{string(pod.UID)},
+	}
+}
+
+func ReschedulingTarget(node *v1.Node, reason string) events.Event {
+	return events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeNormal,
+		Reason:         "DeprovisioningRescheduleTarget",
+		Message:        fmt.Sprintf("Receiving pods displaced by deprovisioning via %s", reason),
+		DedupeValues:   []string{node.Name, reason},
+	}
+}
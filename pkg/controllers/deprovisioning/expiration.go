@@ -18,9 +18,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"math"
 	"sort"
 	"time"
 
+	"github.com/samber/lo"
 	"k8s.io/utils/clock"
 
 	v1 "k8s.io/api/core/v1"
@@ -28,10 +31,16 @@ import (
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
+	deprovisioningevents "github.com/aws/karpenter-core/pkg/controllers/deprovisioning/events"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
+	pscheduling "github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/metrics"
+	"github.com/aws/karpenter-core/pkg/utils/maintenancewindow"
+	"github.com/aws/karpenter-core/pkg/utils/resources"
 )
 
 // Expiration is a subreconciler that deletes empty nodes.
@@ -41,46 +50,166 @@ type Expiration struct {
 	kubeClient  client.Client
 	cluster     *state.Cluster
 	provisioner *provisioning.Provisioner
+	recorder    events.Recorder
 }
 
-func NewExpiration(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, provisioner *provisioning.Provisioner) *Expiration {
+func NewExpiration(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, provisioner *provisioning.Provisioner, recorder events.Recorder) *Expiration {
 	return &Expiration{
 		clock:       clk,
 		kubeClient:  kubeClient,
 		cluster:     cluster,
 		provisioner: provisioner,
+		recorder:    recorder,
 	}
 }
 
 // ShouldDeprovision is a predicate used to filter deprovisionable nodes
 func (e *Expiration) ShouldDeprovision(ctx context.Context, n *state.Node, provisioner *v1alpha5.Provisioner, nodePods []*v1.Pod) bool {
-	return e.clock.Now().After(getExpirationTime(n.Node, provisioner))
+	// nodes are protected from expiration until they've lived for at least the minimum node lifetime
+	if e.clock.Since(n.Node.CreationTimestamp.Time) < settings.FromContext(ctx).MinNodeLifetime.Duration {
+		return false
+	}
+	if !e.clock.Now().After(getExpirationTime(n.Node, provisioner)) {
+		return false
+	}
+	// expired nodes wait for their provisioner's next maintenance window
+	if !inExpirationMaintenanceWindow(provisioner, e.clock.Now()) {
+		return false
+	}
+	// operators can pin a node so that it's never expired, regardless of its provisioner's TTL
+	if n.Node.Annotations[v1alpha5.DoNotExpireNodeAnnotationKey] == "true" {
+		logging.FromContext(ctx).With("node", n.Node.Name).Debugf("not expiring node with %s annotation", v1alpha5.DoNotExpireNodeAnnotationKey)
+		return false
+	}
+	return true
 }
 
-// SortCandidates orders expired nodes by when they've expired
+// WarnOfExpirations records a warning event on each node that expires within its provisioner's warning threshold. This
+// is done separately from finding expired candidates, so that determining candidates never writes to the cluster.
+func (e *Expiration) WarnOfExpirations(ctx context.Context) error {
+	provisionerList := &v1alpha5.ProvisionerList{}
+	if err := e.kubeClient.List(ctx, provisionerList); err != nil {
+		return fmt.Errorf("listing provisioners, %w", err)
+	}
+	provisioners := lo.SliceToMap(provisionerList.Items, func(p v1alpha5.Provisioner) (string, *v1alpha5.Provisioner) {
+		return p.Name, lo.ToPtr(p)
+	})
+	var nodes []*v1.Node
+	e.cluster.ForEachNode(func(n *state.Node) bool {
+		if !n.MarkedForDeletion {
+			nodes = append(nodes, n.Node.DeepCopy())
+		}
+		return true
+	})
+	for _, node := range nodes {
+		if provisioner, ok := provisioners[node.Labels[v1alpha5.ProvisionerNameLabelKey]]; ok {
+			e.warnOfExpiration(ctx, node, provisioner)
+		}
+	}
+	return nil
+}
+
+// warnOfExpiration records a warning event on a node that expires within its provisioner's warning threshold. The
+// expiration time that the node was warned of is annotated, so that it's only warned once for each expiration time.
+func (e *Expiration) warnOfExpiration(ctx context.Context, node *v1.Node, provisioner *v1alpha5.Provisioner) {
+	if provisioner.Spec.Expiration == nil || ptr.Int64Value(provisioner.Spec.Expiration.WarningThresholdSeconds) == 0 {
+		return
+	}
+	expirationTime := getExpirationTime(node, provisioner)
+	untilExpiration := expirationTime.Sub(e.clock.Now())
+	if untilExpiration <= 0 || untilExpiration > time.Duration(*provisioner.Spec.Expiration.WarningThresholdSeconds)*time.Second {
+		return
+	}
+	if node.Annotations[v1alpha5.DoNotExpireNodeAnnotationKey] == "true" {
+		return
+	}
+	warned := expirationTime.UTC().Format(time.RFC3339)
+	if node.Annotations[v1alpha5.ExpirationWarningAnnotationKey] == warned {
+		return
+	}
+	e.recorder.Publish(deprovisioningevents.ExpiringShortly(node, expirationTime))
+	annotated := node.DeepCopy()
+	annotated.Annotations = lo.Assign(annotated.Annotations, map[string]string{v1alpha5.ExpirationWarningAnnotationKey: warned})
+	if err := e.kubeClient.Patch(ctx, annotated, client.MergeFrom(node)); err != nil {
+		logging.FromContext(ctx).With("node", node.Name).Errorf("annotating expiration warning, %s", err)
+	}
+}
+
+// SortCandidates orders expired nodes by when they've expired. The nodes of each provisioner then keep the positions
+// that this gives them, but are reordered amongst themselves by their provisioner's expiration order.
 func (e *Expiration) SortCandidates(nodes []CandidateNode) []CandidateNode {
 	sort.Slice(nodes, func(i int, j int) bool {
 		return getExpirationTime(nodes[i].Node, nodes[i].provisioner).Before(getExpirationTime(nodes[j].Node, nodes[j].provisioner))
 	})
+	positionsByProvisioner := map[string][]int{}
+	for i, n := range nodes {
+		positionsByProvisioner[n.provisioner.Name] = append(positionsByProvisioner[n.provisioner.Name], i)
+	}
+	for _, positions := range positionsByProvisioner {
+		less, ok := expirationOrders[nodes[positions[0]].provisioner.Spec.ExpirationOrder]
+		if !ok {
+			continue
+		}
+		group := lo.Map(positions, func(i int, _ int) CandidateNode { return nodes[i] })
+		sort.SliceStable(group, func(i int, j int) bool { return less(group[i], group[j]) })
+		for k, i := range positions {
+			nodes[i] = group[k]
+		}
+	}
 	return nodes
 }
 
-// ComputeCommand generates a deprovisioning command given deprovisionable nodes
+// expirationOrders are the orderings of expired nodes that differ from the order in which they expired
+var expirationOrders = map[v1alpha5.ExpirationOrder]func(a, b CandidateNode) bool{
+	v1alpha5.ExpirationOrderMostExpensive: func(a, b CandidateNode) bool { return candidatePrice(a) > candidatePrice(b) },
+	v1alpha5.ExpirationOrderLeastUtilized: func(a, b CandidateNode) bool { return cpuUtilization(a) < cpuUtilization(b) },
+}
+
+// candidatePrice returns the effective price of the offering that the node was launched with, or zero if it's unknown
+func candidatePrice(n CandidateNode) float64 {
+	offering, ok := n.instanceType.Offerings.Get(n.capacityType, n.zone)
+	if !ok {
+		return 0
+	}
+	return offering.EffectivePrice()
+}
+
+// cpuUtilization returns the fraction of the node's allocatable CPU that its pods request
+func cpuUtilization(n CandidateNode) float64 {
+	allocatable := n.Node.Status.Allocatable.Cpu().AsApproximateFloat64()
+	if allocatable == 0 {
+		return 0
+	}
+	requests := resources.RequestsForPods(n.pods...)
+	return requests.Cpu().AsApproximateFloat64() / allocatable
+}
+
+// ComputeCommand generates a deprovisioning command given deprovisionable nodes. The command replaces up to the most
+// expired candidate's provisioner's maximum nodes per cycle of its expired nodes.
+//
+//nolint:gocyclo
 func (e *Expiration) ComputeCommand(ctx context.Context, candidates ...CandidateNode) (Command, error) {
-	candidates = e.SortCandidates(candidates)
 	pdbs, err := NewPDBLimits(ctx, e.kubeClient)
 	if err != nil {
 		return Command{}, fmt.Errorf("tracking PodDisruptionBudgets, %w", err)
 	}
+	cmd := Command{action: actionDoNothing}
+	var expiring []CandidateNode
 	for _, candidate := range candidates {
+		// nodes are only expired together with nodes of the same provisioner, up to its limit
+		if len(expiring) > 0 && (candidate.provisioner.Name != expiring[0].provisioner.Name || len(expiring) >= maxExpiredNodesPerCycle(expiring[0].provisioner)) {
+			continue
+		}
 		// is this a node that we can terminate?  This check is meant to be fast so we can save the expense of simulated
-		// scheduling unless its really needed
-		if !canBeTerminated(candidate, pdbs) {
+		// scheduling unless its really needed. Expired nodes must be replaced, so recent disruptions don't block them.
+		if !canBeTerminated(ctx, candidate, pdbs, nil) {
 			continue
 		}
 
-		// Check if we need to create any nodes.
-		newNodes, allPodsScheduled, err := simulateScheduling(ctx, e.kubeClient, e.cluster, e.provisioner, candidate)
+		// Check if we need to create any nodes. The candidate is simulated together with the nodes that we're already
+		// expiring, so that their pods aren't all counted against the same spare capacity.
+		nodesToExpire := append(append([]CandidateNode{}, expiring...), candidate)
+		newNodes, _, allPodsScheduled, err := simulateScheduling(ctx, e.kubeClient, e.cluster, e.provisioner, nodesToExpire...)
 		if err != nil {
 			// if a candidate node is now deleting, just retry
 			if errors.Is(err, errCandidateNodeDeleting) {
@@ -92,22 +221,49 @@ func (e *Expiration) ComputeCommand(ctx context.Context, candidates ...Candidate
 		if !allPodsScheduled {
 			logging.FromContext(ctx).With("node", candidate.Name).Infof("Continuing to expire node after scheduling simulation failed to schedule all pods")
 		}
-		logging.FromContext(ctx).Infof("triggering termination for expired node after %s (+%s)",
-			time.Duration(ptr.Int64Value(candidates[0].provisioner.Spec.TTLSecondsUntilExpired))*time.Second, time.Since(getExpirationTime(candidates[0].Node, candidates[0].provisioner)))
+		// the replacements must be instance types that settings allow
+		for _, n := range newNodes {
+			filterByReplacementAllowList(ctx, n)
+		}
+		if lo.SomeBy(newNodes, func(n *pscheduling.Node) bool { return len(n.InstanceTypeOptions) == 0 }) {
+			logging.FromContext(ctx).With("node", candidate.Name).Debugf("unable to replace expired node with an allowed instance type")
+			continue
+		}
+		logging.FromContext(ctx).With("node", candidate.Name).Infof("triggering termination for expired node after %s (+%s)",
+			time.Duration(ptr.Int64Value(candidate.provisioner.Spec.TTLSecondsUntilExpired))*time.Second, time.Since(getExpirationTime(candidate.Node, candidate.provisioner)))
+		expiring = nodesToExpire
+		cmd = Command{
+			nodesToRemove: lo.Map(expiring, func(n CandidateNode, _ int) *v1.Node { return n.Node }),
+			action:        actionDelete,
+		}
 		// were we able to schedule all the pods on the inflight nodes?
-		if len(newNodes) == 0 {
-			return Command{
-				nodesToRemove: []*v1.Node{candidate.Node},
-				action:        actionDelete,
-			}, nil
+		if len(newNodes) > 0 {
+			cmd.action = actionReplace
+			cmd.replacementNodes = newNodes
 		}
-		return Command{
-			nodesToRemove:    []*v1.Node{candidate.Node},
-			action:           actionReplace,
-			replacementNodes: newNodes,
-		}, nil
 	}
-	return Command{action: actionDoNothing}, nil
+	return cmd, nil
+}
+
+// maxExpiredNodesPerCycle returns the number of the provisioner's expired nodes that a single command may replace
+func maxExpiredNodesPerCycle(provisioner *v1alpha5.Provisioner) int {
+	if provisioner.Spec.Expiration == nil || provisioner.Spec.Expiration.MaxNodesPerCycle == nil {
+		return 1
+	}
+	return int(*provisioner.Spec.Expiration.MaxNodesPerCycle)
+}
+
+// inExpirationMaintenanceWindow returns true if the provisioner's expired nodes may be replaced at the time, because
+// it's within one of the provisioner's maintenance windows or it has none. Windows that don't parse are rejected by
+// validation, and are ignored here.
+func inExpirationMaintenanceWindow(provisioner *v1alpha5.Provisioner, t time.Time) bool {
+	if provisioner.Spec.Expiration == nil || len(provisioner.Spec.Expiration.MaintenanceWindows) == 0 {
+		return true
+	}
+	return lo.SomeBy(provisioner.Spec.Expiration.MaintenanceWindows, func(raw string) bool {
+		window, err := maintenancewindow.Parse(raw)
+		return err == nil && window.Contains(t)
+	})
 }
 
 // String is the string representation of the deprovisioner
@@ -116,10 +272,48 @@ func (e *Expiration) String() string {
 }
 
 func getExpirationTime(node *v1.Node, provisioner *v1alpha5.Provisioner) time.Time {
-	if provisioner == nil || provisioner.Spec.TTLSecondsUntilExpired == nil {
+	if provisioner == nil {
+		return time.Date(5000, 0, 0, 0, 0, 0, 0, time.UTC)
+	}
+	// operators can move a node's expiration with an annotation, unless its provisioner doesn't allow it. Timestamps
+	// that can't be parsed are ignored, so that the node still expires with its provisioner's TTL.
+	if override, ok := expirationOverride(node, provisioner); ok {
+		return override
+	}
+	if provisioner.Spec.TTLSecondsUntilExpired == nil {
 		// If not defined, return some much larger time.
 		return time.Date(5000, 0, 0, 0, 0, 0, 0, time.UTC)
 	}
 	expirationTTL := time.Duration(ptr.Int64Value(provisioner.Spec.TTLSecondsUntilExpired)) * time.Second
-	return node.CreationTimestamp.Add(expirationTTL)
+	return node.CreationTimestamp.Add(expirationTTL + expirationJitter(node, expirationTTL, provisioner.Spec.ExpirationJitterFactor))
+}
+
+// expirationOverride returns the expiration time from the node's expiration override annotation, if its provisioner
+// allows it and the annotation is a valid RFC3339 timestamp
+func expirationOverride(node *v1.Node, provisioner *v1alpha5.Provisioner) (time.Time, bool) {
+	if e := provisioner.Spec.Expiration; e != nil && e.AllowNodeOverride != nil && !*e.AllowNodeOverride {
+		return time.Time{}, false
+	}
+	value, ok := node.Annotations[v1alpha5.ExpirationOverrideAnnotationKey]
+	if !ok {
+		return time.Time{}, false
+	}
+	override, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return override, true
+}
+
+// expirationJitter is the node's offset from its provisioner's expiration TTL. It's spread uniformly across plus or minus
+// the jitter factor of the TTL, and is derived from the node's UID so that it's stable across reconciliations.
+func expirationJitter(node *v1.Node, expirationTTL time.Duration, factor *float64) time.Duration {
+	if factor == nil || *factor == 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(node.UID))
+	// map the hash onto [-1, 1)
+	offset := float64(h.Sum64())/math.MaxUint64*2 - 1
+	return time.Duration(offset * *factor * float64(expirationTTL))
 }
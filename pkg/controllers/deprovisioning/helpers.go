@@ -16,12 +16,16 @@ package deprovisioning
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
@@ -30,17 +34,35 @@ import (
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	nodeutils "github.com/aws/karpenter-core/pkg/utils/node"
 	"github.com/aws/karpenter-core/pkg/utils/pod"
+	"github.com/aws/karpenter-core/pkg/utils/resources"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// simulateScheduling simulates rescheduling the pods of the nodes to delete, returning the new nodes that would be
+// needed and the existing nodes that some of the displaced pods would be rescheduled to
+//
 //nolint:gocyclo
 func simulateScheduling(ctx context.Context, kubeClient client.Client, cluster *state.Cluster, provisioner *provisioning.Provisioner,
-	nodesToDelete ...CandidateNode) (newNodes []*pscheduling.Node, allPodsScheduled bool, err error) {
+	nodesToDelete ...CandidateNode) (newNodes []*pscheduling.Node, targets []*pscheduling.ExistingNode, allPodsScheduled bool, err error) {
+	ctx, span := startSpan(ctx, "deprovisioning.simulateScheduling")
+	defer func() {
+		span.SetAttributes(
+			attribute.StringSlice("candidates", lo.Map(nodesToDelete, func(n CandidateNode, _ int) string { return n.Name })),
+			attribute.Bool("allPodsScheduled", allPodsScheduled),
+			attribute.Int("newNodes", len(newNodes)),
+		)
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
 	var stateNodes []*state.Node
 	var markedForDeletionNodes []*state.Node
 	candidateNodeIsDeleting := false
@@ -63,36 +85,39 @@ func simulateScheduling(ctx context.Context, kubeClient client.Client, cluster *
 	// already handled for deletion by some other controller. This could happen if the node was markedForDeletion
 	// between returning the candidateNodes and getting the stateNodes above
 	if candidateNodeIsDeleting {
-		return nil, false, errCandidateNodeDeleting
+		return nil, nil, false, errCandidateNodeDeleting
 	}
 
 	// We get the pods that are on nodes that are deleting
 	deletingNodePods, err := nodeutils.GetNodePods(ctx, kubeClient, lo.Map(markedForDeletionNodes, func(n *state.Node, _ int) *v1.Node { return n.Node })...)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to get pods from deleting nodes, %w", err)
+		return nil, nil, false, fmt.Errorf("failed to get pods from deleting nodes, %w", err)
 	}
 
 	// start by getting all pending pods
 	pods, err := provisioner.GetPendingPods(ctx)
 	if err != nil {
-		return nil, false, fmt.Errorf("determining pending pods, %w", err)
+		return nil, nil, false, fmt.Errorf("determining pending pods, %w", err)
 	}
 
+	// the scheduler mutates the pods that it schedules (e.g. relaxing preferences), so we copy the candidate pods to
+	// keep the candidates safe to share across concurrent simulations
 	for _, n := range nodesToDelete {
-		pods = append(pods, n.pods...)
+		pods = append(pods, lo.Map(n.pods, func(p *v1.Pod, _ int) *v1.Pod { return pinOperatingSystem(p.DeepCopy(), n.Node) })...)
 	}
 	pods = append(pods, deletingNodePods...)
 	scheduler, err := provisioner.NewScheduler(ctx, pods, stateNodes, pscheduling.SchedulerOptions{
-		SimulationMode: true,
+		SimulationMode:            true,
+		ExcludeUnschedulableNodes: true,
 	})
 
 	if err != nil {
-		return nil, false, fmt.Errorf("creating scheduler, %w", err)
+		return nil, nil, false, fmt.Errorf("creating scheduler, %w", err)
 	}
 
 	newNodes, ifn, err := scheduler.Solve(ctx, pods)
 	if err != nil {
-		return nil, false, fmt.Errorf("simulating scheduling, %w", err)
+		return nil, nil, false, fmt.Errorf("simulating scheduling, %w", err)
 	}
 
 	podsScheduled := 0
@@ -108,10 +133,89 @@ func simulateScheduling(ctx context.Context, kubeClient client.Client, cluster *
 	// move to an existing node which won't occur if that node isn't ready.
 	for _, n := range ifn {
 		if n.Node.Labels[v1alpha5.LabelNodeInitialized] != "true" {
-			return nil, false, nil
+			return nil, nil, false, nil
+		}
+	}
+	for _, n := range ifn {
+		if len(n.Pods) > 0 {
+			targets = append(targets, n)
 		}
 	}
-	return newNodes, podsScheduled == len(pods), nil
+	if podsScheduled != len(pods) && !sideEffectsDisabled(ctx) {
+		recordBlockingResources(pods, newNodes, ifn)
+	}
+	return newNodes, targets, podsScheduled == len(pods), nil
+}
+
+// pinOperatingSystem requires the pod to run on the operating system of the node that it's displaced from, so that the
+// simulation only considers replacements with that operating system and sizes them for its daemons alone
+func pinOperatingSystem(p *v1.Pod, node *v1.Node) *v1.Pod {
+	operatingSystem, ok := node.Labels[v1.LabelOSStable]
+	if !ok {
+		return p
+	}
+	if p.Spec.NodeSelector == nil {
+		p.Spec.NodeSelector = map[string]string{}
+	}
+	p.Spec.NodeSelector[v1.LabelOSStable] = operatingSystem
+	return p
+}
+
+// targetNodes returns the nodes of the existing nodes that the simulation scheduled pods to
+func targetNodes(targets []*pscheduling.ExistingNode) []*v1.Node {
+	return lo.Map(targets, func(n *pscheduling.ExistingNode, _ int) *v1.Node { return n.Node })
+}
+
+// reschedulePlacements maps each of the candidates' pods that the simulation scheduled to an existing node to the
+// name of that node
+func reschedulePlacements(candidates []CandidateNode, targets []*pscheduling.ExistingNode) map[types.NamespacedName]string {
+	displaced := map[types.NamespacedName]bool{}
+	for _, c := range candidates {
+		for _, p := range c.pods {
+			displaced[client.ObjectKeyFromObject(p)] = true
+		}
+	}
+	placements := map[types.NamespacedName]string{}
+	for _, target := range targets {
+		for _, p := range target.Pods {
+			if key := client.ObjectKeyFromObject(p); displaced[key] {
+				placements[key] = target.Node.Name
+			}
+		}
+	}
+	return placements
+}
+
+// recordBlockingResources records the resources that kept pods which failed to reschedule from fitting on the
+// existing nodes. A resource is blocking for a pod if there's too little of it left on every existing node that the
+// pod is otherwise able to schedule to.
+func recordBlockingResources(pods []*v1.Pod, newNodes []*pscheduling.Node, ifn []*pscheduling.ExistingNode) {
+	scheduled := sets.NewString()
+	for _, n := range newNodes {
+		scheduled.Insert(lo.Map(n.Pods, func(p *v1.Pod, _ int) string { return string(p.UID) })...)
+	}
+	for _, n := range ifn {
+		scheduled.Insert(lo.Map(n.Pods, func(p *v1.Pod, _ int) string { return string(p.UID) })...)
+	}
+	blocking := sets.NewString()
+	for _, p := range pods {
+		if scheduled.Has(string(p.UID)) {
+			continue
+		}
+		var podBlocking sets.String
+		for _, n := range ifn {
+			insufficient, ok := n.InsufficientResources(p)
+			if !ok {
+				continue
+			}
+			names := sets.NewString(lo.Map(insufficient, func(r v1.ResourceName, _ int) string { return string(r) })...)
+			podBlocking = lo.Ternary(podBlocking == nil, names, podBlocking.Intersection(names))
+		}
+		blocking = blocking.Union(podBlocking)
+	}
+	for _, resourceName := range blocking.List() {
+		consolidationBlockedByResourceCounter.WithLabelValues(resourceName).Inc()
+	}
 }
 
 // instanceTypesAreSubset returns true if the lhs slice of instance types are a subset of the rhs.
@@ -121,34 +225,306 @@ func instanceTypesAreSubset(lhs []*cloudprovider.InstanceType, rhs []*cloudprovi
 	return len(rhsNames.Intersection(lhsNames)) == len(lhsNames)
 }
 
-// GetPodEvictionCost returns the disruption cost computed for evicting the given pod.
+const (
+	// persistentVolumeEvictionCost is added to the eviction cost of a pod that mounts persistent volume claims, since
+	// its volumes must be detached and reattached wherever it's rescheduled
+	persistentVolumeEvictionCost = 1.0
+	// localStorageEvictionCost is added to the eviction cost of a pod that uses emptyDir volumes, since their contents
+	// are lost when it's evicted
+	localStorageEvictionCost = 0.5
+)
+
+// GetPodEvictionCost returns the disruption cost computed for evicting the given pod. Pods that mount persistent volume
+// claims or use emptyDir volumes cost more to evict, by a fixed amount for each that applies regardless of how many
+// volumes they have. The cost is then scaled by the factors that settings configure for the pod's QoS class and for pods
+// that are controlled by a StatefulSet.
 func GetPodEvictionCost(ctx context.Context, p *v1.Pod) float64 {
 	cost := 1.0
-	podDeletionCostStr, ok := p.Annotations[v1.PodDeletionCost]
-	if ok {
-		podDeletionCost, err := strconv.ParseFloat(podDeletionCostStr, 64)
-		if err != nil {
-			logging.FromContext(ctx).Errorf("parsing %s=%s from pod %s, %s",
-				v1.PodDeletionCost, podDeletionCostStr, client.ObjectKeyFromObject(p), err)
-		} else {
-			// the pod deletion disruptionCost is in [-2147483647, 2147483647]
-			// the min pod disruptionCost makes one pod ~ -15 pods, and the max pod disruptionCost to ~ 17 pods.
-			cost += podDeletionCost / math.Pow(2, 27.0)
-		}
+	if lo.SomeBy(p.Spec.Volumes, func(v v1.Volume) bool { return v.PersistentVolumeClaim != nil || v.Ephemeral != nil }) {
+		cost += persistentVolumeEvictionCost
+	}
+	if lo.SomeBy(p.Spec.Volumes, func(v v1.Volume) bool { return v.EmptyDir != nil }) {
+		cost += localStorageEvictionCost
+	}
+	if podDeletionCostStr, ok := p.Annotations[v1.PodDeletionCost]; ok {
+		// the min pod disruptionCost makes one pod ~ -15 pods, and the max pod disruptionCost to ~ 17 pods.
+		cost += float64(parsePodDeletionCost(ctx, p, podDeletionCostStr)) / math.Pow(2, 27.0)
 	}
 	// the scheduling priority is in [-2147483648, 1000000000]
 	if p.Spec.Priority != nil {
 		cost += float64(*p.Spec.Priority) / math.Pow(2, 25)
 	}
+	s := settings.FromContext(ctx)
+	factor := s.EvictionQoSFactors.Factor(pod.QOSClass(p))
+	if owner := metav1.GetControllerOf(p); owner != nil && owner.Kind == "StatefulSet" && s.StatefulSetEvictionCostFactor > 0 {
+		factor *= s.StatefulSetEvictionCostFactor
+	}
+	// negative costs are divided by the factor, so that a larger factor always makes a pod more costly to evict
+	if cost > 0 {
+		cost *= factor
+	} else {
+		cost /= factor
+	}
 
 	// overall we clamp the pod cost to the range [-10.0, 10.0] with the default being 1.0
 	return clamp(-10.0, cost, 10.0)
 }
 
-func filterByPrice(options []*cloudprovider.InstanceType, reqs scheduling.Requirements, price float64) []*cloudprovider.InstanceType {
+// parsePodDeletionCost parses the pod deletion cost annotation, clamping it to the int32 range that the API server
+// documents for it. Unparseable values are treated as the default cost of zero.
+func parsePodDeletionCost(ctx context.Context, p *v1.Pod, podDeletionCostStr string) int32 {
+	podDeletionCost, err := strconv.ParseInt(strings.TrimSpace(podDeletionCostStr), 10, 64)
+	if err != nil {
+		// out of range values are returned as the closest int64 and clamped below
+		if !errors.Is(err, strconv.ErrRange) {
+			logging.FromContext(ctx).Warnf("ignoring %s=%s from pod %s, %s",
+				v1.PodDeletionCost, podDeletionCostStr, client.ObjectKeyFromObject(p), err)
+			return 0
+		}
+	}
+	return int32(lo.Clamp(podDeletionCost, math.MinInt32, math.MaxInt32))
+}
+
+// filterByProvisionerLimits restricts a replacement node to instance types that fit within its provisioner's limits.
+// The replacement launches before the nodes that it's replacing are removed, so they still count against the limits.
+func filterByProvisionerLimits(ctx context.Context, kubeClient client.Client, cluster *state.Cluster, newNode *pscheduling.Node) error {
+	provisioner := &v1alpha5.Provisioner{}
+	if err := kubeClient.Get(ctx, types.NamespacedName{Name: newNode.ProvisionerName}, provisioner); err != nil {
+		return fmt.Errorf("getting provisioner, %w", err)
+	}
+	if provisioner.Spec.Limits == nil || len(provisioner.Spec.Limits.Resources) == 0 {
+		return nil
+	}
+	var provisioned []v1.ResourceList
+	cluster.ForEachNode(func(n *state.Node) bool {
+		if n.Node.Labels[v1alpha5.ProvisionerNameLabelKey] == provisioner.Name {
+			provisioned = append(provisioned, n.Capacity)
+		}
+		return true
+	})
+	usage := resources.Merge(provisioned...)
+	newNode.InstanceTypeOptions = lo.Filter(newNode.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+		total := resources.Merge(usage, it.Capacity)
+		for resourceName, limit := range provisioner.Spec.Limits.Resources {
+			if resources.Cmp(total[resourceName], limit) > 0 {
+				return false
+			}
+		}
+		return true
+	})
+	if len(newNode.InstanceTypeOptions) == 0 {
+		logging.FromContext(ctx).With("provisioner", provisioner.Name).Debugf("unable to replace node(s) without exceeding provisioner limits")
+	}
+	return nil
+}
+
+// CheapestInstanceTypeFor returns the cheapest instance type and offering that can run all of the pods while
+// satisfying the requirements, or false if none can. The pods' requests include their overhead, and any DaemonSet pods
+// that would run on the node should be included in the pods.
+func CheapestInstanceTypeFor(pods []*v1.Pod, its []*cloudprovider.InstanceType, reqs scheduling.Requirements) (*cloudprovider.InstanceType, cloudprovider.Offering, bool) {
+	requests := resources.RequestsForPods(pods...)
+	var cheapestType *cloudprovider.InstanceType
+	var cheapestOffering cloudprovider.Offering
+	for _, it := range its {
+		for _, of := range feasibleOfferings(it, requests, reqs) {
+			if cheapestType == nil || of.Price < cheapestOffering.Price {
+				cheapestType, cheapestOffering = it, of
+			}
+		}
+	}
+	return cheapestType, cheapestOffering, cheapestType != nil
+}
+
+// feasibleOfferings returns the available offerings of the instance type that satisfy the requirements, or none if
+// the instance type can't satisfy the requirements or fit the requested resources
+func feasibleOfferings(it *cloudprovider.InstanceType, requests v1.ResourceList, reqs scheduling.Requirements) []cloudprovider.Offering {
+	if it.Requirements.Intersects(reqs) != nil || !resources.Fits(resources.Merge(requests, it.Overhead.Total()), it.Capacity) {
+		return nil
+	}
+	return lo.Filter(it.Offerings.Available(), func(of cloudprovider.Offering, _ int) bool {
+		return reqs.Get(v1.LabelTopologyZone).Has(of.Zone) && reqs.Get(v1alpha5.LabelCapacityType).Has(of.CapacityType)
+	})
+}
+
+// limitingRequirements identifies the requirements that leave no instance type able to fit the requests by
+// progressively relaxing them. If relaxing any single requirement makes an instance type feasible, those requirements
+// are returned. Otherwise requirements are relaxed cumulatively until an instance type is feasible, and the smallest
+// set of relaxed requirements that is still needed is returned. Nothing is returned if the requirements don't
+// eliminate every instance type or if no amount of relaxing them helps.
+func limitingRequirements(its []*cloudprovider.InstanceType, requests v1.ResourceList, reqs scheduling.Requirements) []*scheduling.Requirement {
+	feasible := func(relaxed sets.String) bool {
+		remaining := scheduling.NewRequirements(lo.Reject(reqs.Values(), func(r *scheduling.Requirement, _ int) bool { return relaxed.Has(r.Key) })...)
+		return lo.SomeBy(its, func(it *cloudprovider.InstanceType) bool { return len(feasibleOfferings(it, requests, remaining)) > 0 })
+	}
+	if feasible(sets.NewString()) {
+		return nil
+	}
+	keys := reqs.Keys().List()
+	limiting := lo.Filter(keys, func(key string, _ int) bool { return feasible(sets.NewString(key)) })
+	if len(limiting) == 0 {
+		relaxed := sets.NewString()
+		for _, key := range keys {
+			relaxed.Insert(key)
+			if feasible(relaxed) {
+				break
+			}
+		}
+		if !feasible(relaxed) {
+			return nil
+		}
+		// restore any requirements that we relaxed along the way which weren't needed
+		for _, key := range relaxed.List() {
+			relaxed.Delete(key)
+			if !feasible(relaxed) {
+				relaxed.Insert(key)
+			}
+		}
+		limiting = relaxed.List()
+	}
+	return lo.Map(limiting, func(key string, _ int) *scheduling.Requirement { return reqs.Get(key) })
+}
+
+// filterByRequiredNodeAffinity restricts the replacement node's instance type options to those that satisfy the
+// required node affinity of every candidate pod that it's replacing. The simulation works against copies of the pods
+// that may have been relaxed, so this is checked against the candidates' original pods to ensure that no pod is
+// stranded on a replacement that it can't run on.
+func filterByRequiredNodeAffinity(nodes []CandidateNode, newNode *pscheduling.Node) {
+	displaced := sets.NewString(lo.Map(newNode.Pods, func(p *v1.Pod, _ int) string { return string(p.UID) })...)
+	var pods []*v1.Pod
+	for _, n := range nodes {
+		pods = append(pods, lo.Filter(n.pods, func(p *v1.Pod, _ int) bool { return displaced.Has(string(p.UID)) })...)
+	}
+	newNode.InstanceTypeOptions = lo.Filter(newNode.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+		requirements := scheduling.NewRequirements(newNode.Requirements.Values()...)
+		requirements.Add(it.Requirements.Values()...)
+		for _, p := range pods {
+			if _, ok := lo.Find(requiredNodeAffinityTerms(p), func(term scheduling.Requirements) bool {
+				return requirements.Compatible(term) == nil
+			}); !ok {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// filterByReplacementAllowList restricts the replacement node's instance type options to the instance types that
+// settings allow to be launched as replacements
+func filterByReplacementAllowList(ctx context.Context, newNode *pscheduling.Node) {
+	allowed := settings.FromContext(ctx).ReplacementInstanceTypes
+	if allowed.Len() == 0 {
+		return
+	}
+	newNode.InstanceTypeOptions = lo.Filter(newNode.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+		return allowed.Has(it.Name)
+	})
+}
+
+// filterByCapacityType restricts a replacement node to the capacity types of the nodes that it is replacing when
+// settings require it, so that consolidation doesn't silently change the reliability of on-demand or spot capacity.
+// Provisioners can opt out with the allow-capacity-type-change annotation.
+func filterByCapacityType(ctx context.Context, nodes []CandidateNode, newNode *pscheduling.Node) {
+	if !settings.FromContext(ctx).PreserveCapacityType {
+		return
+	}
+	if lo.EveryBy(nodes, func(n CandidateNode) bool {
+		return n.provisioner != nil && n.provisioner.Annotations[v1alpha5.AllowCapacityTypeChangeAnnotationKey] == "true"
+	}) {
+		return
+	}
+	capacityTypes := lo.Uniq(lo.Map(nodes, func(n CandidateNode, _ int) string { return n.capacityType }))
+	newNode.Requirements.Add(scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, capacityTypes...))
+	newNode.InstanceTypeOptions = lo.Filter(newNode.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+		return lo.SomeBy(it.Offerings.Available(), func(o cloudprovider.Offering) bool {
+			return newNode.Requirements.Get(v1alpha5.LabelCapacityType).Has(o.CapacityType)
+		})
+	})
+}
+
+// filterByPreferredCapacityType restricts a replacement node that may launch as either capacity type to spot if any of
+// its instance type options can launch as spot, and to on-demand otherwise, matching provisioning's preference for
+// spot. The options have already been filtered by price assuming that they launch as spot where they can, so this also
+// ensures that if spot capacity is insufficient we don't replace the node with a more expensive on-demand node. Instead
+// the launch fails and we just leave the node alone.
+func filterByPreferredCapacityType(newNode *pscheduling.Node) {
+	ctReq := newNode.Requirements.Get(v1alpha5.LabelCapacityType)
+	if !ctReq.Has(v1alpha5.CapacityTypeSpot) || !ctReq.Has(v1alpha5.CapacityTypeOnDemand) {
+		return
+	}
+	launchableAs := func(capacityType string) []*cloudprovider.InstanceType {
+		return lo.Filter(newNode.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+			return lo.SomeBy(it.Offerings.Available(), func(o cloudprovider.Offering) bool {
+				return o.CapacityType == capacityType && newNode.Requirements.Get(v1.LabelTopologyZone).Has(o.Zone)
+			})
+		})
+	}
+	capacityType := v1alpha5.CapacityTypeSpot
+	options := launchableAs(capacityType)
+	if len(options) == 0 {
+		capacityType = v1alpha5.CapacityTypeOnDemand
+		options = launchableAs(capacityType)
+	}
+	newNode.Requirements.Add(scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, capacityType))
+	newNode.InstanceTypeOptions = options
+}
+
+// filterByZone restricts a replacement node to the zone of the nodes that it is replacing when settings require
+// zonal consolidation, so that displaced pods aren't moved across zones
+func filterByZone(ctx context.Context, nodes []CandidateNode, newNode *pscheduling.Node) {
+	if !settings.FromContext(ctx).ZonalConsolidation {
+		return
+	}
+	zones := lo.Uniq(lo.Map(nodes, func(n CandidateNode, _ int) string { return n.zone }))
+	newNode.Requirements.Add(scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, zones...))
+	newNode.InstanceTypeOptions = lo.Filter(newNode.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+		return lo.SomeBy(it.Offerings.Available(), func(o cloudprovider.Offering) bool {
+			return newNode.Requirements.Get(v1.LabelTopologyZone).Has(o.Zone)
+		})
+	})
+}
+
+// filterBySizeRatio restricts a replacement node to instance types whose CPU and memory are within the configured
+// multiple of the capacity of the nodes that it is replacing, so that consolidation doesn't concentrate the failure risk
+// of many small nodes onto a single enormous node
+func filterBySizeRatio(ctx context.Context, nodes []CandidateNode, newNode *pscheduling.Node) {
+	ratio := settings.FromContext(ctx).MaxConsolidationSizeRatio
+	if ratio == 0 {
+		return
+	}
+	removed := resources.Merge(lo.Map(nodes, func(n CandidateNode, _ int) v1.ResourceList { return n.instanceType.Capacity })...)
+	newNode.InstanceTypeOptions = lo.Filter(newNode.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+		for _, resourceName := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			limit, ok := removed[resourceName]
+			if !ok {
+				continue
+			}
+			if quantity := it.Capacity[resourceName]; quantity.AsApproximateFloat64() > ratio*limit.AsApproximateFloat64() {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// requiredNodeAffinityTerms returns the requirements for each of the pod's required node affinity terms, one of which
+// must be satisfied for the pod to schedule
+func requiredNodeAffinityTerms(p *v1.Pod) []scheduling.Requirements {
+	nodeSelector := scheduling.NewLabelRequirements(p.Spec.NodeSelector)
+	if p.Spec.Affinity == nil || p.Spec.Affinity.NodeAffinity == nil || p.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil ||
+		len(p.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms) == 0 {
+		return []scheduling.Requirements{nodeSelector}
+	}
+	return lo.Map(p.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms, func(term v1.NodeSelectorTerm, _ int) scheduling.Requirements {
+		requirements := scheduling.NewRequirements(nodeSelector.Values()...)
+		requirements.Add(scheduling.NewNodeSelectorRequirements(term.MatchExpressions...).Values()...)
+		return requirements
+	})
+}
+
+func filterByPrice(ctx context.Context, options []*cloudprovider.InstanceType, reqs scheduling.Requirements, price float64) []*cloudprovider.InstanceType {
 	var result []*cloudprovider.InstanceType
 	for _, it := range options {
-		launchPrice := worstLaunchPrice(it.Offerings.Available(), reqs)
+		launchPrice := worstLaunchPrice(it.Offerings.Available(), reqs, settings.FromContext(ctx).ConsolidationSpotFallbackWeight)
 		if launchPrice < price {
 			result = append(result, it)
 		}
@@ -164,17 +540,54 @@ func disruptionCost(ctx context.Context, pods []*v1.Pod) float64 {
 	return cost
 }
 
+// resourceWeight is the factor that a node's disruption cost is scaled by for the resources that its instance type
+// provides, so that nodes with resources that are hard to replace (e.g. GPUs) are preferred to be kept
+func resourceWeight(ctx context.Context, instanceType *cloudprovider.InstanceType) float64 {
+	weight := 1.0
+	for resourceName, w := range settings.FromContext(ctx).DisruptionCostResourceWeights {
+		if quantity, ok := instanceType.Capacity[v1.ResourceName(resourceName)]; ok {
+			weight += w * quantity.AsApproximateFloat64()
+		}
+	}
+	return weight
+}
+
+// committedWeight is the factor that a node's disruption cost is scaled by for the committed discount of the offering
+// that it was launched with, so that nodes that are covered by a commitment are preferred to be kept over on-demand ones
+func committedWeight(instanceType *cloudprovider.InstanceType, capacityType string, zone string) float64 {
+	offering, ok := instanceType.Offerings.Get(capacityType, zone)
+	if !ok {
+		return 1
+	}
+	return 1 + offering.CommittedDiscount
+}
+
+// externallyCordoned returns true if the node was cordoned by something other than deprovisioning, e.g. another
+// controller that's draining it
+func externallyCordoned(node *v1.Node) bool {
+	return node.Spec.Unschedulable && node.Annotations[v1alpha5.DeprovisioningCordonAnnotationKey] != "true"
+}
+
 type CandidateFilter func(context.Context, *state.Node, *v1alpha5.Provisioner, []*v1.Pod) bool
 
-// candidateNodes returns nodes that appear to be currently deprovisionable based off of their provisioner
+// includesUninitialized returns true if the deprovisioner acts on nodes that haven't initialized, which is only the case
+// for deleting nodes that aren't ready, since nodes that fail to bootstrap never initialize
+func includesUninitialized(d Deprovisioner) bool {
+	_, ok := d.(*NotReady)
+	return ok
+}
+
+// candidateNodes returns nodes that appear to be currently deprovisionable based off of their provisioner. Nodes that
+// haven't initialized are only candidates if includeUninitialized is set.
 // nolint:gocyclo
-func candidateNodes(ctx context.Context, cluster *state.Cluster, kubeClient client.Client, clk clock.Clock, cloudProvider cloudprovider.CloudProvider, shouldDeprovision CandidateFilter) ([]CandidateNode, error) {
+func candidateNodes(ctx context.Context, cluster *state.Cluster, kubeClient client.Client, clk clock.Clock, cloudProvider cloudprovider.CloudProvider, shouldDeprovision CandidateFilter, includeUninitialized bool) ([]CandidateNode, error) {
 	provisioners, instanceTypesByProvisioner, err := buildProvisionerMap(ctx, kubeClient, cloudProvider)
 	if err != nil {
 		return nil, err
 	}
 
 	var nodes []CandidateNode
+	var podsByNode map[string][]*v1.Pod
 	cluster.ForEachNode(func(n *state.Node) bool {
 		var provisioner *v1alpha5.Provisioner
 		var instanceTypeMap map[string]*cloudprovider.InstanceType
@@ -207,8 +620,8 @@ func candidateNodes(ctx context.Context, cluster *state.Cluster, kubeClient clie
 			return true
 		}
 
-		// Skip nodes that aren't initialized
-		if n.Node.Labels[v1alpha5.LabelNodeInitialized] != "true" {
+		// skip nodes that aren't initialized
+		if n.Node.Labels[v1alpha5.LabelNodeInitialized] != "true" && !includeUninitialized {
 			return true
 		}
 
@@ -217,24 +630,33 @@ func candidateNodes(ctx context.Context, cluster *state.Cluster, kubeClient clie
 			return true
 		}
 
-		pods, err := nodeutils.GetNodePods(ctx, kubeClient, n.Node)
-		if err != nil {
-			logging.FromContext(ctx).Errorf("Determining node pods, %s", err)
-			return true
+		// the pods of every node are listed at once, the first time that a node needs them
+		if podsByNode == nil {
+			if podsByNode, err = nodeutils.GetPodsByNode(ctx, kubeClient); err != nil {
+				return false
+			}
+		}
+		pods := podsByNode[n.Node.Name]
+		// pods that have been crash looping for the window provide no service, so they still have to reschedule but they
+		// don't keep the node occupied or add to the cost of disrupting it
+		serving := pods
+		if window := settings.FromContext(ctx).CrashLoopingPodWindow.Duration; window > 0 {
+			serving = lo.Reject(pods, func(p *v1.Pod, _ int) bool { return pod.IsCrashLooping(p, clk.Now().Add(-window)) })
 		}
 
-		if !shouldDeprovision(ctx, n, provisioner, pods) {
+		if !shouldDeprovision(ctx, n, provisioner, serving) {
 			return true
 		}
 
 		cn := CandidateNode{
-			Node:           n.Node,
-			instanceType:   instanceType,
-			capacityType:   ct,
-			zone:           az,
-			provisioner:    provisioner,
-			pods:           pods,
-			disruptionCost: disruptionCost(ctx, pods),
+			Node:             n.Node,
+			instanceType:     instanceType,
+			capacityType:     ct,
+			zone:             az,
+			provisioner:      provisioner,
+			pods:             pods,
+			crashLoopingPods: len(pods) - len(serving),
+			disruptionCost:   disruptionCost(ctx, serving) * resourceWeight(ctx, instanceType) * committedWeight(instanceType, ct, az),
 		}
 		// lifetimeRemaining is the fraction of node lifetime remaining in the range [0.0, 1.0].  If the TTLSecondsUntilExpired
 		// is non-zero, we use it to scale down the disruption costs of nodes that are going to expire.  Just after creation, the
@@ -245,7 +667,9 @@ func candidateNodes(ctx context.Context, cluster *state.Cluster, kubeClient clie
 		nodes = append(nodes, cn)
 		return true
 	})
-
+	if err != nil {
+		return nil, fmt.Errorf("determining node pods, %w", err)
+	}
 	return nodes, nil
 }
 
@@ -289,30 +713,36 @@ func calculateLifetimeRemaining(node CandidateNode, clock clock.Clock) float64 {
 
 // worstLaunchPrice gets the worst-case launch price from the offerings that are offered
 // on an instance type. If the instance type has a spot offering available, then it uses the spot offering
-// to get the launch price; else, it uses the on-demand launch price
-func worstLaunchPrice(ofs []cloudprovider.Offering, reqs scheduling.Requirements) float64 {
-	// We prefer to launch spot offerings, so we will get the worst price based on the node requirements
-	if reqs.Get(v1alpha5.LabelCapacityType).Has(v1alpha5.CapacityTypeSpot) {
-		spotOfferings := lo.Filter(ofs, func(of cloudprovider.Offering, _ int) bool {
-			return of.CapacityType == v1alpha5.CapacityTypeSpot && reqs.Get(v1.LabelTopologyZone).Has(of.Zone)
+// to get the launch price; else, it uses the on-demand launch price. If the requirements also allow on-demand, the
+// spot fallback weight of the difference to the on-demand price is added, since spot capacity may be unavailable.
+func worstLaunchPrice(ofs []cloudprovider.Offering, reqs scheduling.Requirements, spotFallbackWeight float64) float64 {
+	onDemandPrice := math.MaxFloat64
+	if reqs.Get(v1alpha5.LabelCapacityType).Has(v1alpha5.CapacityTypeOnDemand) {
+		onDemandOfferings := lo.Filter(ofs, func(of cloudprovider.Offering, _ int) bool {
+			return of.CapacityType == v1alpha5.CapacityTypeOnDemand && reqs.Get(v1.LabelTopologyZone).Has(of.Zone)
 		})
-		if len(spotOfferings) > 0 {
-			return lo.MaxBy(spotOfferings, func(of1, of2 cloudprovider.Offering) bool {
+		if len(onDemandOfferings) > 0 {
+			onDemandPrice = lo.MaxBy(onDemandOfferings, func(of1, of2 cloudprovider.Offering) bool {
 				return of1.Price > of2.Price
 			}).Price
 		}
 	}
-	if reqs.Get(v1alpha5.LabelCapacityType).Has(v1alpha5.CapacityTypeOnDemand) {
-		onDemandOfferings := lo.Filter(ofs, func(of cloudprovider.Offering, _ int) bool {
-			return of.CapacityType == v1alpha5.CapacityTypeOnDemand && reqs.Get(v1.LabelTopologyZone).Has(of.Zone)
+	// We prefer to launch spot offerings, so we will get the worst price based on the node requirements
+	if reqs.Get(v1alpha5.LabelCapacityType).Has(v1alpha5.CapacityTypeSpot) {
+		spotOfferings := lo.Filter(ofs, func(of cloudprovider.Offering, _ int) bool {
+			return of.CapacityType == v1alpha5.CapacityTypeSpot && reqs.Get(v1.LabelTopologyZone).Has(of.Zone)
 		})
-		if len(onDemandOfferings) > 0 {
-			return lo.MaxBy(onDemandOfferings, func(of1, of2 cloudprovider.Offering) bool {
+		if len(spotOfferings) > 0 {
+			spotPrice := lo.MaxBy(spotOfferings, func(of1, of2 cloudprovider.Offering) bool {
 				return of1.Price > of2.Price
 			}).Price
+			if onDemandPrice == math.MaxFloat64 || onDemandPrice < spotPrice {
+				return spotPrice
+			}
+			return spotPrice + spotFallbackWeight*(onDemandPrice-spotPrice)
 		}
 	}
-	return math.MaxFloat64
+	return onDemandPrice
 }
 
 func clamp(min, val, max float64) float64 {
@@ -337,31 +767,49 @@ func mapNodes(nodes []*v1.Node, candidateNodes []CandidateNode) []CandidateNode
 	return ret
 }
 
-func canBeTerminated(node CandidateNode, pdbs *PDBLimits) bool {
+func canBeTerminated(ctx context.Context, node CandidateNode, pdbs *PDBLimits, history *DisruptionHistory) bool {
+	_, blocked := terminationBlockedReason(ctx, node, pdbs, history)
+	return !blocked
+}
+
+// terminationBlockedReason returns the reason that the node can't be terminated and true if it is blocked. The
+// disruption history is optional, if it's nil then recent disruptions of the node's pods aren't considered.
+func terminationBlockedReason(ctx context.Context, node CandidateNode, pdbs *PDBLimits, history *DisruptionHistory) (string, bool) {
 	if !node.DeletionTimestamp.IsZero() {
-		return false
-	}
-	if _, ok := pdbs.CanEvictPods(node.pods); !ok {
-		return false
+		return "node is already deleting", true
 	}
+	return podsBlockedReason(ctx, node.pods, pdbs, history)
+}
 
-	if _, ok := PodsPreventEviction(node.pods); ok {
-		return false
+// podsBlockedReason returns the reason that the pods prevent their node from being terminated and true if they do
+func podsBlockedReason(ctx context.Context, pods []*v1.Pod, pdbs *PDBLimits, history *DisruptionHistory) (string, bool) {
+	if pdb, ok := pdbs.CanEvictPods(pods); !ok {
+		return fmt.Sprintf("pdb %s prevents pod evictions", pdb), true
+	}
+	if reason, blocked := history.Blocked(ctx, pods); blocked {
+		return reason, true
 	}
-	return true
+	return PodsPreventEviction(ctx, pods)
 }
 
 // PodsPreventEviction returns true if there are pods that would prevent eviction
-func PodsPreventEviction(pods []*v1.Pod) (string, bool) {
+func PodsPreventEviction(ctx context.Context, pods []*v1.Pod) (string, bool) {
 	for _, p := range pods {
 		// don't care about pods that are finishing, finished or owned by the node
 		if pod.IsTerminating(p) || pod.IsTerminal(p) || pod.IsOwnedByNode(p) {
 			continue
 		}
 
-		if pod.HasDoNotEvict(p) {
+		if pod.HasDoNotEvict(ctx, p) {
 			return fmt.Sprintf("pod %s/%s has do not evict annotation", p.Namespace, p.Name), true
 		}
+		if pod.IsDrainExcluded(ctx, p) {
+			return fmt.Sprintf("pod %s/%s is excluded from drain", p.Namespace, p.Name), true
+		}
+		// a gated pod can't be rescheduled until its gates are removed
+		if pod.IsSchedulingGated(p) {
+			return fmt.Sprintf("pod %s/%s has scheduling gates", p.Namespace, p.Name), true
+		}
 	}
 	return "", false
 }
@@ -25,9 +25,26 @@ func init() {
 	crmetrics.Registry.MustRegister(deprovisioningDurationHistogram)
 	crmetrics.Registry.MustRegister(deprovisioningReplacementNodeInitializedHistogram)
 	crmetrics.Registry.MustRegister(deprovisioningActionsPerformedCounter)
+	crmetrics.Registry.MustRegister(deprovisioningDryRunActionsCounter)
+	crmetrics.Registry.MustRegister(deprovisioningSavingsGauge)
+	crmetrics.Registry.MustRegister(deprovisioningAbortedCounter)
+	crmetrics.Registry.MustRegister(deprovisioningRescheduleMismatchCounter)
+	crmetrics.Registry.MustRegister(consolidationBlockedByResourceCounter)
+	crmetrics.Registry.MustRegister(consolidationOscillationsCounter)
 }
 
-const deprovisioningSubsystem = "deprovisioning"
+const (
+	deprovisioningSubsystem = "deprovisioning"
+	consolidationSubsystem  = "consolidation"
+)
+
+// Reasons that a deprovisioning action can be aborted when it's re-validated after the validation TTL
+const (
+	abortReasonBecameNonEmpty      = "became-non-empty"
+	abortReasonPodAdded            = "pod-added"
+	abortReasonOfferingUnavailable = "offering-unavailable"
+	abortReasonCandidateChanged    = "candidate-changed"
+)
 
 var deprovisioningDurationHistogram = prometheus.NewHistogramVec(
 	prometheus.HistogramOpts{
@@ -58,3 +75,60 @@ var deprovisioningActionsPerformedCounter = prometheus.NewCounterVec(
 	},
 	[]string{"action"},
 )
+
+var deprovisioningDryRunActionsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: deprovisioningSubsystem,
+		Name:      "dryrun_actions_total",
+		Help:      "Number of deprovisioning actions that were computed but not performed because deprovisioning is in dry run mode. Labeled by action.",
+	},
+	[]string{"action"},
+)
+
+var deprovisioningSavingsGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: deprovisioningSubsystem,
+		Name:      "savings_dollars_per_hour",
+		Help:      "Estimated hourly savings of the most recent consolidation action, from the prices of the removed nodes' offerings less the cheapest offerings of their replacements. Zero if no consolidation action has been taken recently.",
+	},
+)
+
+var deprovisioningAbortedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: deprovisioningSubsystem,
+		Name:      "aborted_total",
+		Help:      "Number of deprovisioning actions aborted because they were no longer valid after the validation TTL. Labeled by reason.",
+	},
+	[]string{"reason"},
+)
+
+var deprovisioningRescheduleMismatchCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: deprovisioningSubsystem,
+		Name:      "reschedule_mismatches_total",
+		Help:      "Number of pods displaced by deleting nodes without replacements that were still pending after the reschedule verification threshold, although the simulation expected them to reschedule.",
+	},
+)
+
+var consolidationBlockedByResourceCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: consolidationSubsystem,
+		Name:      "blocked_by_resource_total",
+		Help:      "Number of scheduling simulations where pods failed to reschedule because there wasn't enough of a resource left on the existing nodes. Labeled by resource.",
+	},
+	[]string{"resource"},
+)
+
+var consolidationOscillationsCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: consolidationSubsystem,
+		Name:      "oscillations_total",
+		Help:      "Number of consolidation replacements that would reverse a recent replacement, such as when offering prices flap.",
+	},
+)
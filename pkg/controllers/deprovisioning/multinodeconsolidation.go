@@ -19,28 +19,36 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/clock"
+	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/utils/pod"
 )
 
 type MultiNodeConsolidation struct {
 	consolidation
 }
 
-func NewMultiNodeConsolidation(clk clock.Clock, cluster *state.Cluster, kubeClient client.Client, provisioner *provisioning.Provisioner, cp cloudprovider.CloudProvider) *MultiNodeConsolidation {
+func NewMultiNodeConsolidation(clk clock.Clock, cluster *state.Cluster, kubeClient client.Client, provisioner *provisioning.Provisioner, cp cloudprovider.CloudProvider,
+	recorder events.Recorder, history *DisruptionHistory) *MultiNodeConsolidation {
 	return &MultiNodeConsolidation{
 		consolidation{
-			clock:         clk,
-			cluster:       cluster,
-			kubeClient:    kubeClient,
-			provisioner:   provisioner,
-			cloudProvider: cp,
+			clock:             clk,
+			cluster:           cluster,
+			kubeClient:        kubeClient,
+			provisioner:       provisioner,
+			cloudProvider:     cp,
+			recorder:          recorder,
+			disruptionHistory: history,
 		},
 	}
 }
@@ -54,9 +62,13 @@ func (m *MultiNodeConsolidation) ComputeCommand(ctx context.Context, candidates
 		return Command{}, fmt.Errorf("sorting candidates, %w", err)
 	}
 
-	// For now, we will consider up to every node in the cluster, might be configurable in the future.
-	maxParallel := len(candidates)
-	cmd, err := m.firstNNodeConsolidationOption(ctx, candidates, maxParallel)
+	var cmd Command
+	if settings.FromContext(ctx).ZonalConsolidation {
+		cmd, err = m.firstNZonalConsolidationOption(ctx, candidates)
+	} else {
+		// For now, we will consider up to every node in the cluster, might be configurable in the future.
+		cmd, err = m.firstNNodeConsolidationOption(ctx, candidates, len(candidates))
+	}
 	if err != nil {
 		return Command{}, err
 	}
@@ -64,7 +76,7 @@ func (m *MultiNodeConsolidation) ComputeCommand(ctx context.Context, candidates
 		return cmd, nil
 	}
 
-	v := NewValidation(consolidationTTL, m.clock, m.cluster, m.kubeClient, m.provisioner, m.cloudProvider)
+	v := NewValidation(validationPeriod(ctx), m.clock, m.cluster, m.kubeClient, m.provisioner, m.cloudProvider)
 	isValid, err := v.IsValid(ctx, cmd)
 	if err != nil {
 		return Command{}, fmt.Errorf("validating, %w", err)
@@ -103,7 +115,7 @@ func (m *MultiNodeConsolidation) firstNNodeConsolidationOption(ctx context.Conte
 		// ensure that the action is sensical for replacements, see explanation on filterOutSameType for why this is
 		// required
 		if action.action == actionReplace {
-			action.replacementNodes[0].InstanceTypeOptions = filterOutSameType(action.replacementNodes[0], nodesToConsolidate)
+			action.replacementNodes[0].InstanceTypeOptions = filterOutSameType(ctx, action.replacementNodes[0], nodesToConsolidate)
 			if len(action.replacementNodes[0].InstanceTypeOptions) == 0 {
 				action.action = actionDoNothing
 			}
@@ -120,6 +132,37 @@ func (m *MultiNodeConsolidation) firstNNodeConsolidationOption(ctx context.Conte
 	return lastSavedCommand, nil
 }
 
+// firstNZonalConsolidationOption looks for the largest set of nodes within a single zone that can be consolidated at
+// once, so that merges never move pods across zones. Candidates keep their disruption order within each zone. The
+// nodes of provisioners that allow cross zone consolidation are grouped together regardless of their zone, unless
+// their pods have zonal constraints; the scheduling simulation still enforces the pods' constraints.
+func (m *MultiNodeConsolidation) firstNZonalConsolidationOption(ctx context.Context, candidates []CandidateNode) (Command, error) {
+	byZone := lo.GroupBy(candidates, consolidationZone)
+	cmd := Command{action: actionDoNothing}
+	for _, zone := range lo.Uniq(lo.Map(candidates, func(n CandidateNode, _ int) string { return consolidationZone(n) })) {
+		zonalCmd, err := m.firstNNodeConsolidationOption(ctx, byZone[zone], len(byZone[zone]))
+		if err != nil {
+			return Command{}, err
+		}
+		if zonalCmd.action != actionDoNothing && len(zonalCmd.nodesToRemove) > len(cmd.nodesToRemove) {
+			cmd = zonalCmd
+		}
+	}
+	return cmd, nil
+}
+
+// consolidationZone is the zone that a candidate is grouped by for zonal consolidation, or empty if it may be merged
+// with candidates in any zone
+func consolidationZone(n CandidateNode) string {
+	if n.provisioner.Spec.Consolidation == nil || !ptr.BoolValue(n.provisioner.Spec.Consolidation.CrossZone) {
+		return n.zone
+	}
+	if lo.SomeBy(n.pods, pod.HasZonalConstraints) {
+		return n.zone
+	}
+	return ""
+}
+
 // filterOutSameType filters out instance types that are more expensive than the cheapest instance type that is being
 // consolidated if the list of replacement instance types include one of the instance types that is being removed
 //
@@ -136,7 +179,7 @@ func (m *MultiNodeConsolidation) firstNNodeConsolidationOption(ctx context.Conte
 // This code sees that t3a.small is the cheapest type in both lists and filters it and anything more expensive out
 // leaving the valid consolidation:
 // nodes=[t3a.2xlarge, t3a.2xlarge, t3a.small] -> 1 of t3a.nano
-func filterOutSameType(newNode *scheduling.Node, consolidate []CandidateNode) []*cloudprovider.InstanceType {
+func filterOutSameType(ctx context.Context, newNode *scheduling.Node, consolidate []CandidateNode) []*cloudprovider.InstanceType {
 	existingInstanceTypes := sets.NewString()
 	nodePricesByInstanceType := map[string]float64{}
 
@@ -151,8 +194,8 @@ func filterOutSameType(newNode *scheduling.Node, consolidate []CandidateNode) []
 		if !ok {
 			existingPrice = math.MaxFloat64
 		}
-		if of.Price < existingPrice {
-			nodePricesByInstanceType[n.instanceType.Name] = of.Price
+		if of.EffectivePrice() < existingPrice {
+			nodePricesByInstanceType[n.instanceType.Name] = of.EffectivePrice()
 		}
 	}
 
@@ -168,5 +211,5 @@ func filterOutSameType(newNode *scheduling.Node, consolidate []CandidateNode) []
 		}
 	}
 
-	return filterByPrice(newNode.InstanceTypeOptions, newNode.Requirements, maxPrice)
+	return filterByPrice(ctx, newNode.InstanceTypeOptions, newNode.Requirements, maxPrice)
 }
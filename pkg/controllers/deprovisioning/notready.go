@@ -0,0 +1,105 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/ptr"
+
+	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/metrics"
+)
+
+// NotReady is a subreconciler that deletes nodes that haven't been ready for longer than their provisioner allows, e.g.
+// because they failed to bootstrap or lost their network.
+// NotReady will respect TTLSecondsAfterNotReady
+type NotReady struct {
+	clock clock.Clock
+}
+
+func NewNotReady(clk clock.Clock) *NotReady {
+	return &NotReady{clock: clk}
+}
+
+// ShouldDeprovision is a predicate used to filter deprovisionable nodes
+func (r *NotReady) ShouldDeprovision(ctx context.Context, n *state.Node, provisioner *v1alpha5.Provisioner, _ []*v1.Pod) bool {
+	if n.MarkedForDeletion {
+		return false
+	}
+	return notReadyPastTTL(n.Node, provisioner, r.clock)
+}
+
+// SortCandidates leaves the candidates in the order that they were found, since every node that isn't ready is deleted
+// at once
+func (r *NotReady) SortCandidates(nodes []CandidateNode) []CandidateNode {
+	return nodes
+}
+
+// ComputeCommand generates a deprovisioning command given deprovisionable nodes. Nodes that aren't ready aren't running
+// their pods, so they're deleted without replacements.
+func (r *NotReady) ComputeCommand(ctx context.Context, nodes ...CandidateNode) (Command, error) {
+	if len(nodes) == 0 {
+		return Command{action: actionDoNothing}, nil
+	}
+	for _, n := range nodes {
+		since, _ := notReadySince(n.Node)
+		logging.FromContext(ctx).With("node", n.Name).Infof("triggering termination for node that has not been ready since %s", since.Format(time.RFC3339))
+	}
+	return Command{
+		nodesToRemove: lo.Map(nodes, func(n CandidateNode, _ int) *v1.Node { return n.Node }),
+		action:        actionDelete,
+	}, nil
+}
+
+// String is the string representation of the deprovisioner
+func (r *NotReady) String() string {
+	return metrics.NotReadyReason
+}
+
+// notReadyPastTTL returns true if the node hasn't been ready for longer than its provisioner's TTLSecondsAfterNotReady
+func notReadyPastTTL(node *v1.Node, provisioner *v1alpha5.Provisioner, clk clock.Clock) bool {
+	if provisioner == nil || provisioner.Spec.TTLSecondsAfterNotReady == nil {
+		return false
+	}
+	since, notReady := notReadySince(node)
+	if !notReady {
+		return false
+	}
+	return clk.Since(since) > time.Duration(ptr.Int64Value(provisioner.Spec.TTLSecondsAfterNotReady))*time.Second
+}
+
+// notReadySince returns when the node stopped being ready and true if its Ready condition is False or Unknown. The time
+// is when the condition last transitioned, so a node that flaps between ready and not ready restarts its TTL each time.
+// Nodes that have never reported the condition haven't been ready since they were created.
+func notReadySince(node *v1.Node) (time.Time, bool) {
+	condition, ok := lo.Find(node.Status.Conditions, func(c v1.NodeCondition) bool { return c.Type == v1.NodeReady })
+	if !ok {
+		return node.CreationTimestamp.Time, true
+	}
+	if condition.Status == v1.ConditionTrue {
+		return time.Time{}, false
+	}
+	if condition.LastTransitionTime.IsZero() {
+		return node.CreationTimestamp.Time, true
+	}
+	return condition.LastTransitionTime.Time, true
+}
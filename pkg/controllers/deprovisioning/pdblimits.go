@@ -16,12 +16,20 @@ package deprovisioning
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
-	policyv1 "k8s.io/api/policy/v1beta1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	podutil "github.com/aws/karpenter-core/pkg/utils/pod"
 )
 
 // PDBLimits is used to evaluate if evicting a list of pods is possible.
@@ -31,18 +39,41 @@ type PDBLimits struct {
 	pdbs       []*pdbItem
 }
 
+// NewPDBLimits lists the PDBs of each version of the policy API that the cluster serves. Clusters that serve both
+// versions return the same PDBs from each, so a PDB that was already listed as policy/v1 isn't listed again.
 func NewPDBLimits(ctx context.Context, kubeClient client.Client) (*PDBLimits, error) {
 	ps := &PDBLimits{
 		ctx:        ctx,
 		kubeClient: kubeClient,
 	}
 
-	var pdbList policyv1.PodDisruptionBudgetList
-	if err := kubeClient.List(ctx, &pdbList); err != nil {
+	var pdbs []policyv1.PodDisruptionBudget
+	served, err := servesPDBVersion(kubeClient, policyv1.SchemeGroupVersion.Version)
+	if err != nil {
 		return nil, err
 	}
-	for _, pdb := range pdbList.Items {
-		pi, err := newPdb(pdb)
+	if served {
+		var pdbList policyv1.PodDisruptionBudgetList
+		if err := kubeClient.List(ctx, &pdbList); err != nil {
+			return nil, err
+		}
+		pdbs = append(pdbs, pdbList.Items...)
+	}
+	served, err = servesPDBVersion(kubeClient, policyv1beta1.SchemeGroupVersion.Version)
+	if err != nil {
+		return nil, err
+	}
+	if served {
+		var pdbList policyv1beta1.PodDisruptionBudgetList
+		if err := kubeClient.List(ctx, &pdbList); err != nil {
+			return nil, err
+		}
+		pdbs = append(pdbs, lo.Map(pdbList.Items, func(pdb policyv1beta1.PodDisruptionBudget, _ int) policyv1.PodDisruptionBudget {
+			return fromV1beta1(pdb)
+		})...)
+	}
+	for _, pdb := range lo.UniqBy(pdbs, func(pdb policyv1.PodDisruptionBudget) client.ObjectKey { return client.ObjectKeyFromObject(&pdb) }) {
+		pi, err := newPdb(ctx, kubeClient, pdb)
 		if err != nil {
 			return nil, err
 		}
@@ -52,12 +83,46 @@ func NewPDBLimits(ctx context.Context, kubeClient client.Client) (*PDBLimits, er
 	return ps, nil
 }
 
+// servesPDBVersion returns true if the cluster serves the version of the PodDisruptionBudget API. policy/v1 is served
+// from Kubernetes 1.21, and policy/v1beta1 was removed in Kubernetes 1.25.
+func servesPDBVersion(kubeClient client.Client, version string) (bool, error) {
+	if _, err := kubeClient.RESTMapper().RESTMapping(schema.GroupKind{Group: policyv1.GroupName, Kind: "PodDisruptionBudget"}, version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("discovering policy/%s, %w", version, err)
+	}
+	return true, nil
+}
+
+// fromV1beta1 converts a policy/v1beta1 PDB to policy/v1. An empty selector matches no pods in policy/v1beta1, but
+// every pod in policy/v1, so it's converted to the nil selector that matches no pods.
+func fromV1beta1(pdb policyv1beta1.PodDisruptionBudget) policyv1.PodDisruptionBudget {
+	selector := pdb.Spec.Selector
+	if selector != nil && len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		selector = nil
+	}
+	return policyv1.PodDisruptionBudget{
+		ObjectMeta: pdb.ObjectMeta,
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable:   pdb.Spec.MinAvailable,
+			Selector:       selector,
+			MaxUnavailable: pdb.Spec.MaxUnavailable,
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{
+			ObservedGeneration: pdb.Status.ObservedGeneration,
+			DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+		},
+	}
+}
+
 // CanEvictPods returns true if every pod in the list is evictable. They may not all be evictable simultaneously, but
-// for every PDB that controls the pods at least one pod can be evicted.
+// for every PDB that controls the pods at least one pod can be evicted. As with eviction, a pod is controlled by the
+// PDBs of its own namespace, and a pod that's controlled by several PDBs is only evictable if all of them allow it.
 func (s *PDBLimits) CanEvictPods(pods []*v1.Pod) (client.ObjectKey, bool) {
 	for _, pod := range pods {
 		for _, pdb := range s.pdbs {
-			if pdb.selector.Matches(labels.Set(pod.Labels)) {
+			if pdb.name.Namespace == pod.Namespace && pdb.selector.Matches(labels.Set(pod.Labels)) {
 				if pdb.disruptionsAllowed == 0 {
 					return pdb.name, false
 				}
@@ -73,14 +138,55 @@ type pdbItem struct {
 	disruptionsAllowed int32
 }
 
-func newPdb(pdb policyv1.PodDisruptionBudget) (*pdbItem, error) {
+func newPdb(ctx context.Context, kubeClient client.Client, pdb policyv1.PodDisruptionBudget) (*pdbItem, error) {
 	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
 	if err != nil {
 		return nil, err
 	}
+	allowed, err := disruptionsAllowed(ctx, kubeClient, pdb, selector)
+	if err != nil {
+		return nil, err
+	}
+	// the status is only meaningful once the disruption controller has observed the current generation of the PDB,
+	// and may lag behind pods that have since gone away, so we never allow more than the spec does
+	if pdb.Status.ObservedGeneration >= pdb.Generation {
+		allowed = lo.Min([]int32{allowed, pdb.Status.DisruptionsAllowed})
+	}
 	return &pdbItem{
 		name:               client.ObjectKeyFromObject(&pdb),
 		selector:           selector,
-		disruptionsAllowed: pdb.Status.DisruptionsAllowed,
+		disruptionsAllowed: allowed,
 	}, nil
 }
+
+// disruptionsAllowed computes the number of disruptions that the PDB's spec allows in the same way as the disruption
+// controller, scaling percentages against the number of pods that the PDB currently matches.
+func disruptionsAllowed(ctx context.Context, kubeClient client.Client, pdb policyv1.PodDisruptionBudget, selector labels.Selector) (int32, error) {
+	if pdb.Spec.MinAvailable == nil && pdb.Spec.MaxUnavailable == nil {
+		return pdb.Status.DisruptionsAllowed, nil
+	}
+	podList := &v1.PodList{}
+	if err := kubeClient.List(ctx, podList, client.InNamespace(pdb.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return 0, fmt.Errorf("listing pods for pdb %s, %w", client.ObjectKeyFromObject(&pdb), err)
+	}
+	pods := lo.Reject(podList.Items, func(p v1.Pod, _ int) bool { return podutil.IsTerminal(&p) })
+	expected := len(pods)
+	// as with the disruption controller, only pods that are ready and aren't terminating count towards the healthy pods
+	healthy := len(lo.Filter(pods, func(p v1.Pod, _ int) bool { return podutil.IsReady(&p) && !podutil.IsTerminating(&p) }))
+
+	var desiredHealthy int
+	if pdb.Spec.MinAvailable != nil {
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, expected, true)
+		if err != nil {
+			return 0, fmt.Errorf("scaling min available for pdb %s, %w", client.ObjectKeyFromObject(&pdb), err)
+		}
+		desiredHealthy = minAvailable
+	} else {
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, expected, true)
+		if err != nil {
+			return 0, fmt.Errorf("scaling max unavailable for pdb %s, %w", client.ObjectKeyFromObject(&pdb), err)
+		}
+		desiredHealthy = expected - maxUnavailable
+	}
+	return int32(lo.Max([]int{healthy - desiredHealthy, 0})), nil
+}
@@ -0,0 +1,150 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+)
+
+// ErrStalePlan is returned by Apply when a planned command is no longer the command that its deprovisioner computes
+// for the current state of the cluster
+var ErrStalePlan = fmt.Errorf("plan is stale")
+
+// Plan is a serializable list of the commands that deprovisioning intends to execute, computed without taking any
+// action so that it can be reviewed before it's passed to Apply
+type Plan struct {
+	Commands []PlannedCommand `json:"commands"`
+}
+
+// PlannedCommand is a single command of a plan
+type PlannedCommand struct {
+	// Deprovisioner is the name of the deprovisioner that computed the command
+	Deprovisioner string `json:"deprovisioner"`
+	// Action is either delete or replace
+	Action string `json:"action"`
+	// Nodes are the names of the nodes that the command removes
+	Nodes []string `json:"nodes"`
+	// Replacements are the instance type options for each of the nodes that the command launches
+	Replacements [][]string `json:"replacements,omitempty"`
+	// Reason describes the command
+	Reason string `json:"reason"`
+	// CostDelta is the projected change in the hourly cost of the cluster if the command executes
+	CostDelta float64 `json:"costDelta"`
+}
+
+// Plan computes the command of each deprovisioner against the current state of the cluster without executing any of
+// them. Nodes that an earlier command removes aren't candidates for the commands that follow it.
+func (c *Controller) Plan(ctx context.Context) (*Plan, error) {
+	plan := &Plan{Commands: []PlannedCommand{}}
+	planned := sets.NewString()
+	for _, d := range c.deprovisioners {
+		if c.suppressedByLaunchFailure(d) || c.suppressedByScaleUp(ctx, d) || c.suppressedByCooldown(ctx, d) {
+			continue
+		}
+		candidates, err := candidateNodes(ctx, c.cluster, c.kubeClient, c.clock, c.cloudProvider, d.ShouldDeprovision, includesUninitialized(d))
+		if err != nil {
+			return nil, fmt.Errorf("determining candidate nodes, %w", err)
+		}
+		candidates = lo.Reject(candidates, func(n CandidateNode, _ int) bool { return planned.Has(n.Name) })
+		if len(candidates) == 0 {
+			continue
+		}
+		cmd, err := d.ComputeCommand(ctx, d.SortCandidates(candidates)...)
+		if err != nil {
+			return nil, fmt.Errorf("computing %s command, %w", d, err)
+		}
+		if cmd.action != actionDelete && cmd.action != actionReplace {
+			continue
+		}
+		projection, err := c.projectCost(ctx, cmd)
+		if err != nil {
+			return nil, fmt.Errorf("projecting cluster cost, %w", err)
+		}
+		command := PlannedCommand{
+			Deprovisioner: d.String(),
+			Action:        cmd.action.String(),
+			Nodes:         lo.Map(cmd.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name }),
+			Reason:        cmd.String(),
+			CostDelta:     projection.Projected - projection.Current,
+		}
+		for _, n := range cmd.replacementNodes {
+			command.Replacements = append(command.Replacements, lo.Map(n.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name }))
+		}
+		plan.Commands = append(plan.Commands, command)
+		planned.Insert(command.Nodes...)
+	}
+	return plan, nil
+}
+
+// Apply executes exactly the commands of a plan, in order. Each command is recomputed and re-validated against the
+// current state of the cluster first, and ErrStalePlan is returned if its deprovisioner would no longer execute the
+// same command. Commands that precede a stale command will already have executed.
+func (c *Controller) Apply(ctx context.Context, plan *Plan) error {
+	for _, planned := range plan.Commands {
+		d, cmd, err := c.recompute(ctx, planned)
+		if err != nil {
+			return err
+		}
+		result, err := c.applyCommand(ctx, d, cmd)
+		if err != nil {
+			return fmt.Errorf("applying %s, %w", planned.Reason, err)
+		}
+		if result.Result != ResultSuccess {
+			return fmt.Errorf("applying %s, %s", planned.Reason, result.Result)
+		}
+	}
+	return nil
+}
+
+// recompute computes the command of the deprovisioner that planned the command, restricted to the planned nodes
+func (c *Controller) recompute(ctx context.Context, planned PlannedCommand) (Deprovisioner, Command, error) {
+	nodes := sets.NewString(planned.Nodes...)
+	// consolidation deprovisioners share a name, so the planned command may have come from any of them
+	for _, d := range c.deprovisioners {
+		if d.String() != planned.Deprovisioner || c.suppressedByLaunchFailure(d) || c.suppressedByScaleUp(ctx, d) || c.suppressedByCooldown(ctx, d) {
+			continue
+		}
+		candidates, err := candidateNodes(ctx, c.cluster, c.kubeClient, c.clock, c.cloudProvider, d.ShouldDeprovision, includesUninitialized(d))
+		if err != nil {
+			return nil, Command{}, fmt.Errorf("determining candidate nodes, %w", err)
+		}
+		candidates = lo.Filter(candidates, func(n CandidateNode, _ int) bool { return nodes.Has(n.Name) })
+		if len(candidates) != nodes.Len() {
+			continue
+		}
+		cmd, err := d.ComputeCommand(ctx, d.SortCandidates(candidates)...)
+		if err != nil {
+			return nil, Command{}, fmt.Errorf("computing %s command, %w", d, err)
+		}
+		if planned.matches(cmd) {
+			return d, cmd, nil
+		}
+	}
+	return nil, Command{}, fmt.Errorf("%w, %s", ErrStalePlan, planned.Reason)
+}
+
+// matches returns true if the command removes the same nodes with the same action and number of replacements
+func (p PlannedCommand) matches(cmd Command) bool {
+	return cmd.action.String() == p.Action &&
+		len(cmd.replacementNodes) == len(p.Replacements) &&
+		sets.NewString(lo.Map(cmd.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name })...).Equal(sets.NewString(p.Nodes...))
+}
@@ -0,0 +1,106 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
+)
+
+// ReplacementHistory tracks the instance types of the nodes that consolidation recently replaced, and of the nodes
+// that it launched in their place, so that a replacement that reverses a recent one (e.g. because offering prices are
+// flapping) can be detected. Replacements older than the configured window decay out of the history.
+type ReplacementHistory struct {
+	mu           sync.Mutex
+	clock        clock.Clock
+	replacements []replacement
+}
+
+type replacement struct {
+	removed  sets.String
+	launched sets.String
+	time     time.Time
+}
+
+func NewReplacementHistory(clk clock.Clock) *ReplacementHistory {
+	return &ReplacementHistory{clock: clk}
+}
+
+// Record records that the removed nodes were replaced by the launched nodes
+func (h *ReplacementHistory) Record(removed []*v1.Node, launched []*v1.Node) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replacements = append(h.replacements, replacement{
+		removed:  instanceTypeNames(removed...),
+		launched: instanceTypeNames(launched...),
+		time:     h.clock.Now(),
+	})
+}
+
+// Reverses returns a description and true if the command would replace nodes that were launched by a replacement
+// within the window with one of the instance types that the replacement removed
+func (h *ReplacementHistory) Reverses(ctx context.Context, cmd Command) (string, bool) {
+	window := settings.FromContext(ctx).ConsolidationOscillationWindow.Duration
+	if window == 0 || cmd.action != actionReplace {
+		return "", false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.decay(window)
+	removing := instanceTypeNames(cmd.nodesToRemove...)
+	launching := sets.NewString()
+	for _, n := range cmd.replacementNodes {
+		for _, it := range n.InstanceTypeOptions {
+			launching.Insert(it.Name)
+		}
+	}
+	for _, r := range h.replacements {
+		if r.launched.HasAny(removing.UnsortedList()...) && r.removed.HasAny(launching.UnsortedList()...) {
+			return fmt.Sprintf("replacing %s with %s would reverse the replacement of %s with %s %s ago",
+				removing.List(), launching.Intersection(r.removed).List(), r.removed.List(), r.launched.List(), h.clock.Since(r.time)), true
+		}
+	}
+	return "", false
+}
+
+// decay removes replacements that are older than the window
+func (h *ReplacementHistory) decay(window time.Duration) {
+	cutoff := h.clock.Now().Add(-window)
+	var recent []replacement
+	for _, r := range h.replacements {
+		if r.time.After(cutoff) {
+			recent = append(recent, r)
+		}
+	}
+	h.replacements = recent
+}
+
+func instanceTypeNames(nodes ...*v1.Node) sets.String {
+	names := sets.NewString()
+	for _, n := range nodes {
+		if name, ok := n.Labels[v1.LabelInstanceTypeStable]; ok {
+			names.Insert(name)
+		}
+	}
+	return names
+}
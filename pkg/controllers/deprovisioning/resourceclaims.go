@@ -0,0 +1,85 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resourceClaimVersions are the versions of the dynamic resource allocation API whose claims are considered, newest
+// first. ResourceClaims aren't part of the API version that we build against, so they're read as unstructured objects.
+var resourceClaimVersions = []schema.GroupVersionKind{
+	{Group: "resource.k8s.io", Version: "v1alpha2", Kind: "ResourceClaimList"},
+	{Group: "resource.k8s.io", Version: "v1alpha1", Kind: "ResourceClaimList"},
+}
+
+// ResourceClaims is used to evaluate if pods have dynamically allocated resource claims. A pod's claims are allocated
+// to a specific set of devices, so we can't simulate whether another node could satisfy them.
+type ResourceClaims struct {
+	// claims are the names of the claims that are reserved for each pod
+	claims map[types.UID]client.ObjectKey
+}
+
+func NewResourceClaims(ctx context.Context, kubeClient client.Client) (*ResourceClaims, error) {
+	rc := &ResourceClaims{claims: map[types.UID]client.ObjectKey{}}
+	for _, gvk := range resourceClaimVersions {
+		claimList := &unstructured.UnstructuredList{}
+		claimList.SetGroupVersionKind(gvk)
+		if err := kubeClient.List(ctx, claimList); err != nil {
+			// the cluster doesn't serve this version of the API
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("listing resource claims, %w", err)
+		}
+		for i := range claimList.Items {
+			claim := &claimList.Items[i]
+			reservedFor, _, err := unstructured.NestedSlice(claim.Object, "status", "reservedFor")
+			if err != nil {
+				return nil, fmt.Errorf("parsing resource claim %s/%s, %w", claim.GetNamespace(), claim.GetName(), err)
+			}
+			for _, consumer := range reservedFor {
+				consumer, ok := consumer.(map[string]interface{})
+				if !ok || consumer["resource"] != "pods" {
+					continue
+				}
+				if uid, ok := consumer["uid"].(string); ok {
+					rc.claims[types.UID(uid)] = client.ObjectKeyFromObject(claim)
+				}
+			}
+		}
+		// the newest version that the cluster serves contains all of the claims
+		return rc, nil
+	}
+	return rc, nil
+}
+
+// Pinned returns the claim and true if any of the pods have a claim reserved for them
+func (r *ResourceClaims) Pinned(pods []*v1.Pod) (client.ObjectKey, bool) {
+	for _, p := range pods {
+		if claim, ok := r.claims[p.UID]; ok {
+			return claim, true
+		}
+	}
+	return client.ObjectKey{}, false
+}
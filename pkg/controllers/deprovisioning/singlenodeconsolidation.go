@@ -19,18 +19,21 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
 	pscheduling "github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/metrics"
-	"github.com/aws/karpenter-core/pkg/scheduling"
 )
 
 // SingleNodeConsolidation is the consolidation controller that performs single node consolidation.
@@ -38,13 +41,16 @@ type SingleNodeConsolidation struct {
 	consolidation
 }
 
-func NewSingleNodeConsolidation(clk clock.Clock, cluster *state.Cluster, kubeClient client.Client, provisioner *provisioning.Provisioner, cp cloudprovider.CloudProvider) *SingleNodeConsolidation {
+func NewSingleNodeConsolidation(clk clock.Clock, cluster *state.Cluster, kubeClient client.Client, provisioner *provisioning.Provisioner, cp cloudprovider.CloudProvider,
+	recorder events.Recorder, history *DisruptionHistory) *SingleNodeConsolidation {
 	return &SingleNodeConsolidation{consolidation: consolidation{
-		clock:         clk,
-		cluster:       cluster,
-		kubeClient:    kubeClient,
-		provisioner:   provisioner,
-		cloudProvider: cp,
+		clock:             clk,
+		cluster:           cluster,
+		kubeClient:        kubeClient,
+		provisioner:       provisioner,
+		cloudProvider:     cp,
+		recorder:          recorder,
+		disruptionHistory: history,
 	},
 	}
 }
@@ -61,31 +67,40 @@ func (c *SingleNodeConsolidation) ComputeCommand(ctx context.Context, candidates
 		return Command{}, fmt.Errorf("sorting candidates, %w", err)
 	}
 
-	v := NewValidation(consolidationTTL, c.clock, c.cluster, c.kubeClient, c.provisioner, c.cloudProvider)
+	v := NewValidation(validationPeriod(ctx), c.clock, c.cluster, c.kubeClient, c.provisioner, c.cloudProvider)
+	concurrency := settings.FromContext(ctx).SimulationConcurrency
 	var failedValidation bool
-	for _, node := range candidates {
-		// compute a possible consolidation option
-		cmd, err := c.computeConsolidation(ctx, node)
-		if err != nil {
-			logging.FromContext(ctx).Errorf("computing consolidation %s", err)
-			continue
-		}
-		if cmd.action == actionDoNothing || cmd.action == actionRetry || cmd.action == actionFailed {
-			continue
-		}
-
-		isValid, err := v.IsValid(ctx, cmd)
-		if err != nil {
-			logging.FromContext(ctx).Errorf("validating consolidation %s", err)
-			continue
-		}
-		if !isValid {
-			failedValidation = true
-			continue
-		}
-
-		if cmd.action == actionReplace || cmd.action == actionDelete {
-			return cmd, nil
+	for start := 0; start < len(candidates); start += concurrency {
+		batch := candidates[start:lo.Min([]int{start + concurrency, len(candidates)})]
+		// compute the possible consolidation options for a batch of candidates in parallel; results are stored by
+		// candidate index so that the command chosen below doesn't depend upon the order in which simulations finish
+		cmds := make([]Command, len(batch))
+		errs := make([]error, len(batch))
+		workqueue.ParallelizeUntil(ctx, concurrency, len(batch), func(i int) {
+			cmds[i], errs[i] = c.computeConsolidation(ctx, batch[i])
+		})
+		for i, cmd := range cmds {
+			if errs[i] != nil {
+				logging.FromContext(ctx).Errorf("computing consolidation %s", errs[i])
+				continue
+			}
+			if cmd.action == actionDoNothing || cmd.action == actionRetry || cmd.action == actionFailed {
+				continue
+			}
+
+			isValid, err := v.IsValid(ctx, cmd)
+			if err != nil {
+				logging.FromContext(ctx).Errorf("validating consolidation %s", err)
+				continue
+			}
+			if !isValid {
+				failedValidation = true
+				continue
+			}
+
+			if cmd.action == actionReplace || cmd.action == actionDelete {
+				return cmd, nil
+			}
 		}
 	}
 
@@ -102,7 +117,7 @@ func (c *SingleNodeConsolidation) ComputeCommand(ctx context.Context, candidates
 func (c *SingleNodeConsolidation) computeConsolidation(ctx context.Context, node CandidateNode) (Command, error) {
 	defer metrics.Measure(deprovisioningDurationHistogram.WithLabelValues("Replace/Delete"))()
 	// Run scheduling simulation to compute consolidation option
-	newNodes, allPodsScheduled, err := simulateScheduling(ctx, c.kubeClient, c.cluster, c.provisioner, node)
+	newNodes, targets, allPodsScheduled, err := simulateScheduling(ctx, c.kubeClient, c.cluster, c.provisioner, node)
 	if err != nil {
 		// if a candidate node is now deleting, just retry
 		if errors.Is(err, errCandidateNodeDeleting) {
@@ -121,25 +136,53 @@ func (c *SingleNodeConsolidation) computeConsolidation(ctx context.Context, node
 		return Command{
 			nodesToRemove: []*v1.Node{node.Node},
 			action:        actionDelete,
+			targetNodes:   targetNodes(targets),
+			placements:    reschedulePlacements([]CandidateNode{node}, targets),
 		}, nil
 	}
 
+	// the delete-only policy never launches nodes, so anything that needs a replacement is left alone
+	if settings.FromContext(ctx).ConsolidationPolicy == settings.ConsolidationPolicyDeleteOnly {
+		return Command{action: actionDoNothing}, nil
+	}
+
 	// we're not going to turn a single node into multiple nodes
 	if len(newNodes) != 1 {
 		return Command{action: actionDoNothing}, nil
 	}
 
+	// displaced pods must be able to satisfy their required node affinity on the replacement, and the replacement must
+	// be an instance type, capacity type, size and zone that settings allow
+	filterByRequiredNodeAffinity([]CandidateNode{node}, newNodes[0])
+	filterByReplacementAllowList(ctx, newNodes[0])
+	filterByCapacityType(ctx, []CandidateNode{node}, newNodes[0])
+	filterBySizeRatio(ctx, []CandidateNode{node}, newNodes[0])
+	filterByZone(ctx, []CandidateNode{node}, newNodes[0])
+	// a replacement can't take its provisioner over its limits
+	if err := filterByProvisionerLimits(ctx, c.kubeClient, c.cluster, newNodes[0]); err != nil {
+		return Command{}, fmt.Errorf("filtering by provisioner limits, %w", err)
+	}
+	if len(newNodes[0].InstanceTypeOptions) == 0 {
+		c.reportLimitingRequirements(ctx, []CandidateNode{node}, newNodes[0])
+		return Command{action: actionDoNothing}, nil
+	}
+
 	// get the current node price based on the offering
 	// fallback if we can't find the specific zonal pricing data
 	offering, ok := node.instanceType.Offerings.Get(node.capacityType, node.zone)
 	if !ok {
-		return Command{}, fmt.Errorf("getting offering price from candidate node, %w", err)
+		return Command{}, fmt.Errorf("getting offering price from candidate node, no %s offering in %s", node.capacityType, node.zone)
 	}
-	newNodes[0].InstanceTypeOptions = filterByPrice(newNodes[0].InstanceTypeOptions, newNodes[0].Requirements, offering.Price)
+	newNodes[0].InstanceTypeOptions = filterByPrice(ctx, newNodes[0].InstanceTypeOptions, newNodes[0].Requirements, maxReplacementPrice(ctx, []CandidateNode{node}, offering.EffectivePrice()))
 	if len(newNodes[0].InstanceTypeOptions) == 0 {
 		// no instance types remain after filtering by price
 		return Command{action: actionDoNothing}, nil
 	}
+	// a replacement that may launch as either capacity type is restricted to the one that provisioning prefers
+	filterByPreferredCapacityType(newNodes[0])
+	if !savesMinimumPercent([]CandidateNode{node}, offering.EffectivePrice(), newNodes[0]) {
+		return Command{action: actionDoNothing}, nil
+	}
 
 	// If the existing node is spot and the replacement is spot, we don't consolidate.  We don't have a reliable
 	// mechanism to determine if this replacement makes sense given instance type availability (e.g. we may replace
@@ -149,18 +192,10 @@ func (c *SingleNodeConsolidation) computeConsolidation(ctx context.Context, node
 		return Command{action: actionDoNothing}, nil
 	}
 
-	// We are consolidating a node from OD -> [OD,Spot] but have filtered the instance types by cost based on the
-	// assumption, that the spot variant will launch. We also need to add a requirement to the node to ensure that if
-	// spot capacity is insufficient we don't replace the node with a more expensive on-demand node.  Instead the launch
-	// should fail and we'll just leave the node alone.
-	ctReq := newNodes[0].Requirements.Get(v1alpha5.LabelCapacityType)
-	if ctReq.Has(v1alpha5.CapacityTypeSpot) && ctReq.Has(v1alpha5.CapacityTypeOnDemand) {
-		newNodes[0].Requirements.Add(scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, v1alpha5.CapacityTypeSpot))
-	}
-
 	return Command{
 		nodesToRemove:    []*v1.Node{node.Node},
 		action:           actionReplace,
 		replacementNodes: []*pscheduling.Node{newNodes[0]},
+		targetNodes:      targetNodes(targets),
 	}, nil
 }
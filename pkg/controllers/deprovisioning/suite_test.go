@@ -16,8 +16,12 @@ package deprovisioning_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"math"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -25,17 +29,40 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	io_prometheus_client "github.com/prometheus/client_model/go"
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
-	policyv1 "k8s.io/api/policy/v1beta1"
+	nodev1 "k8s.io/api/node/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	clock "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/logging"
 	. "knative.dev/pkg/logging/testing"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	"github.com/aws/karpenter-core/pkg/apis"
 	"github.com/aws/karpenter-core/pkg/apis/config/settings"
@@ -45,10 +72,15 @@ import (
 	"github.com/aws/karpenter-core/pkg/controllers/deprovisioning"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
 	"github.com/aws/karpenter-core/pkg/operator/scheme"
+	"github.com/aws/karpenter-core/pkg/scheduling"
 	"github.com/aws/karpenter-core/pkg/test"
 	. "github.com/aws/karpenter-core/pkg/test/expectations"
+	"github.com/aws/karpenter-core/pkg/utils/maintenancewindow"
+	nodeutils "github.com/aws/karpenter-core/pkg/utils/node"
+	"github.com/aws/karpenter-core/pkg/utils/pod"
 )
 
 var ctx context.Context
@@ -74,7 +106,7 @@ func TestAPIs(t *testing.T) {
 }
 
 var _ = BeforeSuite(func() {
-	env = test.NewEnvironment(scheme.Scheme, apis.CRDs...)
+	env = test.NewEnvironment(scheme.Scheme, append([]*apiextensionsv1.CustomResourceDefinition{resourceClaimCRD}, apis.CRDs...)...)
 	ctx = settings.ToContext(ctx, test.Settings())
 	cloudProvider = fake.NewCloudProvider()
 	fakeClock = clock.NewFakeClock(time.Now())
@@ -90,6 +122,28 @@ var _ = AfterSuite(func() {
 	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
 })
 
+// resourceClaimCRD serves dynamic resource allocation claims, which the test environment's API server doesn't
+var resourceClaimCRD = &apiextensionsv1.CustomResourceDefinition{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:        "resourceclaims.resource.k8s.io",
+		Annotations: map[string]string{"api-approved.kubernetes.io": "unapproved, test only"},
+	},
+	Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+		Group: "resource.k8s.io",
+		Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "resourceclaims", Singular: "resourceclaim", Kind: "ResourceClaim", ListKind: "ResourceClaimList"},
+		Scope: apiextensionsv1.NamespaceScoped,
+		Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+			Name:    "v1alpha2",
+			Served:  true,
+			Storage: true,
+			Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+				Type:                   "object",
+				XPreserveUnknownFields: ptr.Bool(true),
+			}},
+		}},
+	},
+}
+
 func triggerVerifyAction() {
 	for i := 0; i < 10; i++ {
 		time.Sleep(250 * time.Millisecond)
@@ -102,8 +156,14 @@ func triggerVerifyAction() {
 
 var _ = BeforeEach(func() {
 	cloudProvider.CreateCalls = nil
+	cloudProvider.CreatedNodes = nil
+	cloudProvider.Tags = nil
+	cloudProvider.TagCalls = nil
+	cloudProvider.UntagCalls = nil
+	cloudProvider.DriftedProviderIDs = nil
 	cloudProvider.InstanceTypes = fake.InstanceTypesAssorted()
 	cloudProvider.AllowedCreateCalls = math.MaxInt
+	cloudProvider.InstanceLimit = math.MaxInt
 	onDemandInstances = lo.Filter(cloudProvider.InstanceTypes, func(i *cloudprovider.InstanceType, _ int) bool {
 		for _, o := range i.Offerings.Available() {
 			if o.CapacityType == v1alpha5.CapacityTypeOnDemand {
@@ -123,7 +183,7 @@ var _ = BeforeEach(func() {
 
 	recorder.Reset()
 	fakeClock.SetTime(time.Now())
-	deprovisioningController = deprovisioning.NewController(fakeClock, env.Client, provisioner, cloudProvider, recorder, cluster)
+	deprovisioningController = deprovisioning.NewController(fakeClock, env.Client, provisioner, cloudProvider, recorder, cluster, false)
 })
 var _ = AfterEach(func() {
 	ExpectCleanedUp(ctx, env.Client)
@@ -202,6 +262,608 @@ var _ = Describe("Expiration", func() {
 		// and delete the old one
 		ExpectNotFound(ctx, env.Client, node)
 	})
+	It("should expire up to the provisioner's max nodes per cycle, most expired first", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(60),
+			Expiration:             &v1alpha5.Expiration{MaxNodesPerCycle: ptr.Int32(3)},
+		})
+		// the nodes expired an increasing number of hours ago
+		var nodes []*v1.Node
+		for i := 0; i < 6; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					},
+					Annotations: map[string]string{
+						v1alpha5.ExpirationOverrideAnnotationKey: fakeClock.Now().Add(-time.Duration(i+1) * time.Hour).Format(time.RFC3339),
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}},
+			))
+		}
+		ExpectApplied(ctx, env.Client, prov)
+		// apply the nodes out of order so that they're chosen by when they expired rather than by the order they were created in
+		for _, i := range []int{2, 5, 0, 4, 1, 3} {
+			ExpectApplied(ctx, env.Client, nodes[i])
+			ExpectMakeNodesReady(ctx, env.Client, nodes[i])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the three most expired nodes are deleted by a single command, and the others are left for the next cycle
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		for _, node := range nodes[3:] {
+			ExpectNotFound(ctx, env.Client, node)
+		}
+		for _, node := range nodes[:3] {
+			ExpectNodeExists(ctx, env.Client, node.Name)
+		}
+	})
+	Context("Expiration Override", func() {
+		var prov *v1alpha5.Provisioner
+		var node *v1.Node
+		expireWithOverride := func(override string) {
+			node = test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					},
+					Annotations: map[string]string{
+						v1alpha5.ExpirationOverrideAnnotationKey: override,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}},
+			)
+			ExpectApplied(ctx, env.Client, node, prov)
+			ExpectMakeNodesReady(ctx, env.Client, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+
+			fakeClock.Step(10 * time.Minute)
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		It("should extend a node's lifetime beyond its provisioner's TTL", func() {
+			prov = test.Provisioner(test.ProvisionerOptions{TTLSecondsUntilExpired: ptr.Int64(60)})
+			expireWithOverride(fakeClock.Now().Add(time.Hour).Format(time.RFC3339))
+			ExpectNodeExists(ctx, env.Client, node.Name)
+		})
+		It("should shorten a node's lifetime below its provisioner's TTL", func() {
+			prov = test.Provisioner(test.ProvisionerOptions{TTLSecondsUntilExpired: ptr.Int64(int64((24 * time.Hour).Seconds()))})
+			expireWithOverride(fakeClock.Now().Add(5 * time.Minute).Format(time.RFC3339))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should ignore the override if the provisioner doesn't allow it", func() {
+			prov = test.Provisioner(test.ProvisionerOptions{
+				TTLSecondsUntilExpired: ptr.Int64(60),
+				Expiration:             &v1alpha5.Expiration{AllowNodeOverride: ptr.Bool(false)},
+			})
+			expireWithOverride(fakeClock.Now().Add(time.Hour).Format(time.RFC3339))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should fall back to the provisioner's TTL if the override is malformed", func() {
+			prov = test.Provisioner(test.ProvisionerOptions{TTLSecondsUntilExpired: ptr.Int64(60)})
+			expireWithOverride("tomorrow")
+			ExpectNotFound(ctx, env.Client, node)
+		})
+	})
+	Context("Expiration Warnings", func() {
+		var node *v1.Node
+		BeforeEach(func() {
+			prov := test.Provisioner(test.ProvisionerOptions{
+				TTLSecondsUntilExpired: ptr.Int64(int64(time.Hour.Seconds())),
+				Expiration:             &v1alpha5.Expiration{WarningThresholdSeconds: ptr.Int64(int64((10 * time.Minute).Seconds()))},
+			})
+			node = test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}},
+			)
+			ExpectApplied(ctx, env.Client, node, prov)
+			ExpectMakeNodesReady(ctx, env.Client, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		})
+		It("should not warn of expiration before the warning threshold", func() {
+			fakeClock.Step(45 * time.Minute)
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Calls("NodeExpiringShortly")).To(Equal(0))
+			Expect(ExpectNodeExists(ctx, env.Client, node.Name).Annotations).ToNot(HaveKey(v1alpha5.ExpirationWarningAnnotationKey))
+		})
+		It("should warn of expiration within the warning threshold without expiring the node", func() {
+			fakeClock.Step(55 * time.Minute)
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Calls("NodeExpiringShortly")).To(Equal(1))
+			recorder.ForEachEvent(func(evt events.Event) {
+				if evt.Reason == "NodeExpiringShortly" {
+					Expect(evt.Type).To(Equal(v1.EventTypeWarning))
+					Expect(evt.Message).To(ContainSubstring(node.CreationTimestamp.Add(time.Hour).UTC().Format(time.RFC3339)))
+				}
+			})
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			Expect(node.Annotations).To(HaveKey(v1alpha5.ExpirationWarningAnnotationKey))
+			Expect(node.Spec.Unschedulable).To(BeFalse())
+		})
+		It("should only warn of expiration once", func() {
+			fakeClock.Step(55 * time.Minute)
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+
+			fakeClock.Step(time.Minute)
+			_, err = deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Calls("NodeExpiringShortly")).To(Equal(1))
+		})
+	})
+	Context("Expiration Maintenance Windows", func() {
+		var node *v1.Node
+		expiredNode := func(windows ...string) {
+			prov := test.Provisioner(test.ProvisionerOptions{
+				TTLSecondsUntilExpired: ptr.Int64(60),
+				Expiration:             &v1alpha5.Expiration{MaintenanceWindows: windows},
+			})
+			node = test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}},
+			)
+			ExpectApplied(ctx, env.Client, node, prov)
+			ExpectMakeNodesReady(ctx, env.Client, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			fakeClock.Step(10 * time.Minute)
+		}
+		// window returns a window of an hour that opens the offset from now
+		window := func(offset time.Duration) string {
+			start := fakeClock.Now().UTC().Add(offset)
+			return fmt.Sprintf("%s-%s", start.Format("15:04"), start.Add(time.Hour).Format("15:04"))
+		}
+		It("should skip expired nodes outside of their provisioner's maintenance windows", func() {
+			expiredNode(window(time.Hour))
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectNodeExists(ctx, env.Client, node.Name)
+		})
+		It("should expire nodes inside of their provisioner's maintenance windows", func() {
+			expiredNode(window(time.Hour), window(-time.Minute))
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should expire nodes at any time if their provisioner has no maintenance windows", func() {
+			expiredNode()
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should expire nodes once the clock advances into a maintenance window", func() {
+			expiredNode(window(time.Hour))
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectNodeExists(ctx, env.Client, node.Name)
+
+			fakeClock.Step(time.Hour)
+			_, err = deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectNotFound(ctx, env.Client, node)
+		})
+	})
+	It("should only expire nodes within a maintenance window", func() {
+		// the window opens an hour from now and lasts for an hour
+		now := fakeClock.Now().UTC()
+		offset := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+		s := test.Settings()
+		s.MaintenanceWindows = []maintenancewindow.Window{{
+			Start: (offset + time.Hour) % (24 * time.Hour),
+			End:   (offset + 2*time.Hour) % (24 * time.Hour),
+		}}
+		windowCtx := settings.ToContext(ctx, s)
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(60),
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}},
+		)
+		ExpectApplied(ctx, env.Client, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+
+		// the node has expired, but the window hasn't opened yet
+		fakeClock.Step(10 * time.Minute)
+		result, err := deprovisioningController.ProcessCluster(windowCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+
+		// once it has, the node is expired
+		fakeClock.Step(time.Hour)
+		_, err = deprovisioningController.ProcessCluster(windowCtx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("should not expire nodes once the provisioner's disruption budget is exhausted", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(60),
+			Disruption:             &v1alpha5.Disruption{Budget: lo.ToPtr(intstr.FromInt(1))},
+		})
+		var nodes []*v1.Node
+		for i := 0; i < 3; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}},
+			))
+		}
+		ExpectApplied(ctx, env.Client, prov, nodes[0], nodes[1], nodes[2])
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for _, node := range nodes {
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		}
+		// another controller is already deleting one of the nodes, which uses the whole budget
+		cluster.MarkForDeletion(nodes[0].Name)
+
+		fakeClock.Step(10 * time.Minute)
+		result, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+		for _, node := range nodes[1:] {
+			ExpectNodeExists(ctx, env.Client, node.Name)
+		}
+	})
+	It("should not execute a command that disrupts more nodes than the provisioner's disruption budget", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(60),
+			Expiration:             &v1alpha5.Expiration{MaxNodesPerCycle: ptr.Int32(3)},
+			Disruption:             &v1alpha5.Disruption{Budget: lo.ToPtr(intstr.FromString("50%"))},
+		})
+		var nodes []*v1.Node
+		for i := 0; i < 4; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}},
+			))
+		}
+		ExpectApplied(ctx, env.Client, prov, nodes[0], nodes[1], nodes[2], nodes[3])
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for _, node := range nodes {
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		}
+
+		// expiration would delete three nodes at once, but the budget only allows two of the four to be disrupted
+		fakeClock.Step(10 * time.Minute)
+		result, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+		for _, node := range nodes {
+			ExpectNodeExists(ctx, env.Client, node.Name)
+		}
+	})
+	It("should spread the expiration of nodes that were created together", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(int64((24 * time.Hour).Seconds())),
+			ExpirationJitterFactor: ptr.Float64(0.5),
+		})
+		nodes := []*v1.Node{}
+		for i := 0; i < 2; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}},
+			))
+		}
+		ExpectApplied(ctx, env.Client, prov, nodes[0], nodes[1])
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for _, node := range nodes {
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		}
+
+		// the nodes' UIDs are assigned by the API server, so their expiration times are only known once they're created
+		expirations := lo.Map(nodes, func(node *v1.Node, _ int) time.Time {
+			return expectedExpirationTime(node, prov)
+		})
+		first, second := nodes[0], nodes[1]
+		if expirations[1].Before(expirations[0]) {
+			first, second = second, first
+			expirations[0], expirations[1] = expirations[1], expirations[0]
+		}
+		for i, expiration := range expirations {
+			created := lo.Ternary(i == 0, first, second).CreationTimestamp.Time
+			Expect(expiration).To(BeTemporally(">=", created.Add(12*time.Hour)))
+			Expect(expiration).To(BeTemporally("<=", created.Add(36*time.Hour)))
+		}
+		Expect(expirations[0]).ToNot(Equal(expirations[1]))
+
+		// only the node that expires first is deprovisioned between the two expiration times
+		fakeClock.SetTime(expirations[0].Add(expirations[1].Sub(expirations[0]) / 2))
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNotFound(ctx, env.Client, first)
+		ExpectNodeExists(ctx, env.Client, second.Name)
+
+		// but both have expired once the jitter window has passed
+		fakeClock.SetTime(lo.MaxBy(nodes, func(a, b *v1.Node) bool {
+			return a.CreationTimestamp.After(b.CreationTimestamp.Time)
+		}).CreationTimestamp.Add(36*time.Hour + time.Second))
+		go triggerVerifyAction()
+		_, err = deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNotFound(ctx, env.Client, second)
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+	})
+	It("should not expire nodes with the do-not-expire annotation", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(60),
+		})
+		nodes := []*v1.Node{}
+		for i := 0; i < 2; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}},
+			))
+		}
+		nodes[0].Annotations = lo.Assign(nodes[0].Annotations, map[string]string{v1alpha5.DoNotExpireNodeAnnotationKey: "true"})
+
+		ExpectApplied(ctx, env.Client, nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for _, node := range nodes {
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		}
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the annotated node survives past its TTL while its sibling expires
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, nodes[0].Name)
+		ExpectNotFound(ctx, env.Client, nodes[1])
+	})
+	It("should defer a second expiration until the expiration cool-down elapses while consolidation can still act", func() {
+		s := test.Settings()
+		s.DeprovisioningCooldowns = map[string]metav1.Duration{"expiration": {Duration: 10 * time.Minute}}
+		cooldownCtx := settings.ToContext(ctx, s)
+
+		expiringProv := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(60),
+		})
+		consolidatingProv := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		newNode := func(prov *v1alpha5.Provisioner) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}},
+			)
+		}
+		expired := []*v1.Node{newNode(expiringProv), newNode(expiringProv)}
+		empty := newNode(consolidatingProv)
+
+		ExpectApplied(ctx, env.Client, expired[0], expired[1], empty, expiringProv, consolidatingProv)
+		ExpectMakeNodesReady(ctx, env.Client, expired[0], expired[1], empty)
+		// inform cluster state about the nodes, and about their deletion after each pass
+		informClusterState := func() {
+			for _, node := range []*v1.Node{expired[0], expired[1], empty} {
+				ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			}
+		}
+		informClusterState()
+		fakeClock.Step(10 * time.Minute)
+
+		// the first expiration starts the cool-down
+		_, err := deprovisioningController.ProcessCluster(cooldownCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lo.Filter(expired, func(n *v1.Node, _ int) bool {
+			return errors.IsNotFound(env.Client.Get(ctx, client.ObjectKeyFromObject(n), &v1.Node{}))
+		})).To(HaveLen(1))
+		ExpectNodeExists(ctx, env.Client, empty.Name)
+		informClusterState()
+
+		// expiration is deferred, but consolidation isn't
+		go triggerVerifyAction()
+		_, err = deprovisioningController.ProcessCluster(cooldownCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lo.Filter(expired, func(n *v1.Node, _ int) bool {
+			return errors.IsNotFound(env.Client.Get(ctx, client.ObjectKeyFromObject(n), &v1.Node{}))
+		})).To(HaveLen(1))
+		ExpectNotFound(ctx, env.Client, empty)
+		informClusterState()
+
+		// once the cool-down elapses, the other node expires
+		fakeClock.Step(10 * time.Minute)
+		_, err = deprovisioningController.ProcessCluster(cooldownCtx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNotFound(ctx, env.Client, expired[0], expired[1])
+	})
+	It("should remove expired nodes in the expiration order of their provisioner", func() {
+		// the nodes of the first provisioner expire before those of the second
+		expensiveFirst := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(60),
+			ExpirationOrder:        v1alpha5.ExpirationOrderMostExpensive,
+		})
+		leastUtilizedFirst := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(120),
+			ExpirationOrder:        v1alpha5.ExpirationOrderLeastUtilized,
+		})
+		newNode := func(prov *v1alpha5.Provisioner, instanceType *cloudprovider.InstanceType, offering cloudprovider.Offering) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       instanceType.Name,
+						v1alpha5.LabelCapacityType:       offering.CapacityType,
+						v1.LabelTopologyZone:             offering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}})
+		}
+		cheap := newNode(expensiveFirst, leastExpensiveInstance, leastExpensiveOffering)
+		expensive := newNode(expensiveFirst, mostExpensiveInstance, mostExpensiveOffering)
+		utilized := newNode(leastUtilizedFirst, leastExpensiveInstance, leastExpensiveOffering)
+		empty := newNode(leastUtilizedFirst, leastExpensiveInstance, leastExpensiveOffering)
+
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}},
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+		})
+
+		ExpectApplied(ctx, env.Client, pod, cheap, expensive, utilized, empty, expensiveFirst, leastUtilizedFirst)
+		ExpectMakeNodesReady(ctx, env.Client, cheap, expensive, utilized, empty)
+		ExpectManualBinding(ctx, env.Client, pod, utilized)
+		for _, node := range []*v1.Node{cheap, expensive, utilized, empty} {
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		}
+		fakeClock.Step(10 * time.Minute)
+
+		// the expensive node is removed before the cheap one, and the empty node before the utilized one
+		for _, removed := range []*v1.Node{expensive, cheap, empty} {
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectNotFound(ctx, env.Client, removed)
+		}
+		ExpectNodeExists(ctx, env.Client, utilized.Name)
+	})
+	It("should not expire nodes until the minimum node lifetime has elapsed", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(1),
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}},
+		)
+
+		ExpectApplied(ctx, env.Client, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		s := test.Settings()
+		s.MinNodeLifetime = metav1.Duration{Duration: 30 * time.Minute}
+		minLifetimeCtx := settings.ToContext(ctx, s)
+
+		// the node's TTL has passed, but it hasn't lived for the minimum node lifetime
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(minLifetimeCtx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, node.Name)
+
+		// once the minimum node lifetime has passed, the node can be expired
+		fakeClock.Step(25 * time.Minute)
+		go triggerVerifyAction()
+		_, err = deprovisioningController.ProcessCluster(minLifetimeCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, node)
+	})
 	It("should expire one node at a time, starting with most expired", func() {
 		expireProv := test.Provisioner(test.ProvisionerOptions{
 			TTLSecondsUntilExpired: ptr.Int64(100),
@@ -300,7 +962,7 @@ var _ = Describe("Expiration", func() {
 
 		ExpectNotFound(ctx, env.Client, node)
 	})
-	It("should uncordon nodes when expiration replacement partially fails", func() {
+	It("should uncordon nodes and remove launched replacements when expiration replacement partially fails", func() {
 		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
 			Name: "current-on-demand",
 			Offerings: []cloudprovider.Offering{
@@ -388,6 +1050,11 @@ var _ = Describe("Expiration", func() {
 
 		Expect(cloudProvider.CreateCalls).To(HaveLen(3))
 
+		// only two of the three replacements were created, and they're removed rather than the node that they replace
+		Expect(cloudProvider.CreatedNodes).To(HaveLen(2))
+		for _, n := range cloudProvider.CreatedNodes {
+			ExpectNotFound(ctx, env.Client, n)
+		}
 		node = ExpectNodeExists(ctx, env.Client, node.Name)
 		Expect(node.Spec.Unschedulable).To(BeFalse())
 	})
@@ -484,73 +1151,17 @@ var _ = Describe("Expiration", func() {
 	})
 })
 
-var _ = Describe("Pod Eviction Cost", func() {
-	const standardPodCost = 1.0
-	It("should have a standard disruptionCost for a pod with no priority or disruptionCost specified", func() {
-		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{})
-		Expect(cost).To(BeNumerically("==", standardPodCost))
-	})
-	It("should have a higher disruptionCost for a pod with a positive deletion disruptionCost", func() {
-		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
-			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
-				v1.PodDeletionCost: "100",
-			}},
-		})
-		Expect(cost).To(BeNumerically(">", standardPodCost))
-	})
-	It("should have a lower disruptionCost for a pod with a positive deletion disruptionCost", func() {
-		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
-			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
-				v1.PodDeletionCost: "-100",
-			}},
-		})
-		Expect(cost).To(BeNumerically("<", standardPodCost))
-	})
-	It("should have higher costs for higher deletion costs", func() {
-		cost1 := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
-			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
-				v1.PodDeletionCost: "101",
-			}},
-		})
-		cost2 := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
-			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
-				v1.PodDeletionCost: "100",
-			}},
-		})
-		cost3 := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
-			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
-				v1.PodDeletionCost: "99",
-			}},
-		})
-		Expect(cost1).To(BeNumerically(">", cost2))
-		Expect(cost2).To(BeNumerically(">", cost3))
-	})
-	It("should have a higher disruptionCost for a pod with a higher priority", func() {
-		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
-			Spec: v1.PodSpec{Priority: ptr.Int32(1)},
-		})
-		Expect(cost).To(BeNumerically(">", standardPodCost))
-	})
-	It("should have a lower disruptionCost for a pod with a lower priority", func() {
-		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
-			Spec: v1.PodSpec{Priority: ptr.Int32(-1)},
-		})
-		Expect(cost).To(BeNumerically("<", standardPodCost))
-	})
-})
-
-var _ = Describe("Replace Nodes", func() {
-	It("can replace node", func() {
-		labels := map[string]string{
-			"app": "test",
-		}
-		// create our RS so we can link a pod to it
+var _ = Describe("Drift", func() {
+	var prov *v1alpha5.Provisioner
+	var node *v1.Node
+	var pod *v1.Pod
+	var driftCtx context.Context
+	BeforeEach(func() {
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
-
-		pod := test.Pod(test.PodOptions{
-			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+		pod = test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -561,11 +1172,8 @@ var _ = Describe("Replace Nodes", func() {
 						BlockOwnerDeletion: ptr.Bool(true),
 					},
 				}}})
-
-		prov := test.Provisioner(test.ProvisionerOptions{
-			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
-		})
-		node := test.Node(test.NodeOptions{
+		prov = test.Provisioner()
+		node = test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
@@ -573,68 +1181,101 @@ var _ = Describe("Replace Nodes", func() {
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
+			ProviderID:  fmt.Sprintf("fake://%s", test.RandomName()),
 			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
 		})
+		cloudProvider.DriftedProviderIDs = sets.NewString(node.Spec.ProviderID)
 
-		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		s := test.Settings()
+		s.DriftEnabled = true
+		driftCtx = settings.ToContext(ctx, s)
+	})
+	It("should ignore drifted nodes when drift is disabled", func() {
+		ExpectApplied(ctx, env.Client, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("should ignore nodes that haven't drifted", func() {
+		cloudProvider.DriftedProviderIDs = nil
+		ExpectApplied(ctx, env.Client, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(driftCtx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("can replace drifted nodes", func() {
+		ExpectApplied(ctx, env.Client, pod, node, prov)
 		ExpectMakeNodesReady(ctx, env.Client, node)
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
 		ExpectManualBinding(ctx, env.Client, pod, node)
 		ExpectScheduled(ctx, env.Client, pod)
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
 
-		// consolidation won't delete the old node until the new node is ready
+		// drift won't delete the old node until the new node is ready
 		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
 		fakeClock.Step(10 * time.Minute)
-		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
+		_, err := deprovisioningController.ProcessCluster(driftCtx)
 		Expect(err).ToNot(HaveOccurred())
 		wg.Wait()
 
-		// should create a new node as there is a cheaper one that can hold the pod
 		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
-		// and delete the old one
 		ExpectNotFound(ctx, env.Client, node)
 	})
-	It("can replace nodes, considers PDB", func() {
-		labels := map[string]string{
-			"app": "test",
-		}
-		// create our RS so we can link a pod to it
-		rs := test.ReplicaSet()
-		ExpectApplied(ctx, env.Client, rs)
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
-
-		pods := test.Pods(3, test.PodOptions{
+	It("can delete drifted nodes whose pods fit on other nodes", func() {
+		target := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels: labels,
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion:         "apps/v1",
-						Kind:               "ReplicaSet",
-						Name:               rs.Name,
-						UID:                rs.UID,
-						Controller:         ptr.Bool(true),
-						BlockOwnerDeletion: ptr.Bool(true),
-					},
-				}}})
-
-		pdb := test.PodDisruptionBudget(test.PDBOptions{
-			Labels:         labels,
-			MaxUnavailable: fromInt(0),
-			Status: &policyv1.PodDisruptionBudgetStatus{
-				ObservedGeneration: 1,
-				DisruptionsAllowed: 0,
-				CurrentHealthy:     1,
-				DesiredHealthy:     1,
-				ExpectedPods:       1,
-			},
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			ProviderID:  fmt.Sprintf("fake://%s", test.RandomName()),
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32"), v1.ResourcePods: resource.MustParse("100")},
 		})
+		ExpectApplied(ctx, env.Client, pod, node, target, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node, target)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(target))
+		ExpectManualBinding(ctx, env.Client, pod, node)
 
-		prov := test.Provisioner(test.ProvisionerOptions{
-			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
-		})
-		node1 := test.Node(test.NodeOptions{
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(driftCtx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, node)
+		ExpectNodeExists(ctx, env.Client, target.Name)
+	})
+	It("should not replace drifted nodes with do-not-evict pods", func() {
+		pod.Annotations = map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"}
+		ExpectApplied(ctx, env.Client, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(driftCtx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("should replace the oldest drifted node first", func() {
+		older := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
@@ -642,61 +1283,95 @@ var _ = Describe("Replace Nodes", func() {
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			},
+			ProviderID:  fmt.Sprintf("fake://%s", test.RandomName()),
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
 		})
+		cloudProvider.DriftedProviderIDs.Insert(older.Spec.ProviderID)
+		olderPod := pod.DeepCopy()
+		olderPod.Name = test.RandomName()
+		ExpectApplied(ctx, env.Client, older)
+		expectNextCreationTimestamp(older)
+		ExpectApplied(ctx, env.Client, pod, olderPod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node, older)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(older))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectManualBinding(ctx, env.Client, olderPod, older)
 
-		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, prov, pdb)
-		ExpectApplied(ctx, env.Client, node1)
-		// all pods on node1
-		ExpectManualBinding(ctx, env.Client, pods[0], node1)
-		ExpectManualBinding(ctx, env.Client, pods[1], node1)
-		ExpectManualBinding(ctx, env.Client, pods[2], node1)
-		ExpectScheduled(ctx, env.Client, pods[0])
-		ExpectScheduled(ctx, env.Client, pods[1])
-		ExpectScheduled(ctx, env.Client, pods[2])
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
-
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		// the older node's pod fits on the newer node, so it's deleted without a replacement
 		fakeClock.Step(10 * time.Minute)
-		_, err := deprovisioningController.ProcessCluster(ctx)
+		_, err := deprovisioningController.ProcessCluster(driftCtx)
 		Expect(err).ToNot(HaveOccurred())
 
-		// we don't need a new node
 		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// and can't delete the node due to the PDB
-		ExpectNodeExists(ctx, env.Client, node1.Name)
+		ExpectNotFound(ctx, env.Client, older)
+		ExpectNodeExists(ctx, env.Client, node.Name)
 	})
-	It("can replace nodes, considers do-not-consolidate annotation", func() {
-		labels := map[string]string{
-			"app": "test",
-		}
+})
 
-		// create our RS so we can link a pod to it
-		rs := test.ReplicaSet()
-		ExpectApplied(ctx, env.Client, rs)
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+var _ = Describe("Not Ready", func() {
+	var prov *v1alpha5.Provisioner
+	BeforeEach(func() {
+		prov = test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsAfterNotReady: ptr.Int64(int64((10 * time.Minute).Seconds())),
+		})
+	})
+	// notReadyNode applies an initialized node of the provisioner that was created an hour ago, and whose Ready
+	// condition has been False for the duration
+	notReadyNode := func(provisioner *v1alpha5.Provisioner, notReadyFor time.Duration) *v1.Node {
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+					v1alpha5.LabelNodeInitialized:    "true",
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+		ExpectApplied(ctx, env.Client, node, provisioner)
+		fakeClock.SetTime(node.CreationTimestamp.Add(time.Hour))
+		node.Status.Conditions = []v1.NodeCondition{{
+			Type:               v1.NodeReady,
+			Status:             v1.ConditionFalse,
+			LastTransitionTime: metav1.NewTime(fakeClock.Now().Add(-notReadyFor)),
+			Reason:             "KubeletNotReady",
+		}}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		return node
+	}
+	It("should delete nodes that have not been ready for longer than the TTL", func() {
+		node := notReadyNode(prov, 20*time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
 
-		pods := test.Pods(3, test.PodOptions{
-			ObjectMeta: metav1.ObjectMeta{Labels: labels,
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion:         "apps/v1",
-						Kind:               "ReplicaSet",
-						Name:               rs.Name,
-						UID:                rs.UID,
-						Controller:         ptr.Bool(true),
-						BlockOwnerDeletion: ptr.Bool(true),
-					},
-				}}})
+		// nodes that aren't ready are never replaced
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("should not delete nodes that have not been ready for less than the TTL", func() {
+		node := notReadyNode(prov, 5*time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("should restart the TTL when a node flaps between ready and not ready", func() {
+		// the node was created long ago, but was ready until a minute ago
+		node := notReadyNode(prov, time.Minute)
+		fakeClock.Step(5 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, node.Name)
 
-		prov := test.Provisioner(test.ProvisionerOptions{
-			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
-		})
-		regularNode := test.Node(test.NodeOptions{
+		fakeClock.Step(5 * time.Minute)
+		_, err = deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("should delete nodes that never became ready", func() {
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
@@ -704,197 +1379,4840 @@ var _ = Describe("Replace Nodes", func() {
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			},
+			ReadyStatus: v1.ConditionUnknown,
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
 		})
+		ExpectApplied(ctx, env.Client, node, prov)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		fakeClock.SetTime(node.CreationTimestamp.Add(20 * time.Minute))
 
-		annotatedNode := test.Node(test.NodeOptions{
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("should not make nodes that never became ready candidates of other deprovisioners", func() {
+		prov.Spec.Consolidation = &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
-				Annotations: map[string]string{
-					v1alpha5.DoNotConsolidateNodeAnnotationKey: "true",
-				},
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
 					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			},
+			ReadyStatus: v1.ConditionUnknown,
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
 		})
+		ExpectApplied(ctx, env.Client, node, prov)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		fakeClock.SetTime(node.CreationTimestamp.Add(20 * time.Minute))
 
-		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], prov)
-		ExpectApplied(ctx, env.Client, regularNode, annotatedNode)
-		ExpectApplied(ctx, env.Client, regularNode, annotatedNode)
-		ExpectMakeNodesReady(ctx, env.Client, regularNode, annotatedNode)
-		ExpectManualBinding(ctx, env.Client, pods[0], regularNode)
-		ExpectManualBinding(ctx, env.Client, pods[1], regularNode)
-		ExpectManualBinding(ctx, env.Client, pods[2], annotatedNode)
-		ExpectScheduled(ctx, env.Client, pods[0])
-		ExpectScheduled(ctx, env.Client, pods[1])
-		ExpectScheduled(ctx, env.Client, pods[2])
-
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(regularNode))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(annotatedNode))
-		fakeClock.Step(10 * time.Minute)
-		go triggerVerifyAction()
+		// the node is empty, but consolidation ignores it since it never initialized
+		evaluations, err := deprovisioningController.ListCandidates(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(evaluations).To(HaveLen(1))
+		Expect(evaluations[0].Candidacy).To(Equal([]string{"not-ready"}))
+	})
+	It("should not delete nodes of provisioners without a TTL", func() {
+		node := notReadyNode(test.Provisioner(), 20*time.Minute)
 		_, err := deprovisioningController.ProcessCluster(ctx)
 		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+})
 
-		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+var _ = Describe("Pod Eviction Cost", func() {
+	// pods without requests or limits are BestEffort, whose cost is scaled by 0.75 by default
+	const standardPodCost = 0.75
+	It("should have a standard disruptionCost for a pod with no priority or disruptionCost specified", func() {
+		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{})
+		Expect(cost).To(BeNumerically("==", standardPodCost))
+	})
+	It("should have a higher disruptionCost for a pod that mounts a persistent volume claim", func() {
+		stateless := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{})
+		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			Spec: v1.PodSpec{Volumes: []v1.Volume{{
+				Name:         "data",
+				VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+			}}},
+		})
+		Expect(cost).To(BeNumerically(">", stateless))
+	})
+	It("should have a higher disruptionCost for a pod that uses an emptyDir volume", func() {
+		stateless := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{})
+		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			Spec: v1.PodSpec{Volumes: []v1.Volume{{
+				Name:         "scratch",
+				VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+			}}},
+		})
+		Expect(cost).To(BeNumerically(">", stateless))
+	})
+	It("should have a higher disruptionCost for a pod with a persistent volume claim than one with an emptyDir volume", func() {
+		pvcCost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			Spec: v1.PodSpec{Volumes: []v1.Volume{{
+				Name:         "data",
+				VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+			}}},
+		})
+		emptyDirCost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			Spec: v1.PodSpec{Volumes: []v1.Volume{{
+				Name:         "scratch",
+				VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+			}}},
+		})
+		Expect(pvcCost).To(BeNumerically(">", emptyDirCost))
+	})
+	It("should combine the volume disruptionCost with the priority and deletion disruptionCost", func() {
+		volumes := []v1.Volume{{
+			Name:         "data",
+			VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+		}}
+		meta := metav1.ObjectMeta{Annotations: map[string]string{v1.PodDeletionCost: "100"}}
+		stateless := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{ObjectMeta: meta, Spec: v1.PodSpec{Priority: ptr.Int32(1)}})
+		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{ObjectMeta: meta, Spec: v1.PodSpec{Priority: ptr.Int32(1), Volumes: volumes}})
+		Expect(cost).To(BeNumerically(">", stateless))
+		Expect(stateless).To(BeNumerically(">", standardPodCost))
+	})
+	It("should have a higher disruptionCost for a pod with a positive deletion disruptionCost", func() {
+		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: "100",
+			}},
+		})
+		Expect(cost).To(BeNumerically(">", standardPodCost))
+	})
+	It("should have a lower disruptionCost for a pod with a positive deletion disruptionCost", func() {
+		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: "-100",
+			}},
+		})
+		Expect(cost).To(BeNumerically("<", standardPodCost))
+	})
+	It("should have higher costs for higher deletion costs", func() {
+		cost1 := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: "101",
+			}},
+		})
+		cost2 := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: "100",
+			}},
+		})
+		cost3 := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: "99",
+			}},
+		})
+		Expect(cost1).To(BeNumerically(">", cost2))
+		Expect(cost2).To(BeNumerically(">", cost3))
+	})
+	It("should clamp deletion costs above the int32 range", func() {
+		maxCost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: fmt.Sprint(math.MaxInt32),
+			}},
+		})
+		belowMaxCost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: fmt.Sprint(math.MaxInt32 - 1),
+			}},
+		})
+		oversizedCost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: fmt.Sprint(int64(math.MaxInt32) + 1),
+			}},
+		})
+		// the overall pod cost is clamped as well, so costs near the boundary can be equal but never decrease
+		Expect(maxCost).To(BeNumerically(">=", belowMaxCost))
+		Expect(oversizedCost).To(BeNumerically("==", maxCost))
+	})
+	It("should clamp deletion costs below the int32 range", func() {
+		minCost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: fmt.Sprint(math.MinInt32),
+			}},
+		})
+		aboveMinCost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: fmt.Sprint(math.MinInt32 + 1),
+			}},
+		})
+		undersizedCost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: fmt.Sprint(int64(math.MinInt32) - 1),
+			}},
+		})
+		Expect(minCost).To(BeNumerically("<=", aboveMinCost))
+		Expect(undersizedCost).To(BeNumerically("==", minCost))
+	})
+	It("should have a standard disruptionCost for a pod with a non-numeric deletion disruptionCost", func() {
+		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1.PodDeletionCost: "not-a-number",
+			}},
+		})
+		Expect(cost).To(BeNumerically("==", standardPodCost))
+	})
+	It("should have a higher disruptionCost for a pod with a higher priority", func() {
+		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			Spec: v1.PodSpec{Priority: ptr.Int32(1)},
+		})
+		Expect(cost).To(BeNumerically(">", standardPodCost))
+	})
+	It("should have a lower disruptionCost for a pod with a lower priority", func() {
+		cost := deprovisioning.GetPodEvictionCost(ctx, &v1.Pod{
+			Spec: v1.PodSpec{Priority: ptr.Int32(-1)},
+		})
+		Expect(cost).To(BeNumerically("<", standardPodCost))
+	})
+	Context("QoS Class", func() {
+		guaranteed := test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+		}}
+		burstable := test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+		}}
+		It("should scale the disruptionCost of a pod by its QoS class", func() {
+			Expect(deprovisioning.GetPodEvictionCost(ctx, test.Pod(guaranteed))).To(BeNumerically("==", 1.5))
+			Expect(deprovisioning.GetPodEvictionCost(ctx, test.Pod(burstable))).To(BeNumerically("==", 1.0))
+			Expect(deprovisioning.GetPodEvictionCost(ctx, test.Pod())).To(BeNumerically("==", 0.75))
+		})
+		It("should order the disruptionCost of otherwise identical pods by their QoS class", func() {
+			priority := test.PodOptions{Priority: ptr.Int32(1000)}
+			guaranteedCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(guaranteed, priority))
+			burstableCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(burstable, priority))
+			bestEffortCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(priority))
+			Expect(guaranteedCost).To(BeNumerically(">", burstableCost))
+			Expect(burstableCost).To(BeNumerically(">", bestEffortCost))
+		})
+		It("should order the disruptionCost of pods with a negative disruptionCost by their QoS class", func() {
+			deletionCost := test.PodOptions{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1.PodDeletionCost: "-200000000"}}}
+			guaranteedCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(guaranteed, deletionCost))
+			burstableCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(burstable, deletionCost))
+			bestEffortCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(deletionCost))
+			Expect(guaranteedCost).To(BeNumerically("<", 0))
+			Expect(guaranteedCost).To(BeNumerically(">", burstableCost))
+			Expect(burstableCost).To(BeNumerically(">", bestEffortCost))
+		})
+		It("should use the QoS class factors from settings", func() {
+			s := test.Settings()
+			s.EvictionQoSFactors = settings.EvictionQoSFactors{Guaranteed: 3, Burstable: 2, BestEffort: 1}
+			qosCtx := settings.ToContext(ctx, s)
+			Expect(deprovisioning.GetPodEvictionCost(qosCtx, test.Pod(guaranteed))).To(BeNumerically("==", 3))
+			Expect(deprovisioning.GetPodEvictionCost(qosCtx, test.Pod(burstable))).To(BeNumerically("==", 2))
+			Expect(deprovisioning.GetPodEvictionCost(qosCtx, test.Pod())).To(BeNumerically("==", 1))
+		})
+		It("should use the QoS class that the API server set", func() {
+			pod := test.Pod()
+			pod.Status.QOSClass = v1.PodQOSGuaranteed
+			Expect(deprovisioning.GetPodEvictionCost(ctx, pod)).To(BeNumerically("==", 1.5))
+		})
+	})
+	Context("StatefulSet", func() {
+		// ownedBy returns pod options for a pod that's controlled by an owner of the kind
+		ownedBy := func(kind string) test.PodOptions {
+			return test.PodOptions{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1",
+				Kind:       kind,
+				Name:       "owner",
+				UID:        types.UID("owner"),
+				Controller: ptr.Bool(true),
+			}}}}
+		}
+		It("should have a higher disruptionCost for a StatefulSet pod than an otherwise identical ReplicaSet pod", func() {
+			statefulSetCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(ownedBy("StatefulSet")))
+			replicaSetCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(ownedBy("ReplicaSet")))
+			Expect(statefulSetCost).To(BeNumerically(">", replicaSetCost))
+			Expect(statefulSetCost).To(BeNumerically("==", 2*replicaSetCost))
+		})
+		It("should apply the StatefulSet factor after the priority and deletion disruptionCost", func() {
+			adjustments := test.PodOptions{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1.PodDeletionCost: "100"}},
+				Priority:   ptr.Int32(1000),
+			}
+			statefulSetCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(ownedBy("StatefulSet"), adjustments))
+			replicaSetCost := deprovisioning.GetPodEvictionCost(ctx, test.Pod(ownedBy("ReplicaSet"), adjustments))
+			Expect(statefulSetCost).To(BeNumerically("~", 2*replicaSetCost, 1e-9))
+		})
+		It("should only apply the StatefulSet factor to pods that a StatefulSet controls", func() {
+			pod := test.Pod(ownedBy("StatefulSet"))
+			pod.OwnerReferences[0].Controller = nil
+			Expect(deprovisioning.GetPodEvictionCost(ctx, pod)).To(BeNumerically("==", standardPodCost))
+		})
+		It("should not scale the disruptionCost of StatefulSet pods if the factor is one", func() {
+			s := test.Settings()
+			s.StatefulSetEvictionCostFactor = 1
+			disabledCtx := settings.ToContext(ctx, s)
+			statefulSetCost := deprovisioning.GetPodEvictionCost(disabledCtx, test.Pod(ownedBy("StatefulSet")))
+			replicaSetCost := deprovisioning.GetPodEvictionCost(disabledCtx, test.Pod(ownedBy("ReplicaSet")))
+			Expect(statefulSetCost).To(BeNumerically("==", replicaSetCost))
+		})
+	})
+})
+
+var _ = Describe("PDB Limits", func() {
+	var labels map[string]string
+	var pods []*v1.Pod
+	BeforeEach(func() {
+		labels = map[string]string{"app": "test"}
+		pods = test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+		})
+	})
+	// canEvict applies a PDB that the disruption controller hasn't observed yet, so the disruptions that it allows are
+	// computed from its spec, and returns whether the pods can be evicted
+	canEvict := func(opts test.PDBOptions) bool {
+		opts.Labels = labels
+		opts.Status = &policyv1beta1.PodDisruptionBudgetStatus{}
+		pdb := test.PodDisruptionBudget(opts)
+		ExpectApplied(ctx, env.Client, pods[0], pods[1], pods[2], pdb)
+		limits, err := deprovisioning.NewPDBLimits(ctx, env.Client)
+		Expect(err).ToNot(HaveOccurred())
+		_, ok := limits.CanEvictPods(pods)
+		return ok
+	}
+	It("should allow eviction with a minAvailable percentage that leaves a pod to spare", func() {
+		// 50% of 3 pods rounds up to 2, so one pod can be disrupted
+		Expect(canEvict(test.PDBOptions{MinAvailable: lo.ToPtr(intstr.FromString("50%"))})).To(BeTrue())
+	})
+	It("should not allow eviction with a minAvailable percentage that rounds up to every pod", func() {
+		// 67% of 3 pods rounds up to 3, so no pods can be disrupted
+		Expect(canEvict(test.PDBOptions{MinAvailable: lo.ToPtr(intstr.FromString("67%"))})).To(BeFalse())
+	})
+	It("should allow eviction with a maxUnavailable percentage that rounds up to a pod", func() {
+		// 30% of 3 pods rounds up to 1, so one pod can be disrupted
+		Expect(canEvict(test.PDBOptions{MaxUnavailable: lo.ToPtr(intstr.FromString("30%"))})).To(BeTrue())
+	})
+	It("should allow eviction with a maxUnavailable percentage that rounds up from a fraction of a pod", func() {
+		// 10% of 3 pods is 0.3, which rounds up to 1, so one pod can be disrupted
+		Expect(canEvict(test.PDBOptions{MaxUnavailable: lo.ToPtr(intstr.FromString("10%"))})).To(BeTrue())
+	})
+	It("should allow eviction with a minAvailable percentage just below every pod", func() {
+		// 66% of 3 pods is 1.98, which rounds up to 2, so one pod can be disrupted
+		Expect(canEvict(test.PDBOptions{MinAvailable: lo.ToPtr(intstr.FromString("66%"))})).To(BeTrue())
+	})
+	It("should not allow eviction with a maxUnavailable percentage of zero", func() {
+		Expect(canEvict(test.PDBOptions{MaxUnavailable: lo.ToPtr(intstr.FromString("0%"))})).To(BeFalse())
+	})
+	It("should allow eviction with a maxUnavailable percentage of every pod", func() {
+		Expect(canEvict(test.PDBOptions{MaxUnavailable: lo.ToPtr(intstr.FromString("100%"))})).To(BeTrue())
+	})
+	It("should not count unready pods as healthy", func() {
+		// one of the 3 pods is unready, so the 2 healthy pods are all that minAvailable allows
+		pods[2].Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse}}
+		Expect(canEvict(test.PDBOptions{MinAvailable: lo.ToPtr(intstr.FromInt(2))})).To(BeFalse())
+	})
+	It("should allow eviction if the ready pods leave a pod to spare", func() {
+		// one of the 3 pods is unready, so one of the 2 healthy pods can be disrupted
+		pods[2].Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse}}
+		Expect(canEvict(test.PDBOptions{MinAvailable: lo.ToPtr(intstr.FromInt(1))})).To(BeTrue())
+	})
+	It("should ignore PDBs whose selector matches no pods", func() {
+		pdb := test.PodDisruptionBudget(test.PDBOptions{
+			Labels:         map[string]string{"app": "other"},
+			MaxUnavailable: lo.ToPtr(intstr.FromString("0%")),
+			Status:         &policyv1beta1.PodDisruptionBudgetStatus{},
+		})
+		ExpectApplied(ctx, env.Client, pods[0], pods[1], pods[2], pdb)
+		limits, err := deprovisioning.NewPDBLimits(ctx, env.Client)
+		Expect(err).ToNot(HaveOccurred())
+		_, ok := limits.CanEvictPods(pods)
+		Expect(ok).To(BeTrue())
+	})
+	It("should ignore PDBs of other namespaces", func() {
+		namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: test.RandomName()}}
+		pdb := test.PodDisruptionBudget(test.PDBOptions{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: namespace.Name},
+			Labels:         labels,
+			MaxUnavailable: lo.ToPtr(intstr.FromString("0%")),
+			Status:         &policyv1beta1.PodDisruptionBudgetStatus{},
+		})
+		ExpectApplied(ctx, env.Client, namespace, pods[0], pods[1], pods[2], pdb)
+		limits, err := deprovisioning.NewPDBLimits(ctx, env.Client)
+		Expect(err).ToNot(HaveOccurred())
+		_, ok := limits.CanEvictPods(pods)
+		Expect(ok).To(BeTrue())
+	})
+	It("should apply the most restrictive of overlapping PDBs", func() {
+		permissive := test.PodDisruptionBudget(test.PDBOptions{
+			Labels:         labels,
+			MaxUnavailable: lo.ToPtr(intstr.FromString("50%")),
+			Status:         &policyv1beta1.PodDisruptionBudgetStatus{},
+		})
+		restrictive := test.PodDisruptionBudget(test.PDBOptions{
+			Labels:       labels,
+			MinAvailable: lo.ToPtr(intstr.FromString("100%")),
+			Status:       &policyv1beta1.PodDisruptionBudgetStatus{},
+		})
+		ExpectApplied(ctx, env.Client, pods[0], pods[1], pods[2], permissive, restrictive)
+		limits, err := deprovisioning.NewPDBLimits(ctx, env.Client)
+		Expect(err).ToNot(HaveOccurred())
+		key, ok := limits.CanEvictPods(pods)
+		Expect(ok).To(BeFalse())
+		Expect(key).To(Equal(client.ObjectKeyFromObject(restrictive)))
+	})
+	It("should not allow more disruptions than the observed status", func() {
+		pdb := test.PodDisruptionBudget(test.PDBOptions{
+			Labels:       labels,
+			MinAvailable: lo.ToPtr(intstr.FromString("50%")),
+			Status: &policyv1beta1.PodDisruptionBudgetStatus{
+				ObservedGeneration: 1,
+				DisruptionsAllowed: 0,
+			},
+		})
+		ExpectApplied(ctx, env.Client, pods[0], pods[1], pods[2], pdb)
+		limits, err := deprovisioning.NewPDBLimits(ctx, env.Client)
+		Expect(err).ToNot(HaveOccurred())
+		_, ok := limits.CanEvictPods(pods)
+		Expect(ok).To(BeFalse())
+	})
+	Context("API Versions", func() {
+		// servingClient returns a client for a cluster that serves the given versions of the policy API
+		servingClient := func(versions []string, objs ...client.Object) client.Client {
+			mapper := meta.NewDefaultRESTMapper(nil)
+			for _, version := range versions {
+				mapper.Add(schema.GroupVersionKind{Group: "policy", Version: version, Kind: "PodDisruptionBudget"}, meta.RESTScopeNamespace)
+			}
+			return crfake.NewClientBuilder().WithScheme(scheme.Scheme).WithRESTMapper(mapper).WithObjects(objs...).Build()
+		}
+		var v1PDB *policyv1.PodDisruptionBudget
+		var v1beta1PDB *policyv1beta1.PodDisruptionBudget
+		BeforeEach(func() {
+			v1PDB = &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: test.RandomName(), Namespace: "default"},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector:       &metav1.LabelSelector{MatchLabels: labels},
+					MaxUnavailable: lo.ToPtr(intstr.FromInt(0)),
+				},
+			}
+			v1beta1PDB = test.PodDisruptionBudget(test.PDBOptions{
+				Labels:         labels,
+				MaxUnavailable: lo.ToPtr(intstr.FromInt(0)),
+				Status:         &policyv1beta1.PodDisruptionBudgetStatus{},
+			})
+		})
+		It("should list policy/v1 PDBs if the cluster only serves policy/v1", func() {
+			kubeClient := servingClient([]string{"v1"}, pods[0], pods[1], pods[2], v1PDB, v1beta1PDB)
+			limits, err := deprovisioning.NewPDBLimits(ctx, kubeClient)
+			Expect(err).ToNot(HaveOccurred())
+			key, ok := limits.CanEvictPods(pods)
+			Expect(ok).To(BeFalse())
+			Expect(key).To(Equal(client.ObjectKeyFromObject(v1PDB)))
+		})
+		It("should list policy/v1beta1 PDBs if the cluster only serves policy/v1beta1", func() {
+			kubeClient := servingClient([]string{"v1beta1"}, pods[0], pods[1], pods[2], v1PDB, v1beta1PDB)
+			limits, err := deprovisioning.NewPDBLimits(ctx, kubeClient)
+			Expect(err).ToNot(HaveOccurred())
+			key, ok := limits.CanEvictPods(pods)
+			Expect(ok).To(BeFalse())
+			Expect(key).To(Equal(client.ObjectKeyFromObject(v1beta1PDB)))
+		})
+		It("should allow eviction if the cluster serves neither version", func() {
+			kubeClient := servingClient(nil, pods[0], pods[1], pods[2], v1PDB, v1beta1PDB)
+			limits, err := deprovisioning.NewPDBLimits(ctx, kubeClient)
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := limits.CanEvictPods(pods)
+			Expect(ok).To(BeTrue())
+		})
+		It("should prefer the policy/v1 PDB if the cluster serves both versions", func() {
+			// the cluster returns the same PDB from both versions, and only the policy/v1 representation is tracked
+			v1beta1PDB.Name = v1PDB.Name
+			v1PDB.Spec.MaxUnavailable = lo.ToPtr(intstr.FromString("100%"))
+			kubeClient := servingClient([]string{"v1", "v1beta1"}, pods[0], pods[1], pods[2], v1PDB, v1beta1PDB)
+			limits, err := deprovisioning.NewPDBLimits(ctx, kubeClient)
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := limits.CanEvictPods(pods)
+			Expect(ok).To(BeTrue())
+		})
+		It("should not match any pods with an empty policy/v1beta1 selector", func() {
+			v1beta1PDB.Spec.Selector = &metav1.LabelSelector{}
+			kubeClient := servingClient([]string{"v1beta1"}, pods[0], pods[1], pods[2], v1beta1PDB)
+			limits, err := deprovisioning.NewPDBLimits(ctx, kubeClient)
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := limits.CanEvictPods(pods)
+			Expect(ok).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("Cheapest Instance Type", func() {
+	var small, large *cloudprovider.InstanceType
+	BeforeEach(func() {
+		small = fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "small",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1.0, Available: true},
+				{CapacityType: v1alpha5.CapacityTypeSpot, Zone: "test-zone-1a", Price: 0.5, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+		})
+		large = fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "large",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 4.0, Available: true},
+				{CapacityType: v1alpha5.CapacityTypeSpot, Zone: "test-zone-1a", Price: 2.0, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+		})
+	})
+	It("should choose the cheapest offering of the cheapest instance type that fits the pods", func() {
+		pods := test.Pods(1, test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+		})
+		it, offering, ok := deprovisioning.CheapestInstanceTypeFor(pods, []*cloudprovider.InstanceType{large, small}, scheduling.NewRequirements())
+		Expect(ok).To(BeTrue())
+		Expect(it.Name).To(Equal(small.Name))
+		Expect(offering.CapacityType).To(Equal(v1alpha5.CapacityTypeSpot))
+		Expect(offering.Price).To(BeNumerically("==", 0.5))
+	})
+	It("should choose a larger instance type when the pods don't fit on a smaller one", func() {
+		pods := test.Pods(3, test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+		})
+		it, _, ok := deprovisioning.CheapestInstanceTypeFor(pods, []*cloudprovider.InstanceType{large, small}, scheduling.NewRequirements())
+		Expect(ok).To(BeTrue())
+		Expect(it.Name).To(Equal(large.Name))
+	})
+	It("should only choose offerings that satisfy the requirements", func() {
+		pods := test.Pods(1, test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+		})
+		it, offering, ok := deprovisioning.CheapestInstanceTypeFor(pods, []*cloudprovider.InstanceType{large, small}, scheduling.NewRequirements(
+			scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, v1alpha5.CapacityTypeOnDemand),
+		))
+		Expect(ok).To(BeTrue())
+		Expect(it.Name).To(Equal(small.Name))
+		Expect(offering.CapacityType).To(Equal(v1alpha5.CapacityTypeOnDemand))
+
+		it, _, ok = deprovisioning.CheapestInstanceTypeFor(pods, []*cloudprovider.InstanceType{large, small}, scheduling.NewRequirements(
+			scheduling.NewRequirement(v1.LabelInstanceTypeStable, v1.NodeSelectorOpIn, large.Name),
+		))
+		Expect(ok).To(BeTrue())
+		Expect(it.Name).To(Equal(large.Name))
+	})
+	It("should not choose an instance type if none can fit the pods or satisfy the requirements", func() {
+		pods := test.Pods(10, test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+		})
+		_, _, ok := deprovisioning.CheapestInstanceTypeFor(pods, []*cloudprovider.InstanceType{large, small}, scheduling.NewRequirements())
+		Expect(ok).To(BeFalse())
+
+		_, _, ok = deprovisioning.CheapestInstanceTypeFor(pods[:1], []*cloudprovider.InstanceType{large, small}, scheduling.NewRequirements(
+			scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, "test-zone-1b"),
+		))
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("Replace Nodes", func() {
+	It("can replace node", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		// consolidation won't delete the old node until the new node is ready
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		result, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// should create a new node as there is a cheaper one that can hold the pod
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		// and delete the old one
+		ExpectNotFound(ctx, env.Client, node)
+
+		// the result describes the replacement
+		Expect(result.Result).To(Equal(deprovisioning.ResultSuccess))
+		Expect(result.Action).To(Equal("consolidation/replace"))
+		Expect(result.RemovedNodes).To(ConsistOf(node.Name))
+		Expect(result.ReplacementInstanceTypes).ToNot(BeEmpty())
+		Expect(result.ReplacementInstanceTypes).ToNot(ContainElement(mostExpensiveInstance.Name))
+		Expect(result.CostDelta).To(BeNumerically("<", 0))
+	})
+	It("won't replace a node if the replacement would exceed its provisioner's limits", func() {
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		// the node already uses all of the CPU that the provisioner allows, and it isn't removed until its replacement
+		// is ready
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+			Limits:        v1.ResourceList{v1.ResourceCPU: resource.MustParse("32")},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// no replacement is launched, and the node is left schedulable
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+	})
+	It("taints a node while its replacement is validated and cordons it once the replacement executes", func() {
+		s := test.Settings()
+		s.ValidationTaintEffect = string(v1.TaintEffectPreferNoSchedule)
+		taintCtx := settings.ToContext(ctx, s)
+
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		// capture the node while the command is validated, and again once its replacement has launched
+		var validating, executing v1.Node
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+			Eventually(fakeClock.HasWaiters, 5*time.Second).Should(BeTrue())
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), &validating)).To(Succeed())
+			fakeClock.Step(45 * time.Second)
+
+			var replacement *v1.Node
+			Eventually(func() bool {
+				var nodeList v1.NodeList
+				Expect(env.Client.List(ctx, &nodeList)).To(Succeed())
+				for i := range nodeList.Items {
+					if nodeList.Items[i].Name != node.Name {
+						replacement = &nodeList.Items[i]
+						return true
+					}
+				}
+				return false
+			}, 10*time.Second).Should(BeTrue())
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), &executing)).To(Succeed())
+			ExpectMakeNodesReady(ctx, env.Client, replacement)
+		}()
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(taintCtx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// the node is softly tainted while the command is validated, but still schedulable
+		Expect(validating.Spec.Taints).To(ContainElement(v1.Taint{Key: v1alpha5.DeprovisioningValidationTaintKey, Effect: v1.TaintEffectPreferNoSchedule}))
+		Expect(validating.Spec.Unschedulable).To(BeFalse())
+		// and the taint is replaced by a cordon when the command executes
+		Expect(executing.Spec.Taints).ToNot(ContainElement(HaveField("Key", v1alpha5.DeprovisioningValidationTaintKey)))
+		Expect(executing.Spec.Unschedulable).To(BeTrue())
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("annotates a node with its replacement's instance types before the replacement launches", func() {
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		// capture the node once its replacement has been requested, before the replacement is ready
+		var replaced v1.Node
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+			var replacement *v1.Node
+			Eventually(func() bool {
+				var nodeList v1.NodeList
+				Expect(env.Client.List(ctx, &nodeList)).To(Succeed())
+				for i := range nodeList.Items {
+					if nodeList.Items[i].Name != node.Name {
+						replacement = &nodeList.Items[i]
+						return true
+					}
+				}
+				return false
+			}, 10*time.Second).Should(BeTrue())
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), &replaced)).To(Succeed())
+			ExpectMakeNodesReady(ctx, env.Client, replacement)
+		}()
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// the annotation lists cheaper instance types that the replacement could launch as
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		launchable := lo.Map(cloudProvider.CreateCalls[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })
+		Expect(replaced.Annotations).To(HaveKey(v1alpha5.DeprovisioningReplacementAnnotationKey))
+		annotated := strings.Split(replaced.Annotations[v1alpha5.DeprovisioningReplacementAnnotationKey], ",")
+		Expect(annotated).ToNot(BeEmpty())
+		Expect(launchable).To(ContainElements(annotated))
+		Expect(annotated).ToNot(ContainElement(mostExpensiveInstance.Name))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("won't replace a node when the consolidation policy is delete-only", func() {
+		s := test.Settings()
+		s.ConsolidationPolicy = settings.ConsolidationPolicyDeleteOnly
+		ctx = settings.ToContext(ctx, s)
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// there is a cheaper node that can hold the pod, but launching it isn't allowed
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectExists(ctx, env.Client, node)
+	})
+	It("can replace nodes, considers PDB", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		pdb := test.PodDisruptionBudget(test.PDBOptions{
+			Labels:         labels,
+			MaxUnavailable: fromInt(0),
+			Status: &policyv1beta1.PodDisruptionBudgetStatus{
+				ObservedGeneration: 1,
+				DisruptionsAllowed: 0,
+				CurrentHealthy:     1,
+				DesiredHealthy:     1,
+				ExpectedPods:       1,
+			},
+		})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, prov, pdb)
+		ExpectApplied(ctx, env.Client, node1)
+		// all pods on node1
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		ExpectManualBinding(ctx, env.Client, pods[2], node1)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need a new node
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// and can't delete the node due to the PDB
+		ExpectNodeExists(ctx, env.Client, node1.Name)
+	})
+	It("can replace nodes, considers do-not-consolidate annotation", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		regularNode := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			},
+		})
+
+		annotatedNode := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1alpha5.DoNotConsolidateNodeAnnotationKey: "true",
+				},
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], prov)
+		ExpectApplied(ctx, env.Client, regularNode, annotatedNode)
+		ExpectApplied(ctx, env.Client, regularNode, annotatedNode)
+		ExpectMakeNodesReady(ctx, env.Client, regularNode, annotatedNode)
+		ExpectManualBinding(ctx, env.Client, pods[0], regularNode)
+		ExpectManualBinding(ctx, env.Client, pods[1], regularNode)
+		ExpectManualBinding(ctx, env.Client, pods[2], annotatedNode)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(regularNode))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(annotatedNode))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
 		// we should delete the non-annotated node
 		ExpectNotFound(ctx, env.Client, regularNode)
 	})
-	It("won't replace node if any spot replacement is more expensive", func() {
-		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
-			Name: "current-on-demand",
-			Offerings: []cloudprovider.Offering{
+	It("won't replace node if any spot replacement is more expensive", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.5,
+					Available:    false,
+				},
+			},
+		})
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "potential-spot-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1a",
+					Price:        1.0,
+					Available:    true,
+				},
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1b",
+					Price:        0.2,
+					Available:    true,
+				},
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1c",
+					Price:        0.4,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			replacementInstance,
+		}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("replaces a node using the only viable offering of an instance type, even if it isn't the first", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1b",
+					Price:        1.0,
+					Available:    true,
+				},
+			},
+		})
+		// the first two offerings are cheaper, but one is unavailable and the other is in a zone the pod can't use
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "replacement-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1b",
+					Price:        0.05,
+					Available:    false,
+				},
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.1,
+					Available:    true,
+				},
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1b",
+					Price:        0.5,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			replacementInstance,
+		}
+
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}},
+			NodeSelector: map[string]string{v1.LabelTopologyZone: "test-zone-1b"},
+		})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+					v1.LabelTopologyZone:             "test-zone-1b",
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// the replacement launches with its third offering, which is the only one that's available in the pod's zone
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(lo.Map(cloudProvider.CreateCalls[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf(replacementInstance.Name))
+		Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1.LabelTopologyZone).Values()).To(ConsistOf("test-zone-1b"))
+		Expect(cloudProvider.CreatedNodes).To(HaveLen(1))
+		Expect(cloudProvider.CreatedNodes[0].Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-1b"))
+		ExpectNotFound(ctx, env.Client, node)
+		// and the projected cost is sized by the same offering
+		projection := deprovisioningController.LastCostProjection()
+		Expect(projection.Current).To(BeNumerically("~", 1.0, 1e-9))
+		Expect(projection.Projected).To(BeNumerically("~", 0.5, 1e-9))
+	})
+	It("won't replace an on-demand node with cheaper spot capacity when capacity types are preserved", func() {
+		s := test.Settings()
+		s.PreserveCapacityType = true
+		ctx = settings.ToContext(ctx, s)
+
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.5,
+					Available:    false,
+				},
+			},
+		})
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "cheaper-spot-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1a",
+					Price:        0.2,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			replacementInstance,
+		}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("won't replace an on-demand node with spot capacity whose on-demand fallback is more expensive", func() {
+		s := test.Settings()
+		s.ConsolidationSpotFallbackWeight = 1
+		fallbackCtx := settings.ToContext(ctx, s)
+
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.5,
+					Available:    false,
+				},
+			},
+		})
+		// the replacement is cheaper if it launches as spot, but more expensive if it falls back to on-demand
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "mixed-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1a",
+					Price:        0.2,
+					Available:    true,
+				},
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.6,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			replacementInstance,
+		}
+
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(fallbackCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("reports the requirement that eliminated every replacement instance type", func() {
+		s := test.Settings()
+		s.PreserveCapacityType = true
+		ctx = settings.ToContext(ctx, s)
+
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.5,
+					Available:    false,
+				},
+			},
+		})
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "cheaper-spot-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1a",
+					Price:        0.2,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			replacementInstance,
+		}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+
+		// only the preserved on-demand capacity type prevents the spot instance type from replacing the node
+		var messages []string
+		recorder.ForEachEvent(func(evt events.Event) {
+			if evt.Reason == "DeprovisioningNoFeasibleReplacement" && evt.InvolvedObject.(*v1.Node).Name == node.Name {
+				messages = append(messages, evt.Message)
+			}
+		})
+		Expect(messages).ToNot(BeEmpty())
+		for _, message := range messages {
+			Expect(message).To(HaveSuffix(fmt.Sprintf("%s In [%s] yields 0 instance types", v1alpha5.LabelCapacityType, v1alpha5.CapacityTypeOnDemand)))
+			Expect(message).ToNot(ContainSubstring(v1.LabelTopologyZone))
+		}
+	})
+	It("replaces an on-demand node with cheaper spot capacity when the provisioner allows capacity type changes", func() {
+		s := test.Settings()
+		s.PreserveCapacityType = true
+		ctx = settings.ToContext(ctx, s)
+
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.5,
+					Available:    false,
+				},
+			},
+		})
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "cheaper-spot-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1a",
+					Price:        0.2,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			replacementInstance,
+		}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		prov.Annotations = map[string]string{v1alpha5.AllowCapacityTypeChangeAnnotationKey: "true"}
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		// consolidation won't delete the old node until the new node is ready
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1alpha5.LabelCapacityType).Has(v1alpha5.CapacityTypeSpot)).To(BeTrue())
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("replaces an on-demand node with spot capacity in preference to cheaper on-demand capacity", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.5,
+					Available:    false,
+				},
+			},
+		})
+		// provisioning prefers spot, so the spot replacement is chosen even though the on-demand one is cheaper
+		spotInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "spot-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1a",
+					Price:        0.3,
+					Available:    true,
+				},
+			},
+		})
+		onDemandInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "on-demand-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.2,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			spotInstance,
+			onDemandInstance,
+		}
+
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		// consolidation won't delete the old node until the new node is ready
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1alpha5.LabelCapacityType).Values()).To(ConsistOf(v1alpha5.CapacityTypeSpot))
+		Expect(lo.Map(cloudProvider.CreateCalls[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })).
+			To(ConsistOf(spotInstance.Name))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("replaces a spot node with on-demand capacity when no spot capacity can replace it", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1a",
+					Price:        0.5,
+					Available:    false,
+				},
+			},
+		})
+		onDemandInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "on-demand-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.2,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			onDemandInstance,
+		}
+
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		// consolidation won't delete the old node until the new node is ready
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1alpha5.LabelCapacityType).Values()).To(ConsistOf(v1alpha5.CapacityTypeOnDemand))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("won't replace on-demand node if on-demand replacement is more expensive", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.5,
+					Available:    false,
+				},
+			},
+		})
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "on-demand-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.6,
+					Available:    true,
+				},
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1b",
+					Price:        0.6,
+					Available:    true,
+				},
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1b",
+					Price:        0.2,
+					Available:    true,
+				},
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1c",
+					Price:        0.3,
+					Available:    true,
+				},
+			},
+		})
+
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			replacementInstance,
+		}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		// provisioner should require on-demand instance for this test case
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+			Requirements: []v1.NodeSelectorRequirement{
+				{
+					Key:      v1alpha5.LabelCapacityType,
+					Operator: v1.NodeSelectorOpIn,
+					Values:   []string{v1alpha5.CapacityTypeOnDemand},
+				},
+			},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("replaces windows nodes with windows nodes sized for windows daemonsets", func() {
+		windowsOffering := func(price float64) []cloudprovider.Offering {
+			return []cloudprovider.Offering{{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: price, Available: true}}
+		}
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:             "current-windows",
+			OperatingSystems: sets.NewString(string(v1.Windows)),
+			Offerings:        windowsOffering(1.0),
+			Resources:        map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+		})
+		linuxInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:             "small-linux",
+			OperatingSystems: sets.NewString(string(v1.Linux)),
+			Offerings:        windowsOffering(0.1),
+			Resources:        map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		smallWindowsInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:             "small-windows",
+			OperatingSystems: sets.NewString(string(v1.Windows)),
+			Offerings:        windowsOffering(0.3),
+			Resources:        map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+		})
+		mediumWindowsInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:             "medium-windows",
+			OperatingSystems: sets.NewString(string(v1.Windows)),
+			Offerings:        windowsOffering(0.5),
+			Resources:        map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			linuxInstance,
+			smallWindowsInstance,
+			mediumWindowsInstance,
+		}
+
+		// the linux daemonset is large enough that if it were counted, the replacement wouldn't fit on small-windows
+		linuxDaemonSet := test.DaemonSet(test.DaemonSetOptions{PodOptions: test.PodOptions{
+			NodeSelector:         map[string]string{v1.LabelOSStable: string(v1.Linux)},
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1.5")}},
+		}})
+		windowsDaemonSet := test.DaemonSet(test.DaemonSetOptions{PodOptions: test.PodOptions{
+			NodeSelector:         map[string]string{v1.LabelOSStable: string(v1.Windows)},
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("0.5")}},
+		}})
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		// the pod doesn't select an OS, so the OS of the replacement is determined by the node that it's on
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}},
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+		})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+					v1.LabelOSStable:                 string(v1.Windows),
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov, linuxDaemonSet, windowsDaemonSet)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		// consolidation won't delete the old node until the new node is ready
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// should only consider windows instance types, and should size the replacement with only the windows daemonset
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(lo.Map(cloudProvider.CreateCalls[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })).
+			To(ConsistOf(smallWindowsInstance.Name, mediumWindowsInstance.Name))
+		Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1.LabelOSStable).Values()).To(ConsistOf(string(v1.Windows)))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("won't cordon or replace a node if the replacement can't be created", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		// we're already at the instance limit
+		cloudProvider.InstanceLimit = 0
+		blockedEvents := recorder.Calls("DeprovisioningLaunchBlocked")
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we shouldn't have tried to launch a replacement
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// and the node should still exist without being cordoned
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+		Expect(recorder.Calls("DeprovisioningLaunchBlocked")).To(BeNumerically(">", blockedEvents))
+	})
+	It("defers consolidation after a replacement fails to launch", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		// the replacement launch fails
+		cloudProvider.AllowedCreateCalls = 0
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+
+		// launches work again, but consolidation is deferred since the remaining capacity is uncertain
+		cloudProvider.AllowedCreateCalls = math.MaxInt
+		result, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+
+		// once the suppression period has passed, consolidation resumes
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(5 * time.Minute)
+		go triggerVerifyAction()
+		_, err = deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(2))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("tags the instance of a cordoned node and untags it when the replacement fails to launch", func() {
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			ProviderID:  "fake://tagged",
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		// the replacement launch fails
+		cloudProvider.AllowedCreateCalls = 0
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).To(HaveOccurred())
+
+		// the instance was tagged when the node was cordoned, and untagged when it was uncordoned
+		Expect(cloudProvider.TagCalls).To(Equal([]string{"fake://tagged"}))
+		Expect(cloudProvider.UntagCalls).To(Equal([]string{"fake://tagged"}))
+		Expect(cloudProvider.Tags).ToNot(HaveKey("fake://tagged"))
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+	})
+	It("won't replace a node with one that can't satisfy a pod's required node affinity", func() {
+		// only the current (large) instance type has the special label, so the cheaper (small) instance can't run the pod
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-large",
+			Resources: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:    resource.MustParse("8"),
+				v1.ResourceMemory: resource.MustParse("16Gi"),
+			},
+		})
+		cheapInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "cheap-small",
+			Resources: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, cheapInstance}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		p := test.Pod(test.PodOptions{
+			NodeRequirements: []v1.NodeSelectorRequirement{
+				{Key: fake.ExoticInstanceLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{"optional"}},
+			},
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+					fake.ExoticInstanceLabelKey:      "optional",
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, p, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, p, node)
+		ExpectScheduled(ctx, env.Client, p)
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the cheaper instance type doesn't have the label that the pod requires, so the node is left alone
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("replaces a node with one in the zone that a pod requires", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "current",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+			Offerings: []cloudprovider.Offering{{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1b", Price: 2, Available: true}},
+		})
+		// the cheapest instance type is only offered in a zone that the pod can't run in
+		otherZoneInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "other-zone",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+			Offerings: []cloudprovider.Offering{{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 0.5, Available: true}},
+		})
+		sameZoneInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "same-zone",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+			Offerings: []cloudprovider.Offering{{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1b", Price: 1, Available: true}},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, otherZoneInstance, sameZoneInstance}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		p := test.Pod(test.PodOptions{
+			NodeRequirements: []v1.NodeSelectorRequirement{
+				{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1b"}},
+			},
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+					v1.LabelTopologyZone:             "test-zone-1b",
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, p, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, p, node)
+		ExpectScheduled(ctx, env.Client, p)
+
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// the hypothetical replacement is labeled with the zone of its offerings, so it satisfies the pod's node selector
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(lo.Map(cloudProvider.CreateCalls[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })).
+			To(ConsistOf(sameZoneInstance.Name))
+		Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1.LabelTopologyZone).Values()).To(ConsistOf("test-zone-1b"))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("replaces a node in the zone that a pod's waiting volume was selected for", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "current",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+			Offerings: []cloudprovider.Offering{{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 2, Available: true}},
+		})
+		// the cheapest instance type is only offered in a zone that the storage class allows, but that the volume
+		// won't be provisioned in
+		otherZoneInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "other-zone",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+			Offerings: []cloudprovider.Offering{{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-2", Price: 0.5, Available: true}},
+		})
+		sameZoneInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "same-zone",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+			Offerings: []cloudprovider.Offering{{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 1, Available: true}},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, otherZoneInstance, sameZoneInstance}
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+					v1.LabelTopologyZone:             "test-zone-1",
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+		})
+
+		// the volume hasn't been provisioned yet, but the node has been selected for it
+		storageClass := test.StorageClass(test.StorageClassOptions{
+			Zones:             []string{"test-zone-1", "test-zone-2"},
+			VolumeBindingMode: lo.ToPtr(storagev1.VolumeBindingWaitForFirstConsumer),
+		})
+		pvc := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{
+			ObjectMeta:       metav1.ObjectMeta{Annotations: map[string]string{"volume.kubernetes.io/selected-node": node.Name}},
+			StorageClassName: &storageClass.Name,
+		})
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		p := test.Pod(test.PodOptions{
+			PersistentVolumeClaims: []string{pvc.Name},
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		ExpectApplied(ctx, env.Client, rs, storageClass, pvc, p, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, p, node)
+		ExpectScheduled(ctx, env.Client, p)
+
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// the replacement has to be in the zone that the volume will be provisioned in
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(lo.Map(cloudProvider.CreateCalls[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })).
+			To(ConsistOf(sameZoneInstance.Name))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("replaces nodes with instance types from the settings allow-list", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "current",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+		})
+		cheapestInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "cheapest",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+		})
+		allowedInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "allowed",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, cheapestInstance, allowedInstance}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		p := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+					v1.LabelTopologyZone:             "test-zone-1",
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, p, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, p, node)
+		ExpectScheduled(ctx, env.Client, p)
+
+		// the cheapest instance type isn't allowed as a replacement
+		s := test.Settings()
+		s.ReplacementInstanceTypes = sets.NewString(currentInstance.Name, allowedInstance.Name)
+		allowListCtx := settings.ToContext(ctx, s)
+
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(allowListCtx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(lo.Map(cloudProvider.CreateCalls[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })).
+			To(ConsistOf(allowedInstance.Name))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("evaluates each node against the min savings percent of its own provisioner", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1.0, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		// the replacement saves 30% of the price of the current instance type
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "replacement-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 0.7, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, replacementInstance}
+
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		// each pod needs most of a node, so the nodes can't be merged
+		pods := test.Pods(2, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}},
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("3")},
+			},
+		})
+
+		// the global setting would allow both replacements, but the provisioners override it
+		s := test.Settings()
+		s.MinConsolidationSavingsPercent = 20
+		ctx = settings.ToContext(ctx, s)
+		tolerantProv := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true), MinSavingsPercent: ptr.Int32(10)},
+		})
+		strictProv := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true), MinSavingsPercent: ptr.Int32(50)},
+		})
+		nodeFor := func(prov *v1alpha5.Provisioner) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       currentInstance.Name,
+						v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+						v1.LabelTopologyZone:             "test-zone-1a",
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+			})
+		}
+		tolerantNode := nodeFor(tolerantProv)
+		strictNode := nodeFor(strictProv)
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], tolerantNode, strictNode, tolerantProv, strictProv)
+		ExpectMakeNodesReady(ctx, env.Client, tolerantNode, strictNode)
+		ExpectManualBinding(ctx, env.Client, pods[0], tolerantNode)
+		ExpectManualBinding(ctx, env.Client, pods[1], strictNode)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(tolerantNode))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(strictNode))
+
+		// consolidation won't delete the old node until the new node is ready
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, tolerantNode, strictNode)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// 30% savings satisfies the tolerant provisioner, but not the strict one
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		ExpectNotFound(ctx, env.Client, tolerantNode)
+		ExpectNodeExists(ctx, env.Client, strictNode.Name)
+	})
+	It("detects and suppresses replacements that reverse a recent replacement when prices flap", func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1.0, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "replacement-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 0.5, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, replacementInstance}
+
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		// each pod needs most of a node, so consolidation can only replace nodes
+		pods := test.Pods(2, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}},
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("3")},
+			},
+		})
+
+		s := test.Settings()
+		s.SuppressConsolidationOscillation = true
+		ctx = settings.ToContext(ctx, s)
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+					v1.LabelTopologyZone:             "test-zone-1a",
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		ExpectApplied(ctx, env.Client, rs, pods[0], node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectManualBinding(ctx, env.Client, pods[0], node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+
+		// the cheaper instance type replaces the node
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		ExpectNotFound(ctx, env.Client, node)
+
+		var nodes v1.NodeList
+		Expect(env.Client.List(ctx, &nodes)).To(Succeed())
+		Expect(nodes.Items).To(HaveLen(1))
+		replacement := &nodes.Items[0]
+		Expect(replacement.Labels[v1.LabelInstanceTypeStable]).To(Equal(replacementInstance.Name))
+		ExpectApplied(ctx, env.Client, pods[1])
+		ExpectManualBinding(ctx, env.Client, pods[1], replacement)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(replacement))
+		// Wait for the nomination cache to expire
+		time.Sleep(time.Second * 11)
+
+		// the prices flap, so the original instance type is now the cheaper one
+		currentInstance.Offerings[0].Price = 0.5
+		replacementInstance.Offerings[0].Price = 1.0
+		oscillations := consolidationOscillations()
+		fakeClock.Step(10 * time.Minute)
+		go func() {
+			triggerVerifyAction()
+			triggerVerifyAction()
+		}()
+		result, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+
+		// replacing the node again would reverse the first replacement, so it's reported and skipped
+		Expect(consolidationOscillations()).To(BeNumerically(">", oscillations))
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		ExpectNodeExists(ctx, env.Client, replacement.Name)
+	})
+	Context("Minimum Savings Percent", func() {
+		var prov *v1alpha5.Provisioner
+		var currentInstance *cloudprovider.InstanceType
+		BeforeEach(func() {
+			prov = test.Provisioner(test.ProvisionerOptions{
+				Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true), MinimumSavingsPercent: ptr.Float64(5)},
+			})
+			currentInstance = fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name: "current-on-demand",
+				Offerings: []cloudprovider.Offering{
+					{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1.0, Available: true},
+				},
+				Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+			})
+		})
+		// nodeWith offers a replacement at the price, relative to the current instance type's price of 1, and returns a
+		// node of the current instance type with the pods
+		nodeWith := func(replacementPrice float64, pods ...*v1.Pod) *v1.Node {
+			cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name: "replacement-on-demand",
+				Offerings: []cloudprovider.Offering{
+					{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: replacementPrice, Available: true},
+				},
+				Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+			})}
+			node := test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       currentInstance.Name,
+						v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+						v1.LabelTopologyZone:             "test-zone-1a",
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+			})
+			ExpectApplied(ctx, env.Client, node, prov)
+			ExpectMakeNodesReady(ctx, env.Client, node)
+			for _, p := range pods {
+				ExpectApplied(ctx, env.Client, p)
+				ExpectManualBinding(ctx, env.Client, p, node)
+			}
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			return node
+		}
+		processCluster := func() {
+			fakeClock.Step(10 * time.Minute)
+			go triggerVerifyAction()
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		podWithReplicaSet := func() *v1.Pod {
+			rs := test.ReplicaSet()
+			ExpectApplied(ctx, env.Client, rs)
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+			return test.Pod(test.PodOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         "apps/v1",
+							Kind:               "ReplicaSet",
+							Name:               rs.Name,
+							UID:                rs.UID,
+							Controller:         ptr.Bool(true),
+							BlockOwnerDeletion: ptr.Bool(true),
+						},
+					}},
+				ResourceRequirements: v1.ResourceRequirements{
+					Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("3")},
+				},
+			})
+		}
+		It("should not replace a node if the savings are below the provisioner's minimum savings percent", func() {
+			// the replacement saves 3%
+			node := nodeWith(0.97, podWithReplicaSet())
+			processCluster()
+			Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+			ExpectNodeExists(ctx, env.Client, node.Name)
+		})
+		It("should replace a node if the savings meet the provisioner's minimum savings percent", func() {
+			// the replacement saves 10%
+			node := nodeWith(0.9, podWithReplicaSet())
+			// consolidation won't delete the old node until the new node is ready
+			wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+			processCluster()
+			wg.Wait()
+			Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should compare the savings to a fractional minimum savings percent", func() {
+			prov.Spec.Consolidation.MinimumSavingsPercent = ptr.Float64(2.5)
+			// the replacement saves 3%
+			node := nodeWith(0.97, podWithReplicaSet())
+			wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+			processCluster()
+			wg.Wait()
+			Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should delete empty nodes regardless of the provisioner's minimum savings percent", func() {
+			// deleting the node saves all of its price, but no replacement is cheap enough either
+			node := nodeWith(0.99)
+			processCluster()
+			Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+	})
+	It("waits for node deletion to finish", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{"unit-test.com/block-deletion"},
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+
+		// consolidation won't delete the old node until the new node is ready
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+
+		var consolidationFinished atomic.Bool
+		go triggerVerifyAction()
+		go func() {
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			consolidationFinished.Store(true)
+		}()
+		wg.Wait()
+
+		// node should still exist
+		ExpectNodeExists(ctx, env.Client, node.Name)
+		// and consolidation should still be running waiting on the node's deletion
+		Expect(consolidationFinished.Load()).To(BeFalse())
+
+		// fetch the latest node object and remove the finalizer
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+		node.SetFinalizers([]string{})
+		Expect(env.Client.Update(ctx, node)).To(Succeed())
+
+		// consolidation should complete now that the finalizer on the node is gone and it can
+		// was actually deleted
+		Eventually(consolidationFinished.Load, 10*time.Second).Should(BeTrue())
+		ExpectNotFound(ctx, env.Client, node)
+
+		// should create a new node as there is a cheaper one that can hold the pod
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Delete Node", func() {
+	It("can delete nodes", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need a new node, but we should evict everything off one of node2 which only has a single pod
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// and delete the old one
+		ExpectNotFound(ctx, env.Client, node2)
+	})
+	It("should report displaced pods that don't reschedule after deleting a node", func() {
+		s := test.Settings()
+		s.RescheduleVerificationThreshold = metav1.Duration{Duration: 5 * time.Minute}
+		verifyCtx := settings.ToContext(ctx, s)
+
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		ownerRefs := []metav1.OwnerReference{
+			{
+				APIVersion:         "apps/v1",
+				Kind:               "ReplicaSet",
+				Name:               rs.Name,
+				UID:                rs.UID,
+				Controller:         ptr.Bool(true),
+				BlockOwnerDeletion: ptr.Bool(true),
+			},
+		}
+		pods := test.Pods(2, test.PodOptions{ObjectMeta: metav1.ObjectMeta{OwnerReferences: ownerRefs}})
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		nodes := lo.Times(2, func(_ int) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}})
+		})
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes[0], nodes[1])
+		ExpectManualBinding(ctx, env.Client, pods[0], nodes[0])
+		ExpectManualBinding(ctx, env.Client, pods[1], nodes[1])
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[0]))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[1]))
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(verifyCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		Expect(lo.Filter(nodes, func(n *v1.Node, _ int) bool {
+			return errors.IsNotFound(env.Client.Get(ctx, client.ObjectKeyFromObject(n), &v1.Node{}))
+		})).To(HaveLen(1))
+
+		// the ReplicaSet recreates the displaced pod, but it can't schedule to the remaining node
+		recreated := test.UnschedulablePod(test.PodOptions{ObjectMeta: metav1.ObjectMeta{OwnerReferences: ownerRefs}})
+		ExpectApplied(ctx, env.Client, recreated)
+		_, err = deprovisioningController.ProcessCluster(verifyCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("DeprovisioningRescheduleMismatch")).To(Equal(0))
+
+		// and is reported by the first pass once the threshold has passed
+		fakeClock.Step(5 * time.Minute)
+		_, err = deprovisioningController.ProcessCluster(verifyCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("DeprovisioningRescheduleMismatch")).To(Equal(1))
+		_, err = deprovisioningController.ProcessCluster(verifyCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("DeprovisioningRescheduleMismatch")).To(Equal(1))
+	})
+	It("can delete nodes, prefers keeping nodes with heavily weighted resources", func() {
+		cpuInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "cpu",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+		gpuInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "gpu",
+			Resources: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:          resource.MustParse("32"),
+				fake.ResourceGPUVendorA: resource.MustParse("1"),
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{cpuInstance, gpuInstance}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(2, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		// the nodes are equivalent apart from the GPU, and each runs a single pod
+		var nodes []*v1.Node
+		for _, instanceType := range []*cloudprovider.InstanceType{gpuInstance, cpuInstance} {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       instanceType.Name,
+						v1alpha5.LabelCapacityType:       instanceType.Offerings[0].CapacityType,
+						v1.LabelTopologyZone:             instanceType.Offerings[0].Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectScheduled(ctx, env.Client, pods[i])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+
+		s := test.Settings()
+		s.ConsolidationPolicy = settings.ConsolidationPolicyDeleteOnly
+		s.DisruptionCostResourceWeights = map[string]float64{string(fake.ResourceGPUVendorA): 10}
+		weightedCtx := settings.ToContext(ctx, s)
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(weightedCtx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the GPU makes its node more costly to disrupt, so the CPU node is removed instead
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, nodes[0].Name)
+		ExpectNotFound(ctx, env.Client, nodes[1])
+	})
+	It("can delete nodes, prefers keeping nodes covered by a committed discount", func() {
+		committedInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "committed",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 1.0, Available: true, CommittedDiscount: 0.4},
+			},
+		})
+		onDemandInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name:      "on-demand",
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 1.0, Available: true},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{committedInstance, onDemandInstance}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(2, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		// the nodes have the same list price, but only the first is covered by a committed discount
+		var nodes []*v1.Node
+		for _, instanceType := range []*cloudprovider.InstanceType{committedInstance, onDemandInstance} {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       instanceType.Name,
+						v1alpha5.LabelCapacityType:       instanceType.Offerings[0].CapacityType,
+						v1.LabelTopologyZone:             instanceType.Offerings[0].Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectScheduled(ctx, env.Client, pods[i])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+
+		s := test.Settings()
+		s.ConsolidationPolicy = settings.ConsolidationPolicyDeleteOnly
+		deleteOnlyCtx := settings.ToContext(ctx, s)
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(deleteOnlyCtx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the committed node is effectively cheaper to keep, so the on-demand node is removed instead
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, nodes[0].Name)
+		ExpectNotFound(ctx, env.Client, nodes[1])
+	})
+	It("won't delete a node if its pods could only reschedule to a cordoned node", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+		// node2 has room for the pod, but it's cordoned by an in-flight deprovisioning command
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+				Annotations: map[string]string{
+					v1alpha5.DoNotConsolidateNodeAnnotationKey: "true",
+					v1alpha5.DeprovisioningCordonAnnotationKey: "true",
+				}},
+			Unschedulable: true,
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		ExpectManualBinding(ctx, env.Client, pod, node1)
+		ExpectScheduled(ctx, env.Client, pod)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+
+		s := test.Settings()
+		s.ConsolidationPolicy = settings.ConsolidationPolicyDeleteOnly
+		deleteOnlyCtx := settings.ToContext(ctx, s)
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(deleteOnlyCtx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the pod can't reschedule to the cordoned node, so node1 can't be deleted
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node1.Name)
+		ExpectNodeExists(ctx, env.Client, node2.Name)
+	})
+	It("won't delete a node if it would drop the cluster's capacity below the floor", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(2, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		var nodes []*v1.Node
+		for i := 0; i < 2; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectScheduled(ctx, env.Client, pods[i])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+
+		// either node's pod fits on the other, but removing either node leaves less CPU than the floor
+		s := test.Settings()
+		s.ConsolidationPolicy = settings.ConsolidationPolicyDeleteOnly
+		s.ConsolidationCapacityFloor = v1.ResourceList{v1.ResourceCPU: resource.MustParse("48")}
+		floorCtx := settings.ToContext(ctx, s)
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(floorCtx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, nodes[0].Name)
+		ExpectNodeExists(ctx, env.Client, nodes[1].Name)
+	})
+	It("records an event on the existing node that displaced pods are rescheduled to", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// node2 is deleted without a replacement, so its pod is rescheduled to node1
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, node2)
+		var targets []string
+		recorder.ForEachEvent(func(evt events.Event) {
+			if evt.Reason == "DeprovisioningRescheduleTarget" {
+				targets = append(targets, evt.InvolvedObject.(*v1.Node).Name)
+			}
+		})
+		Expect(targets).To(ConsistOf(node1.Name))
+	})
+	It("records pod count as the resource blocking consolidation when existing nodes have no pod capacity left", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		// the pods select a label that the provisioner doesn't define, so they can only reschedule to existing nodes
+		pods := test.Pods(2, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}},
+			NodeSelector: map[string]string{"example.com/pool": "a"},
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			},
+		})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		var nodes []*v1.Node
+		for i := 0; i < 2; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+						"example.com/pool":               "a",
+					}},
+				// plenty of CPU, but room for only a single pod
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("1"),
+				}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes[0], nodes[1])
+		ExpectManualBinding(ctx, env.Client, pods[0], nodes[0])
+		ExpectManualBinding(ctx, env.Client, pods[1], nodes[1])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[0]))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[1]))
+		blockedByPods := blockedByResource(string(v1.ResourcePods))
+		blockedByCPU := blockedByResource(string(v1.ResourceCPU))
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// neither node can be removed, and it's the pod count rather than CPU that prevents it
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, nodes[0].Name)
+		ExpectNodeExists(ctx, env.Client, nodes[1].Name)
+		Expect(blockedByResource(string(v1.ResourcePods))).To(BeNumerically(">", blockedByPods))
+		Expect(blockedByResource(string(v1.ResourceCPU))).To(Equal(blockedByCPU))
+	})
+	It("projects the cluster cost after deleting a node", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		var nodes []*v1.Node
+		for i := 0; i < 2; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		ExpectManualBinding(ctx, env.Client, pods[0], nodes[0])
+		ExpectManualBinding(ctx, env.Client, pods[1], nodes[0])
+		ExpectManualBinding(ctx, env.Client, pods[2], nodes[1])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[0]))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[1]))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the node with a single pod is deleted without a replacement, halving the cost of the cluster
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, nodes[1])
+		projection := deprovisioningController.LastCostProjection()
+		Expect(projection.Current).To(BeNumerically("~", 2*leastExpensiveOffering.Price, 1e-9))
+		Expect(projection.Projected).To(BeNumerically("~", leastExpensiveOffering.Price, 1e-9))
+	})
+	It("won't repeatedly disrupt the pods of a recently disrupted owner", func() {
+		s := test.Settings()
+		s.OwnerDisruptionLimit = 1
+		s.OwnerDisruptionWindow = metav1.Duration{Duration: time.Hour}
+		ctx = settings.ToContext(ctx, s)
+
+		// each node can only hold two of the pods, so only a single node can be deleted at a time
+		instanceType := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "default-instance-type",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{instanceType}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}},
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		var nodes []*v1.Node
+		for i := 0; i < 3; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       instanceType.Name,
+						v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+						v1.LabelTopologyZone:             "test-zone-1a",
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], nodes[0], nodes[1], nodes[2], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+		fakeClock.Step(10 * time.Minute)
+
+		countNodes := func() int {
+			var nodeList v1.NodeList
+			Expect(env.Client.List(ctx, &nodeList)).To(Succeed())
+			return len(nodeList.Items)
+		}
+		processCluster := func() {
+			go triggerVerifyAction()
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			for _, n := range nodes {
+				ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(n))
+			}
+		}
+
+		// the first node is deleted which disrupts the replica set
+		processCluster()
+		Expect(countNodes()).To(Equal(2))
+
+		// the replica set has been disrupted as many times as the limit allows within the window, so the remaining
+		// nodes are protected even though they could be consolidated
+		fakeClock.Step(10 * time.Minute)
+		processCluster()
+		Expect(countNodes()).To(Equal(2))
+
+		// once the disruption decays out of the window, the replica set can be disrupted again
+		fakeClock.Step(time.Hour)
+		processCluster()
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		Expect(countNodes()).To(Equal(1))
+	})
+	It("won't delete nodes when pod overhead prevents their pods from fitting on the remaining nodes", func() {
+		// there is a single instance type at the same price as the existing nodes, so they can't be replaced
+		instanceType := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "default-instance-type",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{instanceType}
+
+		runtimeClass := &nodev1.RuntimeClass{
+			ObjectMeta: metav1.ObjectMeta{Name: test.RandomName()},
+			Handler:    "test-handler",
+			Overhead:   &nodev1.Overhead{PodFixed: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")}},
+		}
+		ExpectApplied(ctx, env.Client, runtimeClass)
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		// the container requests of both pods fit on a single node, but not once the pod overhead is included
+		pods := test.Pods(2, test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}},
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+		for _, p := range pods {
+			p.Spec.RuntimeClassName = ptr.String(runtimeClass.Name)
+		}
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		var nodes []*v1.Node
+		for i := 0; i < 2; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       instanceType.Name,
+						v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+						v1.LabelTopologyZone:             "test-zone-1a",
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+		Expect(ExpectPodExists(ctx, env.Client, pods[0].Name, pods[0].Namespace).Spec.Overhead).ToNot(BeEmpty())
+
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectExists(ctx, env.Client, nodes[0])
+		ExpectExists(ctx, env.Client, nodes[1])
+	})
+	It("won't reschedule pods onto a node beyond its live allocatable", func() {
+		// there is a single instance type at the same price as the existing nodes, so they can't be replaced
+		instanceType := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "default-instance-type",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{instanceType}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := lo.Map([]string{"1", "2"}, func(cpu string, _ int) *v1.Pod {
+			return test.Pod(test.PodOptions{
+				ResourceRequirements: v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse(cpu)}},
+				ObjectMeta: metav1.ObjectMeta{Labels: labels,
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         "apps/v1",
+							Kind:               "ReplicaSet",
+							Name:               rs.Name,
+							UID:                rs.UID,
+							Controller:         ptr.Bool(true),
+							BlockOwnerDeletion: ptr.Bool(true),
+						},
+					}}})
+		})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		// the kubelet on the first node reserved more than expected after it joined, so only half of the instance type's
+		// nominal capacity is allocatable. It isn't a candidate itself, only a place that pods could be rescheduled to.
+		var nodes []*v1.Node
+		for _, allocatable := range []string{"2", "4"} {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       instanceType.Name,
+						v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+						v1.LabelTopologyZone:             "test-zone-1a",
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse(allocatable)}}))
+		}
+		nodes[0].Annotations = lo.Assign(nodes[0].Annotations, map[string]string{v1alpha5.DoNotConsolidateNodeAnnotationKey: "true"})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+
+		// the second node's pod would fit in the first node's nominal capacity, but not in what is actually allocatable
+		fakeClock.Step(10 * time.Minute)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectExists(ctx, env.Client, nodes[0])
+		ExpectExists(ctx, env.Client, nodes[1])
+	})
+	It("can delete nodes, considers PDB", func() {
+		var nl v1.NodeList
+		Expect(env.Client.List(ctx, &nl)).To(Succeed())
+		Expect(nl.Items).To(HaveLen(0))
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		// only pod[2] is covered by the PDB
+		pods[2].Labels = labels
+		pdb := test.PodDisruptionBudget(test.PDBOptions{
+			Labels:         labels,
+			MaxUnavailable: fromInt(0),
+			Status: &policyv1beta1.PodDisruptionBudgetStatus{
+				ObservedGeneration: 1,
+				DisruptionsAllowed: 0,
+				CurrentHealthy:     1,
+				DesiredHealthy:     1,
+				ExpectedPods:       1,
+			},
+		})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov, pdb)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		// two pods on node 1
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		// one on node 2, but it has a PDB with zero disruptions allowed
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need a new node
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// but we expect to delete the node with more pods (node1) as the pod on node2 has a PDB preventing
+		// eviction
+		ExpectNotFound(ctx, env.Client, node1)
+	})
+	It("can delete nodes, considers do-not-evict", func() {
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		// only pod[2] has a do not evict annotation
+		pods[2].Annotations = map[string]string{
+			v1alpha5.DoNotEvictPodAnnotationKey: "true",
+		}
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		// two pods on node 1
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		// one on node 2, but it has a do-not-evict annotation
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need a new node
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// but we expect to delete the node with more pods (node1) as the pod on node2 has a do-not-evict annotation
+		ExpectNotFound(ctx, env.Client, node1)
+	})
+	It("can delete nodes, considers the drain exclusion selector", func() {
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		// only pod[2] is selected by the drain exclusion selector
+		pods[2].Labels = map[string]string{"app": "monitoring"}
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		// two pods on node 1
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		// one on node 2, but it is excluded from drain
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		s := test.Settings()
+		s.DrainExclusionSelector = labels.SelectorFromSet(labels.Set{"app": "monitoring"})
+		exclusionCtx := settings.ToContext(ctx, s)
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(exclusionCtx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need a new node
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// but we expect to delete the node with more pods (node1) as the pod on node2 is excluded from drain
+		ExpectNotFound(ctx, env.Client, node1)
+		ExpectNodeExists(ctx, env.Client, node2.Name)
+	})
+	It("can delete nodes, considers indexed job pods that spread their completion indexes", func() {
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		// only pod[2] is an indexed job pod that spreads its completion indexes across nodes
+		pods[2].Annotations = map[string]string{
+			batchv1.JobCompletionIndexAnnotation: "0",
+		}
+		pods[2].Spec.TopologySpreadConstraints = []v1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       v1.LabelHostname,
+			WhenUnsatisfiable: v1.DoNotSchedule,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"job": "indexed"}},
+		}}
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		// two pods on node 1
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		// one on node 2, but it belongs to an indexed job
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need a new node
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// but we expect to delete the node with more pods (node1) as the pod on node2 belongs to an indexed job
+		ExpectNotFound(ctx, env.Client, node1)
+	})
+	It("can delete nodes, considers scheduling gates", func() {
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		// two pods on node 1
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		// one on node 2, but it's gated so it can't be rescheduled
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+		pods[2] = ExpectPodExists(ctx, env.Client, pods[2].Name, pods[2].Namespace)
+		pods[2].Status.Conditions = []v1.PodCondition{{Type: v1.PodScheduled, Reason: pod.PodReasonSchedulingGated, Status: v1.ConditionFalse}}
+		Expect(env.Client.Status().Update(ctx, pods[2])).To(Succeed())
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need a new node
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// but we expect to delete the node with more pods (node1) as the pod on node2 has scheduling gates
+		ExpectNotFound(ctx, env.Client, node1)
+	})
+	It("can delete nodes, considers cluster-autoscaler safe-to-evict: false", func() {
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		// only pod[2] has a safe-to-evict: false annotation
+		pods[2].Annotations = map[string]string{
+			pod.SafeToEvictAnnotationKey: "false",
+		}
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		// two pods on node 1
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		// one on node 2, but it has a safe-to-evict: false annotation
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		s := test.Settings()
+		s.HonorSafeToEvictAnnotation = true
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(settings.ToContext(ctx, s))
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need a new node
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// but we expect to delete the node with more pods (node1) as the pod on node2 has a safe-to-evict: false annotation
+		ExpectNotFound(ctx, env.Client, node1)
+		ExpectNodeExists(ctx, env.Client, node2.Name)
+	})
+	It("can replace nodes, cluster-autoscaler safe-to-evict: true overrides do-not-evict", func() {
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		p := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1alpha5.DoNotEvictPodAnnotationKey: "true",
+					pod.SafeToEvictAnnotationKey:        "true",
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+
+		ExpectApplied(ctx, env.Client, rs, p, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, p, node)
+		ExpectScheduled(ctx, env.Client, p)
+
+		s := test.Settings()
+		s.HonorSafeToEvictAnnotation = true
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(settings.ToContext(ctx, s))
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// the pod is safe to evict, so the node is replaced with a cheaper one
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("can delete nodes, evicts pods without an ownerRef", func() {
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		// pod[2] is a stand-alone (non ReplicaSet) pod
+		pods[2].OwnerReferences = nil
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		// two pods on node 1
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		// one on node 2, but it's a standalone pod
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need a new node
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// but we expect to delete the node with the fewest pods (node 2) even though the pod has no ownerRefs
+		// and will not be recreated
+		ExpectNotFound(ctx, env.Client, node2)
+	})
+})
+
+var _ = Describe("Node Lifetime Consideration", func() {
+	It("should consider node lifetime remaining when calculating disruption cost", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		// node1 is the node that's closest to expiring
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation:          &v1alpha5.Consolidation{Enabled: ptr.Bool(true), MinNodeAgeSeconds: ptr.Int64(0)},
+			TTLSecondsUntilExpired: ptr.Int64(3),
+		})
+		longLivedProv := test.Provisioner(test.ProvisionerOptions{
+			Consolidation:          &v1alpha5.Consolidation{Enabled: ptr.Bool(true), MinNodeAgeSeconds: ptr.Int64(0)},
+			TTLSecondsUntilExpired: ptr.Int64(int64((24 * time.Hour).Seconds())),
+		})
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: longLivedProv.Name,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov, longLivedProv)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		fakeClock.SetTime(node1.CreationTimestamp.Add(2 * time.Second))
+		// two pods on node 1, one on node 2
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the first node has more pods so it would normally not be picked for consolidation, except it has very little
+		// lifetime remaining so it should be deleted
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, node1)
+	})
+})
+
+var _ = Describe("Topology Consideration", func() {
+	It("can replace node maintaining zonal topology spread", func() {
+		labels := map[string]string{
+			"app": "test-zonal-spread",
+		}
+
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		tsc := v1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       v1.LabelTopologyZone,
+			WhenUnsatisfiable: v1.DoNotSchedule,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+		}
+		pods := test.Pods(4, test.PodOptions{
+			ResourceRequirements:      v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}},
+			TopologySpreadConstraints: []v1.TopologySpreadConstraint{tsc},
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		testZone1Instance := leastExpensiveInstanceWithZone("test-zone-1")
+		testZone2Instance := mostExpensiveInstanceWithZone("test-zone-2")
+		testZone3Instance := leastExpensiveInstanceWithZone("test-zone-3")
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		zone1Node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelTopologyZone:             "test-zone-1",
+					v1.LabelInstanceTypeStable:       testZone1Instance.Name,
+					v1alpha5.LabelCapacityType:       testZone1Instance.Offerings[0].CapacityType,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
+
+		zone2Node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelTopologyZone:             "test-zone-2",
+					v1.LabelInstanceTypeStable:       testZone2Instance.Name,
+					v1alpha5.LabelCapacityType:       testZone2Instance.Offerings[0].CapacityType,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
+
+		zone3Node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelTopologyZone:             "test-zone-3",
+					v1.LabelInstanceTypeStable:       testZone3Instance.Name,
+					v1alpha5.LabelCapacityType:       testZone1Instance.Offerings[0].CapacityType,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], zone1Node, zone2Node, zone3Node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, zone1Node, zone2Node, zone3Node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone1Node))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone2Node))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone3Node))
+		ExpectManualBinding(ctx, env.Client, pods[0], zone1Node)
+		ExpectManualBinding(ctx, env.Client, pods[1], zone2Node)
+		ExpectManualBinding(ctx, env.Client, pods[2], zone3Node)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		ExpectSkew(ctx, env.Client, "default", &tsc).To(ConsistOf(1, 1, 1))
+
+		// consolidation won't delete the old node until the new node is ready
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, zone1Node, zone2Node, zone3Node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// should create a new node as there is a cheaper one that can hold the pod
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+
+		// we need to emulate the replicaset deprovisioningController and bind a new pod to the newly created node
+		ExpectApplied(ctx, env.Client, pods[3])
+		var nodes v1.NodeList
+		Expect(env.Client.List(ctx, &nodes)).To(Succeed())
+		Expect(nodes.Items).To(HaveLen(3))
+		for i, n := range nodes.Items {
+			// bind the pod to the new node we don't recognize as it is the one that consolidation created
+			if n.Name != zone1Node.Name && n.Name != zone2Node.Name && n.Name != zone3Node.Name {
+				ExpectManualBinding(ctx, env.Client, pods[3], &nodes.Items[i])
+			}
+		}
+		// we should maintain our skew, the new node must be in the same zone as the old node it replaced
+		ExpectSkew(ctx, env.Client, "default", &tsc).To(ConsistOf(1, 1, 1))
+	})
+	It("won't delete node if it would violate pod anti-affinity", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}},
+			PodAntiRequirements: []v1.PodAffinityTerm{
 				{
-					CapacityType: v1alpha5.CapacityTypeOnDemand,
-					Zone:         "test-zone-1a",
-					Price:        0.5,
-					Available:    false,
+					LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
+					TopologyKey:   v1.LabelHostname,
 				},
 			},
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		testZone1Instance := leastExpensiveInstanceWithZone("test-zone-1")
+		testZone2Instance := leastExpensiveInstanceWithZone("test-zone-2")
+		testZone3Instance := leastExpensiveInstanceWithZone("test-zone-3")
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
-		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
-			Name: "potential-spot-replacement",
-			Offerings: []cloudprovider.Offering{
-				{
-					CapacityType: v1alpha5.CapacityTypeSpot,
-					Zone:         "test-zone-1a",
-					Price:        1.0,
-					Available:    true,
-				},
-				{
-					CapacityType: v1alpha5.CapacityTypeSpot,
-					Zone:         "test-zone-1b",
-					Price:        0.2,
-					Available:    true,
-				},
-				{
-					CapacityType: v1alpha5.CapacityTypeSpot,
-					Zone:         "test-zone-1c",
-					Price:        0.4,
-					Available:    true,
+		zone1Node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelTopologyZone:             "test-zone-1",
+					v1.LabelInstanceTypeStable:       testZone1Instance.Name,
+					v1alpha5.LabelCapacityType:       testZone1Instance.Offerings[0].CapacityType,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
+
+		zone2Node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelTopologyZone:             "test-zone-2",
+					v1.LabelInstanceTypeStable:       testZone2Instance.Name,
+					v1alpha5.LabelCapacityType:       testZone2Instance.Offerings[0].CapacityType,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
+
+		zone3Node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelTopologyZone:             "test-zone-3",
+					v1.LabelInstanceTypeStable:       testZone3Instance.Name,
+					v1alpha5.LabelCapacityType:       testZone3Instance.Offerings[0].CapacityType,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], zone1Node, zone2Node, zone3Node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, zone1Node, zone2Node, zone3Node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone1Node))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone2Node))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone3Node))
+		ExpectManualBinding(ctx, env.Client, pods[0], zone1Node)
+		ExpectManualBinding(ctx, env.Client, pods[1], zone2Node)
+		ExpectManualBinding(ctx, env.Client, pods[2], zone3Node)
+		ExpectScheduled(ctx, env.Client, pods[0])
+		ExpectScheduled(ctx, env.Client, pods[1])
+		ExpectScheduled(ctx, env.Client, pods[2])
+
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, zone1Node, zone2Node, zone3Node)
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// our nodes are already the cheapest available, so we can't replace them.  If we delete, it would
+		// violate the anti-affinity rule so we can't do anything.
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, zone1Node.Name)
+		ExpectNodeExists(ctx, env.Client, zone2Node.Name)
+		ExpectNodeExists(ctx, env.Client, zone3Node.Name)
+
+	})
+})
+
+var _ = Describe("Empty Nodes", func() {
+	It("can delete empty nodes with consolidation", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelNodeInitialized:    "true",
 				},
 			},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, node1, prov)
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need any new nodes
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// and should delete the empty one
+		ExpectNotFound(ctx, env.Client, node1)
+	})
+	It("can delete a node whose only pod is crash looping", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+		pod := test.Pod(test.PodOptions{
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.NewTime(fakeClock.Now())}},
 		})
-		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
-			currentInstance,
-			replacementInstance,
-		}
+		pod.Status.ContainerStatuses = []v1.ContainerStatus{{
+			Name:  "crashing",
+			State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		}}
+
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelNodeInitialized:    "true",
+				},
+			},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		ExpectApplied(ctx, env.Client, pod, node1, prov)
+		ExpectManualBinding(ctx, env.Client, pod, node1)
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+
+		s := test.Settings()
+		s.CrashLoopingPodWindow = metav1.Duration{Duration: 5 * time.Minute}
+		crashLoopCtx := settings.ToContext(ctx, s)
+
+		// the pod has been crash looping for longer than the window, so the node is empty
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(crashLoopCtx)
+		Expect(err).ToNot(HaveOccurred())
 
-		labels := map[string]string{
-			"app": "test",
-		}
+		// we don't need any new nodes
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, node1)
+	})
+	It("reschedules crash looping pods along with the other pods of a consolidated node", func() {
 		// create our RS so we can link a pod to it
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		ownerRefs := []metav1.OwnerReference{
+			{
+				APIVersion:         "apps/v1",
+				Kind:               "ReplicaSet",
+				Name:               rs.Name,
+				UID:                rs.UID,
+				Controller:         ptr.Bool(true),
+				BlockOwnerDeletion: ptr.Bool(true),
+			},
+		}
 
-		pod := test.Pod(test.PodOptions{
-			ObjectMeta: metav1.ObjectMeta{Labels: labels,
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion:         "apps/v1",
-						Kind:               "ReplicaSet",
-						Name:               rs.Name,
-						UID:                rs.UID,
-						Controller:         ptr.Bool(true),
-						BlockOwnerDeletion: ptr.Bool(true),
-					},
-				}}})
-
-		prov := test.Provisioner(test.ProvisionerOptions{
-			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+		nodeOf := func(labels map[string]string) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: lo.Assign(map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelNodeInitialized:    "true",
+					}, labels),
+				},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}})
+		}
+		// the crash looping pod can only run on the node that it's on
+		node1, node2 := nodeOf(map[string]string{"example.com/pinned": "true"}), nodeOf(nil)
+		crashLoopingPod := test.Pod(test.PodOptions{
+			ObjectMeta:   metav1.ObjectMeta{OwnerReferences: ownerRefs},
+			NodeSelector: map[string]string{"example.com/pinned": "true"},
+			Conditions:   []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.NewTime(fakeClock.Now())}},
 		})
-		node := test.Node(test.NodeOptions{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       currentInstance.Name,
-					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
-					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
-				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+		crashLoopingPod.Status.ContainerStatuses = []v1.ContainerStatus{{
+			Name:  "crashing",
+			State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		}}
+		pod := test.Pod(test.PodOptions{ObjectMeta: metav1.ObjectMeta{OwnerReferences: ownerRefs}})
+		// the other node can't be deprovisioned
+		doNotEvictPod := test.Pod(test.PodOptions{ObjectMeta: metav1.ObjectMeta{
+			OwnerReferences: ownerRefs,
+			Annotations:     map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
+		}})
+
+		ExpectApplied(ctx, env.Client, crashLoopingPod, pod, doNotEvictPod, node1, node2, prov)
+		ExpectManualBinding(ctx, env.Client, crashLoopingPod, node1)
+		ExpectManualBinding(ctx, env.Client, pod, node1)
+		ExpectManualBinding(ctx, env.Client, doNotEvictPod, node2)
 
-		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
-		ExpectMakeNodesReady(ctx, env.Client, node)
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
-		ExpectManualBinding(ctx, env.Client, pod, node)
-		ExpectScheduled(ctx, env.Client, pod)
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
 
+		s := test.Settings()
+		s.CrashLoopingPodWindow = metav1.Duration{Duration: 5 * time.Minute}
+		crashLoopCtx := settings.ToContext(ctx, s)
+
+		// the other pod would fit on the other node, but the crash looping pod has nowhere to go
 		fakeClock.Step(10 * time.Minute)
 		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
+		_, err := deprovisioningController.ProcessCluster(crashLoopCtx)
 		Expect(err).ToNot(HaveOccurred())
+
 		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		ExpectNodeExists(ctx, env.Client, node.Name)
+		ExpectNodeExists(ctx, env.Client, node1.Name)
+		ExpectNodeExists(ctx, env.Client, node2.Name)
 	})
-	It("won't replace on-demand node if on-demand replacement is more expensive", func() {
-		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
-			Name: "current-on-demand",
-			Offerings: []cloudprovider.Offering{
-				{
-					CapacityType: v1alpha5.CapacityTypeOnDemand,
-					Zone:         "test-zone-1a",
-					Price:        0.5,
-					Available:    false,
-				},
-			},
-		})
-		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
-			Name: "on-demand-replacement",
-			Offerings: []cloudprovider.Offering{
-				{
-					CapacityType: v1alpha5.CapacityTypeOnDemand,
-					Zone:         "test-zone-1a",
-					Price:        0.6,
-					Available:    true,
-				},
-				{
-					CapacityType: v1alpha5.CapacityTypeOnDemand,
-					Zone:         "test-zone-1b",
-					Price:        0.6,
-					Available:    true,
-				},
-				{
-					CapacityType: v1alpha5.CapacityTypeSpot,
-					Zone:         "test-zone-1b",
-					Price:        0.2,
-					Available:    true,
+	It("keeps a provisioner's warm spare empty nodes", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true), WarmSpareNodes: ptr.Int32(1)}})
+
+		var nodes []*v1.Node
+		for i := 0; i < 3; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelNodeInitialized:    "true",
+					},
 				},
-				{
-					CapacityType: v1alpha5.CapacityTypeSpot,
-					Zone:         "test-zone-1c",
-					Price:        0.3,
-					Available:    true,
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
+		ExpectApplied(ctx, env.Client, nodes[0], nodes[1], nodes[2], prov)
+		for _, node := range nodes {
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		}
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// two of the empty nodes are deleted, and the last is kept as warm capacity
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		Expect(lo.Filter(nodes, func(n *v1.Node, _ int) bool {
+			return env.Client.Get(ctx, client.ObjectKeyFromObject(n), &v1.Node{}) == nil
+		})).To(HaveLen(1))
+
+		// and it isn't consolidated by a later pass either
+		fakeClock.Step(10 * time.Minute)
+		result, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+	})
+	It("defers consolidation for a cool-down after a large scale-up", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+
+		var nodes []*v1.Node
+		for i := 0; i < 3; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelNodeInitialized:    "true",
+					},
 				},
-			},
-		})
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
+		ExpectApplied(ctx, env.Client, nodes[0], nodes[1], nodes[2], prov)
+		for _, node := range nodes {
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		}
 
-		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
-			currentInstance,
-			replacementInstance,
+		s := test.Settings()
+		s.ConsolidationScaleUpCooldown = metav1.Duration{Duration: 15 * time.Minute}
+		s.ConsolidationScaleUpThreshold = 3
+		cooldownCtx := settings.ToContext(ctx, s)
+
+		// all of the nodes launched within the cool-down, so consolidation is deferred
+		fakeClock.Step(10 * time.Minute)
+		result, err := deprovisioningController.ProcessCluster(cooldownCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+		for _, node := range nodes {
+			ExpectNodeExists(ctx, env.Client, node.Name)
 		}
 
-		labels := map[string]string{
-			"app": "test",
+		// once the cool-down has passed, the empty nodes are consolidated
+		fakeClock.Step(6 * time.Minute)
+		go triggerVerifyAction()
+		_, err = deprovisioningController.ProcessCluster(cooldownCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		for _, node := range nodes {
+			ExpectNotFound(ctx, env.Client, node)
 		}
-		// create our RS so we can link a pod to it
+	})
+	It("aborts deleting an empty node that becomes non-empty during validation", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelNodeInitialized:    "true",
+				},
+			},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
-
 		pod := test.Pod(test.PodOptions{
-			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+			ObjectMeta: metav1.ObjectMeta{
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -906,129 +6224,116 @@ var _ = Describe("Replace Nodes", func() {
 					},
 				}}})
 
-		// provisioner should require on-demand instance for this test case
-		prov := test.Provisioner(test.ProvisionerOptions{
-			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
-			Requirements: []v1.NodeSelectorRequirement{
-				{
-					Key:      v1alpha5.LabelCapacityType,
-					Operator: v1.NodeSelectorOpIn,
-					Values:   []string{v1alpha5.CapacityTypeOnDemand},
-				},
-			},
-		})
-		node := test.Node(test.NodeOptions{
+		ExpectApplied(ctx, env.Client, node1, prov)
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		fakeClock.Step(10 * time.Minute)
+		aborted := abortedActions("became-non-empty")
+
+		// a pod is bound to the node while consolidation waits to validate the deletion
+		go func() {
+			defer GinkgoRecover()
+			for i := 0; i < 10 && !fakeClock.HasWaiters(); i++ {
+				time.Sleep(250 * time.Millisecond)
+			}
+			ExpectApplied(ctx, env.Client, pod)
+			ExpectManualBinding(ctx, env.Client, pod, node1)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+			fakeClock.Step(45 * time.Second)
+		}()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		ExpectNodeExists(ctx, env.Client, node1.Name)
+		Expect(abortedActions("became-non-empty")).To(Equal(aborted + 1))
+	})
+	It("can delete multiple empty nodes with consolidation", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+
+		node1 := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       currentInstance.Name,
-					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
-					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+		node2 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
 
-		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
-		ExpectMakeNodesReady(ctx, env.Client, node)
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
-		ExpectManualBinding(ctx, env.Client, pod, node)
-		ExpectScheduled(ctx, env.Client, pod)
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+		ExpectApplied(ctx, env.Client, node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
 
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
 		fakeClock.Step(10 * time.Minute)
 		go triggerVerifyAction()
 		_, err := deprovisioningController.ProcessCluster(ctx)
 		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need any new nodes
 		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		ExpectNodeExists(ctx, env.Client, node.Name)
+		// and should delete both empty ones
+		ExpectNotFound(ctx, env.Client, node1)
+		ExpectNotFound(ctx, env.Client, node2)
 	})
-	It("waits for node deletion to finish", func() {
-		labels := map[string]string{
-			"app": "test",
-		}
-		// create our RS so we can link a pod to it
-		rs := test.ReplicaSet()
-		ExpectApplied(ctx, env.Client, rs)
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
-
-		pod := test.Pod(test.PodOptions{
-			ObjectMeta: metav1.ObjectMeta{Labels: labels,
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion:         "apps/v1",
-						Kind:               "ReplicaSet",
-						Name:               rs.Name,
-						UID:                rs.UID,
-						Controller:         ptr.Bool(true),
-						BlockOwnerDeletion: ptr.Bool(true),
-					},
-				}}})
+	It("can delete empty nodes with TTLSecondsAfterEmpty with the emptiness timestamp", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{TTLSecondsAfterEmpty: ptr.Int64(10)})
 
-		prov := test.Provisioner(test.ProvisionerOptions{
-			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
-		})
 		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
-				Finalizers: []string{"unit-test.com/block-deletion"},
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
 					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+				Annotations: map[string]string{
+					v1alpha5.EmptinessTimestampAnnotationKey: fakeClock.Now().Format(time.RFC3339),
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
-
-		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+		ExpectApplied(ctx, env.Client, prov, node)
 		ExpectMakeNodesReady(ctx, env.Client, node)
+
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
-		ExpectManualBinding(ctx, env.Client, pod, node)
-		ExpectScheduled(ctx, env.Client, pod)
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
 
-		// consolidation won't delete the old node until the new node is ready
-		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
 		fakeClock.Step(10 * time.Minute)
-
-		var consolidationFinished atomic.Bool
 		go triggerVerifyAction()
-		go func() {
-			_, err := deprovisioningController.ProcessCluster(ctx)
-			Expect(err).ToNot(HaveOccurred())
-			consolidationFinished.Store(true)
-		}()
-		wg.Wait()
-
-		// node should still exist
-		ExpectNodeExists(ctx, env.Client, node.Name)
-		// and consolidation should still be running waiting on the node's deletion
-		Expect(consolidationFinished.Load()).To(BeFalse())
-
-		// fetch the latest node object and remove the finalizer
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
-		node.SetFinalizers([]string{})
-		Expect(env.Client.Update(ctx, node)).To(Succeed())
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
 
-		// consolidation should complete now that the finalizer on the node is gone and it can
-		// was actually deleted
-		Eventually(consolidationFinished.Load, 10*time.Second).Should(BeTrue())
+		// we don't need any new nodes
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// and should delete both empty ones
 		ExpectNotFound(ctx, env.Client, node)
-
-		// should create a new node as there is a cheaper one that can hold the pod
-		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
 	})
-})
-
-var _ = Describe("Delete Node", func() {
-	It("can delete nodes", func() {
-		labels := map[string]string{
-			"app": "test",
-		}
+	It("can delete idle nodes with TTLSecondsAfterEmpty when a usage source is available", func() {
 		// create our RS so we can link a pod to it
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
-		pods := test.Pods(3, test.PodOptions{
-			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+		pods := test.Pods(2, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -1039,72 +6344,67 @@ var _ = Describe("Delete Node", func() {
 						BlockOwnerDeletion: ptr.Bool(true),
 					},
 				}}})
-
-		prov := test.Provisioner(test.ProvisionerOptions{
-			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
-		})
-		node1 := test.Node(test.NodeOptions{
+		prov := test.Provisioner(test.ProvisionerOptions{TTLSecondsAfterEmpty: ptr.Int64(30)})
+		idleNode := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
 			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
+				v1.ResourceCPU:    resource.MustParse("32"),
+				v1.ResourceMemory: resource.MustParse("64Gi"),
+				v1.ResourcePods:   resource.MustParse("100"),
 			}})
-
-		node2 := test.Node(test.NodeOptions{
+		busyNode := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
 			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
+				v1.ResourceCPU:    resource.MustParse("32"),
+				v1.ResourceMemory: resource.MustParse("64Gi"),
+				v1.ResourcePods:   resource.MustParse("100"),
 			}})
+		usageSource := fakeUsageSource{
+			idleNode.Name: {v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("256Mi")},
+			busyNode.Name: {v1.ResourceCPU: resource.MustParse("16"), v1.ResourceMemory: resource.MustParse("32Gi")},
+		}
+		deprovisioningController = deprovisioningController.WithUsageSource(usageSource)
 
-		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
-		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
-
-		ExpectManualBinding(ctx, env.Client, pods[0], node1)
-		ExpectManualBinding(ctx, env.Client, pods[1], node1)
-		ExpectManualBinding(ctx, env.Client, pods[2], node2)
-		ExpectScheduled(ctx, env.Client, pods[0])
-		ExpectScheduled(ctx, env.Client, pods[1])
-		ExpectScheduled(ctx, env.Client, pods[2])
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], prov, idleNode, busyNode)
+		ExpectMakeNodesReady(ctx, env.Client, idleNode, busyNode)
+		ExpectManualBinding(ctx, env.Client, pods[0], idleNode)
+		ExpectManualBinding(ctx, env.Client, pods[1], busyNode)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(idleNode))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(busyNode))
 
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
-		fakeClock.Step(10 * time.Minute)
-		go triggerVerifyAction()
+		// the node has only just been observed as idle, so it isn't deleted yet
 		_, err := deprovisioningController.ProcessCluster(ctx)
 		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, idleNode.Name)
 
-		// we don't need a new node, but we should evict everything off one of node2 which only has a single pod
+		// once it has been idle for TTLSecondsAfterEmpty, it's treated as empty and its pod can move to the busy node
+		fakeClock.Step(time.Minute)
+		_, err = deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need any new nodes
 		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// and delete the old one
-		ExpectNotFound(ctx, env.Client, node2)
+		ExpectNotFound(ctx, env.Client, idleNode)
+		ExpectNodeExists(ctx, env.Client, busyNode.Name)
 	})
-	It("can delete nodes, considers PDB", func() {
-		var nl v1.NodeList
-		Expect(env.Client.List(ctx, &nl)).To(Succeed())
-		Expect(nl.Items).To(HaveLen(0))
-		labels := map[string]string{
-			"app": "test",
-		}
-		// create our RS so we can link a pod to it
+	It("forgets when a node became idle once it leaves the cluster", func() {
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
-		pods := test.Pods(3, test.PodOptions{
+		pod := test.Pod(test.PodOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				OwnerReferences: []metav1.OwnerReference{
 					{
@@ -1116,83 +6416,223 @@ var _ = Describe("Delete Node", func() {
 						BlockOwnerDeletion: ptr.Bool(true),
 					},
 				}}})
+		prov := test.Provisioner(test.ProvisionerOptions{TTLSecondsAfterEmpty: ptr.Int64(30)})
+		// idleNode returns a node with the name, so that a node that replaces one that left the cluster can reuse it
+		idleNode := func(name string) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:    resource.MustParse("32"),
+					v1.ResourceMemory: resource.MustParse("64Gi"),
+					v1.ResourcePods:   resource.MustParse("100"),
+				}})
+		}
+		node := idleNode(test.RandomName())
+		// the busy node has room for the idle node's pod
+		busyNode := idleNode(test.RandomName())
+		deprovisioningController = deprovisioningController.WithUsageSource(fakeUsageSource{
+			node.Name:     {v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("256Mi")},
+			busyNode.Name: {v1.ResourceCPU: resource.MustParse("16"), v1.ResourceMemory: resource.MustParse("32Gi")},
+		})
 
-		// only pod[2] is covered by the PDB
-		pods[2].Labels = labels
-		pdb := test.PodDisruptionBudget(test.PDBOptions{
-			Labels:         labels,
-			MaxUnavailable: fromInt(0),
-			Status: &policyv1.PodDisruptionBudgetStatus{
-				ObservedGeneration: 1,
-				DisruptionsAllowed: 0,
-				CurrentHealthy:     1,
-				DesiredHealthy:     1,
-				ExpectedPods:       1,
+		ExpectApplied(ctx, env.Client, rs, pod, prov, node, busyNode)
+		ExpectMakeNodesReady(ctx, env.Client, node, busyNode)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(busyNode))
+
+		// the node is observed as idle, and then leaves the cluster before its TTL
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectDeleted(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		fakeClock.Step(20 * time.Second)
+		_, err = deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// a node with the same name joins, and is only deleted once it has been idle for the TTL itself
+		replacement := idleNode(node.Name)
+		ExpectApplied(ctx, env.Client, replacement)
+		ExpectMakeNodesReady(ctx, env.Client, replacement)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(replacement))
+		fakeClock.Step(20 * time.Second)
+		_, err = deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, replacement.Name)
+
+		fakeClock.Step(time.Minute)
+		_, err = deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNotFound(ctx, env.Client, replacement)
+	})
+	It("considers pending pods when consolidating", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("128"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		// there is a pending pod that should land on the node
+		pod := test.UnschedulablePod(test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU: resource.MustParse("1"),
+				},
+			},
+		})
+		unsched := test.UnschedulablePod(test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU: resource.MustParse("125"),
+				},
 			},
 		})
 
+		ExpectApplied(ctx, env.Client, node1, pod, unsched, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node1)
+		ExpectManualBinding(ctx, env.Client, pod, node1)
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// we don't need any new nodes and consolidation should notice the huge pending pod that needs the large
+		// node to schedule, which prevents the large expensive node from being replaced
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNodeExists(ctx, env.Client, node1.Name)
+	})
+})
+
+var _ = Describe("consolidation TTL", func() {
+	It("should wait for the node TTL for empty nodes before consolidating", func() {
 		prov := test.Provisioner(test.ProvisionerOptions{
 			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
+
 		node1 := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
-				}},
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelNodeInitialized:    "true",
+				},
+			},
 			Allocatable: map[v1.ResourceName]resource.Quantity{
 				v1.ResourceCPU:  resource.MustParse("32"),
 				v1.ResourcePods: resource.MustParse("100"),
 			}})
 
-		node2 := test.Node(test.NodeOptions{
+		ExpectApplied(ctx, env.Client, node1, prov)
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		// the nodes must be old enough to be consolidated
+		fakeClock.Step(10 * time.Minute)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		finished := atomic.Bool{}
+		go func() {
+			defer wg.Done()
+			defer finished.Store(true)
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// the controller blocks until the validation timeout has passed, and should then finish
+		ExpectConsolidationValidated(ctx, fakeClock, deprovisioningController)
+		Eventually(finished.Load, 10*time.Second).Should(BeTrue())
+		wg.Wait()
+
+		// we don't need any new nodes
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// and should delete the empty one
+		ExpectNotFound(ctx, env.Client, node1)
+	})
+	It("should wait for the configured validation period before consolidating", func() {
+		s := test.Settings()
+		s.ConsolidationValidationPeriod = metav1.Duration{Duration: 2 * time.Minute}
+		periodCtx := settings.ToContext(ctx, s)
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node1 := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
-				}},
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelNodeInitialized:    "true",
+				},
+			},
 			Allocatable: map[v1.ResourceName]resource.Quantity{
 				v1.ResourceCPU:  resource.MustParse("32"),
 				v1.ResourcePods: resource.MustParse("100"),
 			}})
 
-		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov, pdb)
-		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
-		// two pods on node 1
-		ExpectManualBinding(ctx, env.Client, pods[0], node1)
-		ExpectManualBinding(ctx, env.Client, pods[1], node1)
-		// one on node 2, but it has a PDB with zero disruptions allowed
-		ExpectManualBinding(ctx, env.Client, pods[2], node2)
-		ExpectScheduled(ctx, env.Client, pods[0])
-		ExpectScheduled(ctx, env.Client, pods[1])
-		ExpectScheduled(ctx, env.Client, pods[2])
+		ExpectApplied(ctx, env.Client, node1, prov)
 
 		// inform cluster state about the nodes
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		// the nodes must be old enough to be consolidated
 		fakeClock.Step(10 * time.Minute)
-		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
-		Expect(err).ToNot(HaveOccurred())
+		var wg sync.WaitGroup
+		wg.Add(1)
+		finished := atomic.Bool{}
+		go func() {
+			defer wg.Done()
+			defer finished.Store(true)
+			_, err := deprovisioningController.ProcessCluster(periodCtx)
+			Expect(err).ToNot(HaveOccurred())
+		}()
 
-		// we don't need a new node
-		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// but we expect to delete the node with more pods (node1) as the pod on node2 has a PDB preventing
-		// eviction
+		// the controller is still blocked once the default validation period has passed
+		Eventually(fakeClock.HasWaiters).WithTimeout(10 * time.Second).Should(BeTrue())
+		fakeClock.Step(45 * time.Second)
+		Consistently(finished.Load, time.Second).Should(BeFalse())
+		ExpectNodeExists(ctx, env.Client, node1.Name)
+
+		// and finishes once the configured period has passed
+		Expect(deprovisioningController.ValidationTimeout()).To(Equal(2 * time.Minute))
+		fakeClock.Step(2 * time.Minute)
+		Eventually(finished.Load, 10*time.Second).Should(BeTrue())
+		wg.Wait()
 		ExpectNotFound(ctx, env.Client, node1)
 	})
-	It("can delete nodes, considers do-not-evict", func() {
+	It("should wait for the node TTL for non-empty nodes before consolidating", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
 		// create our RS so we can link a pod to it
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
 		pods := test.Pods(3, test.PodOptions{
-			ObjectMeta: metav1.ObjectMeta{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -1204,11 +6644,6 @@ var _ = Describe("Delete Node", func() {
 					},
 				}}})
 
-		// only pod[2] has a do not evict annotation
-		pods[2].Annotations = map[string]string{
-			v1alpha5.DoNotEvictPodAnnotationKey: "true",
-		}
-
 		prov := test.Provisioner(test.ProvisionerOptions{
 			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
@@ -1229,9 +6664,9 @@ var _ = Describe("Delete Node", func() {
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
 				}},
 			Allocatable: map[v1.ResourceName]resource.Quantity{
 				v1.ResourceCPU:  resource.MustParse("32"),
@@ -1240,10 +6675,9 @@ var _ = Describe("Delete Node", func() {
 
 		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
 		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
-		// two pods on node 1
+
 		ExpectManualBinding(ctx, env.Client, pods[0], node1)
 		ExpectManualBinding(ctx, env.Client, pods[1], node1)
-		// one on node 2, but it has a do-not-evict annotation
 		ExpectManualBinding(ctx, env.Client, pods[2], node2)
 		ExpectScheduled(ctx, env.Client, pods[0])
 		ExpectScheduled(ctx, env.Client, pods[1])
@@ -1252,24 +6686,100 @@ var _ = Describe("Delete Node", func() {
 		// inform cluster state about the nodes
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		// the nodes must be old enough to be consolidated
 		fakeClock.Step(10 * time.Minute)
-		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
-		Expect(err).ToNot(HaveOccurred())
+		var wg sync.WaitGroup
+		wg.Add(1)
+		finished := atomic.Bool{}
+		go func() {
+			defer wg.Done()
+			defer finished.Store(true)
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+		}()
 
-		// we don't need a new node
+		// the controller blocks until the validation timeout has passed, and should then finish
+		ExpectConsolidationValidated(ctx, fakeClock, deprovisioningController)
+		Eventually(finished.Load, 10*time.Second).Should(BeTrue())
+		wg.Wait()
+
+		// we don't need any new nodes
 		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// but we expect to delete the node with more pods (node1) as the pod on node2 has a do-not-evict annotation
-		ExpectNotFound(ctx, env.Client, node1)
+		// and should delete the empty one
+		ExpectNotFound(ctx, env.Client, node2)
 	})
-	It("can delete nodes, evicts pods without an ownerRef", func() {
+	It("should not consolidate if the action becomes invalid during the node TTL wait", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+
+		node1 := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelNodeInitialized:    "true",
+				},
+			},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+
+		pod := test.Pod()
+		ExpectApplied(ctx, env.Client, node1, prov, pod)
+
+		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		// the nodes must be old enough to be consolidated
+		fakeClock.Step(10 * time.Minute)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		finished := atomic.Bool{}
+		go func() {
+			defer wg.Done()
+			defer finished.Store(true)
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		// wait for the deprovisioningController to block on the validation timeout
+		Eventually(fakeClock.HasWaiters, time.Second*10).Should(BeTrue())
+		// controller should be blocking during the timeout
+		Expect(finished.Load()).To(BeFalse())
+		// and the node should not be deleted yet
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node1), node1)).To(Succeed())
+
+		// make the node non-empty
+		ExpectManualBinding(ctx, env.Client, pod, node1)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+
+		// advance the clock so that the timeout expires
+		fakeClock.Step(31 * time.Second)
+		// controller should finish
+		Eventually(finished.Load, 10*time.Second).Should(BeTrue())
+		wg.Wait()
+
+		// we don't need any new nodes
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		// and the empty one is now not empty, so we should keep it
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node1), node1)).To(Succeed())
+	})
+})
+var _ = Describe("Parallelization", func() {
+	It("should schedule an additional node when receiving pending pods while consolidating", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
 		// create our RS so we can link a pod to it
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
-		pods := test.Pods(3, test.PodOptions{
-			ObjectMeta: metav1.ObjectMeta{
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -1281,77 +6791,253 @@ var _ = Describe("Delete Node", func() {
 					},
 				}}})
 
-		// pod[2] is a stand-alone (non ReplicaSet) pod
-		pods[2].OwnerReferences = nil
-
 		prov := test.Provisioner(test.ProvisionerOptions{
 			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
-		node1 := test.Node(test.NodeOptions{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
-				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
 
-		node2 := test.Node(test.NodeOptions{
+		// Add a finalizer to the node so that it sticks around for the scheduling loop
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
 					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
-				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
+				},
+				Finalizers: []string{"karpenter.sh/test-finalizer"},
+			},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
 
-		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
-		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
-		// two pods on node 1
-		ExpectManualBinding(ctx, env.Client, pods[0], node1)
-		ExpectManualBinding(ctx, env.Client, pods[1], node1)
-		// one on node 2, but it's a standalone pod
-		ExpectManualBinding(ctx, env.Client, pods[2], node2)
-		ExpectScheduled(ctx, env.Client, pods[0])
-		ExpectScheduled(ctx, env.Client, pods[1])
-		ExpectScheduled(ctx, env.Client, pods[2])
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
 
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
 		fakeClock.Step(10 * time.Minute)
-		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
+
+		// Run the processing loop in parallel in the background with environment context
+		go triggerVerifyAction()
+		go func() {
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
+		Eventually(func(g Gomega) {
+			// should create a new node as there is a cheaper one that can hold the pod
+			nodes := &v1.NodeList{}
+			g.Expect(env.Client.List(ctx, nodes)).To(Succeed())
+			g.Expect(len(nodes.Items)).To(Equal(2))
+		}, time.Second*10).Should(Succeed())
+		wg.Wait()
+		// Add a new pending pod that should schedule while node is not yet deleted
+		pods := ExpectProvisionedNoBinding(ctx, env.Client, provisioningController, provisioner, test.UnschedulablePod())
+		nodes := &v1.NodeList{}
+		Expect(env.Client.List(ctx, nodes)).To(Succeed())
+		Expect(len(nodes.Items)).To(Equal(3))
+		Expect(pods[0].Spec.NodeName).NotTo(Equal(node.Name))
+	})
+	It("should not consolidate a node that is launched for pods on a deleting node", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		podOpts := test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				},
+			},
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceCPU: resource.MustParse("1"),
+				},
+			},
+		}
+
+		var pods []*v1.Pod
+		for i := 0; i < 5; i++ {
+			pod := test.UnschedulablePod(podOpts)
+			pods = append(pods, pod)
+		}
+		ExpectApplied(ctx, env.Client, rs, prov)
+		ExpectProvisioned(ctx, env.Client, recorder, provisioningController, provisioner, lo.Map(pods, func(p *v1.Pod, _ int) *v1.Pod { return p.DeepCopy() })...)
+
+		nodeList := &v1.NodeList{}
+		Expect(env.Client.List(ctx, nodeList)).To(Succeed())
+		Expect(len(nodeList.Items)).To(Equal(1))
+
+		// Update cluster state with new node
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(&nodeList.Items[0]))
+
+		// Reset the bindings so we can re-record bindings
+		recorder.ResetBindings()
+
+		// Mark the node for deletion and re-trigger reconciliation
+		oldNodeName := nodeList.Items[0].Name
+		cluster.MarkForDeletion(nodeList.Items[0].Name)
+
+		ExpectProvisionedNoBinding(ctx, env.Client, provisioningController, provisioner)
+
+		// Make sure that the cluster state is aware of the current node state
+		Expect(env.Client.List(ctx, nodeList)).To(Succeed())
+		Expect(len(nodeList.Items)).To(Equal(2))
+		newNode, _ := lo.Find(nodeList.Items, func(n v1.Node) bool { return n.Name != oldNodeName })
+
+		for i := range nodeList.Items {
+			node := nodeList.Items[i]
+			ExpectMakeNodesReady(ctx, env.Client, &node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(&node))
+		}
+
+		// Wait for the nomination cache to expire
+		time.Sleep(time.Second * 11)
+
+		// Re-create the pods to re-bind them
+		for i := 0; i < 2; i++ {
+			ExpectDeleted(ctx, env.Client, pods[i])
+			pod := test.UnschedulablePod(podOpts)
+			ExpectApplied(ctx, env.Client, pod)
+			ExpectManualBinding(ctx, env.Client, pod, &newNode)
+		}
+
+		// Trigger a reconciliation run which should take into account the deleting node
+		// cnsolidation shouldn't trigger additional actions
+		fakeClock.Step(10 * time.Minute)
+		result, err := deprovisioningController.ProcessCluster(ctx)
 		Expect(err).ToNot(HaveOccurred())
-
-		// we don't need a new node
-		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// but we expect to delete the node with the fewest pods (node 2) even though the pod has no ownerRefs
-		// and will not be recreated
-		ExpectNotFound(ctx, env.Client, node2)
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
 	})
 })
 
-var _ = Describe("Node Lifetime Consideration", func() {
-	It("should consider node lifetime remaining when calculating disruption cost", func() {
-		labels := map[string]string{
-			"app": "test",
+var _ = Describe("Simulation Concurrency", func() {
+	It("should choose the same command when simulating candidates in parallel as when simulating sequentially", func() {
+		// consolidate runs single node consolidation against three expensive nodes whose pods can't share a node and
+		// returns the names of the nodes that were removed along with the instance types the replacement could launch as
+		consolidate := func(concurrency int) ([]string, []string) {
+			labels := map[string]string{
+				"app": "test",
+			}
+			rs := test.ReplicaSet()
+			ExpectApplied(ctx, env.Client, rs)
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+			prov := test.Provisioner(test.ProvisionerOptions{
+				Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+			})
+			var pods []*v1.Pod
+			var nodes []*v1.Node
+			for i := 0; i < 3; i++ {
+				pods = append(pods, test.Pod(test.PodOptions{
+					ResourceRequirements: v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}},
+					PodAntiRequirements: []v1.PodAffinityTerm{
+						{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
+							TopologyKey:   v1.LabelHostname,
+						},
+					},
+					ObjectMeta: metav1.ObjectMeta{Labels: labels,
+						// give each node a distinct disruption cost so the candidate order is well defined
+						Annotations: map[string]string{v1.PodDeletionCost: fmt.Sprint(i * 100)},
+						OwnerReferences: []metav1.OwnerReference{
+							{
+								APIVersion:         "apps/v1",
+								Kind:               "ReplicaSet",
+								Name:               rs.Name,
+								UID:                rs.UID,
+								Controller:         ptr.Bool(true),
+								BlockOwnerDeletion: ptr.Bool(true),
+							},
+						}}}))
+				nodes = append(nodes, test.Node(test.NodeOptions{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							v1alpha5.ProvisionerNameLabelKey: prov.Name,
+							v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+							v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+							v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+						}},
+					Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+				}))
+			}
+			ExpectApplied(ctx, env.Client, prov)
+			for i := range nodes {
+				ExpectApplied(ctx, env.Client, pods[i], nodes[i])
+				ExpectMakeNodesReady(ctx, env.Client, nodes[i])
+				ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+				ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+				ExpectScheduled(ctx, env.Client, pods[i])
+			}
+
+			s := test.Settings()
+			s.SimulationConcurrency = concurrency
+			concurrencyCtx := settings.ToContext(ctx, s)
+			cloudProvider.CreateCalls = nil
+			deprovisioningController = deprovisioning.NewController(fakeClock, env.Client, provisioner, cloudProvider, recorder, cluster, false)
+
+			wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, nodes...)
+			fakeClock.Step(10 * time.Minute)
+			go triggerVerifyAction()
+			_, err := deprovisioningController.ProcessCluster(concurrencyCtx)
+			Expect(err).ToNot(HaveOccurred())
+			wg.Wait()
+
+			Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+			var removed []string
+			for i, node := range nodes {
+				if err := env.Client.Get(ctx, client.ObjectKeyFromObject(node), &v1.Node{}); errors.IsNotFound(err) {
+					removed = append(removed, fmt.Sprintf("node-%d", i))
+				}
+			}
+			instanceTypes := lo.Map(cloudProvider.CreateCalls[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })
+
+			// clean up so that the next simulation starts from an identical cluster
+			ExpectCleanedUp(ctx, env.Client)
+			var nodeKeys []client.ObjectKey
+			cluster.ForEachNode(func(n *state.Node) bool {
+				nodeKeys = append(nodeKeys, client.ObjectKeyFromObject(n.Node))
+				return true
+			})
+			for _, nodeKey := range nodeKeys {
+				ExpectReconcileSucceeded(ctx, nodeStateController, nodeKey)
+			}
+			return removed, instanceTypes
 		}
-		// create our RS so we can link a pod to it
+
+		sequentialRemoved, sequentialInstanceTypes := consolidate(1)
+		parallelRemoved, parallelInstanceTypes := consolidate(3)
+
+		// the node with the lowest disruption cost is replaced regardless of the simulation concurrency
+		Expect(sequentialRemoved).To(Equal([]string{"node-0"}))
+		Expect(parallelRemoved).To(Equal(sequentialRemoved))
+		Expect(parallelInstanceTypes).To(ConsistOf(sequentialInstanceTypes))
+	})
+})
+
+var _ = Describe("Custom Deprovisioners", func() {
+	It("should invoke a registered deprovisioner when the built-in deprovisioners have nothing to do", func() {
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
-
-		pods := test.Pods(3, test.PodOptions{
-			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -1362,25 +7048,9 @@ var _ = Describe("Node Lifetime Consideration", func() {
 						BlockOwnerDeletion: ptr.Bool(true),
 					},
 				}}})
-
-		prov := test.Provisioner(test.ProvisionerOptions{
-			Consolidation:          &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
-			TTLSecondsUntilExpired: ptr.Int64(3),
-		})
-		node1 := test.Node(test.NodeOptions{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
-				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
-
-		node2 := test.Node(test.NodeOptions{
+		// without consolidation or TTLs, none of the built-in deprovisioners consider the node
+		prov := test.Provisioner()
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
@@ -1392,58 +7062,37 @@ var _ = Describe("Node Lifetime Consideration", func() {
 				v1.ResourceCPU:  resource.MustParse("32"),
 				v1.ResourcePods: resource.MustParse("100"),
 			}})
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
 
-		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], prov)
-		ExpectApplied(ctx, env.Client, node1) // ensure node1 is the oldest node
-		time.Sleep(2 * time.Second)           // this sleep is unfortunate, but necessary.  The creation time is from etcd and we can't mock it, so we
-		// need to sleep to force the second node to be created a bit after the first node.
-		ExpectApplied(ctx, env.Client, node2)
-		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
-		// two pods on node 1, one on node 2
-		ExpectManualBinding(ctx, env.Client, pods[0], node1)
-		ExpectManualBinding(ctx, env.Client, pods[1], node1)
-		ExpectManualBinding(ctx, env.Client, pods[2], node2)
-		ExpectScheduled(ctx, env.Client, pods[0])
-		ExpectScheduled(ctx, env.Client, pods[1])
-		ExpectScheduled(ctx, env.Client, pods[2])
-
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
-		fakeClock.SetTime(time.Now())
-		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
+		custom := &recordingDeprovisioner{}
+		controller := deprovisioning.NewController(fakeClock, env.Client, provisioner, cloudProvider, recorder, cluster, false, custom)
+		result, err := controller.ProcessCluster(ctx)
 		Expect(err).ToNot(HaveOccurred())
-
-		// the second node has more pods so it would normally not be picked for consolidation, except it very little
-		// lifetime remaining so it should be deleted
-		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		ExpectNotFound(ctx, env.Client, node1)
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+		Expect(custom.candidates).To(ConsistOf(node.Name))
+		ExpectNodeExists(ctx, env.Client, node.Name)
 	})
 })
 
-var _ = Describe("Topology Consideration", func() {
-	It("can replace node maintaining zonal topology spread", func() {
-		labels := map[string]string{
-			"app": "test-zonal-spread",
-		}
-
-		// create our RS so we can link a pod to it
+var _ = Describe("Dry Run", func() {
+	var logs *observer.ObservedLogs
+	var dryRunCtx context.Context
+	BeforeEach(func() {
+		var core zapcore.Core
+		core, logs = observer.New(zap.InfoLevel)
+		dryRunCtx = logging.WithLogger(ctx, zap.New(core).Sugar())
+		deprovisioningController = deprovisioning.NewController(fakeClock, env.Client, provisioner, cloudProvider, recorder, cluster, true)
+	})
+	It("should report a replacement without launching a node or evicting pods", func() {
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
-		tsc := v1.TopologySpreadConstraint{
-			MaxSkew:           1,
-			TopologyKey:       v1.LabelTopologyZone,
-			WhenUnsatisfiable: v1.DoNotSchedule,
-			LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
-		}
-		pods := test.Pods(4, test.PodOptions{
-			ResourceRequirements:      v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}},
-			TopologySpreadConstraints: []v1.TopologySpreadConstraint{tsc},
+		pod := test.Pod(test.PodOptions{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels: labels,
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -1454,215 +7103,136 @@ var _ = Describe("Topology Consideration", func() {
 						BlockOwnerDeletion: ptr.Bool(true),
 					},
 				}}})
-
-		testZone1Instance := leastExpensiveInstanceWithZone("test-zone-1")
-		testZone2Instance := mostExpensiveInstanceWithZone("test-zone-2")
-		testZone3Instance := leastExpensiveInstanceWithZone("test-zone-3")
-
 		prov := test.Provisioner(test.ProvisionerOptions{
 			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
-		zone1Node := test.Node(test.NodeOptions{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelTopologyZone:             "test-zone-1",
-					v1.LabelInstanceTypeStable:       testZone1Instance.Name,
-					v1alpha5.LabelCapacityType:       testZone1Instance.Offerings[0].CapacityType,
-				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
-
-		zone2Node := test.Node(test.NodeOptions{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelTopologyZone:             "test-zone-2",
-					v1.LabelInstanceTypeStable:       testZone2Instance.Name,
-					v1alpha5.LabelCapacityType:       testZone2Instance.Offerings[0].CapacityType,
-				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
-
-		zone3Node := test.Node(test.NodeOptions{
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelTopologyZone:             "test-zone-3",
-					v1.LabelInstanceTypeStable:       testZone3Instance.Name,
-					v1alpha5.LabelCapacityType:       testZone1Instance.Offerings[0].CapacityType,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
-
-		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], zone1Node, zone2Node, zone3Node, prov)
-		ExpectMakeNodesReady(ctx, env.Client, zone1Node, zone2Node, zone3Node)
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone1Node))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone2Node))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone3Node))
-		ExpectManualBinding(ctx, env.Client, pods[0], zone1Node)
-		ExpectManualBinding(ctx, env.Client, pods[1], zone2Node)
-		ExpectManualBinding(ctx, env.Client, pods[2], zone3Node)
-		ExpectScheduled(ctx, env.Client, pods[0])
-		ExpectScheduled(ctx, env.Client, pods[1])
-		ExpectScheduled(ctx, env.Client, pods[2])
-
-		ExpectSkew(ctx, env.Client, "default", &tsc).To(ConsistOf(1, 1, 1))
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
 
-		// consolidation won't delete the old node until the new node is ready
-		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, zone1Node, zone2Node, zone3Node)
+		before := dryRunActions("consolidation/replace")
 		fakeClock.Step(10 * time.Minute)
 		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
+		result, err := deprovisioningController.ProcessCluster(dryRunCtx)
 		Expect(err).ToNot(HaveOccurred())
-		wg.Wait()
-
-		// should create a new node as there is a cheaper one that can hold the pod
-		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+		Expect(result.Action).To(Equal("consolidation/replace"))
 
-		// we need to emulate the replicaset deprovisioningController and bind a new pod to the newly created node
-		ExpectApplied(ctx, env.Client, pods[3])
-		var nodes v1.NodeList
-		Expect(env.Client.List(ctx, &nodes)).To(Succeed())
-		Expect(nodes.Items).To(HaveLen(3))
-		for i, n := range nodes.Items {
-			// bind the pod to the new node we don't recognize as it is the one that consolidation created
-			if n.Name != zone1Node.Name && n.Name != zone2Node.Name && n.Name != zone3Node.Name {
-				ExpectManualBinding(ctx, env.Client, pods[3], &nodes.Items[i])
-			}
-		}
-		// we should maintain our skew, the new node must be in the same zone as the old node it replaced
-		ExpectSkew(ctx, env.Client, "default", &tsc).To(ConsistOf(1, 1, 1))
+		// the replacement is reported, but nothing is launched, cordoned or deleted
+		Expect(dryRunActions("consolidation/replace")).To(Equal(before + 1))
+		Expect(logs.FilterMessageSnippet("dry run").FilterField(zap.String("action", "replace")).Len()).To(Equal(1))
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+		Expect(node.DeletionTimestamp.IsZero()).To(BeTrue())
+		Expect(ExpectPodExists(ctx, env.Client, pod.Name, pod.Namespace).Spec.NodeName).To(Equal(node.Name))
 	})
-	It("won't delete node if it would violate pod anti-affinity", func() {
-		labels := map[string]string{
-			"app": "test",
-		}
-		// create our RS so we can link a pod to it
-		rs := test.ReplicaSet()
-		ExpectApplied(ctx, env.Client, rs)
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
-
-		pods := test.Pods(3, test.PodOptions{
-			ResourceRequirements: v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}},
-			PodAntiRequirements: []v1.PodAffinityTerm{
-				{
-					LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
-					TopologyKey:   v1.LabelHostname,
-				},
-			},
-			ObjectMeta: metav1.ObjectMeta{Labels: labels,
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion:         "apps/v1",
-						Kind:               "ReplicaSet",
-						Name:               rs.Name,
-						UID:                rs.UID,
-						Controller:         ptr.Bool(true),
-						BlockOwnerDeletion: ptr.Bool(true),
-					},
-				}}})
-
-		testZone1Instance := leastExpensiveInstanceWithZone("test-zone-1")
-		testZone2Instance := leastExpensiveInstanceWithZone("test-zone-2")
-		testZone3Instance := leastExpensiveInstanceWithZone("test-zone-3")
-
+	It("should report a deletion without deleting the node", func() {
 		prov := test.Provisioner(test.ProvisionerOptions{
 			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
-		zone1Node := test.Node(test.NodeOptions{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelTopologyZone:             "test-zone-1",
-					v1.LabelInstanceTypeStable:       testZone1Instance.Name,
-					v1alpha5.LabelCapacityType:       testZone1Instance.Offerings[0].CapacityType,
-				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
-
-		zone2Node := test.Node(test.NodeOptions{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelTopologyZone:             "test-zone-2",
-					v1.LabelInstanceTypeStable:       testZone2Instance.Name,
-					v1alpha5.LabelCapacityType:       testZone2Instance.Offerings[0].CapacityType,
-				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
-
-		zone3Node := test.Node(test.NodeOptions{
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelTopologyZone:             "test-zone-3",
-					v1.LabelInstanceTypeStable:       testZone3Instance.Name,
-					v1alpha5.LabelCapacityType:       testZone3Instance.Offerings[0].CapacityType,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1")}})
-
-		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], zone1Node, zone2Node, zone3Node, prov)
-		ExpectMakeNodesReady(ctx, env.Client, zone1Node, zone2Node, zone3Node)
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone1Node))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone2Node))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(zone3Node))
-		ExpectManualBinding(ctx, env.Client, pods[0], zone1Node)
-		ExpectManualBinding(ctx, env.Client, pods[1], zone2Node)
-		ExpectManualBinding(ctx, env.Client, pods[2], zone3Node)
-		ExpectScheduled(ctx, env.Client, pods[0])
-		ExpectScheduled(ctx, env.Client, pods[1])
-		ExpectScheduled(ctx, env.Client, pods[2])
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+		ExpectApplied(ctx, env.Client, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
 
-		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, zone1Node, zone2Node, zone3Node)
+		before := dryRunActions("consolidation/delete")
 		fakeClock.Step(10 * time.Minute)
 		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
+		result, err := deprovisioningController.ProcessCluster(dryRunCtx)
 		Expect(err).ToNot(HaveOccurred())
-		wg.Wait()
-
-		// our nodes are already the cheapest available, so we can't replace them.  If we delete, it would
-		// violate the anti-affinity rule so we can't do anything.
-		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		ExpectNodeExists(ctx, env.Client, zone1Node.Name)
-		ExpectNodeExists(ctx, env.Client, zone2Node.Name)
-		ExpectNodeExists(ctx, env.Client, zone3Node.Name)
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
 
+		Expect(dryRunActions("consolidation/delete")).To(Equal(before + 1))
+		Expect(result.Action).To(Equal("consolidation/delete"))
+		Expect(result.RemovedNodes).To(ConsistOf(node.Name))
+		Expect(logs.FilterMessageSnippet("dry run").FilterField(zap.String("nodes", node.Name)).Len()).To(Equal(1))
+		ExpectNodeExists(ctx, env.Client, node.Name)
 	})
-})
-
-var _ = Describe("Empty Nodes", func() {
-	It("can delete empty nodes with consolidation", func() {
-		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+	It("should neither taint the nodes it validates nor write summaries", func() {
+		s := test.Settings()
+		s.ValidationTaintEffect = string(v1.TaintEffectPreferNoSchedule)
+		taintCtx := settings.ToContext(dryRunCtx, s)
 
-		node1 := test.Node(test.NodeOptions{
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
-					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
-					v1alpha5.LabelNodeInitialized:    "true",
-				},
-			},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
-
-		ExpectApplied(ctx, env.Client, node1, prov)
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+		ExpectApplied(ctx, env.Client, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		resourceVersion := node.ResourceVersion
 
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		// capture the node while the command is validated
+		var validating v1.Node
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+			Eventually(fakeClock.HasWaiters, 5*time.Second).Should(BeTrue())
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), &validating)).To(Succeed())
+			fakeClock.Step(45 * time.Second)
+		}()
 		fakeClock.Step(10 * time.Minute)
-		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
+		result, err := deprovisioningController.ProcessCluster(taintCtx)
 		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+		Expect(result.Action).To(Equal("consolidation/delete"))
 
-		// we don't need any new nodes
-		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// and should delete the empty one
-		ExpectNotFound(ctx, env.Client, node1)
+		Expect(validating.Spec.Taints).ToNot(ContainElement(HaveField("Key", v1alpha5.DeprovisioningValidationTaintKey)))
+		Expect(ExpectNodeExists(ctx, env.Client, node.Name).ResourceVersion).To(Equal(resourceVersion))
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(prov), prov)).To(Succeed())
+		Expect(prov.Annotations).ToNot(HaveKey(v1alpha5.DeprovisioningSummaryAnnotationKey))
 	})
-	It("can delete multiple empty nodes with consolidation", func() {
-		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+	It("should report that there's nothing to do", func() {
+		result, err := deprovisioningController.ProcessCluster(dryRunCtx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultNothingToDo))
+		Expect(result.Action).To(BeEmpty())
+		Expect(result.RemovedNodes).To(BeEmpty())
+		Expect(logs.FilterMessageSnippet("dry run").Len()).To(BeZero())
+	})
+})
 
-		node1 := test.Node(test.NodeOptions{
+var _ = Describe("Consolidation Min Node Age", func() {
+	// agedNode applies an empty node of a provisioner with the min node age that was created the age ago
+	agedNode := func(minNodeAge *int64, age time.Duration) *v1.Node {
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true), MinNodeAgeSeconds: minNodeAge},
+		})
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
@@ -1670,43 +7240,195 @@ var _ = Describe("Empty Nodes", func() {
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
-		node2 := test.Node(test.NodeOptions{
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+		ExpectApplied(ctx, env.Client, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		fakeClock.SetTime(node.CreationTimestamp.Add(age))
+		return node
+	}
+	It("should not consolidate nodes that are younger than the min node age", func() {
+		node := agedNode(ptr.Int64(300), 30*time.Second)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("should consolidate nodes that are older than the min node age", func() {
+		node := agedNode(ptr.Int64(300), 600*time.Second)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("should default the min node age to five minutes", func() {
+		node := agedNode(nil, 30*time.Second)
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("should consolidate nodes of any age if the min node age is zero", func() {
+		node := agedNode(ptr.Int64(0), 0)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("should not consolidate nodes with pods that are younger than the min node age", func() {
+		node := agedNode(ptr.Int64(300), 30*time.Second)
+		pod := test.Pod()
+		ExpectApplied(ctx, env.Client, pod)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+	})
+})
+
+var _ = Describe("Consolidation Cascade", func() {
+	var prov *v1alpha5.Provisioner
+	var emptyNode, node1, node2 *v1.Node
+	BeforeEach(func() {
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		pods := test.Pods(2, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+		prov = test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		nodeOptions := test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
 				}},
 			Allocatable: map[v1.ResourceName]resource.Quantity{
 				v1.ResourceCPU:  resource.MustParse("32"),
 				v1.ResourcePods: resource.MustParse("100"),
-			}})
+			}}
+		emptyNode = test.Node(nodeOptions)
+		node1 = test.Node(nodeOptions)
+		node2 = test.Node(nodeOptions)
 
-		ExpectApplied(ctx, env.Client, node1, node2, prov)
-		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], emptyNode, node1, node2, prov)
+		ExpectMakeNodesReady(ctx, env.Client, emptyNode, node1, node2)
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node2)
 
 		// inform cluster state about the nodes
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(emptyNode))
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
 		fakeClock.Step(10 * time.Minute)
+	})
+	It("should perform a single action per pass by default", func() {
 		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
+		result, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultSuccess))
+		Expect(result.Action).To(Equal("consolidation/delete"))
+		Expect(result.RemovedNodes).To(ConsistOf(emptyNode.Name))
+		Expect(result.ReplacementInstanceTypes).To(BeEmpty())
+		Expect(result.CostDelta).To(BeNumerically("~", -leastExpensiveOffering.Price, 1e-6))
+
+		// only the empty node is deleted, consolidating the others waits for the next pass
+		ExpectNotFound(ctx, env.Client, emptyNode)
+		ExpectNodeExists(ctx, env.Client, node1.Name)
+		ExpectNodeExists(ctx, env.Client, node2.Name)
+	})
+	It("should consolidate the remaining nodes in the same pass after deleting an empty node", func() {
+		s := test.Settings()
+		s.ConsolidationCascadeLimit = 3
+		// without replacements, the nodes with pods can only be consolidated by deleting one of them
+		s.ConsolidationPolicy = settings.ConsolidationPolicyDeleteOnly
+		ctx = settings.ToContext(ctx, s)
+
+		// each pass validates its consolidation after the consolidation TTL
+		go func() {
+			triggerVerifyAction()
+			triggerVerifyAction()
+		}()
+		result, err := deprovisioningController.ProcessCluster(ctx)
 		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Result).To(Equal(deprovisioning.ResultSuccess))
 
-		// we don't need any new nodes
+		// the empty node is deleted first, and then one of the nodes with pods is consolidated onto the other
 		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// and should delete both empty ones
-		ExpectNotFound(ctx, env.Client, node1)
-		ExpectNotFound(ctx, env.Client, node2)
+		ExpectNotFound(ctx, env.Client, emptyNode)
+		Expect(lo.Filter([]*v1.Node{node1, node2}, func(n *v1.Node, _ int) bool {
+			return env.Client.Get(ctx, client.ObjectKeyFromObject(n), &v1.Node{}) == nil
+		})).To(HaveLen(1))
+		// the result describes both of the commands
+		Expect(result.RemovedNodes).To(HaveLen(2))
+		Expect(result.RemovedNodes).To(ContainElement(emptyNode.Name))
+		Expect(result.CostDelta).To(BeNumerically("~", -2*leastExpensiveOffering.Price, 1e-6))
 	})
-	It("can delete empty nodes with TTLSecondsAfterEmpty with the emptiness timestamp", func() {
-		prov := test.Provisioner(test.ProvisionerOptions{TTLSecondsAfterEmpty: ptr.Int64(10)})
+	It("should report the savings of a consolidation action", func() {
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(savings()).To(BeNumerically("~", leastExpensiveOffering.Price, 1e-6))
+	})
+	// outsideMaintenanceWindow returns a context whose maintenance window opens an hour from now, so that reconciling
+	// doesn't attempt any deprovisioning
+	outsideMaintenanceWindow := func() context.Context {
+		now := fakeClock.Now().UTC()
+		offset := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+		s := test.Settings()
+		s.MaintenanceWindows = []maintenancewindow.Window{{
+			Start: (offset + time.Hour) % (24 * time.Hour),
+			End:   (offset + 2*time.Hour) % (24 * time.Hour),
+		}}
+		return settings.ToContext(ctx, s)
+	}
+	It("should zero the savings once consolidation hasn't taken an action recently", func() {
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(savings()).To(BeNumerically(">", 0))
+
+		fakeClock.Step(time.Minute)
+		ExpectReconcileSucceeded(outsideMaintenanceWindow(), deprovisioningController, client.ObjectKey{})
+		Expect(savings()).To(BeNumerically("~", leastExpensiveOffering.Price, 1e-6))
+
+		fakeClock.Step(15 * time.Minute)
+		ExpectReconcileSucceeded(outsideMaintenanceWindow(), deprovisioningController, client.ObjectKey{})
+		Expect(savings()).To(BeNumerically("==", 0))
+	})
+	It("should not report savings in dry run mode", func() {
+		deprovisioningController = deprovisioning.NewController(fakeClock, env.Client, provisioner, cloudProvider, recorder, cluster, true)
+		// zero the savings of any earlier action
+		ExpectReconcileSucceeded(outsideMaintenanceWindow(), deprovisioningController, client.ObjectKey{})
+		Expect(savings()).To(BeNumerically("==", 0))
+
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(savings()).To(BeNumerically("==", 0))
+		ExpectNodeExists(ctx, env.Client, emptyNode.Name)
+	})
+})
 
+var _ = Describe("Evaluate Node", func() {
+	It("should report that an empty node would be deleted without deleting it", func() {
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
 		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
@@ -1714,134 +7436,139 @@ var _ = Describe("Empty Nodes", func() {
 					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
-				},
-				Annotations: map[string]string{
-					v1alpha5.EmptinessTimestampAnnotationKey: fakeClock.Now().Format(time.RFC3339),
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
-		ExpectApplied(ctx, env.Client, prov, node)
-		ExpectMakeNodesReady(ctx, env.Client, node)
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
 
+		ExpectApplied(ctx, env.Client, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
-
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
 		fakeClock.Step(10 * time.Minute)
-		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
+
+		reason, cmd, err := deprovisioningController.EvaluateNode(ctx, node)
 		Expect(err).ToNot(HaveOccurred())
+		Expect(reason).To(Equal("consolidation"))
+		Expect(cmd).ToNot(BeNil())
+		Expect(cmd.String()).To(HavePrefix("delete"))
 
-		// we don't need any new nodes
+		// evaluating the node has no side effects
 		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// and should delete both empty ones
-		ExpectNotFound(ctx, env.Client, node)
+		Expect(ExpectNodeExists(ctx, env.Client, node.Name).ResourceVersion).To(Equal(node.ResourceVersion))
+		recorder.ForEachEvent(func(evt events.Event) { Fail(fmt.Sprintf("unexpected %s event", evt.Reason)) })
 	})
-	It("considers pending pods when consolidating", func() {
-		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+	It("should report where the pods of a deleted node are expected to reschedule", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
-		node1 := test.Node(test.NodeOptions{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
-				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("128"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
 
-		// there is a pending pod that should land on the node
-		pod := test.UnschedulablePod(test.PodOptions{
-			ResourceRequirements: v1.ResourceRequirements{
-				Requests: map[v1.ResourceName]resource.Quantity{
-					v1.ResourceCPU: resource.MustParse("1"),
-				},
-			},
-		})
-		unsched := test.UnschedulablePod(test.PodOptions{
-			ResourceRequirements: v1.ResourceRequirements{
-				Requests: map[v1.ResourceName]resource.Quantity{
-					v1.ResourceCPU: resource.MustParse("125"),
-				},
-			},
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
+		var nodes []*v1.Node
+		for i := 0; i < 2; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
 
-		ExpectApplied(ctx, env.Client, node1, pod, unsched, prov)
-		ExpectMakeNodesReady(ctx, env.Client, node1)
-		ExpectManualBinding(ctx, env.Client, pod, node1)
-
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
-
+		ExpectApplied(ctx, env.Client, rs, pod, nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		ExpectManualBinding(ctx, env.Client, pod, nodes[0])
+		for _, node := range nodes {
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		}
 		fakeClock.Step(10 * time.Minute)
-		go triggerVerifyAction()
-		_, err := deprovisioningController.ProcessCluster(ctx)
-		Expect(err).ToNot(HaveOccurred())
 
-		// we don't need any new nodes and consolidation should notice the huge pending pod that needs the large
-		// node to schedule, which prevents the large expensive node from being replaced
-		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		ExpectNodeExists(ctx, env.Client, node1.Name)
+		_, cmd, err := deprovisioningController.EvaluateNode(ctx, nodes[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmd).ToNot(BeNil())
+		Expect(cmd.String()).To(HavePrefix("delete"))
+		// the only existing node that the pod can reschedule to is the other node
+		Expect(cmd.Placements()).To(Equal(map[types.NamespacedName]string{client.ObjectKeyFromObject(pod): nodes[1].Name}))
 	})
-})
+	It("should report that an expired node would be replaced without replacing it", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
-var _ = Describe("consolidation TTL", func() {
-	It("should wait for the node TTL for empty nodes before consolidating", func() {
+		p := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
 		prov := test.Provisioner(test.ProvisionerOptions{
-			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+			TTLSecondsUntilExpired: ptr.Int64(30),
 		})
-
-		node1 := test.Node(test.NodeOptions{
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
-					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
-					v1alpha5.LabelNodeInitialized:    "true",
-				},
-			},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
-
-		ExpectApplied(ctx, env.Client, node1, prov)
-
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
-		var wg sync.WaitGroup
-		wg.Add(1)
-		finished := atomic.Bool{}
-		go func() {
-			defer wg.Done()
-			defer finished.Store(true)
-			_, err := deprovisioningController.ProcessCluster(ctx)
-			Expect(err).ToNot(HaveOccurred())
-		}()
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
 
-		// wait for the deprovisioningController to block on the validation timeout
-		Eventually(fakeClock.HasWaiters, time.Second*10).Should(BeTrue())
-		// controller should be blocking during the timeout
-		Expect(finished.Load()).To(BeFalse())
-		// and the node should not be deleted yet
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node1), node1)).To(Succeed())
+		ExpectApplied(ctx, env.Client, rs, p, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, p, node)
+		ExpectScheduled(ctx, env.Client, p)
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		fakeClock.Step(10 * time.Minute)
 
-		// advance the clock so that the timeout expires
-		fakeClock.Step(31 * time.Second)
-		// controller should finish
-		Eventually(finished.Load, 10*time.Second).Should(BeTrue())
-		wg.Wait()
+		reason, cmd, err := deprovisioningController.EvaluateNode(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reason).To(Equal("expiration"))
+		Expect(cmd).ToNot(BeNil())
+		Expect(cmd.String()).To(HavePrefix("replace"))
 
-		// we don't need any new nodes
+		// evaluating the node has no side effects, not even the expiration warning that ProcessCluster gives
 		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// and should delete the empty one
-		ExpectNotFound(ctx, env.Client, node1)
+		Expect(ExpectNodeExists(ctx, env.Client, node.Name).ResourceVersion).To(Equal(node.ResourceVersion))
+		recorder.ForEachEvent(func(evt events.Event) { Fail(fmt.Sprintf("unexpected %s event", evt.Reason)) })
+		ExpectScheduled(ctx, env.Client, p)
 	})
-	It("should wait for the node TTL for non-empty nodes before consolidating", func() {
+	It("should report why a node is blocked from deprovisioning", func() {
 		labels := map[string]string{
 			"app": "test",
 		}
@@ -1850,8 +7577,9 @@ var _ = Describe("consolidation TTL", func() {
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
-		pods := test.Pods(3, test.PodOptions{
+		p := test.Pod(test.PodOptions{
 			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				Annotations: map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -1862,137 +7590,221 @@ var _ = Describe("consolidation TTL", func() {
 						BlockOwnerDeletion: ptr.Bool(true),
 					},
 				}}})
-
 		prov := test.Provisioner(test.ProvisionerOptions{
 			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
-		node1 := test.Node(test.NodeOptions{
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
 
-		node2 := test.Node(test.NodeOptions{
+		ExpectApplied(ctx, env.Client, rs, p, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, p, node)
+		ExpectScheduled(ctx, env.Client, p)
+		fakeClock.Step(10 * time.Minute)
+
+		reason, cmd, err := deprovisioningController.EvaluateNode(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmd).To(BeNil())
+		Expect(reason).To(ContainSubstring("do not evict"))
+		ExpectNodeExists(ctx, env.Client, node.Name)
+	})
+	It("should not write to the cluster or publish events while evaluating a node", func() {
+		s := test.Settings()
+		s.PreserveCapacityType = true
+		ctx = settings.ToContext(ctx, s)
+
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.5,
+					Available:    false,
+				},
+			},
+		})
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "cheaper-spot-replacement",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1a",
+					Price:        0.2,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			currentInstance,
+			replacementInstance,
+		}
+
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
-					v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
-					v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
-					v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
 				}},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
 
-		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], node1, node2, prov)
-		ExpectMakeNodesReady(ctx, env.Client, node1, node2)
+		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectScheduled(ctx, env.Client, pod)
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		fakeClock.Step(10 * time.Minute)
 
-		ExpectManualBinding(ctx, env.Client, pods[0], node1)
-		ExpectManualBinding(ctx, env.Client, pods[1], node1)
-		ExpectManualBinding(ctx, env.Client, pods[2], node2)
-		ExpectScheduled(ctx, env.Client, pods[0])
-		ExpectScheduled(ctx, env.Client, pods[1])
-		ExpectScheduled(ctx, env.Client, pods[2])
+		// consolidation finds no feasible replacement, which ProcessCluster would report with an event
+		reason, cmd, err := deprovisioningController.EvaluateNode(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reason).To(BeEmpty())
+		Expect(cmd).To(BeNil())
 
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
-		var wg sync.WaitGroup
-		wg.Add(1)
-		finished := atomic.Bool{}
-		go func() {
-			defer wg.Done()
-			defer finished.Store(true)
-			_, err := deprovisioningController.ProcessCluster(ctx)
-			Expect(err).ToNot(HaveOccurred())
-		}()
+		_, err = deprovisioningController.ListCandidates(ctx)
+		Expect(err).ToNot(HaveOccurred())
 
-		// wait for the controller to block on the validation timeout
-		Eventually(fakeClock.HasWaiters, time.Second*10).Should(BeTrue())
-		// controller should be blocking during the timeout
-		Expect(finished.Load()).To(BeFalse())
-		// and the node should not be deleted yet
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node1), node1)).To(Succeed())
+		Expect(ExpectNodeExists(ctx, env.Client, node.Name).ResourceVersion).To(Equal(node.ResourceVersion))
+		var published []string
+		recorder.ForEachEvent(func(evt events.Event) { published = append(published, evt.Reason) })
+		Expect(published).To(BeEmpty())
+	})
+	It("should not start idle timers while evaluating a node", func() {
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
-		// advance the clock so that the timeout expires
-		fakeClock.Step(31 * time.Second)
-		// controller should finish
-		Eventually(finished.Load, 10*time.Second).Should(BeTrue())
-		wg.Wait()
+		pods := test.Pods(2, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+		prov := test.Provisioner(test.ProvisionerOptions{TTLSecondsAfterEmpty: ptr.Int64(30)})
+		nodeOf := func() *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:    resource.MustParse("32"),
+					v1.ResourceMemory: resource.MustParse("64Gi"),
+					v1.ResourcePods:   resource.MustParse("100"),
+				}})
+		}
+		idleNode, busyNode := nodeOf(), nodeOf()
+		deprovisioningController = deprovisioningController.WithUsageSource(fakeUsageSource{
+			idleNode.Name: {v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("256Mi")},
+			busyNode.Name: {v1.ResourceCPU: resource.MustParse("16"), v1.ResourceMemory: resource.MustParse("32Gi")},
+		})
 
-		// we don't need any new nodes
-		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// and should delete the empty one
-		ExpectNotFound(ctx, env.Client, node2)
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], prov, idleNode, busyNode)
+		ExpectMakeNodesReady(ctx, env.Client, idleNode, busyNode)
+		ExpectManualBinding(ctx, env.Client, pods[0], idleNode)
+		ExpectManualBinding(ctx, env.Client, pods[1], busyNode)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(idleNode))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(busyNode))
+
+		_, cmd, err := deprovisioningController.EvaluateNode(ctx, idleNode)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmd).To(BeNil())
+
+		// the idle timer starts with the first deprovisioning pass rather than the evaluation, so the node isn't
+		// deleted until it has been idle for TTLSecondsAfterEmpty after that pass
+		fakeClock.Step(time.Minute)
+		_, err = deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNodeExists(ctx, env.Client, idleNode.Name)
 	})
-	It("should not consolidate if the action becomes invalid during the node TTL wait", func() {
+	It("should defer nodes as ProcessCluster does", func() {
 		prov := test.Provisioner(test.ProvisionerOptions{
 			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
-
-		node1 := test.Node(test.NodeOptions{
+		node := test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
-					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
-					v1alpha5.LabelNodeInitialized:    "true",
-				},
-			},
-			Allocatable: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:  resource.MustParse("32"),
-				v1.ResourcePods: resource.MustParse("100"),
-			}})
-
-		pod := test.Pod()
-		ExpectApplied(ctx, env.Client, node1, prov, pod)
-
-		// inform cluster state about the nodes
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
-		var wg sync.WaitGroup
-		wg.Add(1)
-		finished := atomic.Bool{}
-		go func() {
-			defer wg.Done()
-			defer finished.Store(true)
-			_, err := deprovisioningController.ProcessCluster(ctx)
-			Expect(err).ToNot(HaveOccurred())
-		}()
-
-		// wait for the deprovisioningController to block on the validation timeout
-		Eventually(fakeClock.HasWaiters, time.Second*10).Should(BeTrue())
-		// controller should be blocking during the timeout
-		Expect(finished.Load()).To(BeFalse())
-		// and the node should not be deleted yet
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node1), node1)).To(Succeed())
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
 
-		// make the node non-empty
-		ExpectManualBinding(ctx, env.Client, pod, node1)
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
+		ExpectApplied(ctx, env.Client, node, prov)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
 
-		// advance the clock so that the timeout expires
-		fakeClock.Step(31 * time.Second)
-		// controller should finish
-		Eventually(finished.Load, 10*time.Second).Should(BeTrue())
-		wg.Wait()
+		// the node is younger than the minimum node age
+		_, cmd, err := deprovisioningController.EvaluateNode(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmd).To(BeNil())
 
-		// we don't need any new nodes
-		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
-		// and the empty one is now not empty, so we should keep it
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node1), node1)).To(Succeed())
+		// and once it's old enough, it's still deferred outside of the maintenance windows
+		fakeClock.Step(10 * time.Minute)
+		now := fakeClock.Now().UTC()
+		offset := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+		s := test.Settings()
+		s.MaintenanceWindows = []maintenancewindow.Window{{
+			Start: (offset + time.Hour) % (24 * time.Hour),
+			End:   (offset + 2*time.Hour) % (24 * time.Hour),
+		}}
+		reason, cmd, err := deprovisioningController.EvaluateNode(settings.ToContext(ctx, s), node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmd).To(BeNil())
+		Expect(reason).To(ContainSubstring("maintenance window"))
+
+		_, cmd, err = deprovisioningController.EvaluateNode(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmd).ToNot(BeNil())
 	})
 })
-var _ = Describe("Parallelization", func() {
-	It("should schedule an additional node when receiving pending pods while consolidating", func() {
+
+var _ = Describe("List Candidates", func() {
+	It("should report what would happen to each node, matching what ProcessCluster does", func() {
 		labels := map[string]string{
 			"app": "test",
 		}
@@ -2001,8 +7813,9 @@ var _ = Describe("Parallelization", func() {
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
 
-		pod := test.Pod(test.PodOptions{
+		p := test.Pod(test.PodOptions{
 			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				Annotations: map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -2013,70 +7826,348 @@ var _ = Describe("Parallelization", func() {
 						BlockOwnerDeletion: ptr.Bool(true),
 					},
 				}}})
-
-		prov := test.Provisioner(test.ProvisionerOptions{
+		consolidated := test.Provisioner(test.ProvisionerOptions{
 			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
+		expiring := test.Provisioner(test.ProvisionerOptions{
+			TTLSecondsUntilExpired: ptr.Int64(30),
+		})
+		nodeOf := func(prov *v1alpha5.Provisioner) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}})
+		}
+		emptyNode, expiredNode, blockedNode := nodeOf(consolidated), nodeOf(expiring), nodeOf(consolidated)
 
-		// Add a finalizer to the node so that it sticks around for the scheduling loop
-		node := test.Node(test.NodeOptions{
+		ExpectApplied(ctx, env.Client, rs, p, emptyNode, expiredNode, blockedNode, consolidated, expiring)
+		ExpectMakeNodesReady(ctx, env.Client, emptyNode, expiredNode, blockedNode)
+		ExpectManualBinding(ctx, env.Client, p, blockedNode)
+		for _, node := range []*v1.Node{emptyNode, expiredNode, blockedNode} {
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		}
+		fakeClock.Step(10 * time.Minute)
+
+		evaluations, err := deprovisioningController.ListCandidates(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(evaluations).To(HaveLen(3))
+		evaluationOf := func(node *v1.Node) deprovisioning.CandidateEvaluation {
+			evaluation, ok := lo.Find(evaluations, func(e deprovisioning.CandidateEvaluation) bool { return e.Node == node.Name })
+			Expect(ok).To(BeTrue())
+			return evaluation
+		}
+		Expect(evaluationOf(emptyNode).Candidacy).To(Equal([]string{"consolidation"}))
+		Expect(evaluationOf(emptyNode).Deprovisioner).To(Equal("consolidation"))
+		Expect(evaluationOf(emptyNode).Action).To(Equal("delete"))
+		Expect(evaluationOf(expiredNode).Candidacy).To(Equal([]string{"expiration"}))
+		Expect(evaluationOf(expiredNode).Deprovisioner).To(Equal("expiration"))
+		Expect(evaluationOf(expiredNode).Action).To(Equal("delete"))
+		Expect(evaluationOf(blockedNode).Candidacy).To(Equal([]string{"consolidation"}))
+		Expect(evaluationOf(blockedNode).Deprovisioner).To(BeEmpty())
+		Expect(evaluationOf(blockedNode).Action).To(Equal("do nothing"))
+		Expect(evaluationOf(blockedNode).Reason).To(ContainSubstring("do not evict"))
+
+		// listing the candidates has no side effects
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		for _, node := range []*v1.Node{emptyNode, expiredNode, blockedNode} {
+			Expect(ExpectNodeExists(ctx, env.Client, node.Name).Spec.Unschedulable).To(BeFalse())
+		}
+
+		// and deprovisioning does what was reported
+		for i := 0; i < 2; i++ {
+			go triggerVerifyAction()
+			_, err = deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, emptyNode, expiredNode)
+		ExpectNodeExists(ctx, env.Client, blockedNode.Name)
+	})
+})
+
+var _ = Describe("Pod Blocks Consolidation", func() {
+	It("should not report pods that don't block consolidation", func() {
+		p := test.Pod()
+		blocked, reason, err := deprovisioningController.PodBlocksConsolidation(ctx, p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blocked).To(BeFalse())
+		Expect(reason).To(BeEmpty())
+	})
+	It("should report pods with the do-not-evict annotation", func() {
+		p := test.Pod(test.PodOptions{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
+		}})
+		blocked, reason, err := deprovisioningController.PodBlocksConsolidation(ctx, p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blocked).To(BeTrue())
+		Expect(reason).To(Equal(fmt.Sprintf("pod %s/%s has do not evict annotation", p.Namespace, p.Name)))
+	})
+	It("should report pods that are excluded from drain", func() {
+		s := test.Settings()
+		s.DrainExclusionSelector = labels.SelectorFromSet(labels.Set{"app": "monitoring"})
+		exclusionCtx := settings.ToContext(ctx, s)
+
+		p := test.Pod(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "monitoring"}}})
+		blocked, reason, err := deprovisioningController.PodBlocksConsolidation(exclusionCtx, p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blocked).To(BeTrue())
+		Expect(reason).To(Equal(fmt.Sprintf("pod %s/%s is excluded from drain", p.Namespace, p.Name)))
+	})
+	It("should report pods with scheduling gates", func() {
+		p := test.Pod()
+		p.Status.Conditions = []v1.PodCondition{{Type: v1.PodScheduled, Reason: pod.PodReasonSchedulingGated, Status: v1.ConditionFalse}}
+		blocked, reason, err := deprovisioningController.PodBlocksConsolidation(ctx, p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blocked).To(BeTrue())
+		Expect(reason).To(Equal(fmt.Sprintf("pod %s/%s has scheduling gates", p.Namespace, p.Name)))
+	})
+	It("should report pods whose PDB doesn't allow disruptions", func() {
+		p := test.Pod(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"}}})
+		pdb := test.PodDisruptionBudget(test.PDBOptions{
+			Labels:         map[string]string{"app": "test"},
+			MaxUnavailable: lo.ToPtr(intstr.FromInt(0)),
+		})
+		ExpectApplied(ctx, env.Client, p, pdb)
+		blocked, reason, err := deprovisioningController.PodBlocksConsolidation(ctx, p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blocked).To(BeTrue())
+		Expect(reason).To(Equal(fmt.Sprintf("pdb %s prevents pod evictions", client.ObjectKeyFromObject(pdb))))
+	})
+	It("should report pods with resource claims when they're pinned", func() {
+		s := test.Settings()
+		s.PinResourceClaimPods = true
+		claimsCtx := settings.ToContext(ctx, s)
+
+		p := test.Pod()
+		ExpectApplied(ctx, env.Client, p)
+		claim := &unstructured.Unstructured{}
+		claim.SetGroupVersionKind(resourceClaimCRD.GroupVersionKind().GroupVersion().WithKind("ResourceClaim"))
+		claim.SetName(test.RandomName())
+		claim.SetNamespace(p.Namespace)
+		Expect(unstructured.SetNestedSlice(claim.Object, []interface{}{
+			map[string]interface{}{"resource": "pods", "name": p.Name, "uid": string(p.UID)},
+		}, "status", "reservedFor")).To(Succeed())
+		Expect(env.Client.Create(ctx, claim)).To(Succeed())
+		DeferCleanup(func() { Expect(client.IgnoreNotFound(env.Client.Delete(ctx, claim))).To(Succeed()) })
+
+		blocked, _, err := deprovisioningController.PodBlocksConsolidation(ctx, p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blocked).To(BeFalse())
+		blocked, reason, err := deprovisioningController.PodBlocksConsolidation(claimsCtx, p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blocked).To(BeTrue())
+		Expect(reason).To(Equal(fmt.Sprintf("resource claim %s is allocated to the node", client.ObjectKeyFromObject(claim))))
+	})
+})
+
+var _ = Describe("Plan", func() {
+	var prov *v1alpha5.Provisioner
+	var node *v1.Node
+	BeforeEach(func() {
+		prov = test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		node = test.Node(test.NodeOptions{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels: map[string]string{
 					v1alpha5.ProvisionerNameLabelKey: prov.Name,
 					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
 					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
 					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
-				},
-				Finalizers: []string{"karpenter.sh/test-finalizer"},
-			},
-			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")}})
-
-		ExpectApplied(ctx, env.Client, rs, pod, node, prov)
+				}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+		})
+		ExpectApplied(ctx, env.Client, node, prov)
 		ExpectMakeNodesReady(ctx, env.Client, node)
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
-		ExpectManualBinding(ctx, env.Client, pod, node)
-		ExpectScheduled(ctx, env.Client, pod)
-		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
-
 		fakeClock.Step(10 * time.Minute)
+	})
+	It("should plan without acting and apply exactly the planned commands", func() {
+		go triggerVerifyAction()
+		plan, err := deprovisioningController.Plan(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plan.Commands).To(HaveLen(1))
+		Expect(plan.Commands[0].Deprovisioner).To(Equal("consolidation"))
+		Expect(plan.Commands[0].Action).To(Equal("delete"))
+		Expect(plan.Commands[0].Nodes).To(ConsistOf(node.Name))
+		Expect(plan.Commands[0].CostDelta).To(BeNumerically("==", -mostExpensiveOffering.Price))
+
+		// planning has no side effects
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+
+		// the plan survives a round trip through its serialized form
+		raw, err := json.Marshal(plan)
+		Expect(err).ToNot(HaveOccurred())
+		reviewed := &deprovisioning.Plan{}
+		Expect(json.Unmarshal(raw, reviewed)).To(Succeed())
+		Expect(reviewed).To(Equal(plan))
 
-		// Run the processing loop in parallel in the background with environment context
 		go triggerVerifyAction()
-		go func() {
-			_, err := deprovisioningController.ProcessCluster(ctx)
-			Expect(err).ToNot(HaveOccurred())
-		}()
+		Expect(deprovisioningController.Apply(ctx, reviewed)).To(Succeed())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, node)
+	})
+	It("should reject a plan that is stale", func() {
+		go triggerVerifyAction()
+		plan, err := deprovisioningController.Plan(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plan.Commands).To(HaveLen(1))
 
-		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, node)
-		Eventually(func(g Gomega) {
-			// should create a new node as there is a cheaper one that can hold the pod
-			nodes := &v1.NodeList{}
-			g.Expect(env.Client.List(ctx, nodes)).To(Succeed())
-			g.Expect(len(nodes.Items)).To(Equal(2))
-		}, time.Second*10).Should(Succeed())
-		wg.Wait()
-		// Add a new pending pod that should schedule while node is not yet deleted
-		pods := ExpectProvisionedNoBinding(ctx, env.Client, provisioningController, provisioner, test.UnschedulablePod())
-		nodes := &v1.NodeList{}
-		Expect(env.Client.List(ctx, nodes)).To(Succeed())
-		Expect(len(nodes.Items)).To(Equal(3))
-		Expect(pods[0].Spec.NodeName).NotTo(Equal(node.Name))
+		// the node is no longer a candidate for consolidation by the time the plan is applied
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{v1alpha5.DoNotConsolidateNodeAnnotationKey: "true"})
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+
+		err = deprovisioningController.Apply(ctx, plan)
+		Expect(err).To(MatchError(deprovisioning.ErrStalePlan))
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeFalse())
 	})
-	It("should not consolidate a node that is launched for pods on a deleting node", func() {
-		labels := map[string]string{
-			"app": "test",
-		}
-		// create our RS so we can link a pod to it
+})
+
+var _ = Describe("Orphaned Cordons", func() {
+	It("should uncordon nodes that were cordoned for deprovisioning without a command in-flight", func() {
+		prov := test.Provisioner()
+		// a command cordoned the node, but the controller restarted before it completed
+		orphaned := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{v1alpha5.ProvisionerNameLabelKey: prov.Name},
+				Annotations: map[string]string{v1alpha5.DeprovisioningCordonAnnotationKey: "true"},
+			},
+			Unschedulable: true,
+		})
+		// an operator cordoned the node
+		cordoned := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{v1alpha5.ProvisionerNameLabelKey: prov.Name},
+			},
+			Unschedulable: true,
+		})
+		ExpectApplied(ctx, env.Client, orphaned, cordoned, prov)
+		ExpectMakeNodesReady(ctx, env.Client, orphaned, cordoned)
+
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		orphaned = ExpectNodeExists(ctx, env.Client, orphaned.Name)
+		Expect(orphaned.Spec.Unschedulable).To(BeFalse())
+		Expect(orphaned.Annotations).ToNot(HaveKey(v1alpha5.DeprovisioningCordonAnnotationKey))
+		cordoned = ExpectNodeExists(ctx, env.Client, cordoned.Name)
+		Expect(cordoned.Spec.Unschedulable).To(BeTrue())
+	})
+	It("should only check for orphaned cordons periodically", func() {
+		prov := test.Provisioner()
+		ExpectApplied(ctx, env.Client, prov)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		orphaned := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{v1alpha5.ProvisionerNameLabelKey: prov.Name},
+				Annotations: map[string]string{v1alpha5.DeprovisioningCordonAnnotationKey: "true"},
+			},
+			Unschedulable: true,
+		})
+		ExpectApplied(ctx, env.Client, orphaned)
+		ExpectMakeNodesReady(ctx, env.Client, orphaned)
+
+		// the cordons were checked on the first reconcile, so they aren't checked again right away
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+		orphaned = ExpectNodeExists(ctx, env.Client, orphaned.Name)
+		Expect(orphaned.Spec.Unschedulable).To(BeTrue())
+
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+		orphaned = ExpectNodeExists(ctx, env.Client, orphaned.Name)
+		Expect(orphaned.Spec.Unschedulable).To(BeFalse())
+	})
+})
+
+var _ = Describe("Resource Claims", func() {
+	It("won't consolidate a node whose pods have resource claims", func() {
+		s := test.Settings()
+		s.PinResourceClaimPods = true
+		claimsCtx := settings.ToContext(ctx, s)
+
 		rs := test.ReplicaSet()
 		ExpectApplied(ctx, env.Client, rs)
 		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
-
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
 		prov := test.Provisioner(test.ProvisionerOptions{
 			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
 		})
-		podOpts := test.PodOptions{
+		nodes := lo.Times(2, func(_ int) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}})
+		})
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes[0], nodes[1])
+		ExpectManualBinding(ctx, env.Client, pods[0], nodes[0])
+		ExpectManualBinding(ctx, env.Client, pods[1], nodes[0])
+		ExpectManualBinding(ctx, env.Client, pods[2], nodes[1])
+		pod := ExpectPodExists(ctx, env.Client, pods[2].Name, pods[2].Namespace)
+
+		// the pod's claim is allocated to a device on its node, so it can't move to the other node
+		claim := &unstructured.Unstructured{}
+		claim.SetGroupVersionKind(resourceClaimCRD.GroupVersionKind().GroupVersion().WithKind("ResourceClaim"))
+		claim.SetName(test.RandomName())
+		claim.SetNamespace(pod.Namespace)
+		Expect(unstructured.SetNestedSlice(claim.Object, []interface{}{
+			map[string]interface{}{"resource": "pods", "name": pod.Name, "uid": string(pod.UID)},
+		}, "status", "reservedFor")).To(Succeed())
+		Expect(env.Client.Create(ctx, claim)).To(Succeed())
+		DeferCleanup(func() { Expect(client.IgnoreNotFound(env.Client.Delete(ctx, claim))).To(Succeed()) })
+
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[0]))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[1]))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(claimsCtx)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the node with a single pod is the cheapest to disrupt, but its pod is pinned by its claim so the other node's
+		// pods are moved to it instead
+		ExpectNodeExists(ctx, env.Client, nodes[1].Name)
+		ExpectNotFound(ctx, env.Client, nodes[0])
+	})
+})
+
+var _ = Describe("Deprovisioning Summary", func() {
+	It("should annotate the provisioner with a summary of the nodes that consolidation removed", func() {
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		pod := test.Pod(test.PodOptions{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels: labels,
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion:         "apps/v1",
@@ -2086,67 +8177,338 @@ var _ = Describe("Parallelization", func() {
 						Controller:         ptr.Bool(true),
 						BlockOwnerDeletion: ptr.Bool(true),
 					},
-				},
-			},
-			ResourceRequirements: v1.ResourceRequirements{
-				Requests: v1.ResourceList{
-					v1.ResourceCPU: resource.MustParse("1"),
-				},
+				}}})
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		nodes := lo.Times(2, func(_ int) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}})
+		})
+		ExpectApplied(ctx, env.Client, rs, pod, nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes[0], nodes[1])
+		ExpectManualBinding(ctx, env.Client, pod, nodes[0])
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[0]))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[1]))
+
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectNotFound(ctx, env.Client, nodes[1])
+
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(prov), prov)).To(Succeed())
+		Expect(prov.Annotations).To(HaveKey(v1alpha5.DeprovisioningSummaryAnnotationKey))
+		var summary deprovisioning.Summary
+		Expect(json.Unmarshal([]byte(prov.Annotations[v1alpha5.DeprovisioningSummaryAnnotationKey]), &summary)).To(Succeed())
+		Expect(summary.NodesRemoved).To(Equal(1))
+		Expect(summary.NodesLaunched).To(Equal(0))
+		Expect(summary.HourlySavings).To(BeNumerically("~", leastExpensiveOffering.Price, 0.0001))
+		Expect(summary.Time.IsZero()).To(BeFalse())
+	})
+})
+
+var _ = Describe("Tracing", func() {
+	It("should trace a consolidation pass from the pass down to its scheduling simulations", func() {
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		pods := test.Pods(2, test.PodOptions{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{
+			{
+				APIVersion:         "apps/v1",
+				Kind:               "ReplicaSet",
+				Name:               rs.Name,
+				UID:                rs.UID,
+				Controller:         ptr.Bool(true),
+				BlockOwnerDeletion: ptr.Bool(true),
 			},
+		}}})
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+		nodes := lo.Times(2, func(_ int) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             leastExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}})
+		})
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], nodes[0], nodes[1], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes[0], nodes[1])
+		ExpectManualBinding(ctx, env.Client, pods[0], nodes[0])
+		ExpectManualBinding(ctx, env.Client, pods[1], nodes[1])
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[0]))
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[1]))
+
+		spans := tracetest.NewSpanRecorder()
+		deprovisioningController = deprovisioningController.WithTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("deprovisioning"))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+
+		// the pass is the root span
+		roots := endedSpans(spans, "deprovisioning.ProcessCluster")
+		Expect(roots).To(HaveLen(1))
+		Expect(roots[0].Parent().IsValid()).To(BeFalse())
+		Expect(spanAttributes(roots[0])).To(HaveKeyWithValue("result", deprovisioning.ResultSuccess.String()))
+
+		// each deprovisioner that computed a command is a child of the pass, and the one that acted records its decision
+		commands := endedSpans(spans, "deprovisioning.ComputeCommand")
+		Expect(commands).ToNot(BeEmpty())
+		for _, span := range commands {
+			Expect(span.Parent().SpanID()).To(Equal(roots[0].SpanContext().SpanID()))
 		}
+		deleted, ok := lo.Find(commands, func(span sdktrace.ReadOnlySpan) bool { return spanAttributes(span)["action"] == "delete" })
+		Expect(ok).To(BeTrue())
+		Expect(spanAttributes(deleted)).To(HaveKeyWithValue("candidates", "2"))
+
+		// and the scheduling simulations are children of the commands that ran them
+		commandIDs := lo.Map(commands, func(span sdktrace.ReadOnlySpan, _ int) trace.SpanID { return span.SpanContext().SpanID() })
+		simulations := endedSpans(spans, "deprovisioning.simulateScheduling")
+		Expect(simulations).ToNot(BeEmpty())
+		for _, span := range simulations {
+			Expect(commandIDs).To(ContainElement(span.Parent().SpanID()))
+			Expect(spanAttributes(span)).To(HaveKey("allPodsScheduled"))
+		}
+	})
+})
 
-		var pods []*v1.Pod
-		for i := 0; i < 5; i++ {
-			pod := test.UnschedulablePod(podOpts)
-			pods = append(pods, pod)
+var _ = Describe("External Cordons", func() {
+	var prov *v1alpha5.Provisioner
+	var rs *appsv1.ReplicaSet
+	BeforeEach(func() {
+		prov = test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		// create our RS so we can link a pod to it
+		rs = test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+	})
+	newPods := func(count int) []*v1.Pod {
+		return test.Pods(count, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+	}
+	newNode := func(unschedulable bool) *v1.Node {
+		return test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				}},
+			Unschedulable: unschedulable,
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			}})
+	}
+	It("should prioritize removing nodes that were cordoned by another controller", func() {
+		pods := newPods(3)
+		// the nodes are equivalent, but the second was cordoned by another controller
+		nodes := []*v1.Node{newNode(false), newNode(true), newNode(false)}
+
+		ExpectApplied(ctx, env.Client, prov, pods[0], pods[1], pods[2], nodes[0], nodes[1], nodes[2])
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
 		}
-		ExpectApplied(ctx, env.Client, rs, prov)
-		ExpectProvisioned(ctx, env.Client, recorder, provisioningController, provisioner, lo.Map(pods, func(p *v1.Pod, _ int) *v1.Pod { return p.DeepCopy() })...)
 
-		nodeList := &v1.NodeList{}
-		Expect(env.Client.List(ctx, nodeList)).To(Succeed())
-		Expect(len(nodeList.Items)).To(Equal(1))
+		s := test.Settings()
+		s.ConsolidationPolicy = settings.ConsolidationPolicyDeleteOnly
+		deleteOnlyCtx := settings.ToContext(ctx, s)
 
-		// Update cluster state with new node
-		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(&nodeList.Items[0]))
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(deleteOnlyCtx)
+		Expect(err).ToNot(HaveOccurred())
 
-		// Reset the bindings so we can re-record bindings
-		recorder.ResetBindings()
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, nodes[1])
+	})
+	It("should not uncordon a node that was cordoned by another controller when consolidation fails", func() {
+		pod := newPods(1)[0]
+		node := newNode(true)
+
+		ExpectApplied(ctx, env.Client, prov, pod, node)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
 
-		// Mark the node for deletion and re-trigger reconciliation
-		oldNodeName := nodeList.Items[0].Name
-		cluster.MarkForDeletion(nodeList.Items[0].Name)
+		// the replacement launch fails
+		cloudProvider.AllowedCreateCalls = 0
+		fakeClock.Step(10 * time.Minute)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
 
-		ExpectProvisionedNoBinding(ctx, env.Client, provisioningController, provisioner)
+		// the cordon isn't ours, so it's left in place
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeTrue())
+		Expect(node.Annotations).ToNot(HaveKey(v1alpha5.DeprovisioningCordonAnnotationKey))
+	})
+})
 
-		// Make sure that the cluster state is aware of the current node state
-		Expect(env.Client.List(ctx, nodeList)).To(Succeed())
-		Expect(len(nodeList.Items)).To(Equal(2))
-		newNode, _ := lo.Find(nodeList.Items, func(n v1.Node) bool { return n.Name != oldNodeName })
+var _ = Describe("Candidate Gathering", func() {
+	It("should gather the same pods for each node as listing the pods of each node", func() {
+		ds := test.DaemonSet()
+		ExpectApplied(ctx, env.Client, ds)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(ds), ds)).To(Succeed())
+
+		var nodes []*v1.Node
+		for i := 0; i < 3; i++ {
+			node := test.Node()
+			nodes = append(nodes, node)
+			ExpectApplied(ctx, env.Client, node)
+			// each node has a running pod, a daemonset pod and a terminal pod, and the second node has a second pod
+			pods := []*v1.Pod{
+				test.Pod(test.PodOptions{NodeName: node.Name}),
+				test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         "apps/v1",
+							Kind:               "DaemonSet",
+							Name:               ds.Name,
+							UID:                ds.UID,
+							Controller:         ptr.Bool(true),
+							BlockOwnerDeletion: ptr.Bool(true),
+						},
+					}}}),
+				test.Pod(test.PodOptions{NodeName: node.Name, Phase: v1.PodSucceeded}),
+			}
+			if i == 1 {
+				pods = append(pods, test.Pod(test.PodOptions{NodeName: node.Name}))
+			}
+			for _, pod := range pods {
+				ExpectApplied(ctx, env.Client, pod)
+			}
+		}
+		// pending pods aren't on any node
+		ExpectApplied(ctx, env.Client, test.UnschedulablePod())
 
-		for i := range nodeList.Items {
-			node := nodeList.Items[i]
-			ExpectMakeNodesReady(ctx, env.Client, &node)
-			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(&node))
+		podsByNode, err := nodeutils.GetPodsByNode(ctx, env.Client)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(podsByNode).To(HaveLen(len(nodes)))
+		for _, node := range nodes {
+			pods, err := nodeutils.GetNodePods(ctx, env.Client, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(podsByNode[node.Name]).To(HaveLen(len(pods)))
+			for _, pod := range pods {
+				Expect(podsByNode[node.Name]).To(ContainElement(pod))
+			}
 		}
+	})
+})
 
-		// Wait for the nomination cache to expire
-		time.Sleep(time.Second * 11)
+var _ = Describe("Consolidation Tie Breaking", func() {
+	It("should spread consolidation across equally disruptive nodes", func() {
+		s := test.Settings()
+		s.SpreadConsolidationTies = true
+		spreadCtx := settings.ToContext(ctx, s)
+
+		deletedByZone := map[string]int{}
+		for i := 0; i < 20; i++ {
+			// create our RS so we can link a pod to it
+			rs := test.ReplicaSet()
+			ExpectApplied(ctx, env.Client, rs)
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+			pods := test.Pods(2, test.PodOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         "apps/v1",
+							Kind:               "ReplicaSet",
+							Name:               rs.Name,
+							UID:                rs.UID,
+							Controller:         ptr.Bool(true),
+							BlockOwnerDeletion: ptr.Bool(true),
+						},
+					}}})
+			prov := test.Provisioner(test.ProvisionerOptions{
+				Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+			})
+			// two nodes that are identical other than their zone, each with a single pod, so either can be deleted
+			var nodes []*v1.Node
+			for _, zone := range []string{"test-zone-1", "test-zone-2"} {
+				nodes = append(nodes, test.Node(test.NodeOptions{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							v1alpha5.ProvisionerNameLabelKey: prov.Name,
+							v1.LabelInstanceTypeStable:       leastExpensiveInstance.Name,
+							v1alpha5.LabelCapacityType:       leastExpensiveOffering.CapacityType,
+							v1.LabelTopologyZone:             zone,
+						}},
+					Allocatable: map[v1.ResourceName]resource.Quantity{
+						v1.ResourceCPU:  resource.MustParse("32"),
+						v1.ResourcePods: resource.MustParse("100"),
+					}}))
+			}
+			ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], nodes[0], nodes[1], prov)
+			ExpectMakeNodesReady(ctx, env.Client, nodes...)
+			ExpectManualBinding(ctx, env.Client, pods[0], nodes[0])
+			ExpectManualBinding(ctx, env.Client, pods[1], nodes[1])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[0]))
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[1]))
+
+			fakeClock.Step(10 * time.Minute)
+			go triggerVerifyAction()
+			_, err := deprovisioningController.ProcessCluster(spreadCtx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProvider.CreateCalls).To(HaveLen(0))
 
-		// Re-create the pods to re-bind them
-		for i := 0; i < 2; i++ {
-			ExpectDeleted(ctx, env.Client, pods[i])
-			pod := test.UnschedulablePod(podOpts)
-			ExpectApplied(ctx, env.Client, pod)
-			ExpectManualBinding(ctx, env.Client, pod, &newNode)
+			for _, node := range nodes {
+				if err := env.Client.Get(ctx, client.ObjectKeyFromObject(node), &v1.Node{}); errors.IsNotFound(err) {
+					deletedByZone[node.Labels[v1.LabelTopologyZone]]++
+				}
+			}
+
+			// clean up so that the next pass starts from an identical cluster
+			ExpectCleanedUp(ctx, env.Client)
+			var nodeKeys []client.ObjectKey
+			cluster.ForEachNode(func(n *state.Node) bool {
+				nodeKeys = append(nodeKeys, client.ObjectKeyFromObject(n.Node))
+				return true
+			})
+			for _, nodeKey := range nodeKeys {
+				ExpectReconcileSucceeded(ctx, nodeStateController, nodeKey)
+			}
 		}
 
-		// Trigger a reconciliation run which should take into account the deleting node
-		// cnsolidation shouldn't trigger additional actions
-		fakeClock.Step(10 * time.Minute)
-		result, err := deprovisioningController.ProcessCluster(ctx)
-		Expect(err).ToNot(HaveOccurred())
-		Expect(result).To(Equal(deprovisioning.ResultNothingToDo))
+		// a single node is deleted on each pass, and the deletions are spread across both nodes
+		Expect(deletedByZone["test-zone-1"] + deletedByZone["test-zone-2"]).To(Equal(20))
+		Expect(deletedByZone["test-zone-1"]).To(BeNumerically(">=", 4))
+		Expect(deletedByZone["test-zone-2"]).To(BeNumerically(">=", 4))
 	})
 })
 
@@ -2240,6 +8602,89 @@ var _ = Describe("Multi-Node Consolidation", func() {
 		ExpectNotFound(ctx, env.Client, node2)
 		ExpectNotFound(ctx, env.Client, node3)
 	})
+	It("cordons all nodes before any of them start draining when merging 3 nodes into 1", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+		var nodes []*v1.Node
+		for i := 0; i < 3; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					// block deletion so that we can observe the nodes while they would be draining
+					Finalizers: []string{"unit-test.com/block-deletion"},
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+						v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+						v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], nodes[0], nodes[1], nodes[2], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectScheduled(ctx, env.Client, pods[i])
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+		fakeClock.Step(10 * time.Minute)
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, nodes...)
+
+		var consolidationFinished atomic.Bool
+		go triggerVerifyAction()
+		go func() {
+			defer GinkgoRecover()
+			_, err := deprovisioningController.ProcessCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			consolidationFinished.Store(true)
+		}()
+		wg.Wait()
+
+		// as soon as any of the nodes starts draining, all of them must already be cordoned
+		Eventually(func() bool {
+			return lo.SomeBy(nodes, func(n *v1.Node) bool {
+				return !ExpectNodeExists(ctx, env.Client, n.Name).DeletionTimestamp.IsZero()
+			})
+		}, 10*time.Second).Should(BeTrue())
+		for _, n := range nodes {
+			Expect(ExpectNodeExists(ctx, env.Client, n.Name).Spec.Unschedulable).To(BeTrue())
+		}
+
+		// remove the finalizers so that consolidation can finish
+		for _, n := range nodes {
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(n), n)).To(Succeed())
+			n.SetFinalizers([]string{})
+			Expect(env.Client.Update(ctx, n)).To(Succeed())
+		}
+		Eventually(consolidationFinished.Load, 10*time.Second).Should(BeTrue())
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		for _, n := range nodes {
+			ExpectNotFound(ctx, env.Client, n)
+		}
+	})
 	It("won't merge 2 nodes into 1 of the same type", func() {
 		labels := map[string]string{
 			"app": "test",
@@ -2317,6 +8762,292 @@ var _ = Describe("Multi-Node Consolidation", func() {
 		// and left the other node alone
 		ExpectNodeExists(ctx, env.Client, node2.Name)
 	})
+	It("only merges nodes within the same zone when zonal consolidation is enabled", func() {
+		s := test.Settings()
+		s.ZonalConsolidation = true
+		zonalCtx := settings.ToContext(ctx, s)
+
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-small",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 1, Available: true},
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-2", Price: 1, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+		})
+		// the replacement is large enough to hold the pods of all three nodes
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "replacement-large",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 0.8, Available: true},
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-2", Price: 0.8, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, replacementInstance}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		// each pod fills most of its node, so no pod can move onto one of the other nodes
+		pods := test.Pods(3, test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1.5")}},
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+		var nodes []*v1.Node
+		for _, zone := range []string{"test-zone-1", "test-zone-1", "test-zone-2"} {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       currentInstance.Name,
+						v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+						v1.LabelTopologyZone:             zone,
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("2"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], nodes[0], nodes[1], nodes[2], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectScheduled(ctx, env.Client, pods[i])
+			// inform cluster state about the nodes
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+		fakeClock.Step(10 * time.Minute)
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, nodes...)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(zonalCtx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// the two nodes in the first zone are merged into a replacement in that zone, while the node in the other zone
+		// is left alone even though the replacement could have held its pod too
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1.LabelTopologyZone).Values()).To(ConsistOf("test-zone-1"))
+		ExpectNotFound(ctx, env.Client, nodes[0], nodes[1])
+		ExpectNodeExists(ctx, env.Client, nodes[2].Name)
+	})
+	Context("Cross Zone", func() {
+		// mergeNodes applies three nodes, two in the first zone and one in the second, that each hold one of the pods, and
+		// returns them once consolidation has run with zonal consolidation enabled
+		mergeNodes := func(crossZone bool, pods []*v1.Pod) []*v1.Node {
+			s := test.Settings()
+			s.ZonalConsolidation = true
+			zonalCtx := settings.ToContext(ctx, s)
+
+			currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name: "current-small",
+				Offerings: []cloudprovider.Offering{
+					{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 1, Available: true},
+					{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-2", Price: 1, Available: true},
+				},
+				Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+			})
+			// the replacement is large enough to hold the pods of all three nodes
+			replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name: "replacement-large",
+				Offerings: []cloudprovider.Offering{
+					{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 0.8, Available: true},
+					{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-2", Price: 0.8, Available: true},
+				},
+				Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+			})
+			cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, replacementInstance}
+
+			prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true), CrossZone: ptr.Bool(crossZone)}})
+			var nodes []*v1.Node
+			for _, zone := range []string{"test-zone-1", "test-zone-1", "test-zone-2"} {
+				nodes = append(nodes, test.Node(test.NodeOptions{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							v1alpha5.ProvisionerNameLabelKey: prov.Name,
+							v1.LabelInstanceTypeStable:       currentInstance.Name,
+							v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+							v1.LabelTopologyZone:             zone,
+						}},
+					Allocatable: map[v1.ResourceName]resource.Quantity{
+						v1.ResourceCPU:  resource.MustParse("2"),
+						v1.ResourcePods: resource.MustParse("100"),
+					}}))
+			}
+			ExpectApplied(ctx, env.Client, prov, nodes[0], nodes[1], nodes[2])
+			ExpectMakeNodesReady(ctx, env.Client, nodes...)
+			for i := range nodes {
+				ExpectApplied(ctx, env.Client, pods[i])
+				ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+				ExpectScheduled(ctx, env.Client, pods[i])
+				// inform cluster state about the nodes
+				ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+			}
+			fakeClock.Step(10 * time.Minute)
+			wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, nodes...)
+			go triggerVerifyAction()
+			_, err := deprovisioningController.ProcessCluster(zonalCtx)
+			Expect(err).ToNot(HaveOccurred())
+			wg.Wait()
+			return nodes
+		}
+		// replicaSetPods returns pods owned by a replica set that each fill most of a node, so no pod can move onto one of
+		// the other nodes
+		replicaSetPods := func(options test.PodOptions) []*v1.Pod {
+			rs := test.ReplicaSet()
+			ExpectApplied(ctx, env.Client, rs)
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+			options.ResourceRequirements = v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1.5")}}
+			options.ObjectMeta = metav1.ObjectMeta{Labels: map[string]string{"app": "test"},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}
+			return test.Pods(3, options)
+		}
+		It("merges nodes across zones if the provisioner allows it", func() {
+			nodes := mergeNodes(true, replicaSetPods(test.PodOptions{}))
+
+			// all three nodes are merged into a single replacement in one of their zones
+			Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+			Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1.LabelTopologyZone).Values()).To(ConsistOf("test-zone-1", "test-zone-2"))
+			ExpectNotFound(ctx, env.Client, nodes[0], nodes[1], nodes[2])
+		})
+		It("only merges nodes within the same zone if the provisioner doesn't allow cross zone consolidation", func() {
+			nodes := mergeNodes(false, replicaSetPods(test.PodOptions{}))
+
+			Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+			Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1.LabelTopologyZone).Values()).To(ConsistOf("test-zone-1"))
+			ExpectNotFound(ctx, env.Client, nodes[0], nodes[1])
+			ExpectNodeExists(ctx, env.Client, nodes[2].Name)
+		})
+		It("won't merge nodes across zones if their pods have zonal constraints", func() {
+			pods := replicaSetPods(test.PodOptions{})
+			// the pod on the node in the second zone must stay in that zone
+			pods[2].Spec.NodeSelector = map[string]string{v1.LabelTopologyZone: "test-zone-2"}
+			nodes := mergeNodes(true, pods)
+
+			Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+			Expect(cloudProvider.CreateCalls[0].Template.Requirements.Get(v1.LabelTopologyZone).Values()).To(ConsistOf("test-zone-1"))
+			ExpectNotFound(ctx, env.Client, nodes[0], nodes[1])
+			ExpectNodeExists(ctx, env.Client, nodes[2].Name)
+		})
+	})
+	It("won't merge nodes into a replacement that exceeds the max consolidation size ratio", func() {
+		s := test.Settings()
+		s.MaxConsolidationSizeRatio = 2
+		ctx = settings.ToContext(ctx, s)
+
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-small",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("2")},
+		})
+		// both replacements are cheaper than the nodes that they replace, but the large one is more than twice the size
+		mediumInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "replacement-medium",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 0.8, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")},
+		})
+		largeInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "replacement-large",
+			Offerings: []cloudprovider.Offering{
+				{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 0.5, Available: true},
+			},
+			Resources: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("16")},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, mediumInstance, largeInstance}
+
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		// each pod fills most of its node, so no pod can move onto one of the other nodes
+		pods := test.Pods(3, test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("1.5")}},
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)}})
+		var nodes []*v1.Node
+		for i := 0; i < 3; i++ {
+			nodes = append(nodes, test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       currentInstance.Name,
+						v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+						v1.LabelTopologyZone:             "test-zone-1a",
+					}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("2"),
+					v1.ResourcePods: resource.MustParse("100"),
+				}}))
+		}
+
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], nodes[0], nodes[1], nodes[2], prov)
+		ExpectMakeNodesReady(ctx, env.Client, nodes...)
+		for i := range nodes {
+			ExpectManualBinding(ctx, env.Client, pods[i], nodes[i])
+			ExpectScheduled(ctx, env.Client, pods[i])
+			// inform cluster state about the nodes
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(nodes[i]))
+		}
+		fakeClock.Step(10 * time.Minute)
+		wg := ExpectMakeNewNodesReady(ctx, env.Client, 1, nodes...)
+		go triggerVerifyAction()
+		_, err := deprovisioningController.ProcessCluster(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+
+		// should merge the three nodes into the medium instance type rather than the cheaper, but much larger, one
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(lo.Map(cloudProvider.CreateCalls[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })).
+			To(ConsistOf(mediumInstance.Name))
+		for _, n := range nodes {
+			ExpectNotFound(ctx, env.Client, n)
+		}
+	})
 	It("should wait for the node TTL for non-empty nodes before consolidating (multi-node)", func() {
 		labels := map[string]string{
 			"app": "test",
@@ -2381,6 +9112,8 @@ var _ = Describe("Multi-Node Consolidation", func() {
 		// inform cluster state about the nodes
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node1))
 		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node2))
+		// the nodes must be old enough to be consolidated
+		fakeClock.Step(10 * time.Minute)
 		ExpectMakeNewNodesReady(ctx, env.Client, 1, node1, node2)
 		var wg sync.WaitGroup
 		wg.Add(1)
@@ -2492,6 +9225,15 @@ func ExpectMakeNewNodesReady(ctx context.Context, client client.Client, numNewNo
 	return &wg
 }
 
+// ExpectConsolidationValidated waits for the controller to block on the fake clock for consolidation validation, checks
+// that it stays blocked until the validation timeout has fully passed, and then advances the clock exactly past it
+func ExpectConsolidationValidated(ctx context.Context, clk *clock.FakeClock, c *deprovisioning.Controller) {
+	Eventually(clk.HasWaiters).WithContext(ctx).WithTimeout(10 * time.Second).Should(BeTrue())
+	clk.Step(c.ValidationTimeout() - time.Nanosecond)
+	Expect(clk.HasWaiters()).To(BeTrue())
+	clk.Step(time.Nanosecond)
+}
+
 func ExpectMakeNodesReady(ctx context.Context, c client.Client, nodes ...*v1.Node) {
 	for _, node := range nodes {
 		var n v1.Node
@@ -2515,6 +9257,133 @@ func ExpectMakeNodesReady(ctx context.Context, c client.Client, nodes ...*v1.Nod
 	}
 }
 
+// fakeUsageSource reports fixed resource usage by node name
+type fakeUsageSource map[string]v1.ResourceList
+
+func (f fakeUsageSource) NodeUsage(_ context.Context, node *v1.Node) (v1.ResourceList, error) {
+	usage, ok := f[node.Name]
+	if !ok {
+		return nil, fmt.Errorf("no usage for node %s", node.Name)
+	}
+	return usage, nil
+}
+
+// endedSpans returns the spans with the name that the recorder has seen end
+func endedSpans(spans *tracetest.SpanRecorder, spanName string) []sdktrace.ReadOnlySpan {
+	return lo.Filter(spans.Ended(), func(span sdktrace.ReadOnlySpan, _ int) bool { return span.Name() == spanName })
+}
+
+// spanAttributes returns the span's attributes with their values formatted as strings
+func spanAttributes(span sdktrace.ReadOnlySpan) map[string]string {
+	return lo.Associate(span.Attributes(), func(kv attribute.KeyValue) (string, string) { return string(kv.Key), kv.Value.Emit() })
+}
+
+// recordingDeprovisioner is a trivial custom deprovisioner that considers every node and records the names of the
+// candidates that it's asked to compute a command for
+type recordingDeprovisioner struct {
+	candidates []string
+}
+
+func (r *recordingDeprovisioner) ShouldDeprovision(context.Context, *state.Node, *v1alpha5.Provisioner, []*v1.Pod) bool {
+	return true
+}
+
+func (r *recordingDeprovisioner) SortCandidates(nodes []deprovisioning.CandidateNode) []deprovisioning.CandidateNode {
+	return nodes
+}
+
+func (r *recordingDeprovisioner) ComputeCommand(_ context.Context, nodes ...deprovisioning.CandidateNode) (deprovisioning.Command, error) {
+	r.candidates = append(r.candidates, lo.Map(nodes, func(n deprovisioning.CandidateNode, _ int) string { return n.Name })...)
+	return deprovisioning.NewDoNothingCommand(), nil
+}
+
+func (r *recordingDeprovisioner) String() string {
+	return "recording"
+}
+
+// dryRunActions returns the number of deprovisioning actions that were computed but not performed in dry run mode
+func dryRunActions(action string) float64 {
+	families, err := crmetrics.Registry.Gather()
+	Expect(err).ToNot(HaveOccurred())
+	for _, mf := range families {
+		if mf.GetName() != "karpenter_deprovisioning_dryrun_actions_total" {
+			continue
+		}
+		for _, m := range mf.Metric {
+			if lo.ContainsBy(m.Label, func(l *io_prometheus_client.LabelPair) bool {
+				return l.GetName() == "action" && l.GetValue() == action
+			}) {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+// savings returns the reported hourly savings of the most recent consolidation action
+func savings() float64 {
+	families, err := crmetrics.Registry.Gather()
+	Expect(err).ToNot(HaveOccurred())
+	for _, mf := range families {
+		if mf.GetName() == "karpenter_deprovisioning_savings_dollars_per_hour" {
+			return mf.Metric[0].GetGauge().GetValue()
+		}
+	}
+	return 0
+}
+
+// abortedActions returns the number of deprovisioning actions that have been aborted for the reason
+func abortedActions(reason string) float64 {
+	families, err := crmetrics.Registry.Gather()
+	Expect(err).ToNot(HaveOccurred())
+	for _, mf := range families {
+		if mf.GetName() != "karpenter_deprovisioning_aborted_total" {
+			continue
+		}
+		for _, m := range mf.Metric {
+			if lo.ContainsBy(m.Label, func(l *io_prometheus_client.LabelPair) bool {
+				return l.GetName() == "reason" && l.GetValue() == reason
+			}) {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+// consolidationOscillations returns the number of consolidation replacements that would have reversed a recent
+// replacement
+func consolidationOscillations() float64 {
+	families, err := crmetrics.Registry.Gather()
+	Expect(err).ToNot(HaveOccurred())
+	for _, mf := range families {
+		if mf.GetName() == "karpenter_consolidation_oscillations_total" {
+			return mf.Metric[0].GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+// blockedByResource returns the number of scheduling simulations in which there wasn't enough of the resource left on
+// the existing nodes for displaced pods to reschedule
+func blockedByResource(resourceName string) float64 {
+	families, err := crmetrics.Registry.Gather()
+	Expect(err).ToNot(HaveOccurred())
+	for _, mf := range families {
+		if mf.GetName() != "karpenter_consolidation_blocked_by_resource_total" {
+			continue
+		}
+		for _, m := range mf.Metric {
+			if lo.ContainsBy(m.Label, func(l *io_prometheus_client.LabelPair) bool {
+				return l.GetName() == "resource" && l.GetValue() == resourceName
+			}) {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
 // cheapestOffering grabs the cheapest offering from the passed offerings
 func cheapestOffering(ofs []cloudprovider.Offering) cloudprovider.Offering {
 	offering := cloudprovider.Offering{Price: math.MaxFloat64}
@@ -2525,3 +9394,19 @@ func cheapestOffering(ofs []cloudprovider.Offering) cloudprovider.Offering {
 	}
 	return offering
 }
+
+// expectNextCreationTimestamp waits until objects that are created from now on have a later creation timestamp than the
+// node, since the API server sets creation timestamps with a granularity of a second
+func expectNextCreationTimestamp(node *v1.Node) {
+	time.Sleep(time.Until(node.CreationTimestamp.Add(time.Second)))
+}
+
+// expectedExpirationTime mirrors the deprovisioner's UID-derived expiration jitter, so that tests can tell when each of
+// a set of nodes that were created together should expire
+func expectedExpirationTime(node *v1.Node, provisioner *v1alpha5.Provisioner) time.Time {
+	ttl := time.Duration(ptr.Int64Value(provisioner.Spec.TTLSecondsUntilExpired)) * time.Second
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(node.UID))
+	offset := float64(h.Sum64())/math.MaxUint64*2 - 1
+	return node.CreationTimestamp.Add(ttl + time.Duration(offset*ptr.Float64Value(provisioner.Spec.ExpirationJitterFactor)*float64(ttl)))
+}
@@ -0,0 +1,144 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+)
+
+// summaryWritePeriod is the minimum time between writes of a provisioner's summary annotation. Deprovisioning that
+// happens in between is rolled up into the next write.
+const summaryWritePeriod = time.Minute
+
+// Summary is the deprovisioning of a provisioner's nodes since its summary was last written, and is written to the
+// provisioner's annotations so that recent deprovisioning is visible without searching logs or events
+type Summary struct {
+	// NodesRemoved is the number of the provisioner's nodes that were deprovisioned
+	NodesRemoved int `json:"nodesRemoved"`
+	// NodesLaunched is the number of replacement nodes that were launched for the provisioner
+	NodesLaunched int `json:"nodesLaunched"`
+	// HourlySavings is the reduction in the hourly cost of the provisioner's nodes
+	HourlySavings float64 `json:"hourlySavings"`
+	// LastSkipReason describes the last command for the provisioner's nodes that was computed but not executed
+	LastSkipReason string `json:"lastSkipReason,omitempty"`
+	// Time is when the summary was written
+	Time metav1.Time `json:"time"`
+}
+
+// Summaries accumulates the deprovisioning of each provisioner's nodes, and writes it to the provisioner at most once
+// per summaryWritePeriod
+type Summaries struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	pending map[string]*Summary
+	written map[string]time.Time
+}
+
+func NewSummaries(clk clock.Clock) *Summaries {
+	return &Summaries{clock: clk, pending: map[string]*Summary{}, written: map[string]time.Time{}}
+}
+
+// RecordCommand records that the command executed, attributing each removed node and each replacement to its
+// provisioner along with the difference in their prices
+func (s *Summaries) RecordCommand(cmd Command, instanceTypesByProvisioner map[string]map[string]*cloudprovider.InstanceType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, node := range cmd.nodesToRemove {
+		summary := s.summary(node.Labels[v1alpha5.ProvisionerNameLabelKey])
+		summary.NodesRemoved++
+		if price, ok := nodePrice(node, instanceTypesByProvisioner); ok {
+			summary.HourlySavings += price
+		}
+	}
+	for _, replacement := range cmd.replacementNodes {
+		summary := s.summary(replacement.ProvisionerName)
+		summary.NodesLaunched++
+		if price := cheapestLaunchPrice(replacement); price != math.MaxFloat64 {
+			summary.HourlySavings -= price
+		}
+	}
+}
+
+// RecordSkip records why a command for the nodes was computed but not executed
+func (s *Summaries) RecordSkip(nodes []*v1.Node, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, node := range nodes {
+		s.summary(node.Labels[v1alpha5.ProvisionerNameLabelKey]).LastSkipReason = reason
+	}
+}
+
+func (s *Summaries) summary(provisionerName string) *Summary {
+	if _, ok := s.pending[provisionerName]; !ok {
+		s.pending[provisionerName] = &Summary{}
+	}
+	return s.pending[provisionerName]
+}
+
+// Write writes the pending summary of each provisioner that wasn't written within the summaryWritePeriod to its
+// annotations. It's best effort, so failures are logged and the summary is retried on the next write.
+func (s *Summaries) Write(ctx context.Context, kubeClient client.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, summary := range s.pending {
+		if name == "" {
+			delete(s.pending, name)
+			continue
+		}
+		if written, ok := s.written[name]; ok && s.clock.Since(written) < summaryWritePeriod {
+			continue
+		}
+		summary.Time = metav1.NewTime(s.clock.Now())
+		if err := writeSummary(ctx, kubeClient, name, summary); err != nil {
+			if errors.IsNotFound(err) {
+				delete(s.pending, name)
+				continue
+			}
+			logging.FromContext(ctx).With("provisioner", name).Errorf("writing deprovisioning summary, %s", err)
+			continue
+		}
+		s.written[name] = s.clock.Now()
+		delete(s.pending, name)
+	}
+}
+
+func writeSummary(ctx context.Context, kubeClient client.Client, provisionerName string, summary *Summary) error {
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	provisioner := &v1alpha5.Provisioner{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: provisionerName}, provisioner); err != nil {
+		return err
+	}
+	stored := provisioner.DeepCopy()
+	provisioner.Annotations = lo.Assign(provisioner.Annotations, map[string]string{v1alpha5.DeprovisioningSummaryAnnotationKey: string(raw)})
+	return kubeClient.Patch(ctx, provisioner, client.MergeFrom(stored))
+}
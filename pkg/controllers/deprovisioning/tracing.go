@@ -0,0 +1,44 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// noopTracer is used if no tracer is configured, so that spans cost nothing unless a pass is traced
+var noopTracer = trace.NewNoopTracerProvider().Tracer("")
+
+type tracerKey struct{}
+
+// withTracer returns a context whose spans are started by the tracer. The spans of a pass are nested beneath the span
+// that's carried by the context.
+func withTracer(ctx context.Context, tracer trace.Tracer) context.Context {
+	if tracer == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// startSpan starts a span with the context's tracer, and is a no-op if no tracer is configured
+func startSpan(ctx context.Context, spanName string) (context.Context, trace.Span) {
+	tracer, ok := ctx.Value(tracerKey{}).(trace.Tracer)
+	if !ok {
+		tracer = noopTracer
+	}
+	return tracer.Start(ctx, spanName)
+}
@@ -19,7 +19,9 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 
@@ -53,8 +55,39 @@ func (r Result) String() string {
 	}
 }
 
+// DeprovisioningResult describes what a call to ProcessCluster did. If consolidation cascaded through several commands,
+// it describes all of them, and Action is the action of the last one. In dry run mode Result is ResultNothingToDo, as
+// nothing executed, and the rest describes the command that would have.
+type DeprovisioningResult struct {
+	Result Result
+	// Action is the deprovisioner and action of the command that executed, e.g. "consolidation/replace", and is empty
+	// if no command executed
+	Action string
+	// RemovedNodes are the names of the nodes that were removed
+	RemovedNodes []string
+	// ReplacementInstanceTypes are the instance types that the replacements may launch as, cheapest first
+	ReplacementInstanceTypes []string
+	// CostDelta is the projected change in the cluster's hourly cost, which is negative if the command saves money
+	CostDelta float64
+}
+
+// cascade combines the result of a command that the result's command enabled
+func (r DeprovisioningResult) cascade(next DeprovisioningResult) DeprovisioningResult {
+	return DeprovisioningResult{
+		Result:                   next.Result,
+		Action:                   lo.Ternary(next.Action != "", next.Action, r.Action),
+		RemovedNodes:             append(r.RemovedNodes, next.RemovedNodes...),
+		ReplacementInstanceTypes: append(r.ReplacementInstanceTypes, next.ReplacementInstanceTypes...),
+		CostDelta:                r.CostDelta + next.CostDelta,
+	}
+}
+
+// Deprovisioner is a deprovisioning strategy. The controller attempts each registered deprovisioner in order, filtering
+// the cluster's nodes to its candidates with ShouldDeprovision and ordering them with SortCandidates, and executes the
+// first command that isn't a no-op.
 type Deprovisioner interface {
 	ShouldDeprovision(context.Context, *state.Node, *v1alpha5.Provisioner, []*v1.Pod) bool
+	SortCandidates([]CandidateNode) []CandidateNode
 	ComputeCommand(context.Context, ...CandidateNode) (Command, error)
 	String() string
 }
@@ -94,6 +127,31 @@ type Command struct {
 	nodesToRemove    []*v1.Node
 	action           action
 	replacementNodes []*scheduling.Node
+	// targetNodes are the existing nodes that some of the displaced pods are expected to be rescheduled to
+	targetNodes []*v1.Node
+	// placements maps each displaced pod to the name of the existing node that it's expected to be rescheduled to, for
+	// commands that delete nodes without launching replacements
+	placements map[types.NamespacedName]string
+	// dryRun commands are reported without terminating or launching any nodes
+	dryRun bool
+}
+
+// NewDeleteCommand returns a command that deletes the nodes without launching replacements, for use by deprovisioners
+// that are registered with the controller
+func NewDeleteCommand(nodes ...*v1.Node) Command {
+	return Command{nodesToRemove: nodes, action: actionDelete}
+}
+
+// NewDoNothingCommand returns a command that indicates that the deprovisioner found nothing to do
+func NewDoNothingCommand() Command {
+	return Command{action: actionDoNothing}
+}
+
+// Placements returns the name of the existing node that each displaced pod is expected to be rescheduled to, as
+// predicted by the scheduling simulation. It's only populated for consolidations that delete nodes without launching
+// replacements.
+func (o Command) Placements() map[types.NamespacedName]string {
+	return o.placements
 }
 
 func (o Command) String() string {
@@ -21,11 +21,16 @@ import (
 	"sync"
 	"time"
 
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
@@ -60,13 +65,38 @@ func NewValidation(validationPeriod time.Duration, clk clock.Clock, cluster *sta
 	}
 }
 
-func (v *Validation) IsValid(ctx context.Context, cmd Command) (bool, error) {
-	var err error
+type dryRunKey struct{}
+
+// withDryRun returns a context in which commands are validated without tainting the nodes that they remove
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// dryRun returns true if the context is being used to compute commands for a dry run
+func dryRun(ctx context.Context) bool {
+	isDryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return isDryRun
+}
+
+func (v *Validation) IsValid(ctx context.Context, cmd Command) (isValid bool, err error) {
 	v.once.Do(func() {
 		v.start = v.clock.Now()
 	})
 
 	waitDuration := v.validationPeriod - v.clock.Since(v.start)
+	if waitDuration > 0 && !dryRun(ctx) {
+		// taint the nodes while we wait so that the scheduler avoids them. The taint is removed again unless the command
+		// is still valid, in which case it's replaced by a cordon when the command executes.
+		nodeNames := lo.Map(cmd.nodesToRemove, func(n *v1.Node, _ int) string { return n.Name })
+		if err := setValidationTaint(ctx, v.kubeClient, true, nodeNames...); err != nil {
+			return false, multierr.Combine(fmt.Errorf("tainting nodes, %w", err), setValidationTaint(ctx, v.kubeClient, false, nodeNames...))
+		}
+		defer func() {
+			if !isValid {
+				err = multierr.Append(err, setValidationTaint(ctx, v.kubeClient, false, nodeNames...))
+			}
+		}()
+	}
 	if waitDuration > 0 {
 		select {
 		case <-ctx.Done():
@@ -76,7 +106,7 @@ func (v *Validation) IsValid(ctx context.Context, cmd Command) (bool, error) {
 	}
 
 	if len(v.validationCandidates) == 0 {
-		v.validationCandidates, err = candidateNodes(ctx, v.cluster, v.kubeClient, v.clock, v.cloudProvider, v.ShouldDeprovision)
+		v.validationCandidates, err = candidateNodes(ctx, v.cluster, v.kubeClient, v.clock, v.cloudProvider, v.ShouldDeprovision, false)
 		if err != nil {
 			return false, fmt.Errorf("constructing validation candidates, %w", err)
 		}
@@ -86,11 +116,12 @@ func (v *Validation) IsValid(ctx context.Context, cmd Command) (bool, error) {
 	// before continuing consolidation
 	for _, n := range cmd.nodesToRemove {
 		if v.cluster.IsNodeNominated(n.Name) {
+			deprovisioningAbortedCounter.WithLabelValues(abortReasonPodAdded).Inc()
 			return false, nil
 		}
 	}
 
-	isValid, err := v.ValidateCommand(ctx, cmd, v.validationCandidates)
+	isValid, err = v.ValidateCommand(ctx, cmd, v.validationCandidates)
 	if err != nil {
 		return false, fmt.Errorf("validating command, %w", err)
 	}
@@ -98,6 +129,44 @@ func (v *Validation) IsValid(ctx context.Context, cmd Command) (bool, error) {
 	return isValid, nil
 }
 
+// setValidationTaint adds or removes the taint that's applied to nodes while a command that removes them is validated.
+// The taint is only added if settings configure its effect, but is always removed.
+func setValidationTaint(ctx context.Context, kubeClient client.Client, tainted bool, nodeNames ...string) error {
+	effect := v1.TaintEffect(settings.FromContext(ctx).ValidationTaintEffect)
+	if tainted && effect == "" {
+		return nil
+	}
+	var multiErr error
+	for _, nodeName := range nodeNames {
+		var node v1.Node
+		if err := kubeClient.Get(ctx, client.ObjectKey{Name: nodeName}, &node); err != nil {
+			// the node was deleted, so there's no taint to remove
+			if !tainted && apierrors.IsNotFound(err) {
+				continue
+			}
+			multiErr = multierr.Append(multiErr, fmt.Errorf("getting node, %w", err))
+			continue
+		}
+		persisted := node.DeepCopy()
+		node.Spec.Taints = withoutValidationTaint(node.Spec.Taints)
+		if tainted {
+			node.Spec.Taints = append(node.Spec.Taints, v1.Taint{Key: v1alpha5.DeprovisioningValidationTaintKey, Effect: effect})
+		}
+		if equality.Semantic.DeepEqual(node.Spec.Taints, persisted.Spec.Taints) {
+			continue
+		}
+		if err := kubeClient.Patch(ctx, &node, client.MergeFrom(persisted)); err != nil {
+			multiErr = multierr.Append(multiErr, fmt.Errorf("patching node %s, %w", node.Name, err))
+		}
+	}
+	return multiErr
+}
+
+// withoutValidationTaint returns the taints other than the validation taint
+func withoutValidationTaint(taints []v1.Taint) []v1.Taint {
+	return lo.Reject(taints, func(t v1.Taint, _ int) bool { return t.Key == v1alpha5.DeprovisioningValidationTaintKey })
+}
+
 // ShouldDeprovision is a predicate used to filter deprovisionable nodes
 func (v *Validation) ShouldDeprovision(_ context.Context, n *state.Node, provisioner *v1alpha5.Provisioner, _ []*v1.Pod) bool {
 	if val, ok := n.Node.Annotations[v1alpha5.DoNotConsolidateNodeAnnotationKey]; ok {
@@ -108,19 +177,32 @@ func (v *Validation) ShouldDeprovision(_ context.Context, n *state.Node, provisi
 
 // ValidateCommand validates a command for a deprovisioner
 func (v *Validation) ValidateCommand(ctx context.Context, cmd Command, candidateNodes []CandidateNode) (bool, error) {
+	abortReason, err := v.validateCommand(ctx, cmd, candidateNodes)
+	if err != nil {
+		return false, err
+	}
+	if abortReason != "" {
+		deprovisioningAbortedCounter.WithLabelValues(abortReason).Inc()
+		return false, nil
+	}
+	return true, nil
+}
+
+// validateCommand returns the reason that the command should be aborted, or an empty string if it's still valid
+func (v *Validation) validateCommand(ctx context.Context, cmd Command, candidateNodes []CandidateNode) (string, error) {
 	// map from nodes we are about to remove back into candidate nodes with cluster state
 	nodesToDelete := mapNodes(cmd.nodesToRemove, candidateNodes)
 	// None of the chosen candidate nodes are valid for execution, so retry
 	if len(nodesToDelete) == 0 {
-		return false, nil
+		return abortReasonCandidateChanged, nil
 	}
 
-	newNodes, allPodsScheduled, err := simulateScheduling(ctx, v.kubeClient, v.cluster, v.provisioner, nodesToDelete...)
+	newNodes, _, allPodsScheduled, err := simulateScheduling(ctx, v.kubeClient, v.cluster, v.provisioner, nodesToDelete...)
 	if err != nil {
-		return false, fmt.Errorf("simluating scheduling, %w", err)
+		return "", fmt.Errorf("simluating scheduling, %w", err)
 	}
 	if !allPodsScheduled {
-		return false, nil
+		return abortReasonPodAdded, nil
 	}
 
 	// We want to ensure that the re-simulated scheduling using the current cluster state produces the same result.
@@ -132,22 +214,33 @@ func (v *Validation) ValidateCommand(ctx context.Context, cmd Command, candidate
 	if len(newNodes) == 0 {
 		if len(cmd.replacementNodes) == 0 {
 			// scheduling produced zero new nodes and we weren't expecting any, so this is valid.
-			return true, nil
+			return "", nil
 		}
 		// if it produced no new nodes, but we were expecting one we should re-simulate as there is likely a better
 		// consolidation option now
-		return false, nil
+		return abortReasonCandidateChanged, nil
 	}
 
 	// we need more than one replacement node which is never valid currently (all of our node replacement is m->1, never m->n)
 	if len(newNodes) > 1 {
-		return false, nil
+		return abortReasonPodAdded, nil
 	}
 
 	// we now know that scheduling simulation wants to create one new node
 	if len(cmd.replacementNodes) == 0 {
 		// but we weren't expecting any new nodes, so this is invalid
-		return false, nil
+		return abortReasonPodAdded, nil
+	}
+
+	// displaced pods must be able to satisfy their required node affinity on the replacement, and the replacement must
+	// be an instance type, capacity type, size and zone that settings allow
+	filterByRequiredNodeAffinity(nodesToDelete, newNodes[0])
+	filterByReplacementAllowList(ctx, newNodes[0])
+	filterByCapacityType(ctx, nodesToDelete, newNodes[0])
+	filterBySizeRatio(ctx, nodesToDelete, newNodes[0])
+	filterByZone(ctx, nodesToDelete, newNodes[0])
+	if len(newNodes[0].InstanceTypeOptions) == 0 {
+		return abortReasonOfferingUnavailable, nil
 	}
 
 	// We know that the scheduling simulation wants to create a new node and that the command we are verifying wants
@@ -162,11 +255,11 @@ func (v *Validation) ValidateCommand(ctx context.Context, cmd Command, candidate
 	// now says that we need to launch a 4xlarge. It's still launching the correct number of nodes, but it's just
 	// as expensive or possibly more so we shouldn't validate.
 	if !instanceTypesAreSubset(cmd.replacementNodes[0].InstanceTypeOptions, newNodes[0].InstanceTypeOptions) {
-		return false, nil
+		return abortReasonOfferingUnavailable, nil
 	}
 
 	// Now we know:
 	// - current scheduling simulation says to create a new node with types T = {T_0, T_1, ..., T_n}
 	// - our lifecycle command says to create a node with types {U_0, U_1, ..., U_n} where U is a subset of T
-	return true, nil
+	return "", nil
 }
@@ -55,7 +55,7 @@ func (t *Termination) Check(ctx context.Context, node *v1.Node, provisioner *v1a
 		})
 	}
 
-	if reason, ok := deprovisioning.PodsPreventEviction(pods); ok {
+	if reason, ok := deprovisioning.PodsPreventEviction(ctx, pods); ok {
 		issues = append(issues, Issue{
 			node:    node,
 			message: fmt.Sprintf("Can't drain node, %s", reason),
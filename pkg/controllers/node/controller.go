@@ -51,6 +51,7 @@ type Controller struct {
 	initialization *Initialization
 	emptiness      *Emptiness
 	finalizer      *Finalizer
+	zombie         *Zombie
 }
 
 // NewController constructs a nodeController instance
@@ -60,6 +61,7 @@ func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider clou
 		cluster:        cluster,
 		initialization: &Initialization{kubeClient: kubeClient, cloudProvider: cloudProvider},
 		emptiness:      &Emptiness{kubeClient: kubeClient, clock: clk, cluster: cluster},
+		zombie:         &Zombie{kubeClient: kubeClient, cloudProvider: cloudProvider},
 	})
 }
 
@@ -74,9 +76,14 @@ func (c *Controller) Reconcile(ctx context.Context, node *v1.Node) (reconcile.Re
 		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Nodes whose instances no longer exist are removed without running the other reconcilers
+	removed, res, errs := c.zombie.Reconcile(ctx, provisioner, node)
+	if removed {
+		return res, errs
+	}
+
 	// Execute Reconcilers
-	var results []reconcile.Result
-	var errs error
+	results := []reconcile.Result{res}
 	for _, reconciler := range []interface {
 		Reconcile(context.Context, *v1alpha5.Provisioner, *v1.Node) (reconcile.Result, error)
 	}{
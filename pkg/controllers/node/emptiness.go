@@ -28,6 +28,7 @@ import (
 
 	"github.com/samber/lo"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/utils/pod"
@@ -81,8 +82,13 @@ func (r *Emptiness) isEmpty(ctx context.Context, n *v1.Node) (bool, error) {
 	if err := r.kubeClient.List(ctx, pods, client.MatchingFields{"spec.nodeName": n.Name}); err != nil {
 		return false, fmt.Errorf("listing pods for node, %w", err)
 	}
+	window := settings.FromContext(ctx).CrashLoopingPodWindow.Duration
 	for i := range pods.Items {
 		p := pods.Items[i]
+		// pods that have been crash looping for the window provide no service, so they don't keep the node occupied
+		if window > 0 && pod.IsCrashLooping(&p, r.clock.Now().Add(-window)) {
+			continue
+		}
 		if !pod.IsTerminal(&p) && !pod.IsOwnedByDaemonSet(&p) && !pod.IsOwnedByNode(&p) {
 			return false, nil
 		}
@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clock "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/ptr"
 	controllerruntime "sigs.k8s.io/controller-runtime"
@@ -50,6 +51,7 @@ var ctx context.Context
 var nodeController controller.Controller
 var env *test.Environment
 var fakeClock *clock.FakeClock
+var cloudProvider *fake.CloudProvider
 
 func TestAPIs(t *testing.T) {
 	ctx = TestContextWithLogger(t)
@@ -61,9 +63,9 @@ var _ = BeforeSuite(func() {
 	fakeClock = clock.NewFakeClock(time.Now())
 	env = test.NewEnvironment(scheme.Scheme, apis.CRDs...)
 	ctx = settings.ToContext(ctx, test.Settings())
-	cp := fake.NewCloudProvider()
-	cluster := state.NewCluster(ctx, fakeClock, env.Client, cp)
-	nodeController = node.NewController(fakeClock, env.Client, cp, cluster)
+	cloudProvider = fake.NewCloudProvider()
+	cluster := state.NewCluster(ctx, fakeClock, env.Client, cloudProvider)
+	nodeController = node.NewController(fakeClock, env.Client, cloudProvider, cluster)
 })
 
 var _ = AfterSuite(func() {
@@ -81,6 +83,7 @@ var _ = Describe("Controller", func() {
 
 	AfterEach(func() {
 		fakeClock.SetTime(time.Now())
+		cloudProvider.MissingProviderIDs = nil
 		ExpectCleanedUp(ctx, env.Client)
 	})
 
@@ -384,6 +387,82 @@ var _ = Describe("Controller", func() {
 			}}))
 		})
 	})
+	Context("Zombie", func() {
+		It("should remove the node without draining once its instance is gone", func() {
+			n := test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:     map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+					Finalizers: []string{v1alpha5.TerminationFinalizer},
+				},
+				ProviderID: "fake://zombie",
+			})
+			ExpectApplied(ctx, env.Client, provisioner, n)
+			cloudProvider.MissingProviderIDs = sets.NewString(n.Spec.ProviderID)
+
+			// the instance could be temporarily unreachable, so the node isn't removed on the first miss
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+			ExpectNodeExists(ctx, env.Client, n.Name)
+
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+			ExpectNotFound(ctx, env.Client, n)
+		})
+		It("should not remove the node if its instance is found again", func() {
+			n := test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:     map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+					Finalizers: []string{v1alpha5.TerminationFinalizer},
+				},
+				ProviderID: "fake://unreachable",
+			})
+			ExpectApplied(ctx, env.Client, provisioner, n)
+			cloudProvider.MissingProviderIDs = sets.NewString(n.Spec.ProviderID)
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+
+			// the instance becomes reachable again which resets the count of misses
+			cloudProvider.MissingProviderIDs = nil
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+
+			cloudProvider.MissingProviderIDs = sets.NewString(n.Spec.ProviderID)
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+			n = ExpectNodeExists(ctx, env.Client, n.Name)
+			Expect(n.Finalizers).To(ContainElement(v1alpha5.TerminationFinalizer))
+		})
+		It("should forget the misses of a node that's deleted by other means", func() {
+			n := test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:     map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+					Finalizers: []string{v1alpha5.TerminationFinalizer},
+				},
+				ProviderID: "fake://deleted",
+			})
+			ExpectApplied(ctx, env.Client, provisioner, n)
+			cloudProvider.MissingProviderIDs = sets.NewString(n.Spec.ProviderID)
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+
+			// the node is deleted by other means, and its finalizer is removed once it's been reconciled while deleting
+			Expect(env.Client.Delete(ctx, n)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+			ExpectFinalizersRemoved(ctx, env.Client, n)
+			ExpectNotFound(ctx, env.Client, n)
+
+			// a node with the same name starts counting its misses afresh
+			n = test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       n.Name,
+					Labels:     map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+					Finalizers: []string{v1alpha5.TerminationFinalizer},
+				},
+				ProviderID: "fake://deleted",
+			})
+			ExpectApplied(ctx, env.Client, n)
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(n))
+			ExpectNodeExists(ctx, env.Client, n.Name)
+		})
+	})
 	Context("Filters", func() {
 		BeforeEach(func() {
 			innerCtx, cancel := context.WithCancel(ctx)
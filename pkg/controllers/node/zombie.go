@@ -0,0 +1,128 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+)
+
+const (
+	// zombieNotFoundThreshold is the number of consecutive times that the cloudprovider must report a node's instance
+	// as not found before the node is removed. This avoids removing nodes whose instances are only temporarily
+	// unreachable or not yet visible due to eventual consistency.
+	zombieNotFoundThreshold = 3
+	zombieRetryInterval     = 10 * time.Second
+)
+
+// Zombie is a subreconciler that removes node objects whose backing instances no longer exist. The termination
+// finalizer is removed before the node is deleted since there is nothing left to drain.
+type Zombie struct {
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+
+	mu       sync.Mutex
+	notFound map[string]int
+}
+
+// Reconcile reconciles the node, returning true if the node was removed
+func (r *Zombie) Reconcile(ctx context.Context, _ *v1alpha5.Provisioner, node *v1.Node) (bool, reconcile.Result, error) {
+	// a node that's being deleted by other means is no longer tracked, since it's about to be gone
+	if !node.DeletionTimestamp.IsZero() {
+		r.resetNotFound(node.Name)
+		return false, reconcile.Result{}, nil
+	}
+	getter, ok := r.cloudProvider.(cloudprovider.NodeGetter)
+	if !ok || node.Spec.ProviderID == "" {
+		return false, reconcile.Result{}, nil
+	}
+	if _, err := getter.Get(ctx, node); err != nil {
+		if !cloudprovider.IsNodeNotFoundError(err) {
+			return false, reconcile.Result{}, fmt.Errorf("getting node from cloudprovider, %w", err)
+		}
+		r.prune(ctx)
+		if count := r.recordNotFound(node.Name); count < zombieNotFoundThreshold {
+			return false, reconcile.Result{RequeueAfter: zombieRetryInterval}, nil
+		}
+		if err := r.remove(ctx, node); err != nil {
+			return false, reconcile.Result{}, err
+		}
+		logging.FromContext(ctx).Infof("removed node whose instance no longer exists")
+		return true, reconcile.Result{}, nil
+	}
+	r.resetNotFound(node.Name)
+	return false, reconcile.Result{}, nil
+}
+
+// remove deletes the node without draining it by removing the termination finalizer first
+func (r *Zombie) remove(ctx context.Context, node *v1.Node) error {
+	stored := node.DeepCopy()
+	controllerutil.RemoveFinalizer(stored, v1alpha5.TerminationFinalizer)
+	if err := r.kubeClient.Patch(ctx, stored, client.MergeFrom(node)); err != nil {
+		if errors.IsNotFound(err) {
+			r.resetNotFound(node.Name)
+			return nil
+		}
+		return fmt.Errorf("removing termination finalizer, %w", err)
+	}
+	if err := r.kubeClient.Delete(ctx, stored); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("deleting node, %w", err)
+	}
+	r.resetNotFound(node.Name)
+	return nil
+}
+
+// prune forgets the misses of nodes that no longer exist, e.g. because they were removed by other means without being
+// reconciled while they were deleting
+func (r *Zombie) prune(ctx context.Context) {
+	r.mu.Lock()
+	nodeNames := lo.Keys(r.notFound)
+	r.mu.Unlock()
+	for _, nodeName := range nodeNames {
+		if err := r.kubeClient.Get(ctx, types.NamespacedName{Name: nodeName}, &v1.Node{}); errors.IsNotFound(err) {
+			r.resetNotFound(nodeName)
+		}
+	}
+}
+
+func (r *Zombie) recordNotFound(nodeName string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.notFound == nil {
+		r.notFound = map[string]int{}
+	}
+	r.notFound[nodeName]++
+	return r.notFound[nodeName]
+}
+
+func (r *Zombie) resetNotFound(nodeName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.notFound, nodeName)
+}
@@ -52,7 +52,6 @@ import (
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	"github.com/aws/karpenter-core/pkg/utils/node"
 	"github.com/aws/karpenter-core/pkg/utils/pod"
-	"github.com/aws/karpenter-core/pkg/utils/resources"
 )
 
 // WaitForClusterSync controls whether or not we synchronize before scheduling. This is exposed for
@@ -288,11 +287,11 @@ func (p *Provisioner) NewScheduler(ctx context.Context, pods []*v1.Pod, stateNod
 	}
 
 	// Calculate daemon overhead
-	daemonOverhead, err := p.getDaemonOverhead(ctx, nodeTemplates)
+	daemons, err := p.getDaemons(ctx, nodeTemplates)
 	if err != nil {
 		return nil, fmt.Errorf("getting daemon overhead, %w", err)
 	}
-	return scheduler.NewScheduler(ctx, p.kubeClient, nodeTemplates, provisionerList.Items, p.cluster, stateNodes, topology, instanceTypes, daemonOverhead, p.recorder, opts), nil
+	return scheduler.NewScheduler(ctx, p.kubeClient, nodeTemplates, provisionerList.Items, p.cluster, stateNodes, topology, instanceTypes, daemons, p.recorder, opts), nil
 }
 
 func (p *Provisioner) schedule(ctx context.Context, pods []*v1.Pod, stateNodes []*state.Node) ([]*scheduler.Node, error) {
@@ -363,8 +362,10 @@ func (p *Provisioner) launch(ctx context.Context, opts LaunchOptions, node *sche
 	return k8sNode.Name, nil
 }
 
-func (p *Provisioner) getDaemonOverhead(ctx context.Context, nodeTemplates []*scheduling.NodeTemplate) (map[*scheduling.NodeTemplate]v1.ResourceList, error) {
-	overhead := map[*scheduling.NodeTemplate]v1.ResourceList{}
+// getDaemons returns the pods of the DaemonSets that are compatible with each node template. The scheduler computes their
+// overhead against the requirements of each node, since a node's requirements may rule out some of its template's daemons.
+func (p *Provisioner) getDaemons(ctx context.Context, nodeTemplates []*scheduling.NodeTemplate) (map[*scheduling.NodeTemplate][]*v1.Pod, error) {
+	daemonsByTemplate := map[*scheduling.NodeTemplate][]*v1.Pod{}
 
 	daemonSetList := &appsv1.DaemonSetList{}
 	if err := p.kubeClient.List(ctx, daemonSetList); err != nil {
@@ -383,10 +384,10 @@ func (p *Provisioner) getDaemonOverhead(ctx context.Context, nodeTemplates []*sc
 			}
 			daemons = append(daemons, p)
 		}
-		overhead[nodeTemplate] = resources.RequestsForPods(daemons...)
+		daemonsByTemplate[nodeTemplate] = daemons
 	}
 
-	return overhead, nil
+	return daemonsByTemplate, nil
 }
 
 func (p *Provisioner) Validate(ctx context.Context, pod *v1.Pod) error {
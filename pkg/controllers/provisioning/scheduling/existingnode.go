@@ -143,8 +143,28 @@ func (n *ExistingNode) Add(ctx context.Context, pod *v1.Pod) error {
 	n.Pods = append(n.Pods, pod)
 	n.requests = requests
 	n.requirements = nodeRequirements
-	n.topology.Record(pod, nodeRequirements)
+	n.topology.Record(pod, nodeRequirements, n.taints...)
 	n.hostPortUsage.Add(ctx, pod)
 	n.volumeUsage.Add(ctx, pod)
 	return nil
 }
+
+// InsufficientResources returns the resources that the pod requests more of than remain available on the node. It
+// returns false if the pod can't schedule to the node regardless of its resources, because the pod doesn't tolerate
+// the node's taints or is incompatible with its requirements.
+func (n *ExistingNode) InsufficientResources(pod *v1.Pod) ([]v1.ResourceName, bool) {
+	if err := scheduling.Taints(n.taints).Tolerates(pod); err != nil {
+		return nil, false
+	}
+	if err := n.requirements.Compatible(scheduling.NewPodRequirements(pod)); err != nil {
+		return nil, false
+	}
+	remaining := resources.Subtract(n.available, n.requests)
+	var insufficient []v1.ResourceName
+	for resourceName, quantity := range resources.RequestsForPods(pod) {
+		if resources.Cmp(quantity, remaining[resourceName]) > 0 {
+			insufficient = append(insufficient, resourceName)
+		}
+	}
+	return insufficient, true
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 
@@ -38,13 +39,14 @@ type Node struct {
 	Pods                []*v1.Pod
 
 	topology      *Topology
+	daemons       []*v1.Pod
 	requests      v1.ResourceList
 	hostPortUsage *scheduling.HostPortUsage
 }
 
 var nodeID int64
 
-func NewNode(nodeTemplate *scheduling.NodeTemplate, topology *Topology, daemonResources v1.ResourceList, instanceTypes []*cloudprovider.InstanceType) *Node {
+func NewNode(nodeTemplate *scheduling.NodeTemplate, topology *Topology, daemons []*v1.Pod, instanceTypes []*cloudprovider.InstanceType) *Node {
 	// Copy the template, and add hostname
 	hostname := fmt.Sprintf("hostname-placeholder-%04d", atomic.AddInt64(&nodeID, 1))
 	topology.Register(v1.LabelHostname, hostname)
@@ -52,13 +54,17 @@ func NewNode(nodeTemplate *scheduling.NodeTemplate, topology *Topology, daemonRe
 	template.Requirements = scheduling.NewRequirements()
 	template.Requirements.Add(nodeTemplate.Requirements.Values()...)
 	template.Requirements.Add(scheduling.NewRequirement(v1.LabelHostname, v1.NodeSelectorOpIn, hostname))
+	// a hypothetical node can only be labeled with the zone and capacity type of one of its instance types' offerings,
+	// so required node selectors and topology are evaluated against those rather than any value
+	template.Requirements.Add(offeringRequirements(instanceTypes)...)
 
 	return &Node{
 		NodeTemplate:        template,
 		InstanceTypeOptions: instanceTypes,
 		hostPortUsage:       scheduling.NewHostPortUsage(),
 		topology:            topology,
-		requests:            daemonResources,
+		daemons:             daemons,
+		requests:            daemonRequests(daemons, template.Requirements),
 	}
 }
 
@@ -92,8 +98,9 @@ func (n *Node) Add(ctx context.Context, pod *v1.Pod) error {
 	}
 	nodeRequirements.Add(topologyRequirements.Values()...)
 
-	// Check instance type combinations
-	requests := resources.Merge(n.requests, resources.RequestsForPods(pod))
+	// Check instance type combinations. The pod's requirements may rule out some of the daemons, e.g. by selecting an
+	// operating system, so the daemon overhead is recomputed against the node's new requirements.
+	requests := resources.Merge(daemonRequests(n.daemons, nodeRequirements), resources.RequestsForPods(n.Pods...), resources.RequestsForPods(pod))
 	instanceTypes := filterInstanceTypesByRequirements(n.InstanceTypeOptions, nodeRequirements, requests)
 	if len(instanceTypes) == 0 {
 		return fmt.Errorf("no instance type satisfied resources %s and requirements %s", resources.String(resources.RequestsForPods(pod)), nodeRequirements)
@@ -104,7 +111,7 @@ func (n *Node) Add(ctx context.Context, pod *v1.Pod) error {
 	n.InstanceTypeOptions = instanceTypes
 	n.requests = requests
 	n.Requirements = nodeRequirements
-	n.topology.Record(pod, nodeRequirements)
+	n.topology.Record(pod, nodeRequirements, n.Taints...)
 	n.hostPortUsage.Add(ctx, pod)
 	return nil
 }
@@ -122,6 +129,31 @@ func (n *Node) String() string {
 		InstanceTypeList(n.InstanceTypeOptions))
 }
 
+// daemonRequests returns the requests of the daemons that would run on a node with the requirements. A node only runs a
+// single operating system, so while its requirements allow several, it's sized for the operating system whose daemons
+// request the most rather than for the daemons of every operating system.
+func daemonRequests(daemons []*v1.Pod, requirements scheduling.Requirements) v1.ResourceList {
+	daemons = lo.Filter(daemons, func(p *v1.Pod, _ int) bool {
+		return requirements.Compatible(scheduling.NewPodRequirements(p)) == nil
+	})
+	operatingSystems := sets.NewString()
+	for _, p := range daemons {
+		if r := scheduling.NewPodRequirements(p).Get(v1.LabelOSStable); r.Operator() == v1.NodeSelectorOpIn {
+			operatingSystems.Insert(lo.Filter(r.Values(), func(value string, _ int) bool { return requirements.Get(v1.LabelOSStable).Has(value) })...)
+		}
+	}
+	if operatingSystems.Len() <= 1 {
+		return resources.RequestsForPods(daemons...)
+	}
+	var requests []v1.ResourceList
+	for _, operatingSystem := range operatingSystems.List() {
+		osRequirements := scheduling.NewRequirements(requirements.Values()...)
+		osRequirements.Add(scheduling.NewRequirement(v1.LabelOSStable, v1.NodeSelectorOpIn, operatingSystem))
+		requests = append(requests, daemonRequests(daemons, osRequirements))
+	}
+	return resources.MaxResources(requests...)
+}
+
 func InstanceTypeList(instanceTypeOptions []*cloudprovider.InstanceType) string {
 	var itSb strings.Builder
 	for i, it := range instanceTypeOptions {
@@ -137,6 +169,28 @@ func InstanceTypeList(instanceTypeOptions []*cloudprovider.InstanceType) string
 	return itSb.String()
 }
 
+// offeringRequirements returns requirements for the zones and capacity types of the available offerings of the
+// instance types. The remaining well known labels (instance type, architecture and operating system) are implied by
+// the instance types' own requirements.
+func offeringRequirements(instanceTypes []*cloudprovider.InstanceType) []*scheduling.Requirement {
+	if len(instanceTypes) == 0 {
+		return nil
+	}
+	zones := scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn)
+	capacityTypes := scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn)
+	for _, instanceType := range instanceTypes {
+		for _, offering := range instanceType.Offerings.Available() {
+			zones.Insert(offering.Zone)
+			capacityTypes.Insert(offering.CapacityType)
+		}
+	}
+	// without any available offerings, the instance types are filtered out when pods are added
+	if zones.Len() == 0 {
+		return nil
+	}
+	return []*scheduling.Requirement{zones, capacityTypes}
+}
+
 func filterInstanceTypesByRequirements(instanceTypes []*cloudprovider.InstanceType, requirements scheduling.Requirements, requests v1.ResourceList) []*cloudprovider.InstanceType {
 	return lo.Filter(instanceTypes, func(instanceType *cloudprovider.InstanceType, _ int) bool {
 		return compatible(instanceType, requirements) && fits(instanceType, requests) && hasOffering(instanceType, requirements)
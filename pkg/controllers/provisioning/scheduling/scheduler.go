@@ -38,11 +38,14 @@ import (
 type SchedulerOptions struct {
 	// SimulationMode if true will prevent recording of the pod nomination decisions as events
 	SimulationMode bool
+	// ExcludeUnschedulableNodes if true will treat existing nodes that are cordoned as though they carry the
+	// unschedulable taint, so that they're only targets for pods that tolerate it
+	ExcludeUnschedulableNodes bool
 }
 
 func NewScheduler(ctx context.Context, kubeClient client.Client, nodeTemplates []*scheduling.NodeTemplate,
 	provisioners []v1alpha5.Provisioner, cluster *state.Cluster, stateNodes []*state.Node, topology *Topology,
-	instanceTypes map[string][]*cloudprovider.InstanceType, daemonOverhead map[*scheduling.NodeTemplate]v1.ResourceList,
+	instanceTypes map[string][]*cloudprovider.InstanceType, daemons map[*scheduling.NodeTemplate][]*v1.Pod,
 	recorder events.Recorder, opts SchedulerOptions) *Scheduler {
 
 	// if any of the provisioners add a taint with a prefer no schedule effect, we add a toleration for the taint
@@ -63,7 +66,7 @@ func NewScheduler(ctx context.Context, kubeClient client.Client, nodeTemplates [
 		topology:           topology,
 		cluster:            cluster,
 		instanceTypes:      instanceTypes,
-		daemonOverhead:     daemonOverhead,
+		daemons:            daemons,
 		recorder:           recorder,
 		opts:               opts,
 		preferences:        &Preferences{ToleratePreferNoSchedule: toleratePreferNoSchedule},
@@ -91,7 +94,7 @@ type Scheduler struct {
 	nodeTemplates      []*scheduling.NodeTemplate
 	remainingResources map[string]v1.ResourceList // provisioner name -> remaining resources for that provisioner
 	instanceTypes      map[string][]*cloudprovider.InstanceType
-	daemonOverhead     map[*scheduling.NodeTemplate]v1.ResourceList
+	daemons            map[*scheduling.NodeTemplate][]*v1.Pod
 	preferences        *Preferences
 	topology           *Topology
 	cluster            *state.Cluster
@@ -212,7 +215,7 @@ func (s *Scheduler) add(ctx context.Context, pod *v1.Pod) error {
 			}
 		}
 
-		node := NewNode(nodeTemplate, s.topology, s.daemonOverhead[nodeTemplate], instanceTypes)
+		node := NewNode(nodeTemplate, s.topology, s.daemons[nodeTemplate], instanceTypes)
 		if err := node.Add(ctx, pod); err != nil {
 			errs = multierr.Append(errs, fmt.Errorf("incompatible with provisioner %q, %w", nodeTemplate.ProvisionerName, err))
 			continue
@@ -238,7 +241,12 @@ func (s *Scheduler) calculateExistingNodes(namedNodeTemplates map[string]*schedu
 			// ignoring this node as it wasn't launched by a provisioner that we recognize
 			continue
 		}
-		s.existingNodes = append(s.existingNodes, NewExistingNode(node, s.topology, nodeTemplate.StartupTaints, s.daemonOverhead[nodeTemplate]))
+		existingNode := NewExistingNode(node, s.topology, nodeTemplate.StartupTaints, daemonRequests(s.daemons[nodeTemplate], nodeTemplate.Requirements))
+		// the node lifecycle controller taints cordoned nodes asynchronously, so we don't rely on the taint being present
+		if s.opts.ExcludeUnschedulableNodes && node.Node.Spec.Unschedulable {
+			existingNode.taints = append(existingNode.taints, v1.Taint{Key: v1.TaintNodeUnschedulable, Effect: v1.TaintEffectNoSchedule})
+		}
+		s.existingNodes = append(s.existingNodes, existingNode)
 
 		// We don't use the status field and instead recompute the remaining resources to ensure we have a consistent view
 		// of the cluster during scheduling.  Depending on how node creation falls out, this will also work for cases where
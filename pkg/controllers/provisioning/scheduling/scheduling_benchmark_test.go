@@ -119,7 +119,7 @@ func benchmarkScheduler(b *testing.B, instanceCount, podCount int) {
 	cloudProv.InstanceTypes = instanceTypes
 	scheduler := pscheduling.NewScheduler(ctx, nil, []*scheduling.NodeTemplate{scheduling.NewNodeTemplate(provisioner)},
 		nil, state.NewCluster(ctx, &clock.RealClock{}, nil, cloudProv), nil, &pscheduling.Topology{},
-		map[string][]cloudprovider.InstanceType{provisioner.Name: instanceTypes}, map[*scheduling.NodeTemplate]v1.ResourceList{},
+		map[string][]cloudprovider.InstanceType{provisioner.Name: instanceTypes}, map[*scheduling.NodeTemplate][]*v1.Pod{},
 		test.NewEventRecorder(),
 		pscheduling.SchedulerOptions{})
 
@@ -749,6 +749,37 @@ var _ = Describe("Topology", func() {
 			)
 			ExpectSkew(ctx, env.Client, "default", &topology[0]).To(ConsistOf(1, 1, 2))
 		})
+		It("should not count pods on nodes whose taints the pod doesn't tolerate when honoring taints", func() {
+			// launch a pair of pods onto a tainted node in test-zone-1
+			provisioner.Spec.Taints = []v1.Taint{{Key: "test-key", Value: "test-value", Effect: v1.TaintEffectNoSchedule}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pods := ExpectProvisioned(ctx, env.Client, recorder, provisioningController, prov, MakePods(2, test.PodOptions{
+				ObjectMeta:   metav1.ObjectMeta{Labels: labels},
+				Tolerations:  []v1.Toleration{{Key: "test-key", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}},
+				NodeSelector: map[string]string{v1.LabelTopologyZone: "test-zone-1"},
+			})...)
+			for _, pod := range pods {
+				ExpectScheduled(ctx, env.Client, pod)
+			}
+
+			honor := v1.NodeInclusionPolicyHonor
+			topology := []v1.TopologySpreadConstraint{{
+				TopologyKey:       v1.LabelTopologyZone,
+				WhenUnsatisfiable: v1.DoNotSchedule,
+				LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+				MaxSkew:           1,
+				NodeTaintsPolicy:  &honor,
+			}}
+			// these pods don't tolerate the taint, so the existing pods are excluded from the skew and they spread evenly
+			// across all of the zones, rather than avoiding test-zone-1
+			provisioner.Spec.Taints = nil
+			ExpectApplied(ctx, env.Client, provisioner)
+			ExpectProvisioned(ctx, env.Client, recorder, provisioningController, prov, MakePods(3, test.PodOptions{
+				ObjectMeta:                metav1.ObjectMeta{Labels: labels},
+				TopologySpreadConstraints: topology,
+			})...)
+			ExpectSkew(ctx, env.Client, "default", &topology[0]).To(ConsistOf(3, 1, 1))
+		})
 		It("should respect provisioner zonal constraints", func() {
 			provisioner.Spec.Requirements = []v1.NodeSelectorRequirement{
 				{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1", "test-zone-2", "test-zone-3"}}}
@@ -117,11 +117,11 @@ func (t *Topology) Update(ctx context.Context, p *v1.Pod) error {
 	return nil
 }
 
-// Record records the topology changes given that pod p schedule on a node with the given requirements
-func (t *Topology) Record(p *v1.Pod, requirements scheduling.Requirements) {
+// Record records the topology changes given that pod p schedule on a node with the given requirements and taints
+func (t *Topology) Record(p *v1.Pod, requirements scheduling.Requirements, taints ...v1.Taint) {
 	// once we've committed to a domain, we record the usage in every topology that cares about it
 	for _, tc := range t.topologies {
-		if tc.Counts(p, requirements) {
+		if tc.Counts(p, requirements, taints...) {
 			domains := requirements.Get(tc.Key)
 			if tc.Type == TopologyTypePodAntiAffinity {
 				// for anti-affinity topologies we need to block out all possible domains that the pod could land in
@@ -211,7 +211,7 @@ func (t *Topology) updateInverseAntiAffinity(ctx context.Context, pod *v1.Pod, d
 			return err
 		}
 
-		tg := NewTopologyGroup(TopologyTypePodAntiAffinity, term.TopologyKey, pod, namespaces, term.LabelSelector, math.MaxInt32, t.domains[term.TopologyKey])
+		tg := NewTopologyGroup(TopologyTypePodAntiAffinity, term.TopologyKey, TopologyNodeFilter{}, namespaces, term.LabelSelector, math.MaxInt32, t.domains[term.TopologyKey])
 
 		hash := tg.Hash()
 		if existing, ok := t.inverseTopologies[hash]; !ok {
@@ -267,7 +267,8 @@ func (t *Topology) countDomains(ctx context.Context, tg *TopologyGroup) error {
 			continue // Don't include pods if node doesn't contain domain https://kubernetes.io/docs/concepts/workloads/pods/pod-topology-spread-constraints/#conventions
 		}
 		// nodes may or may not be considered for counting purposes for topology spread constraints depending on if they
-		// are selected by the pod's node selectors and required node affinities.  If these are unset, the node always counts.
+		// are selected by the pod's node selectors and required node affinities, and if the constraint honors taints,
+		// whether the pod tolerates them.  If these are unset, the node always counts.
 		if !tg.nodeFilter.Matches(node) {
 			continue
 		}
@@ -279,7 +280,7 @@ func (t *Topology) countDomains(ctx context.Context, tg *TopologyGroup) error {
 func (t *Topology) newForTopologies(p *v1.Pod) []*TopologyGroup {
 	var topologyGroups []*TopologyGroup
 	for _, cs := range p.Spec.TopologySpreadConstraints {
-		topologyGroups = append(topologyGroups, NewTopologyGroup(TopologyTypeSpread, cs.TopologyKey, p, utilsets.NewString(p.Namespace), cs.LabelSelector, cs.MaxSkew, t.domains[cs.TopologyKey]))
+		topologyGroups = append(topologyGroups, NewTopologyGroup(TopologyTypeSpread, cs.TopologyKey, MakeTopologyNodeFilter(p, cs), utilsets.NewString(p.Namespace), cs.LabelSelector, cs.MaxSkew, t.domains[cs.TopologyKey]))
 	}
 	return topologyGroups
 }
@@ -316,7 +317,7 @@ func (t *Topology) newForAffinities(ctx context.Context, p *v1.Pod) ([]*Topology
 			if err != nil {
 				return nil, err
 			}
-			topologyGroups = append(topologyGroups, NewTopologyGroup(topologyType, term.TopologyKey, p, namespaces, term.LabelSelector, math.MaxInt32, t.domains[term.TopologyKey]))
+			topologyGroups = append(topologyGroups, NewTopologyGroup(topologyType, term.TopologyKey, TopologyNodeFilter{}, namespaces, term.LabelSelector, math.MaxInt32, t.domains[term.TopologyKey]))
 		}
 	}
 	return topologyGroups, nil
@@ -63,22 +63,19 @@ type TopologyGroup struct {
 	domains map[string]int32       // TODO(ellistarn) explore replacing with a minheap
 }
 
-func NewTopologyGroup(topologyType TopologyType, topologyKey string, pod *v1.Pod, namespaces utilsets.String, labelSelector *metav1.LabelSelector, maxSkew int32, domains utilsets.String) *TopologyGroup {
+// NewTopologyGroup constructs a topology group. The zero-value TopologyNodeFilter always passes, which is what we need
+// for affinity/anti-affinity.
+func NewTopologyGroup(topologyType TopologyType, topologyKey string, nodeFilter TopologyNodeFilter, namespaces utilsets.String, labelSelector *metav1.LabelSelector, maxSkew int32, domains utilsets.String) *TopologyGroup {
 	domainCounts := map[string]int32{}
 	for domain := range domains {
 		domainCounts[domain] = 0
 	}
-	// the nil *TopologyNodeFilter always passes which is what we need for affinity/anti-affinity
-	var nodeSelector TopologyNodeFilter
-	if topologyType == TopologyTypeSpread {
-		nodeSelector = MakeTopologyNodeFilter(pod)
-	}
 	return &TopologyGroup{
 		Type:       topologyType,
 		Key:        topologyKey,
 		namespaces: namespaces,
 		selector:   labelSelector,
-		nodeFilter: nodeSelector,
+		nodeFilter: nodeFilter,
 		maxSkew:    maxSkew,
 		domains:    domainCounts,
 		owners:     map[types.UID]struct{}{},
@@ -105,9 +102,9 @@ func (t *TopologyGroup) Record(domains ...string) {
 }
 
 // Counts returns true if the pod would count for the topology, given that it schedule to a node with the provided
-// requirements
-func (t *TopologyGroup) Counts(pod *v1.Pod, requirements scheduling.Requirements) bool {
-	return t.selects(pod) && t.nodeFilter.MatchesRequirements(requirements)
+// requirements and taints
+func (t *TopologyGroup) Counts(pod *v1.Pod, requirements scheduling.Requirements, taints ...v1.Taint) bool {
+	return t.selects(pod) && t.nodeFilter.MatchesRequirements(requirements, taints...)
 }
 
 // Register ensures that the topology is aware of the given domain names.
@@ -21,27 +21,45 @@ import (
 )
 
 // TopologyNodeFilter is used to determine if a given actual node or scheduling node matches the pod's node selectors
-// and required node affinity terms.  This is used with topology spread constraints to determine if the node should be
-// included for topology counting purposes. This is only used with topology spread constraints as affinities/anti-affinities
-// always count across all nodes. A nil or zero-value TopologyNodeFilter behaves well and the filter returns true for
-// all nodes.
-type TopologyNodeFilter []scheduling.Requirements
+// and required node affinity terms, and if the constraint honors taints, whether the pod tolerates the node's taints.
+// This is used with topology spread constraints to determine if the node should be included for topology counting
+// purposes, matching the constraint's nodeAffinityPolicy and nodeTaintsPolicy. This is only used with topology spread
+// constraints as affinities/anti-affinities always count across all nodes. A zero-value TopologyNodeFilter behaves
+// well and the filter returns true for all nodes.
+type TopologyNodeFilter struct {
+	// Requirements are OR'd together, and a node that's compatible with any of them matches
+	Requirements []scheduling.Requirements
+	// Tolerations are the pod's tolerations, which must tolerate the node's taints for it to match if the constraint
+	// honors taints
+	Tolerations []v1.Toleration
+	HonorTaints bool
+}
 
-func MakeTopologyNodeFilter(p *v1.Pod) TopologyNodeFilter {
+func MakeTopologyNodeFilter(p *v1.Pod, constraint v1.TopologySpreadConstraint) TopologyNodeFilter {
+	var filter TopologyNodeFilter
+	// nodes' taints are ignored unless the constraint opts in
+	if constraint.NodeTaintsPolicy != nil && *constraint.NodeTaintsPolicy == v1.NodeInclusionPolicyHonor {
+		filter.HonorTaints = true
+		filter.Tolerations = p.Spec.Tolerations
+	}
+	// but the pod's node selector and required node affinity are honored unless the constraint opts out
+	if constraint.NodeAffinityPolicy != nil && *constraint.NodeAffinityPolicy == v1.NodeInclusionPolicyIgnore {
+		return filter
+	}
 	nodeSelectorRequirements := scheduling.NewLabelRequirements(p.Spec.NodeSelector)
 	// if we only have a label selector, that's the only requirement that must match
 	if p.Spec.Affinity == nil || p.Spec.Affinity.NodeAffinity == nil || p.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
-		return TopologyNodeFilter{nodeSelectorRequirements}
+		filter.Requirements = []scheduling.Requirements{nodeSelectorRequirements}
+		return filter
 	}
 
 	// otherwise, we need to match the combination of label selector and any term of the required node affinities since
 	// those terms are OR'd together
-	var filter TopologyNodeFilter
 	for _, term := range p.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
 		requirements := scheduling.NewRequirements()
 		requirements.Add(nodeSelectorRequirements.Values()...)
 		requirements.Add(scheduling.NewNodeSelectorRequirements(term.MatchExpressions...).Values()...)
-		filter = append(filter, requirements)
+		filter.Requirements = append(filter.Requirements, requirements)
 	}
 
 	return filter
@@ -49,19 +67,24 @@ func MakeTopologyNodeFilter(p *v1.Pod) TopologyNodeFilter {
 
 // Matches returns true if the TopologyNodeFilter doesn't prohibit node from the participating in the topology
 func (t TopologyNodeFilter) Matches(node *v1.Node) bool {
-	return t.MatchesRequirements(scheduling.NewLabelRequirements(node.Labels))
+	return t.MatchesRequirements(scheduling.NewLabelRequirements(node.Labels), node.Spec.Taints...)
 }
 
-// MatchesRequirements returns true if the TopologyNodeFilter doesn't prohibit a node with the requirements from
-// participating in the topology. This method allows checking the requirements from a scheduling.Node to see if the
+// MatchesRequirements returns true if the TopologyNodeFilter doesn't prohibit a node with the requirements and taints
+// from participating in the topology. This method allows checking the requirements from a scheduling.Node to see if the
 // node we will soon create participates in this topology.
-func (t TopologyNodeFilter) MatchesRequirements(requirements scheduling.Requirements) bool {
+func (t TopologyNodeFilter) MatchesRequirements(requirements scheduling.Requirements, taints ...v1.Taint) bool {
+	if t.HonorTaints {
+		if err := scheduling.Taints(taints).Tolerates(&v1.Pod{Spec: v1.PodSpec{Tolerations: t.Tolerations}}); err != nil {
+			return false
+		}
+	}
 	// no requirements, so it always matches
-	if len(t) == 0 {
+	if len(t.Requirements) == 0 {
 		return true
 	}
 	// these are an OR, so if any passes the filter passes
-	for _, req := range t {
+	for _, req := range t.Requirements {
 		if err := requirements.Compatible(req); err == nil {
 			return true
 		}
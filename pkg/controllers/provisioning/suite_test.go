@@ -461,6 +461,45 @@ var _ = Describe("Provisioning", func() {
 			Expect(*allocatable.Cpu()).To(Equal(resource.MustParse("2")))
 			Expect(*allocatable.Memory()).To(Equal(resource.MustParse("2Gi")))
 		})
+		It("should ignore daemonsets of other operating systems than the pod selects", func() {
+			ExpectApplied(ctx, env.Client, test.Provisioner(), test.DaemonSet(
+				test.DaemonSetOptions{PodOptions: test.PodOptions{
+					NodeSelector:         map[string]string{v1.LabelOSStable: string(v1.Windows)},
+					ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")}},
+				}},
+			))
+			pod := ExpectProvisioned(ctx, env.Client, recorder, pendingPodController, prov, test.UnschedulablePod(
+				test.PodOptions{
+					NodeSelector:         map[string]string{v1.LabelOSStable: string(v1.Linux)},
+					ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")}},
+				},
+			))[0]
+			node := ExpectScheduled(ctx, env.Client, pod)
+			allocatable := instanceTypeMap[node.Labels[v1.LabelInstanceTypeStable]].Capacity
+			Expect(*allocatable.Cpu()).To(Equal(resource.MustParse("2")))
+			Expect(*allocatable.Memory()).To(Equal(resource.MustParse("2Gi")))
+		})
+		It("should account for the daemonsets of a single operating system if the pod doesn't select one", func() {
+			// a node only runs one operating system, so it's sized for the larger of the daemonsets rather than both
+			ExpectApplied(ctx, env.Client, test.Provisioner(),
+				test.DaemonSet(test.DaemonSetOptions{PodOptions: test.PodOptions{
+					NodeSelector:         map[string]string{v1.LabelOSStable: string(v1.Linux)},
+					ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1.5Gi")}},
+				}}),
+				test.DaemonSet(test.DaemonSetOptions{PodOptions: test.PodOptions{
+					NodeSelector:         map[string]string{v1.LabelOSStable: string(v1.Windows)},
+					ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1.5Gi")}},
+				}}),
+			)
+			pod := ExpectProvisioned(ctx, env.Client, recorder, pendingPodController, prov, test.UnschedulablePod(
+				test.PodOptions{
+					ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1.5Gi")}},
+				},
+			))[0]
+			node := ExpectScheduled(ctx, env.Client, pod)
+			allocatable := instanceTypeMap[node.Labels[v1.LabelInstanceTypeStable]].Capacity
+			Expect(*allocatable.Memory()).To(Equal(resource.MustParse("4Gi")))
+		})
 		It("should ignore daemonsets with an invalid selector", func() {
 			ExpectApplied(ctx, env.Client, test.Provisioner(), test.DaemonSet(
 				test.DaemonSetOptions{PodOptions: test.PodOptions{
@@ -25,6 +25,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// annSelectedNode is set on a persistent volume claim by the scheduler to the node that its volume is provisioned for
+const annSelectedNode = "volume.kubernetes.io/selected-node"
+
 func NewVolumeTopology(kubeClient client.Client) *VolumeTopology {
 	return &VolumeTopology{kubeClient: kubeClient}
 }
@@ -102,6 +105,19 @@ func (v *VolumeTopology) getStorageClassRequirements(ctx context.Context, pvc *v
 			requirements = append(requirements, v1.NodeSelectorRequirement{Key: requirement.Key, Operator: v1.NodeSelectorOpIn, Values: requirement.Values})
 		}
 	}
+	// A volume that waits for its first consumer is free to be provisioned in any allowed zone, until a node is
+	// selected for it. From then on, it's provisioned in that node's zone, so the pod can't move out of it.
+	if storageClass.VolumeBindingMode != nil && *storageClass.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+		if nodeName, ok := pvc.Annotations[annSelectedNode]; ok {
+			node := &v1.Node{}
+			if err := v.kubeClient.Get(ctx, types.NamespacedName{Name: nodeName}, node); client.IgnoreNotFound(err) != nil {
+				return nil, fmt.Errorf("getting selected node %q, %w", nodeName, err)
+			}
+			if zone, ok := node.Labels[v1.LabelTopologyZone]; ok {
+				requirements = append(requirements, v1.NodeSelectorRequirement{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{zone}})
+			}
+		}
+	}
 	return requirements, nil
 }
 
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
@@ -37,6 +38,7 @@ import (
 	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/metrics"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	atomicutils "github.com/aws/karpenter-core/pkg/utils/atomic"
 	podutils "github.com/aws/karpenter-core/pkg/utils/pod"
@@ -290,8 +292,9 @@ func (c *Cluster) populateCapacity(ctx context.Context, node *v1.Node, n *Node)
 		}
 	}
 	n.Allocatable = lo.Assign(node.Status.Allocatable) // ensure map not nil
-	// Use instance type resource value if resource isn't currently registered in .Status.Allocatable
-	for resourceName, quantity := range instanceType.Capacity {
+	// Use the instance type's expected allocatable if resource isn't currently registered in .Status.Allocatable. The
+	// nominal capacity is never allocatable in full, so using it would let us pack pods onto capacity that won't exist.
+	for resourceName, quantity := range resources.Subtract(instanceType.Capacity, instanceType.Overhead.Total()) {
 		if resources.IsZero(node.Status.Allocatable[resourceName]) {
 			n.Allocatable[resourceName] = quantity
 		}
@@ -355,7 +358,11 @@ func (c *Cluster) populateVolumeLimits(ctx context.Context, node *v1.Node, n *No
 func (c *Cluster) deleteNode(nodeName string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	oldNode, ok := c.nodes[nodeName]
 	delete(c.nodes, nodeName)
+	if ok {
+		c.updateProvisionerNodesMetric(oldNode.Node)
+	}
 	c.recordConsolidationChange()
 }
 
@@ -363,14 +370,17 @@ func (c *Cluster) deleteNode(nodeName string) {
 func (c *Cluster) updateNode(ctx context.Context, node *v1.Node) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	oldNode, ok := c.nodes[node.Name]
 	n, err := c.newNode(ctx, node)
 	if err != nil {
 		// ensure that the out of date node is forgotten
 		delete(c.nodes, node.Name)
+		if ok {
+			c.updateProvisionerNodesMetric(oldNode.Node)
+		}
 		return err
 	}
 
-	oldNode, ok := c.nodes[node.Name]
 	// If the old node existed and its initialization status changed, we want to reconsider consolidation.  This handles
 	// a situation where we re-start with an unready node and it becomes ready later.
 	if ok {
@@ -383,6 +393,12 @@ func (c *Cluster) updateNode(ctx context.Context, node *v1.Node) error {
 		n.MarkedForDeletion = n.MarkedForDeletion || oldNode.MarkedForDeletion
 	}
 	c.nodes[node.Name] = n
+	// the node's provisioner or capacity type labels may have changed, so recompute the counts for both
+	if ok {
+		c.updateProvisionerNodesMetric(oldNode.Node, node)
+	} else {
+		c.updateProvisionerNodesMetric(node)
+	}
 
 	if node.DeletionTimestamp != nil {
 		nodeDeletionTime := node.DeletionTimestamp.UnixMilli()
@@ -397,6 +413,25 @@ func (c *Cluster) updateNode(ctx context.Context, node *v1.Node) error {
 	return nil
 }
 
+// updateProvisionerNodesMetric recomputes the number of nodes that share the provisioner and capacity type of each of
+// the given nodes. It must be called while holding the cluster lock.
+func (c *Cluster) updateProvisionerNodesMetric(nodes ...*v1.Node) {
+	for _, node := range nodes {
+		provisionerName, ok := node.Labels[v1alpha5.ProvisionerNameLabelKey]
+		if !ok {
+			continue
+		}
+		capacityType := node.Labels[v1alpha5.LabelCapacityType]
+		count := lo.CountBy(lo.Values(c.nodes), func(n *Node) bool {
+			return n.Node.Labels[v1alpha5.ProvisionerNameLabelKey] == provisionerName && n.Node.Labels[v1alpha5.LabelCapacityType] == capacityType
+		})
+		provisionerNodesGaugeVec.With(prometheus.Labels{
+			metrics.ProvisionerLabel: provisionerName,
+			capacityTypeLabel:        capacityType,
+		}).Set(float64(count))
+	}
+}
+
 // ClusterConsolidationState returns a number representing the state of the cluster with respect to consolidation.  If
 // consolidation can't occur and this number hasn't changed, there is no point in re-attempting consolidation. This
 // allows reducing overall CPU utilization by pausing consolidation when the cluster is in a static state.
@@ -0,0 +1,41 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/aws/karpenter-core/pkg/metrics"
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(provisionerNodesGaugeVec)
+}
+
+const (
+	provisionerSubsystem = "provisioner"
+	capacityTypeLabel    = "capacity_type"
+)
+
+var provisionerNodesGaugeVec = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: provisionerSubsystem,
+		Name:      "nodes",
+		Help:      "Number of nodes owned by a provisioner. Labeled by provisioner and capacity type.",
+	},
+	[]string{metrics.ProvisionerLabel, capacityTypeLabel},
+)
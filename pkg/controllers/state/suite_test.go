@@ -22,6 +22,8 @@ import (
 	"testing"
 	"time"
 
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/samber/lo"
 	clock "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/ptr"
 
@@ -752,6 +754,35 @@ var _ = Describe("Provisioner Spec Updates", func() {
 	})
 })
 
+var _ = Describe("Provisioner Node Metrics", func() {
+	It("should track the number of nodes for a provisioner as nodes are created and deleted", func() {
+		nodes := lo.Times(2, func(_ int) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+					v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+					v1.LabelInstanceTypeStable:       cloudProvider.InstanceTypes[0].Name,
+				}},
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU: resource.MustParse("4"),
+				}})
+		})
+		for _, node := range nodes {
+			ExpectApplied(ctx, env.Client, node)
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+		}
+		ExpectProvisionerNodesMetric(provisioner.Name, v1alpha5.CapacityTypeOnDemand, 2)
+
+		ExpectDeleted(ctx, env.Client, nodes[0])
+		ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(nodes[0]))
+		ExpectProvisionerNodesMetric(provisioner.Name, v1alpha5.CapacityTypeOnDemand, 1)
+
+		ExpectDeleted(ctx, env.Client, nodes[1])
+		ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(nodes[1]))
+		ExpectProvisionerNodesMetric(provisioner.Name, v1alpha5.CapacityTypeOnDemand, 0)
+	})
+})
+
 func ExpectNodeResourceRequest(node *v1.Node, resourceName v1.ResourceName, amount string) {
 	cluster.ForEachNode(func(n *state.Node) bool {
 		if n.Node.Name != node.Name {
@@ -786,3 +817,12 @@ func ExpectNodeDeletionMarked(node *v1.Node) {
 		return false
 	})
 }
+
+func ExpectProvisionerNodesMetric(provisionerName string, capacityType string, count float64) {
+	metric, ok := lo.Find(ExpectMetric("karpenter_provisioner_nodes").Metric, func(m *io_prometheus_client.Metric) bool {
+		labels := lo.SliceToMap(m.Label, func(l *io_prometheus_client.LabelPair) (string, string) { return l.GetName(), l.GetValue() })
+		return labels["provisioner"] == provisionerName && labels["capacity_type"] == capacityType
+	})
+	ExpectWithOffset(1, ok).To(BeTrue(), fmt.Sprintf("expected a metric for provisioner %s and capacity type %s", provisionerName, capacityType))
+	ExpectWithOffset(1, metric.GetGauge().GetValue()).To(Equal(count))
+}
@@ -35,6 +35,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	corecontroller "github.com/aws/karpenter-core/pkg/operator/controller"
 	"github.com/aws/karpenter-core/pkg/operator/injection"
@@ -54,10 +55,30 @@ var (
 			Objectives: metrics.SummaryObjectives(),
 		},
 	)
+	terminationDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "karpenter",
+			Subsystem: "nodes",
+			Name:      "termination_duration_seconds",
+			Help:      "The time taken between a node being cordoned for termination and the removal of its finalizer, by the reason that it was terminated",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+		},
+		[]string{"reason"},
+	)
+	drainFinalizerBlockedPodsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "karpenter",
+			Subsystem: "nodes",
+			Name:      "drain_finalizer_blocked_pods_total",
+			Help:      "The number of times a drain proceeded without waiting for a terminating pod that was held by its finalizers past the drain finalizer timeout",
+		},
+	)
 )
 
 func init() {
 	crmetrics.Registry.MustRegister(terminationSummary)
+	crmetrics.Registry.MustRegister(terminationDurationHistogram)
+	crmetrics.Registry.MustRegister(drainFinalizerBlockedPodsCounter)
 }
 
 // Controller for the resource
@@ -98,6 +119,20 @@ func (c *Controller) Reconcile(ctx context.Context, req reconcile.Request) (reco
 	if err := c.Terminator.cordon(ctx, node); err != nil {
 		return reconcile.Result{}, fmt.Errorf("cordoning node, %w", err)
 	}
+	elapsed := c.Terminator.Clock.Since(cordonTime(node))
+	if limit := settings.FromContext(ctx).TerminationHardLimit.Duration; limit > 0 && elapsed > limit {
+		c.Recorder.Publish(events.NodeTerminationExceededLimit(node, elapsed, limit))
+	}
+	if delay, err := c.Terminator.loadBalancerDrainDelay(ctx, node); err != nil {
+		return reconcile.Result{}, fmt.Errorf("computing load balancer drain delay, %w", err)
+	} else if delay > 0 {
+		return reconcile.Result{RequeueAfter: delay}, nil
+	}
+	if delay, err := c.Terminator.sessionAffinityDrainDelay(ctx, node); err != nil {
+		return reconcile.Result{}, fmt.Errorf("computing session affinity drain delay, %w", err)
+	} else if delay > 0 {
+		return reconcile.Result{RequeueAfter: delay}, nil
+	}
 	if err := c.Terminator.drain(ctx, node); err != nil {
 		if IsNodeDrainErr(err) {
 			c.Recorder.Publish(events.NodeFailedToDrain(node, err))
@@ -109,9 +144,26 @@ func (c *Controller) Reconcile(ctx context.Context, req reconcile.Request) (reco
 		return reconcile.Result{}, fmt.Errorf("terminating node, %w", err)
 	}
 	terminationSummary.Observe(time.Since(node.DeletionTimestamp.Time).Seconds())
+	terminationDurationHistogram.WithLabelValues(terminationReason(node)).Observe(c.Terminator.Clock.Since(cordonTime(node)).Seconds())
 	return reconcile.Result{}, nil
 }
 
+// cordonTime returns when the node was cordoned for termination, falling back to when its deletion was requested
+func cordonTime(node *v1.Node) time.Time {
+	if cordoned, err := time.Parse(time.RFC3339, node.Annotations[v1alpha5.CordonTimestampAnnotationKey]); err == nil {
+		return cordoned
+	}
+	return node.DeletionTimestamp.Time
+}
+
+// terminationReason returns the reason that the node is being terminated, if whatever deleted it recorded one
+func terminationReason(node *v1.Node) string {
+	if reason, ok := node.Annotations[v1alpha5.TerminationReasonAnnotationKey]; ok {
+		return reason
+	}
+	return "unknown"
+}
+
 func (c *Controller) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
 	return corecontroller.Adapt(controllerruntime.
 		NewControllerManagedBy(m).
@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter-core/pkg/apis"
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
 	"github.com/aws/karpenter-core/pkg/controllers/termination"
@@ -38,6 +39,7 @@ import (
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	. "knative.dev/pkg/logging/testing"
 	"knative.dev/pkg/ptr"
@@ -51,6 +53,7 @@ var evictionQueue *termination.EvictionQueue
 var env *test.Environment
 var defaultOwnerRefs = []metav1.OwnerReference{{Kind: "ReplicaSet", APIVersion: "appsv1", Name: "rs", UID: "1234567890"}}
 var fakeClock *clock.FakeClock
+var eventRecorder *test.EventRecorder
 
 func TestAPIs(t *testing.T) {
 	ctx = TestContextWithLogger(t)
@@ -61,9 +64,10 @@ func TestAPIs(t *testing.T) {
 var _ = BeforeSuite(func() {
 	fakeClock = clock.NewFakeClock(time.Now())
 	env = test.NewEnvironment(scheme.Scheme, apis.CRDs...)
+	ctx = settings.ToContext(ctx, test.Settings())
 
 	cloudProvider := fake.NewCloudProvider()
-	eventRecorder := test.NewEventRecorder()
+	eventRecorder = test.NewEventRecorder()
 	evictionQueue = termination.NewEvictionQueue(ctx, env.KubernetesInterface.CoreV1(), eventRecorder)
 	terminationController = termination.NewController(fakeClock, env.Client, evictionQueue, eventRecorder, cloudProvider)
 })
@@ -138,6 +142,114 @@ var _ = Describe("Termination", func() {
 			node = ExpectNodeExists(ctx, env.Client, node.Name)
 			Expect(node.Labels[v1.LabelNodeExcludeBalancers]).Should(Equal("karpenter"))
 		})
+		It("should delay eviction after excluding the node from load balancers", func() {
+			s := test.Settings()
+			s.LoadBalancerDrainDelay = metav1.Duration{Duration: 30 * time.Second}
+			ctx := settings.ToContext(ctx, s)
+
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			ExpectApplied(ctx, env.Client, node, pod)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			result := ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(result.RequeueAfter).To(BeNumerically("~", 30*time.Second, time.Second))
+
+			// the node is excluded from load balancers, but its pods aren't evicted until the delay has elapsed
+			node = ExpectNodeDraining(env.Client, node.Name)
+			Expect(node.Labels[v1.LabelNodeExcludeBalancers]).To(Equal("karpenter"))
+			ExpectNotEnqueuedForEviction(evictionQueue, pod)
+
+			fakeClock.Step(20 * time.Second)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotEnqueuedForEviction(evictionQueue, pod)
+
+			fakeClock.Step(10 * time.Second)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, pod)
+			ExpectDeleted(ctx, env.Client, pod)
+
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should delay eviction of pods behind a service with client IP session affinity", func() {
+			s := test.Settings()
+			s.SessionAffinityDrainDelay = metav1.Duration{Duration: 2 * time.Minute}
+			ctx := settings.ToContext(ctx, s)
+
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{
+				Labels:          map[string]string{"app": "sticky"},
+				OwnerReferences: defaultOwnerRefs,
+			}})
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "sticky", Namespace: pod.Namespace},
+				Spec: v1.ServiceSpec{
+					Selector:        map[string]string{"app": "sticky"},
+					SessionAffinity: v1.ServiceAffinityClientIP,
+					Ports:           []v1.ServicePort{{Port: 80}},
+				},
+			}
+			ExpectApplied(ctx, env.Client, node, pod, svc)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			result := ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(result.RequeueAfter).To(BeNumerically("~", 2*time.Minute, time.Second))
+			ExpectNotEnqueuedForEviction(evictionQueue, pod)
+
+			fakeClock.Step(time.Minute)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotEnqueuedForEviction(evictionQueue, pod)
+
+			fakeClock.Step(time.Minute)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, pod)
+			ExpectDeleted(ctx, env.Client, pod, svc)
+
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should publish an event when termination exceeds the hard limit", func() {
+			s := test.Settings()
+			s.TerminationHardLimit = metav1.Duration{Duration: time.Hour}
+			ctx := settings.ToContext(ctx, s)
+
+			// the pod can't be evicted, so the node is stuck terminating
+			podNoEvict := test.Pod(test.PodOptions{
+				NodeName: node.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations:     map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
+					OwnerReferences: defaultOwnerRefs,
+				},
+			})
+			ExpectApplied(ctx, env.Client, node, podNoEvict)
+			eventRecorder.Reset()
+
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(eventRecorder.Calls("TerminationExceededLimit")).To(BeZero())
+
+			fakeClock.Step(30 * time.Minute)
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(eventRecorder.Calls("TerminationExceededLimit")).To(BeZero())
+
+			fakeClock.Step(31 * time.Minute)
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(eventRecorder.Calls("TerminationExceededLimit")).To(Equal(1))
+
+			// once the pod is gone, the node terminates
+			ExpectDeleted(ctx, env.Client, podNoEvict)
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
 		It("should not evict pods that tolerate unschedulable taint", func() {
 			podEvict := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
 			podSkip := test.Pod(test.PodOptions{
@@ -425,6 +537,10 @@ var _ = Describe("Termination", func() {
 			fakeClock.Step(2 * time.Minute)
 			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
 			ExpectNotFound(ctx, env.Client, node)
+			// force deletion is disabled by default, so the pods are left to the kubelet
+			for _, pod := range pods {
+				ExpectPodExists(ctx, env.Client, pod.Name, pod.Namespace)
+			}
 		})
 		It("should fail to evict pods that violate a PDB", func() {
 			minAvailable := intstr.FromInt(1)
@@ -499,6 +615,41 @@ var _ = Describe("Termination", func() {
 			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
 			ExpectNotFound(ctx, env.Client, node)
 		})
+		It("should evict pods from the lowest to the highest priority", func() {
+			podLow := test.Pod(test.PodOptions{NodeName: node.Name, Priority: ptr.Int32(10), ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			podMedium := test.Pod(test.PodOptions{NodeName: node.Name, Priority: ptr.Int32(100), ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			podHigh := test.Pod(test.PodOptions{NodeName: node.Name, Priority: ptr.Int32(1000), ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			ExpectApplied(ctx, env.Client, node, podLow, podMedium, podHigh)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNodeDraining(env.Client, node.Name)
+
+			// Only the lowest priority pod is evicted first
+			ExpectEvicted(env.Client, podLow)
+			ExpectNotEnqueuedForEviction(evictionQueue, podMedium, podHigh)
+			ExpectDeleted(ctx, env.Client, podLow)
+
+			// Then the next lowest
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, podMedium)
+			ExpectNotEnqueuedForEviction(evictionQueue, podHigh)
+			ExpectDeleted(ctx, env.Client, podMedium)
+
+			// And finally the highest priority pod
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, podHigh)
+			ExpectDeleted(ctx, env.Client, podHigh)
+
+			// Reconcile to delete node
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
 		It("should not evict static pods", func() {
 			podEvict := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
 			ExpectApplied(ctx, env.Client, node, podEvict)
@@ -576,6 +727,179 @@ var _ = Describe("Termination", func() {
 			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
 			ExpectNotFound(ctx, env.Client, node)
 		})
+		It("should escalate eviction of a pod that doesn't terminate before force deleting it", func() {
+			s := test.Settings()
+			s.DrainReevictionDelay = metav1.Duration{Duration: 10 * time.Second}
+			s.DrainReevictionGracePeriod = metav1.Duration{Duration: 5 * time.Second}
+			s.DrainForceDeleteDelay = metav1.Duration{Duration: time.Minute}
+			ctx := settings.ToContext(ctx, s)
+
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			pod.Spec.TerminationGracePeriodSeconds = ptr.Int64(30)
+			start := time.Now()
+			fakeClock.SetTime(start)
+			ExpectApplied(ctx, env.Client, node, pod)
+
+			// the pod is evicted with its own grace period
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, pod)
+			Expect(lo.FromPtr(ExpectPodExists(ctx, env.Client, pod.Name, pod.Namespace).DeletionGracePeriodSeconds)).To(BeNumerically("==", 30))
+
+			// the pod ignores the eviction, so it's deleted again with a shorter grace period
+			fakeClock.SetTime(start.Add(45 * time.Second))
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNodeExists(ctx, env.Client, node.Name)
+			Expect(lo.FromPtr(ExpectPodExists(ctx, env.Client, pod.Name, pod.Namespace).DeletionGracePeriodSeconds)).To(BeNumerically("==", 5))
+
+			// the pod still hasn't terminated, but it isn't force deleted until the final stage
+			fakeClock.SetTime(start.Add(50 * time.Second))
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectPodExists(ctx, env.Client, pod.Name, pod.Namespace)
+
+			// the final stage force deletes the pod, which allows the node to be deleted
+			fakeClock.SetTime(start.Add(70 * time.Second))
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, pod)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should proceed with the drain once a pod held by its finalizers exceeds the drain finalizer timeout", func() {
+			s := test.Settings()
+			s.DrainForceDeleteDelay = metav1.Duration{Duration: time.Minute}
+			s.DrainFinalizerTimeout = metav1.Duration{Duration: 2 * time.Minute}
+			ctx := settings.ToContext(ctx, s)
+
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: defaultOwnerRefs,
+				Finalizers:      []string{"example.com/finalizer"},
+			}})
+			pod.Spec.TerminationGracePeriodSeconds = ptr.Int64(30)
+			start := time.Now()
+			fakeClock.SetTime(start)
+			ExpectApplied(ctx, env.Client, node, pod)
+			blocked := ExpectMetric("karpenter_nodes_drain_finalizer_blocked_pods_total").GetMetric()[0].GetCounter().GetValue()
+
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, pod)
+
+			// the pod is force deleted, but its finalizer keeps it around so the drain waits for it
+			fakeClock.SetTime(start.Add(100 * time.Second))
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectPodExists(ctx, env.Client, pod.Name, pod.Namespace)
+			ExpectNodeExists(ctx, env.Client, node.Name)
+
+			// once the timeout elapses the drain proceeds without the pod, which is still held by its finalizer
+			fakeClock.SetTime(start.Add(200 * time.Second))
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+			pod = ExpectPodExists(ctx, env.Client, pod.Name, pod.Namespace)
+			Expect(ExpectMetric("karpenter_nodes_drain_finalizer_blocked_pods_total").GetMetric()[0].GetCounter().GetValue()).To(BeNumerically(">", blocked))
+
+			// remove the finalizer so that the pod can be cleaned up
+			pod.Finalizers = nil
+			Expect(env.Client.Update(ctx, pod)).To(Succeed())
+		})
+		It("should force delete pods once the provisioner termination grace period elapses", func() {
+			s := test.Settings()
+			s.DrainForceDeleteDelay = metav1.Duration{Duration: 10 * time.Minute}
+			ctx := settings.ToContext(ctx, s)
+
+			provisioner := test.Provisioner(test.ProvisionerOptions{TerminationGracePeriod: &metav1.Duration{Duration: time.Minute}})
+			node = test.Node(test.NodeOptions{ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{v1alpha5.TerminationFinalizer},
+				Labels:     map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+			}})
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			pod.Spec.TerminationGracePeriodSeconds = ptr.Int64(300)
+			start := time.Now()
+			fakeClock.SetTime(start)
+			ExpectApplied(ctx, env.Client, provisioner, node, pod)
+
+			// the pod is evicted with its own grace period, which is longer than the provisioner allows
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, pod)
+
+			// within the provisioner's budget the pod is left to terminate gracefully
+			fakeClock.SetTime(start.Add(30 * time.Second))
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectPodExists(ctx, env.Client, pod.Name, pod.Namespace)
+
+			// the provisioner's budget is the outer bound, so the pod is force deleted well before its own grace period
+			// or the drain force delete delay would allow
+			fakeClock.SetTime(start.Add(90 * time.Second))
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, pod)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should only force delete the pods that the drain would evict once the provisioner termination grace period elapses", func() {
+			s := test.Settings()
+			s.DrainExclusionSelector = labels.SelectorFromSet(labels.Set{"app": "monitoring"})
+			ctx := settings.ToContext(ctx, s)
+
+			provisioner := test.Provisioner(test.ProvisionerOptions{TerminationGracePeriod: &metav1.Duration{Duration: time.Minute}})
+			node = test.Node(test.NodeOptions{ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{v1alpha5.TerminationFinalizer},
+				Labels:     map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+			}})
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			pod.Spec.TerminationGracePeriodSeconds = ptr.Int64(300)
+			excludedPod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{
+				Labels:          map[string]string{"app": "monitoring"},
+				OwnerReferences: defaultOwnerRefs,
+			}})
+			toleratingPod := test.Pod(test.PodOptions{
+				NodeName:    node.Name,
+				Tolerations: []v1.Toleration{{Key: v1.TaintNodeUnschedulable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}},
+				ObjectMeta:  metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs},
+			})
+			start := time.Now()
+			fakeClock.SetTime(start)
+			ExpectApplied(ctx, env.Client, provisioner, node, pod, excludedPod, toleratingPod)
+
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, pod)
+
+			// the evictable pod is force deleted, and the node waits for it to be gone
+			fakeClock.SetTime(start.Add(90 * time.Second))
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, pod)
+			ExpectNodeExists(ctx, env.Client, node.Name)
+
+			// the pods that the drain never evicts are left alone
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+			for _, p := range []*v1.Pod{excludedPod, toleratingPod} {
+				Expect(ExpectPodExists(ctx, env.Client, p.Name, p.Namespace).DeletionTimestamp.IsZero()).To(BeTrue())
+			}
+		})
+		It("should not force delete pods once the provisioner termination grace period elapses if a pod has the do-not-evict annotation", func() {
+			provisioner := test.Provisioner(test.ProvisionerOptions{TerminationGracePeriod: &metav1.Duration{Duration: time.Minute}})
+			node = test.Node(test.NodeOptions{ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{v1alpha5.TerminationFinalizer},
+				Labels:     map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+			}})
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			doNotEvictPod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{
+				Annotations:     map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
+				OwnerReferences: defaultOwnerRefs,
+			}})
+			start := time.Now()
+			fakeClock.SetTime(start)
+			ExpectApplied(ctx, env.Client, provisioner, node, pod, doNotEvictPod)
+
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			fakeClock.SetTime(start.Add(90 * time.Second))
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNodeExists(ctx, env.Client, node.Name)
+			for _, p := range []*v1.Pod{pod, doNotEvictPod} {
+				Expect(ExpectPodExists(ctx, env.Client, p.Name, p.Namespace).DeletionTimestamp.IsZero()).To(BeTrue())
+			}
+		})
 		It("should wait for pods to terminate", func() {
 			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
 			fakeClock.SetTime(time.Now()) // make our fake clock match the pod creation time
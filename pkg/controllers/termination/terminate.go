@@ -24,16 +24,23 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/samber/lo"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	podutil "github.com/aws/karpenter-core/pkg/utils/pod"
 )
 
+// stuckTerminatingPeriod is how long after its grace period the drain waits for a terminating pod when force deletion
+// is disabled, since a pod on a partitioned kubelet may never be reported as gone
+const stuckTerminatingPeriod = time.Minute
+
 type Terminator struct {
 	EvictionQueue *EvictionQueue
 	KubeClient    client.Client
@@ -48,13 +55,21 @@ func IsNodeDrainErr(err error) bool {
 	return errors.As(err, &nodeDrainErr)
 }
 
-// cordon cordons a node
+// cordon cordons a node and excludes it from load balancers, recording when it was first cordoned
 func (t *Terminator) cordon(ctx context.Context, node *v1.Node) error {
 	mergeFrom := client.MergeFrom(node.DeepCopy())
 	node.Spec.Unschedulable = true
-	node.Labels = lo.Assign(node.Labels, map[string]string{
-		v1.LabelNodeExcludeBalancers: "karpenter",
-	})
+	// a node that was already excluded from load balancers keeps its existing exclusion
+	if _, ok := node.Labels[v1.LabelNodeExcludeBalancers]; !ok {
+		node.Labels = lo.Assign(node.Labels, map[string]string{
+			v1.LabelNodeExcludeBalancers: "karpenter",
+		})
+	}
+	if _, ok := node.Annotations[v1alpha5.CordonTimestampAnnotationKey]; !ok {
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{
+			v1alpha5.CordonTimestampAnnotationKey: t.Clock.Now().Format(time.RFC3339),
+		})
+	}
 	if err := t.KubeClient.Patch(ctx, node, mergeFrom); err != nil {
 		return fmt.Errorf("patching node labels, %w", err)
 	}
@@ -62,6 +77,86 @@ func (t *Terminator) cordon(ctx context.Context, node *v1.Node) error {
 	return nil
 }
 
+// loadBalancerDrainDelay returns how much longer the node should wait before its pods are evicted so that load
+// balancers can deregister it. The delay applies to nodes that Karpenter excluded from load balancers and to nodes
+// hosting pods that back a LoadBalancer service, since those pods may be load balancer targets themselves.
+func (t *Terminator) loadBalancerDrainDelay(ctx context.Context, node *v1.Node) (time.Duration, error) {
+	delay := settings.FromContext(ctx).LoadBalancerDrainDelay.Duration
+	if delay <= 0 {
+		return 0, nil
+	}
+	cordoned, err := time.Parse(time.RFC3339, node.Annotations[v1alpha5.CordonTimestampAnnotationKey])
+	if err != nil {
+		return 0, fmt.Errorf("parsing cordon timestamp, %w", err)
+	}
+	remaining := delay - t.Clock.Since(cordoned)
+	if remaining <= 0 {
+		return 0, nil
+	}
+	if node.Labels[v1.LabelNodeExcludeBalancers] == "karpenter" {
+		return remaining, nil
+	}
+	backsLoadBalancer, err := t.hostsServicePods(ctx, node, func(svc *v1.Service) bool {
+		return svc.Spec.Type == v1.ServiceTypeLoadBalancer
+	})
+	if err != nil {
+		return 0, err
+	}
+	return lo.Ternary(backsLoadBalancer, remaining, 0), nil
+}
+
+// sessionAffinityDrainDelay returns how much longer the node should wait before its pods are evicted so that the
+// sticky sessions of pods behind a Service with ClientIP session affinity can migrate to other endpoints
+func (t *Terminator) sessionAffinityDrainDelay(ctx context.Context, node *v1.Node) (time.Duration, error) {
+	delay := settings.FromContext(ctx).SessionAffinityDrainDelay.Duration
+	if delay <= 0 {
+		return 0, nil
+	}
+	cordoned, err := time.Parse(time.RFC3339, node.Annotations[v1alpha5.CordonTimestampAnnotationKey])
+	if err != nil {
+		return 0, fmt.Errorf("parsing cordon timestamp, %w", err)
+	}
+	remaining := delay - t.Clock.Since(cordoned)
+	if remaining <= 0 {
+		return 0, nil
+	}
+	backsStickyService, err := t.hostsServicePods(ctx, node, func(svc *v1.Service) bool {
+		return svc.Spec.SessionAffinity == v1.ServiceAffinityClientIP
+	})
+	if err != nil {
+		return 0, err
+	}
+	return lo.Ternary(backsStickyService, remaining, 0), nil
+}
+
+// hostsServicePods returns true if any of the pods on the node are selected by a service that matches
+func (t *Terminator) hostsServicePods(ctx context.Context, node *v1.Node, matches func(*v1.Service) bool) (bool, error) {
+	pods, err := t.getPods(ctx, node)
+	if err != nil {
+		return false, err
+	}
+	if len(pods) == 0 {
+		return false, nil
+	}
+	serviceList := &v1.ServiceList{}
+	if err := t.KubeClient.List(ctx, serviceList); err != nil {
+		return false, fmt.Errorf("listing services, %w", err)
+	}
+	for i := range serviceList.Items {
+		svc := &serviceList.Items[i]
+		if !matches(svc) || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		if lo.SomeBy(pods, func(p *v1.Pod) bool {
+			return p.Namespace == svc.Namespace && selector.Matches(labels.Set(p.Labels))
+		}) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // drain evicts pods from the node and returns true when all pods are evicted
 // https://kubernetes.io/docs/concepts/architecture/nodes/#graceful-node-shutdown
 func (t *Terminator) drain(ctx context.Context, node *v1.Node) error {
@@ -70,16 +165,37 @@ func (t *Terminator) drain(ctx context.Context, node *v1.Node) error {
 	if err != nil {
 		return fmt.Errorf("listing pods for node, %w", err)
 	}
+	exceeded, err := t.drainBudgetExceeded(ctx, node)
+	if err != nil {
+		return err
+	}
+	if exceeded {
+		return t.forceDelete(ctx, pods)
+	}
 	var podsToEvict []*v1.Pod
 	// Skip node due to pods that are not able to be evicted
 	for _, p := range pods {
-		if podutil.HasDoNotEvict(p) {
+		// Ignore if the pod was force deleted or is stuck after not terminating within the escalation ladder
+		abandoned, err := t.escalate(ctx, p)
+		if err != nil {
+			return err
+		}
+		// A force deleted pod is gone unless its finalizers are holding it, in which case we wait for them for a bounded
+		// time rather than letting the pod hang the whole drain
+		if abandoned && !t.awaitingFinalizers(ctx, p) {
+			continue
+		}
+		if podutil.HasDoNotEvict(ctx, p) {
 			return NodeDrainErr(fmt.Errorf("pod %s/%s has do-not-evict annotation", p.Namespace, p.Name))
 		}
 		// Ignore if unschedulable is tolerated, since they will reschedule
 		if podutil.ToleratesUnschedulableTaint(p) {
 			continue
 		}
+		// Ignore pods that are excluded from drain, since they're never evicted
+		if podutil.IsDrainExcluded(ctx, p) {
+			continue
+		}
 		// Ignore static mirror pods
 		if podutil.IsOwnedByNode(p) {
 			continue
@@ -91,6 +207,51 @@ func (t *Terminator) drain(ctx context.Context, node *v1.Node) error {
 	return lo.Ternary(len(podsToEvict) > 0, NodeDrainErr(fmt.Errorf("%d pods are waiting to be evicted", len(podsToEvict))), nil)
 }
 
+// drainBudgetExceeded returns true if the node has been draining for longer than the termination grace period of the
+// provisioner that owns it. This budget is the outer bound on draining, so it takes precedence over the per-pod
+// escalation ladder.
+func (t *Terminator) drainBudgetExceeded(ctx context.Context, node *v1.Node) (bool, error) {
+	name, ok := node.Labels[v1alpha5.ProvisionerNameLabelKey]
+	if !ok || node.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+	provisioner := &v1alpha5.Provisioner{}
+	if err := t.KubeClient.Get(ctx, types.NamespacedName{Name: name}, provisioner); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	if provisioner.Spec.TerminationGracePeriod == nil {
+		return false, nil
+	}
+	return t.Clock.Since(node.DeletionTimestamp.Time) > provisioner.Spec.TerminationGracePeriod.Duration, nil
+}
+
+// forceDelete deletes the pods without waiting for them to terminate gracefully. Pods are skipped as drain skips them,
+// so static mirror pods, daemonset pods and pods that tolerate the unschedulable taint go away along with the node, drain
+// excluded pods are never deleted and do-not-evict pods block the drain. Force deleted pods that are held by their
+// finalizers are waited for as long as the drain waits for them.
+func (t *Terminator) forceDelete(ctx context.Context, pods []*v1.Pod) error {
+	for _, p := range pods {
+		if podutil.HasDoNotEvict(ctx, p) {
+			return NodeDrainErr(fmt.Errorf("pod %s/%s has do-not-evict annotation", p.Namespace, p.Name))
+		}
+	}
+	var deleted int
+	for _, p := range pods {
+		if podutil.ToleratesUnschedulableTaint(p) || podutil.IsDrainExcluded(ctx, p) || podutil.IsOwnedByDaemonSet(p) || podutil.IsOwnedByNode(p) {
+			continue
+		}
+		if lo.FromPtr(p.DeletionGracePeriodSeconds) == 0 && !p.DeletionTimestamp.IsZero() && !t.awaitingFinalizers(ctx, p) {
+			continue
+		}
+		if err := t.KubeClient.Delete(ctx, p, client.GracePeriodSeconds(0)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("force deleting pod, %w", err)
+		}
+		logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(p)).Infof("force deleted pod after exceeding the provisioner termination grace period")
+		deleted++
+	}
+	return lo.Ternary(deleted > 0, NodeDrainErr(fmt.Errorf("%d pods are being force deleted", deleted)), nil)
+}
+
 // terminate calls cloud provider delete then removes the finalizer to delete the node
 func (t *Terminator) terminate(ctx context.Context, node *v1.Node) error {
 	// Delete the instance associated with node
@@ -118,10 +279,6 @@ func (t *Terminator) getPods(ctx context.Context, node *v1.Node) ([]*v1.Pod, err
 		if podutil.IsTerminal(lo.ToPtr(p)) {
 			continue
 		}
-		// Ignore if kubelet is partitioned and pods are beyond graceful termination window
-		if t.isStuckTerminating(lo.ToPtr(p)) {
-			continue
-		}
 		pods = append(pods, lo.ToPtr(p))
 	}
 	return pods, nil
@@ -145,13 +302,62 @@ func (t *Terminator) evict(pods []*v1.Pod) {
 	if len(nonCritical) == 0 {
 		t.EvictionQueue.Add(critical)
 	} else {
-		t.EvictionQueue.Add(nonCritical)
+		// 3. Evict the lowest priority noncritical pods first, so that higher priority pods have the most time to reschedule
+		t.EvictionQueue.Add(lowestPriority(nonCritical))
 	}
 }
 
-func (t *Terminator) isStuckTerminating(pod *v1.Pod) bool {
-	if pod.DeletionTimestamp == nil {
+// lowestPriority returns the pods that share the lowest priority of the given pods
+func lowestPriority(pods []*v1.Pod) []*v1.Pod {
+	priority := func(p *v1.Pod) int32 { return lo.FromPtr(p.Spec.Priority) }
+	lowest := priority(lo.MinBy(pods, func(a, b *v1.Pod) bool { return priority(a) < priority(b) }))
+	return lo.Filter(pods, func(p *v1.Pod, _ int) bool { return priority(p) == lowest })
+}
+
+// awaitingFinalizers returns true if a terminating pod is held by its finalizers and hasn't yet exceeded the drain
+// finalizer timeout
+func (t *Terminator) awaitingFinalizers(ctx context.Context, pod *v1.Pod) bool {
+	if len(pod.Finalizers) == 0 || pod.DeletionTimestamp.IsZero() {
 		return false
 	}
-	return t.Clock.Now().After(pod.DeletionTimestamp.Time.Add(1 * time.Minute))
+	if t.Clock.Since(pod.DeletionTimestamp.Time) <= settings.FromContext(ctx).DrainFinalizerTimeout.Duration {
+		return true
+	}
+	logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(pod), "finalizers", pod.Finalizers).
+		Infof("proceeding with drain without waiting for pod held by its finalizers")
+	drainFinalizerBlockedPodsCounter.Inc()
+	return false
+}
+
+// escalate moves a terminating pod that hasn't gone away after its grace period (e.g. because the kubelet is
+// partitioned or the pod ignores its eviction) up the escalation ladder. It's first deleted again with a shorter grace
+// period, and is finally force deleted if force deletion is enabled. Otherwise the drain stops waiting for the pod once
+// it has been stuck terminating for a minute. It returns true if the drain no longer waits for the pod.
+func (t *Terminator) escalate(ctx context.Context, pod *v1.Pod) (bool, error) {
+	if pod.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+	s := settings.FromContext(ctx)
+	overdue := t.Clock.Since(pod.DeletionTimestamp.Time)
+	// a pod with finalizers that's already been deleted without a grace period is only still around because of them,
+	// so deleting it again won't help
+	if len(pod.Finalizers) > 0 && pod.DeletionGracePeriodSeconds != nil && *pod.DeletionGracePeriodSeconds == 0 {
+		return true, nil
+	}
+	if s.DrainForceDeleteDelay.Duration > 0 && overdue > s.DrainForceDeleteDelay.Duration {
+		if err := t.KubeClient.Delete(ctx, pod, client.GracePeriodSeconds(0)); client.IgnoreNotFound(err) != nil {
+			return false, fmt.Errorf("force deleting pod, %w", err)
+		}
+		logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(pod)).Infof("force deleted pod")
+		return true, nil
+	}
+	gracePeriodSeconds := int64(s.DrainReevictionGracePeriod.Seconds())
+	if s.DrainReevictionDelay.Duration > 0 && overdue > s.DrainReevictionDelay.Duration &&
+		(pod.DeletionGracePeriodSeconds == nil || *pod.DeletionGracePeriodSeconds > gracePeriodSeconds) {
+		if err := t.KubeClient.Delete(ctx, pod, client.GracePeriodSeconds(gracePeriodSeconds)); client.IgnoreNotFound(err) != nil {
+			return false, fmt.Errorf("deleting pod with a shorter grace period, %w", err)
+		}
+		logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(pod)).Infof("deleted pod with a grace period of %ds", gracePeriodSeconds)
+	}
+	return s.DrainForceDeleteDelay.Duration == 0 && overdue > stuckTerminatingPeriod, nil
 }
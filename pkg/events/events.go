@@ -16,6 +16,7 @@ package events
 
 import (
 	"fmt"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/flowcontrol"
@@ -65,6 +66,16 @@ func NodeFailedToDrain(node *v1.Node, err error) Event {
 	}
 }
 
+func NodeTerminationExceededLimit(node *v1.Node, elapsed time.Duration, limit time.Duration) Event {
+	return Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeWarning,
+		Reason:         "TerminationExceededLimit",
+		Message:        fmt.Sprintf("Node has been terminating for %s, exceeding the limit of %s", elapsed.Round(time.Second), limit),
+		DedupeValues:   []string{node.Name},
+	}
+}
+
 func NodeInflightCheck(node *v1.Node, message string) Event {
 	return Event{
 		InvolvedObject: node,
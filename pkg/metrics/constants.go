@@ -32,7 +32,9 @@ const (
 	ConsolidationReason  = "consolidation"
 	ProvisioningReason   = "provisioning"
 	ExpirationReason     = "expiration"
+	DriftReason          = "drift"
 	EmptinessReason      = "emptiness"
+	NotReadyReason       = "not-ready"
 )
 
 // DurationBuckets returns a []float64 of default threshold values for duration histograms.
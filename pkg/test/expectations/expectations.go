@@ -90,6 +90,23 @@ func ExpectNotFoundWithOffset(offset int, ctx context.Context, c client.Client,
 	}
 }
 
+// ExpectProvisionerNodesRemoved asserts that all nodes launched by the named provisioner have been deleted
+func ExpectProvisionerNodesRemoved(ctx context.Context, c client.Client, provisionerName string) {
+	ExpectProvisionerNodesRemovedWithOffset(1, ctx, c, provisionerName)
+}
+
+func ExpectProvisionerNodesRemovedWithOffset(offset int, ctx context.Context, c client.Client, provisionerName string) {
+	EventuallyWithOffset(offset+1, func() []string {
+		nodes := &v1.NodeList{}
+		ExpectWithOffset(offset+2, c.List(ctx, nodes, client.MatchingLabels{v1alpha5.ProvisionerNameLabelKey: provisionerName})).To(Succeed())
+		var names []string
+		for _, node := range nodes.Items {
+			names = append(names, node.Name)
+		}
+		return names
+	}, ReconcilerPropagationTime, RequestInterval).Should(BeEmpty(), fmt.Sprintf("expected all nodes for provisioner %s to be deleted", provisionerName))
+}
+
 func ExpectScheduled(ctx context.Context, c client.Client, pod *v1.Pod) *v1.Node {
 	p := ExpectPodExistsWithOffset(1, ctx, c, pod.Name, pod.Namespace)
 	Expect(p.Spec.NodeName).ToNot(BeEmpty(), fmt.Sprintf("expected %s/%s to be scheduled", pod.Namespace, pod.Name))
@@ -31,6 +31,7 @@ type PodOptions struct {
 	InitImage                     string
 	NodeName                      string
 	PriorityClassName             string
+	Priority                      *int32
 	InitResourceRequirements      v1.ResourceRequirements
 	ResourceRequirements          v1.ResourceRequirements
 	NodeSelector                  map[string]string
@@ -92,6 +93,7 @@ func Pod(overrides ...PodOptions) *v1.Pod {
 			NodeName:                      options.NodeName,
 			Volumes:                       volumes,
 			PriorityClassName:             options.PriorityClassName,
+			Priority:                      options.Priority,
 			RestartPolicy:                 options.RestartPolicy,
 			TerminationGracePeriodSeconds: options.TerminationGracePeriodSeconds,
 		},
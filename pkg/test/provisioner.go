@@ -32,20 +32,26 @@ import (
 // ProvisionerOptions customizes a Provisioner.
 type ProvisionerOptions struct {
 	metav1.ObjectMeta
-	Limits                 v1.ResourceList
-	Provider               interface{}
-	ProviderRef            *v1alpha5.ProviderRef
-	Kubelet                *v1alpha5.KubeletConfiguration
-	Annotations            map[string]string
-	Labels                 map[string]string
-	Taints                 []v1.Taint
-	StartupTaints          []v1.Taint
-	Requirements           []v1.NodeSelectorRequirement
-	Status                 v1alpha5.ProvisionerStatus
-	TTLSecondsUntilExpired *int64
-	Weight                 *int32
-	TTLSecondsAfterEmpty   *int64
-	Consolidation          *v1alpha5.Consolidation
+	Limits                  v1.ResourceList
+	Provider                interface{}
+	ProviderRef             *v1alpha5.ProviderRef
+	Kubelet                 *v1alpha5.KubeletConfiguration
+	Annotations             map[string]string
+	Labels                  map[string]string
+	Taints                  []v1.Taint
+	StartupTaints           []v1.Taint
+	Requirements            []v1.NodeSelectorRequirement
+	Status                  v1alpha5.ProvisionerStatus
+	TTLSecondsUntilExpired  *int64
+	ExpirationOrder         v1alpha5.ExpirationOrder
+	ExpirationJitterFactor  *float64
+	TerminationGracePeriod  *metav1.Duration
+	Weight                  *int32
+	TTLSecondsAfterEmpty    *int64
+	TTLSecondsAfterNotReady *int64
+	Consolidation           *v1alpha5.Consolidation
+	Disruption              *v1alpha5.Disruption
+	Expiration              *v1alpha5.Expiration
 }
 
 // Provisioner creates a test provisioner with defaults that can be overridden by ProvisionerOptions.
@@ -69,19 +75,25 @@ func Provisioner(overrides ...ProvisionerOptions) *v1alpha5.Provisioner {
 	provisioner := &v1alpha5.Provisioner{
 		ObjectMeta: ObjectMeta(options.ObjectMeta),
 		Spec: v1alpha5.ProvisionerSpec{
-			Requirements:           options.Requirements,
-			KubeletConfiguration:   options.Kubelet,
-			ProviderRef:            options.ProviderRef,
-			Taints:                 options.Taints,
-			StartupTaints:          options.StartupTaints,
-			Annotations:            options.Annotations,
-			Labels:                 lo.Assign(options.Labels, map[string]string{DiscoveryLabel: "unspecified"}), // For node cleanup discovery
-			Limits:                 &v1alpha5.Limits{Resources: options.Limits},
-			TTLSecondsAfterEmpty:   options.TTLSecondsAfterEmpty,
-			TTLSecondsUntilExpired: options.TTLSecondsUntilExpired,
-			Weight:                 options.Weight,
-			Consolidation:          options.Consolidation,
-			Provider:               raw,
+			Requirements:            options.Requirements,
+			KubeletConfiguration:    options.Kubelet,
+			ProviderRef:             options.ProviderRef,
+			Taints:                  options.Taints,
+			StartupTaints:           options.StartupTaints,
+			Annotations:             options.Annotations,
+			Labels:                  lo.Assign(options.Labels, map[string]string{DiscoveryLabel: "unspecified"}), // For node cleanup discovery
+			Limits:                  &v1alpha5.Limits{Resources: options.Limits},
+			TTLSecondsAfterEmpty:    options.TTLSecondsAfterEmpty,
+			TTLSecondsAfterNotReady: options.TTLSecondsAfterNotReady,
+			TTLSecondsUntilExpired:  options.TTLSecondsUntilExpired,
+			ExpirationOrder:         options.ExpirationOrder,
+			ExpirationJitterFactor:  options.ExpirationJitterFactor,
+			TerminationGracePeriod:  options.TerminationGracePeriod,
+			Weight:                  options.Weight,
+			Consolidation:           options.Consolidation,
+			Disruption:              options.Disruption,
+			Expiration:              options.Expiration,
+			Provider:                raw,
 		},
 		Status: options.Status,
 	}
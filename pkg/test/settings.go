@@ -38,7 +38,16 @@ func (ss SettingsStore) InjectSettings(ctx context.Context) context.Context {
 
 func Settings() settings.Settings {
 	return settings.Settings{
-		BatchMaxDuration:  metav1.Duration{Duration: time.Second * 10},
-		BatchIdleDuration: metav1.Duration{Duration: time.Second},
+		BatchMaxDuration:               metav1.Duration{Duration: time.Second * 10},
+		BatchIdleDuration:              metav1.Duration{Duration: time.Second},
+		ConsolidationOscillationWindow: metav1.Duration{Duration: time.Hour},
+		ConsolidationPolicy:            settings.ConsolidationPolicyDeleteOrReplace,
+		ConsolidationScaleUpThreshold:  10,
+		DrainFinalizerTimeout:          metav1.Duration{Duration: time.Minute * 5},
+		EvictionQoSFactors:             settings.EvictionQoSFactors{Guaranteed: 1.5, Burstable: 1, BestEffort: 0.75},
+		IdleUsageThreshold:             0.05,
+		OwnerDisruptionWindow:          metav1.Duration{Duration: time.Hour},
+		SimulationConcurrency:          1,
+		StatefulSetEvictionCostFactor:  2,
 	}
 }
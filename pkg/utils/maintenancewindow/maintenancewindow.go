@@ -0,0 +1,119 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenancewindow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Window is a daily range of times, in UTC, on the given days of the week
+type Window struct {
+	// Days are the days of the week that the window starts on, and the window starts every day if it's empty
+	Days []time.Weekday
+	// Start and End are the offsets from midnight that the window starts and ends at
+	Start time.Duration
+	End   time.Duration
+}
+
+// Contains returns true if the time is within the window
+func (w Window) Contains(t time.Time) bool {
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := t.Sub(midnight)
+	startsOn := func(day time.Weekday) bool {
+		return len(w.Days) == 0 || lo.Contains(w.Days, day)
+	}
+	if w.Start < w.End {
+		return startsOn(t.Weekday()) && offset >= w.Start && offset < w.End
+	}
+	// the window continues into the next day
+	return (startsOn(t.Weekday()) && offset >= w.Start) || (startsOn((t.Weekday()+6)%7) && offset < w.End)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Parse parses a single maintenance window, e.g. "Mon-Fri 22:00-04:00" or "02:00-06:00". Times are UTC.
+func Parse(raw string) (Window, error) {
+	window := Window{}
+	fields := strings.Fields(raw)
+	if len(fields) == 0 || len(fields) > 2 {
+		return window, fmt.Errorf("%q is not a maintenance window", raw)
+	}
+	if len(fields) == 2 {
+		first, last, isRange := strings.Cut(strings.ToLower(fields[0]), "-")
+		if !isRange {
+			last = first
+		}
+		firstDay, ok := weekdays[first]
+		if !ok {
+			return window, fmt.Errorf("%q is not a day of the week", first)
+		}
+		lastDay, ok := weekdays[last]
+		if !ok {
+			return window, fmt.Errorf("%q is not a day of the week", last)
+		}
+		for day := firstDay; ; day = (day + 1) % 7 {
+			window.Days = append(window.Days, day)
+			if day == lastDay {
+				break
+			}
+		}
+	}
+	start, end, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return window, fmt.Errorf("%q is not a range of times", fields[len(fields)-1])
+	}
+	var err error
+	if window.Start, err = parseTimeOfDay(start); err != nil {
+		return window, err
+	}
+	if window.End, err = parseTimeOfDay(end); err != nil {
+		return window, err
+	}
+	if window.Start == window.End {
+		return window, fmt.Errorf("%q is an empty range of times", fields[len(fields)-1])
+	}
+	return window, nil
+}
+
+// parseTimeOfDay parses a time of day in HH:MM format into its offset from midnight, allowing 24:00 for midnight at
+// the end of the day
+func parseTimeOfDay(raw string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(raw, ":")
+	if !ok {
+		return 0, fmt.Errorf("%q is not a time of day", raw)
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day", raw)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day", raw)
+	}
+	offset := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute
+	if h < 0 || m < 0 || m > 59 || offset > 24*time.Hour {
+		return 0, fmt.Errorf("%q is not a time of day", raw)
+	}
+	return offset, nil
+}
@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenancewindow
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MaintenanceWindow Suite")
+}
+
+var _ = Describe("Maintenance Window", func() {
+	// 2022-10-03 is a Monday
+	monday := time.Date(2022, time.October, 3, 0, 0, 0, 0, time.UTC)
+	It("should contain times within a daily window", func() {
+		w := Window{Start: 2 * time.Hour, End: 6 * time.Hour}
+		Expect(w.Contains(monday.Add(2 * time.Hour))).To(BeTrue())
+		Expect(w.Contains(monday.Add(5*time.Hour + 59*time.Minute))).To(BeTrue())
+		Expect(w.Contains(monday.Add(6 * time.Hour))).To(BeFalse())
+		Expect(w.Contains(monday.Add(time.Hour))).To(BeFalse())
+	})
+	It("should only contain times on the window's days", func() {
+		w := Window{Days: []time.Weekday{time.Saturday}, Start: 0, End: 24 * time.Hour}
+		Expect(w.Contains(monday.Add(12 * time.Hour))).To(BeFalse())
+		Expect(w.Contains(monday.AddDate(0, 0, 5).Add(12 * time.Hour))).To(BeTrue())
+	})
+	It("should continue a window whose end is before its start into the next day", func() {
+		w := Window{Days: []time.Weekday{time.Friday}, Start: 22 * time.Hour, End: 4 * time.Hour}
+		friday := monday.AddDate(0, 0, 4)
+		Expect(w.Contains(friday.Add(23 * time.Hour))).To(BeTrue())
+		Expect(w.Contains(friday.AddDate(0, 0, 1).Add(3 * time.Hour))).To(BeTrue())
+		Expect(w.Contains(friday.Add(3 * time.Hour))).To(BeFalse())
+	})
+	It("should parse a window on a range of days", func() {
+		w, err := Parse("Fri-Mon 22:00-04:00")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w).To(Equal(Window{
+			Days:  []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday},
+			Start: 22 * time.Hour,
+			End:   4 * time.Hour,
+		}))
+	})
+	It("should fail to parse a window that isn't a range of times", func() {
+		_, err := Parse("0 2 * * *")
+		Expect(err).To(HaveOccurred())
+		_, err = Parse("Mon 02:00-02:00")
+		Expect(err).To(HaveOccurred())
+		_, err = Parse("Mon 02:00-25:00")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -15,9 +15,15 @@ limitations under the License.
 package pod
 
 import (
+	"context"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"github.com/aws/karpenter-core/pkg/apis/config/settings"
 	"github.com/aws/karpenter-core/pkg/apis/provisioning/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 )
@@ -39,6 +45,20 @@ func FailedToSchedule(pod *v1.Pod) bool {
 	return false
 }
 
+// PodReasonSchedulingGated is the reason of the PodScheduled condition that the scheduler sets on a pod while it has
+// scheduling gates, which aren't part of the pod spec in the API version that we build against
+const PodReasonSchedulingGated = "SchedulingGated"
+
+// IsSchedulingGated returns true if the pod has scheduling gates that must be removed before it can be scheduled
+func IsSchedulingGated(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Reason == PodReasonSchedulingGated {
+			return true
+		}
+	}
+	return false
+}
+
 func IsScheduled(pod *v1.Pod) bool {
 	return pod.Spec.NodeName != ""
 }
@@ -55,6 +75,16 @@ func IsTerminating(pod *v1.Pod) bool {
 	return pod.DeletionTimestamp != nil
 }
 
+// IsReady returns true if the pod's PodReady condition is true
+func IsReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
 func IsOwnedByDaemonSet(pod *v1.Pod) bool {
 	return IsOwnedBy(pod, []schema.GroupVersionKind{
 		{Group: "apps", Version: "v1", Kind: "DaemonSet"},
@@ -79,13 +109,57 @@ func IsOwnedBy(pod *v1.Pod, gvks []schema.GroupVersionKind) bool {
 	return false
 }
 
-func HasDoNotEvict(pod *v1.Pod) bool {
+// SafeToEvictAnnotationKey is the cluster-autoscaler annotation that marks whether or not a pod can be evicted
+const SafeToEvictAnnotationKey = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+
+// HasDoNotEvict returns true if the pod has opted out of eviction. If the safe-to-evict annotation is honored, a
+// value of "false" prevents eviction and a value of "true" allows eviction even with the do-not-evict annotation.
+func HasDoNotEvict(ctx context.Context, pod *v1.Pod) bool {
 	if pod.Annotations == nil {
 		return false
 	}
+	if settings.FromContext(ctx).HonorSafeToEvictAnnotation {
+		switch pod.Annotations[SafeToEvictAnnotationKey] {
+		case "false":
+			return true
+		case "true":
+			return false
+		}
+	}
 	return pod.Annotations[v1alpha5.DoNotEvictPodAnnotationKey] == "true"
 }
 
+// IsDrainExcluded returns true if the pod is selected by the drain exclusion selector, so it's never evicted
+func IsDrainExcluded(ctx context.Context, pod *v1.Pod) bool {
+	selector := settings.FromContext(ctx).DrainExclusionSelector
+	return selector != nil && !selector.Empty() && selector.Matches(labels.Set(pod.Labels))
+}
+
+// IsCrashLooping returns true if one of the pod's containers is in CrashLoopBackOff and the pod hasn't been ready
+// since the given time, so it's providing no service
+func IsCrashLooping(pod *v1.Pod, since time.Time) bool {
+	crashLooping := false
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" {
+			crashLooping = true
+			break
+		}
+	}
+	if !crashLooping {
+		return false
+	}
+	notReadySince := pod.CreationTimestamp.Time
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			if condition.Status == v1.ConditionTrue {
+				return false
+			}
+			notReadySince = condition.LastTransitionTime.Time
+		}
+	}
+	return !notReadySince.After(since)
+}
+
 // HasUnschedulableToleration returns true if the pod tolerates node.kubernetes.io/unschedulable taint
 func ToleratesUnschedulableTaint(pod *v1.Pod) bool {
 	return (scheduling.Taints{{Key: v1.TaintNodeUnschedulable, Effect: v1.TaintEffectNoSchedule}}).Tolerates(pod) == nil
@@ -98,6 +172,101 @@ func HasRequiredPodAntiAffinity(pod *v1.Pod) bool {
 		len(pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) != 0
 }
 
+// HasSpreadCompletionIndex returns true if the pod belongs to an indexed Job and spreads its completion indexes across
+// topology domains with topology spread constraints or required pod anti-affinity
+func HasSpreadCompletionIndex(pod *v1.Pod) bool {
+	if _, ok := pod.Annotations[batchv1.JobCompletionIndexAnnotation]; !ok {
+		return false
+	}
+	return len(pod.Spec.TopologySpreadConstraints) != 0 || HasRequiredPodAntiAffinity(pod)
+}
+
+// QOSClass returns the pod's QoS class, computing it from the requests and limits of its containers if the API server
+// hasn't set it. Missing requests default to their limits, as they would when the pod is created.
+func QOSClass(pod *v1.Pod) v1.PodQOSClass {
+	if pod.Status.QOSClass != "" {
+		return pod.Status.QOSClass
+	}
+	isBestEffort, isGuaranteed := true, true
+	for _, c := range append(append([]v1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		for _, resourceName := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			limit, hasLimit := c.Resources.Limits[resourceName]
+			hasLimit = hasLimit && !limit.IsZero()
+			request, hasRequest := c.Resources.Requests[resourceName]
+			hasRequest = hasRequest && !request.IsZero()
+			if !hasRequest && hasLimit {
+				request, hasRequest = limit, true
+			}
+			if hasRequest || hasLimit {
+				isBestEffort = false
+			}
+			if !hasLimit || request.Cmp(limit) != 0 {
+				isGuaranteed = false
+			}
+		}
+	}
+	switch {
+	case isBestEffort:
+		return v1.PodQOSBestEffort
+	case isGuaranteed:
+		return v1.PodQOSGuaranteed
+	default:
+		return v1.PodQOSBurstable
+	}
+}
+
+// HasZonalConstraints returns true if the pod's node selector, node affinity, pod affinity, pod anti-affinity or
+// topology spread constraints refer to the topology zone
+func HasZonalConstraints(pod *v1.Pod) bool {
+	if _, ok := pod.Spec.NodeSelector[v1.LabelTopologyZone]; ok {
+		return true
+	}
+	for _, tsc := range pod.Spec.TopologySpreadConstraints {
+		if tsc.TopologyKey == v1.LabelTopologyZone {
+			return true
+		}
+	}
+	affinity := pod.Spec.Affinity
+	if affinity == nil {
+		return false
+	}
+	if affinity.NodeAffinity != nil {
+		var terms []v1.NodeSelectorTerm
+		if affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+			terms = append(terms, affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms...)
+		}
+		for _, term := range affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			terms = append(terms, term.Preference)
+		}
+		for _, term := range terms {
+			for _, req := range term.MatchExpressions {
+				if req.Key == v1.LabelTopologyZone {
+					return true
+				}
+			}
+		}
+	}
+	var podTerms []v1.PodAffinityTerm
+	if affinity.PodAffinity != nil {
+		podTerms = append(podTerms, affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution...)
+		for _, term := range affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			podTerms = append(podTerms, term.PodAffinityTerm)
+		}
+	}
+	if affinity.PodAntiAffinity != nil {
+		podTerms = append(podTerms, affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution...)
+		for _, term := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			podTerms = append(podTerms, term.PodAffinityTerm)
+		}
+	}
+	for _, term := range podTerms {
+		if term.TopologyKey == v1.LabelTopologyZone {
+			return true
+		}
+	}
+	return false
+}
+
 // HasPodAntiAffinity returns true if a non-empty PodAntiAffinity is defined in the pod spec
 func HasPodAntiAffinity(pod *v1.Pod) bool {
 	return pod.Spec.Affinity != nil && pod.Spec.Affinity.PodAntiAffinity != nil &&
@@ -74,7 +74,7 @@ func Subtract(lhs, rhs v1.ResourceList) v1.ResourceList {
 	return result
 }
 
-// Ceiling calculates the max between the sum of container resources and max of initContainers
+// Ceiling calculates the max between the sum of container resources and max of initContainers, plus the pod overhead
 func Ceiling(pod *v1.Pod) v1.ResourceRequirements {
 	var resources v1.ResourceRequirements
 	for _, container := range pod.Spec.Containers {
@@ -85,6 +85,17 @@ func Ceiling(pod *v1.Pod) v1.ResourceRequirements {
 		resources.Requests = MaxResources(resources.Requests, MergeResourceLimitsIntoRequests(container))
 		resources.Limits = MaxResources(resources.Limits, container.Resources.Limits)
 	}
+	// the overhead of the pod's RuntimeClass is consumed in addition to the container requests, and is added to any
+	// limits that are set in the same way that the kubelet accounts for it
+	if len(pod.Spec.Overhead) > 0 {
+		resources.Requests = Merge(resources.Requests, pod.Spec.Overhead)
+		for resourceName, quantity := range pod.Spec.Overhead {
+			if limit, ok := resources.Limits[resourceName]; ok {
+				limit.Add(quantity)
+				resources.Limits[resourceName] = limit
+			}
+		}
+	}
 	return resources
 }
 